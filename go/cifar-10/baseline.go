@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang/internal/metricslog"
+)
+
+// flattenImages copies the images into one contiguous buffer, in order
+func flattenImages(images [][]float32) []float32 {
+	size := 0
+	for _, image := range images {
+		size += len(image)
+	}
+	pixels := make([]float32, 0, size)
+	for _, image := range images {
+		pixels = append(pixels, image...)
+	}
+	return pixels
+}
+
+// checksum sums the pixel values in order, so two layouts of the same data processed the same
+// way give the identical result
+func checksum(pixels []float32) float64 {
+	var sum float64
+	for _, p := range pixels {
+		sum += float64(p)
+	}
+	return sum
+}
+
+// RunSequentialTask processes the batches of RunProcessingTask one after another on the calling
+// goroutine: the same batches, ImageBatch values and per-image kernel calls, without concurrency.
+// Concurrency overhead and spawn time are zero.
+func RunSequentialTask(cfg BenchmarkConfig, images [][]float32, labels []int) (executionTime, concurrencyOverhead, goroutineSpawnDuration time.Duration, imagesProcessed, pixelsProcessed int) {
+	numBatches := len(images) / cfg.BatchSize
+	start := time.Now()
+	for i := 0; i < numBatches; i++ {
+		begin, end := i*cfg.BatchSize, (i+1)*cfg.BatchSize
+		var wg sync.WaitGroup
+		wg.Add(1)
+		ProcessBatch(cfg, ImageBatch{Images: images[begin:end], Labels: labels[begin:end]}, &wg)
+	}
+	executionTime = time.Since(start)
+	imagesProcessed = numBatches * cfg.BatchSize
+	pixelsProcessed = imagesProcessed * cfg.ImageHeight * cfg.ImageWidth
+	return executionTime, 0, 0, imagesProcessed, pixelsProcessed
+}
+
+// RunBareLoop doubles every value of the contiguous pixels buffer in a single loop, with no
+// batches, ImageBatch values or per-image calls: the single-core ceiling for the double kernel
+func RunBareLoop(cfg BenchmarkConfig, pixels []float32) (executionTime, concurrencyOverhead, goroutineSpawnDuration time.Duration, imagesProcessed, pixelsProcessed int) {
+	start := time.Now()
+	for i := range pixels {
+		pixels[i] *= 2
+	}
+	executionTime = time.Since(start)
+	imagesProcessed = len(pixels) / cfg.ImageSize()
+	pixelsProcessed = imagesProcessed * cfg.ImageHeight * cfg.ImageWidth
+	return executionTime, 0, 0, imagesProcessed, pixelsProcessed
+}
+
+// baselineSummary holds the measured runs of the single-core baselines
+type baselineSummary struct {
+	Sequential runSummary // The harness on one goroutine
+	BareLoop   runSummary // The kernel alone over the contiguous buffer
+}
+
+// runBaselines measures the sequential harness and the bare loop over the full batches of images
+// with the same instrumentation and run counts as the benchmark, and logs how much of the
+// sequential time the harness itself costs. Only the double kernel has a bare loop.
+func runBaselines(cfg BenchmarkConfig, logger *metricslog.Logger, images [][]float32, labels []int) (baselineSummary, error) {
+	if cfg.Kernel != KernelDouble {
+		return baselineSummary{}, fmt.Errorf("the bare loop baseline only implements the %q kernel, got %q", KernelDouble, cfg.Kernel)
+	}
+	images = images[:len(images)/cfg.BatchSize*cfg.BatchSize]
+	pixels := flattenImages(images)
+
+	var baselines baselineSummary
+	var err error
+	logger.Printf("\nBaseline: sequential harness (1 goroutine)")
+	baselines.Sequential, err = runBenchmark(cfg, logger, func() (runResult, error) {
+		return measureTask(1, func() (time.Duration, time.Duration, time.Duration, int, int) {
+			return RunSequentialTask(cfg, images, labels)
+		})
+	})
+	if err != nil {
+		return baselines, err
+	}
+	logger.Printf("\nBaseline: bare loop over the contiguous buffer (1 goroutine)")
+	baselines.BareLoop, err = runBenchmark(cfg, logger, func() (runResult, error) {
+		return measureTask(1, func() (time.Duration, time.Duration, time.Duration, int, int) {
+			return RunBareLoop(cfg, pixels)
+		})
+	})
+	if err != nil {
+		return baselines, err
+	}
+
+	sequential, bare := baselines.Sequential.Total, baselines.BareLoop.Total
+	logger.Printf("\nSingle-core Ceiling (bare loop): %.2f images/second", throughput(bare.ImagesProcessed, bare.ExecutionTime))
+	logger.Printf("Sequential Harness: %.2f images/second", throughput(sequential.ImagesProcessed, sequential.ExecutionTime))
+	if bare.ExecutionTime > 0 {
+		overhead := float64(sequential.ExecutionTime-bare.ExecutionTime) / float64(bare.ExecutionTime)
+		logger.Printf("Harness Overhead: %.2f%% over the bare loop", overhead*100)
+	}
+	return baselines, nil
+}
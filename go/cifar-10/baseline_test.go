@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+// copyImages returns a deep copy of images, so each processing path starts from the same pixels
+func copyImages(images [][]float32) [][]float32 {
+	copied := make([][]float32, len(images))
+	for i, image := range images {
+		copied[i] = append([]float32(nil), image...)
+	}
+	return copied
+}
+
+func baselineDataset(t *testing.T) (BenchmarkConfig, [][]float32, []int) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize = 10
+	cfg.Warmup, cfg.NumRuns = 1, 2
+	cfg.SyntheticImages = 100
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	return cfg, images, labels
+}
+
+func TestBaselineChecksumsMatch(t *testing.T) {
+	cfg, images, labels := baselineDataset(t)
+
+	pixels := flattenImages(images)
+	RunBareLoop(cfg, pixels)
+	expected := checksum(pixels)
+
+	sequential := copyImages(images)
+	RunSequentialTask(cfg, sequential, labels)
+	if got := checksum(flattenImages(sequential)); got != expected {
+		t.Errorf("Expected sequential checksum %v, got %v", expected, got)
+	}
+
+	concurrent := copyImages(images)
+	RunProcessingTask(cfg, concurrent, labels)
+	if got := checksum(flattenImages(concurrent)); got != expected {
+		t.Errorf("Expected concurrent checksum %v, got %v", expected, got)
+	}
+}
+
+func TestRunBaselines(t *testing.T) {
+	cfg, images, labels := baselineDataset(t)
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	baselines, err := runBaselines(cfg, logger, images, labels)
+	if err != nil {
+		t.Fatalf("Failed to run baselines: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	for name, summary := range map[string]runSummary{"sequential": baselines.Sequential, "bare loop": baselines.BareLoop} {
+		if summary.Runs != cfg.NumRuns {
+			t.Errorf("%s: expected %d runs, got %d", name, cfg.NumRuns, summary.Runs)
+		}
+		if expected := cfg.NumRuns * len(images); summary.Total.ImagesProcessed != expected {
+			t.Errorf("%s: expected %d images processed, got %d", name, expected, summary.Total.ImagesProcessed)
+		}
+	}
+
+	logContent, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read metrics log: %v", err)
+	}
+	for _, expected := range []string{"Single-core Ceiling (bare loop)", "Sequential Harness", "Harness Overhead"} {
+		if !strings.Contains(string(logContent), expected) {
+			t.Errorf("Expected %q in metrics log", expected)
+		}
+	}
+
+	records := append(metricRecords("cifar10-train-sequential", baselines.Sequential),
+		metricRecords("cifar10-train-bare-loop", baselines.BareLoop)...)
+	if len(records) != 2*cfg.NumRuns {
+		t.Fatalf("Expected %d records, got %d", 2*cfg.NumRuns, len(records))
+	}
+	if records[0].Dataset == records[len(records)-1].Dataset {
+		t.Errorf("Expected distinct dataset names for the baselines, got %q", records[0].Dataset)
+	}
+}
+
+func TestRunBaselinesRejectsBlur(t *testing.T) {
+	cfg, images, labels := baselineDataset(t)
+	cfg.Kernel = KernelBlur
+
+	logger, err := metricslog.New(filepath.Join(t.TempDir(), "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+	if _, err := runBaselines(cfg, logger, images, labels); err == nil {
+		t.Error("Expected an error for the blur kernel")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang/internal/metricslog"
+	"golang/internal/result"
+)
+
+// defaultDataDir is where the CIFAR-10 binary batches are read from unless told otherwise
+const defaultDataDir = "../../cifar-10-batches-bin/"
+
+// Option configures RunBenchmark
+type Option func(*benchmarkOptions)
+
+// benchmarkOptions collects the settings applied by the options passed to RunBenchmark
+type benchmarkOptions struct {
+	cfg        BenchmarkConfig
+	dataDir    string
+	numWorkers int
+	outputPath string
+	logOutput  io.Writer
+}
+
+// WithConfig replaces the whole configuration; options after it adjust the replacement
+func WithConfig(cfg BenchmarkConfig) Option {
+	return func(o *benchmarkOptions) { o.cfg = cfg }
+}
+
+// WithDataDir reads the binary files of the configured dataset from dir
+func WithDataDir(dir string) Option {
+	return func(o *benchmarkOptions) { o.dataDir = dir }
+}
+
+// WithNumRuns sets the number of measured runs
+func WithNumRuns(n int) Option {
+	return func(o *benchmarkOptions) { o.cfg.NumRuns = n }
+}
+
+// WithBatchSize sets the number of images per batch
+func WithBatchSize(n int) Option {
+	return func(o *benchmarkOptions) { o.cfg.BatchSize = n }
+}
+
+// WithNumWorkers processes the batches on a BoundedWorkerPool of n goroutines. By default every
+// batch gets its own goroutine, as in RunProcessingTask.
+func WithNumWorkers(n int) Option {
+	return func(o *benchmarkOptions) { o.numWorkers = n }
+}
+
+// WithOutputPath writes the result as a JSON record, in the -once format, to path
+func WithOutputPath(path string) Option {
+	return func(o *benchmarkOptions) { o.outputPath = path }
+}
+
+// WithLogOutput writes the per-run metrics log to w; it is discarded by default
+func WithLogOutput(w io.Writer) Option {
+	return func(o *benchmarkOptions) { o.logOutput = w }
+}
+
+// BenchmarkResult is the outcome of RunBenchmark
+type BenchmarkResult struct {
+	Record result.Record // Averages of the measured runs, with metadata and configuration
+	Runs   []result.Run  // Every measured run, in order
+}
+
+// RunBenchmark loads one CIFAR-10 split and runs the warmup and measured runs over it, without
+// the logging, monitoring and file output of main. It lets integration tests and comparison
+// harnesses call the benchmark as a library. The defaults are those of DefaultConfig.
+func RunBenchmark(opts ...Option) (*BenchmarkResult, error) {
+	o := benchmarkOptions{cfg: DefaultConfig(), logOutput: io.Discard}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.dataDir == "" {
+		o.dataDir = dataDirFor(o.cfg.Dataset)
+	}
+	cfg := o.cfg
+	if cfg.NumRuns < 1 {
+		return nil, fmt.Errorf("number of runs must be at least 1, got %d", cfg.NumRuns)
+	}
+	if cfg.BatchSize < 1 {
+		return nil, fmt.Errorf("batch size must be at least 1, got %d", cfg.BatchSize)
+	}
+	if o.numWorkers < 0 {
+		return nil, fmt.Errorf("number of workers must not be negative, got %d", o.numWorkers)
+	}
+	if cfg.Split == SplitBoth && cfg.SyntheticImages == 0 {
+		return nil, fmt.Errorf("RunBenchmark measures a single split, got %q", cfg.Split)
+	}
+
+	datasets, err := loadDatasets(cfg, o.dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", datasetTitle(cfg.Dataset), err)
+	}
+	dataset := datasets[0]
+
+	logger := metricslog.NewStream(o.logOutput)
+	cfg = withChannelStats(cfg, logger, dataset.Images)
+	summary, err := runBenchmark(cfg, logger, func() (runResult, error) {
+		if o.numWorkers > 0 {
+			return measureTask(o.numWorkers, func() (time.Duration, time.Duration, time.Duration, int, int) {
+				return runPoolTask(cfg, dataset.Images, dataset.Labels, o.numWorkers)
+			})
+		}
+		return measureRun(cfg, dataset.Images, dataset.Labels)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := logger.Close(); err != nil {
+		return nil, err
+	}
+
+	res := newBenchmarkResult(cfg.Dataset, cfg, dataset.Split, len(dataset.Images), summary)
+	if o.outputPath != "" {
+		if err := writeRecord(o.outputPath, res.Record); err != nil {
+			return nil, err
+		}
+	}
+	return &res, nil
+}
+
+// newBenchmarkResult collects the averages of summary in a record, next to every measured run
+func newBenchmarkResult(benchmark string, cfg BenchmarkConfig, split string, numImages int, summary runSummary) BenchmarkResult {
+	res := BenchmarkResult{Record: newRecord(benchmark, cfg, split, numImages, summary)}
+	for _, r := range summary.Results {
+		res.Runs = append(res.Runs, newRun(r, r.ImagesProcessed, r.PixelsProcessed, r.ExecutionTime))
+	}
+	return res
+}
+
+// WriteInfluxLineProtocol writes every measured run of res to outputPath as InfluxDB line
+// protocol, one point per run, for time-series dashboards
+func WriteInfluxLineProtocol(res BenchmarkResult, outputPath string) error {
+	return writeInflux(outputPath, []BenchmarkResult{res})
+}
+
+// writeInflux writes the runs of every result to path as line protocol, creating or truncating it
+func writeInflux(path string, results []BenchmarkResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	for _, res := range results {
+		if err := result.WriteInflux(file, res.Record, res.Runs); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// runPoolTask processes the full batches of images on a BoundedWorkerPool of numWorkers
+// goroutines and returns the same timings and counts as RunProcessingTask. The pool's
+// goroutines are started before the timing begins, so spawn time is reported as zero.
+func runPoolTask(cfg BenchmarkConfig, images [][]float32, labels []int, numWorkers int) (executionTime, concurrencyOverhead, goroutineSpawnDuration time.Duration, imagesProcessed, pixelsProcessed int) {
+	startOverhead := time.Now()
+	pool := NewBoundedWorkerPool(cfg, numWorkers, numWorkers)
+	for i := 0; i < len(images)/cfg.BatchSize; i++ {
+		start, end := i*cfg.BatchSize, (i+1)*cfg.BatchSize
+		pool.Submit(ImageBatch{Images: images[start:end], Labels: labels[start:end]})
+	}
+	r := pool.Wait()
+	return r.Elapsed, time.Since(startOverhead), 0, r.ImagesProcessed, r.ImagesProcessed * cfg.ImageHeight * cfg.ImageWidth
+}
+
+// writeRecord writes record as JSON to path, creating or truncating it
+func writeRecord(path string, record result.Record) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	if err := result.Write(file, record); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
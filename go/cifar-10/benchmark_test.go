@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// syntheticConfig returns a small configuration over generated images for RunBenchmark tests
+func syntheticConfig() BenchmarkConfig {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.SyntheticImages = 100
+	cfg.Warmup = 1
+	return cfg
+}
+
+func TestRunBenchmarkOptions(t *testing.T) {
+	var logOutput bytes.Buffer
+	res, err := RunBenchmark(WithConfig(syntheticConfig()), WithNumRuns(3), WithBatchSize(16), WithLogOutput(&logOutput))
+	if err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+	if res.Record.Runs != 3 || len(res.Runs) != 3 {
+		t.Fatalf("Expected 3 measured runs, got %d and %d", res.Record.Runs, len(res.Runs))
+	}
+	// 100 images in batches of 16 leave 96 in full batches
+	for i, run := range res.Runs {
+		if run.ImagesProcessed != 96 {
+			t.Errorf("Run %d: expected 96 images processed, got %d", i+1, run.ImagesProcessed)
+		}
+	}
+	if res.Record.Benchmark != "cifar10" || res.Record.Dataset.Images != 100 {
+		t.Errorf("Unexpected record metadata: %+v", res.Record)
+	}
+	if strings.Count(logOutput.String(), "Execution Time for Run") != 3 {
+		t.Errorf("Expected 3 runs in the log output, got:\n%s", logOutput.String())
+	}
+}
+
+func TestRunBenchmarkWithNumWorkers(t *testing.T) {
+	res, err := RunBenchmark(WithConfig(syntheticConfig()), WithNumRuns(2), WithBatchSize(10), WithNumWorkers(3))
+	if err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+	for i, run := range res.Runs {
+		if run.ImagesProcessed != 100 || run.PixelsProcessed != 100*8*8 {
+			t.Errorf("Run %d: expected 100 images and 6400 pixels, got %d and %d", i+1, run.ImagesProcessed, run.PixelsProcessed)
+		}
+	}
+}
+
+func TestRunBenchmarkWritesOutput(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "result.json")
+	if _, err := RunBenchmark(WithConfig(syntheticConfig()), WithNumRuns(1), WithOutputPath(outputPath)); err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var record struct {
+		Benchmark string
+		Runs      int
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Output is not a JSON record: %v", err)
+	}
+	if record.Benchmark != "cifar10" || record.Runs != 1 {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+}
+
+func TestRunBenchmarkRejectsInvalidOptions(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"zero runs":        {WithNumRuns(0)},
+		"zero batch size":  {WithBatchSize(0)},
+		"negative workers": {WithNumWorkers(-1)},
+		"missing data dir": {WithDataDir(filepath.Join(t.TempDir(), "missing"))},
+	} {
+		if _, err := RunBenchmark(opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWriteInfluxLineProtocol(t *testing.T) {
+	res, err := RunBenchmark(WithConfig(syntheticConfig()), WithNumRuns(2))
+	if err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+	outputPath := filepath.Join(t.TempDir(), "runs.lp")
+	if err := WriteInfluxLineProtocol(*res, outputPath); err != nil {
+		t.Fatalf("WriteInfluxLineProtocol failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read line protocol: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per run, got:\n%s", data)
+	}
+	for i, line := range lines {
+		prefix := fmt.Sprintf("go_benchmark,dataset=cifar10,run=%d,split=synthetic execution_time=", i+1)
+		if !strings.HasPrefix(line, prefix) {
+			t.Errorf("Expected line %d to start with %q, got %q", i+1, prefix, line)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// LoadCIFAR10ByClass loads the CIFAR-10 training batches and groups the images by label
+func LoadCIFAR10ByClass(cfg BenchmarkConfig, dataDir string) (map[int][][]float32, error) {
+	images, labels, err := LoadCIFAR10(cfg, dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return groupByClass(images, labels), nil
+}
+
+// groupByClass groups images by their label, keeping the dataset order within each class
+func groupByClass(images [][]float32, labels []int) map[int][][]float32 {
+	byClass := make(map[int][][]float32)
+	for i, image := range images {
+		byClass[labels[i]] = append(byClass[labels[i]], image)
+	}
+	return byClass
+}
+
+// classResult holds the processing metrics of one class
+type classResult struct {
+	Class           int
+	ImagesProcessed int
+	ExecutionTime   time.Duration
+}
+
+// RunPerClassBenchmark processes one class at a time, in label order, finishing every image of
+// a class before starting the next, and logs the throughput of each class. An image the kernel
+// fails on stops the benchmark with that error, so no class reports throughput for partial work.
+func RunPerClassBenchmark(cfg BenchmarkConfig, logger *metricslog.Logger, byClass map[int][][]float32) ([]classResult, error) {
+	classes := make([]int, 0, len(byClass))
+	for class := range byClass {
+		classes = append(classes, class)
+	}
+	sort.Ints(classes)
+
+	results := make([]classResult, 0, len(classes))
+	for _, class := range classes {
+		images := byClass[class]
+		labels := make([]int, len(images))
+		for i := range labels {
+			labels[i] = class
+		}
+
+		task, err := runProcessingTask(context.Background(), cfg, images, labels)
+		if err != nil {
+			return results, fmt.Errorf("class %d: %w", class, err)
+		}
+		results = append(results, classResult{Class: class, ImagesProcessed: task.ImagesProcessed, ExecutionTime: task.ExecutionTime})
+		logger.Printf("Class %d: %d images in %s seconds, %.2f images/second",
+			class, task.ImagesProcessed, metrics.FormatDuration(task.ExecutionTime), throughput(task.ImagesProcessed, task.ExecutionTime))
+	}
+	return results, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestLoadCIFAR10ByClass(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 2, ImageWidth: 2, Channels: 3, ImagesPerBatch: 4, BatchSize: 2, NumRuns: 1}
+	dataDir := t.TempDir()
+	writeSyntheticBatches(t, cfg, dataDir)
+
+	byClass, err := LoadCIFAR10ByClass(cfg, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to load by class: %v", err)
+	}
+	// Labels are the index within each of the five batches, so every class has five images
+	if len(byClass) != cfg.ImagesPerBatch {
+		t.Fatalf("Expected %d classes, got %d", cfg.ImagesPerBatch, len(byClass))
+	}
+	for class, images := range byClass {
+		if len(images) != 5 {
+			t.Errorf("Class %d: expected 5 images, got %d", class, len(images))
+		}
+	}
+}
+
+func TestRunPerClassBenchmark(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize = 10
+	cfg.SyntheticImages = 205
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	byClass := groupByClass(images, labels)
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	results, err := RunPerClassBenchmark(cfg, logger, byClass)
+	if err != nil {
+		t.Fatalf("Per-class benchmark failed: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	if len(results) != len(byClass) {
+		t.Fatalf("Expected %d class results, got %d", len(byClass), len(results))
+	}
+	for i, r := range results {
+		if i > 0 && r.Class <= results[i-1].Class {
+			t.Errorf("Classes not processed in label order: %d after %d", r.Class, results[i-1].Class)
+		}
+		expected := len(byClass[r.Class]) / cfg.BatchSize * cfg.BatchSize
+		if r.ImagesProcessed != expected {
+			t.Errorf("Class %d: expected %d images processed, got %d", r.Class, expected, r.ImagesProcessed)
+		}
+	}
+
+	content, _ := os.ReadFile(logFilePath)
+	if !strings.Contains(string(content), "Class 0:") || !strings.Contains(string(content), "images/second") {
+		t.Errorf("Expected per-class throughput in the log, got:\n%s", content)
+	}
+}
+
+func TestRunPerClassBenchmarkKernelError(t *testing.T) {
+	registerFailingKernel(t)
+	cfg := syntheticConfig()
+	cfg.BatchSize = 5
+	cfg.Kernel = failingKernel
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	images[0][0] = failMarker
+
+	logger, err := metricslog.New(filepath.Join(t.TempDir(), "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+	if _, err := RunPerClassBenchmark(cfg, logger, groupByClass(images, labels)); !errors.Is(err, ErrBadImage) {
+		t.Errorf("Expected the kernel error, got %v", err)
+	}
+}
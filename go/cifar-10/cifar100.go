@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Number of CIFAR-100 coarse (superclass) and fine classes
+const (
+	CIFAR100CoarseClasses = 20
+	CIFAR100FineClasses   = 100
+)
+
+// Names of the datasets that can be selected with the -dataset flag
+const (
+	DatasetCIFAR10  = "cifar10"
+	DatasetCIFAR100 = "cifar100"
+)
+
+// cifar100DataDir is where the CIFAR-100 binary files are read from unless told otherwise
+const cifar100DataDir = "../../cifar-100-binary/"
+
+// validateDataset returns an error unless name is one of the datasets the benchmark can load
+func validateDataset(name string) error {
+	switch name {
+	case DatasetCIFAR10, DatasetCIFAR100:
+		return nil
+	}
+	return fmt.Errorf("unknown dataset %q, expected %s or %s", name, DatasetCIFAR10, DatasetCIFAR100)
+}
+
+// dataDirFor returns the default directory holding the binary files of dataset
+func dataDirFor(dataset string) string {
+	if dataset == DatasetCIFAR100 {
+		return cifar100DataDir
+	}
+	return defaultDataDir
+}
+
+// datasetTitle returns the name of dataset as written in the logs and error messages
+func datasetTitle(dataset string) string {
+	if dataset == DatasetCIFAR100 {
+		return "CIFAR-100"
+	}
+	return "CIFAR-10"
+}
+
+// loadCIFAR100Dataset loads the CIFAR-100 training set as a Dataset labelled with the fine
+// classes, stopping at cfg.Limit images when a limit is set. CIFAR-100 has no batch files
+// to split, so only the train split is supported.
+func loadCIFAR100Dataset(cfg BenchmarkConfig, dataDir string) (Dataset, error) {
+	if cfg.Split != SplitTrain {
+		return Dataset{}, fmt.Errorf("CIFAR-100 supports only the %s split, got %q", SplitTrain, cfg.Split)
+	}
+	images, _, fineLabels, err := LoadCIFAR100(cfg, dataDir)
+	if err != nil {
+		return Dataset{}, err
+	}
+	if cfg.Limit > 0 && len(images) > cfg.Limit {
+		images, fineLabels = images[:cfg.Limit], fineLabels[:cfg.Limit]
+	}
+	return Dataset{Split: SplitTrain, Images: images, Labels: fineLabels}, nil
+}
+
+// LoadCIFAR100 loads the CIFAR-100 training set from train.bin in dataDir. CIFAR-100 records
+// hold a coarse and a fine label before the pixels, so both label sets are returned; either
+// can be passed to RunProcessingTask alongside the images.
+func LoadCIFAR100(cfg BenchmarkConfig, dataDir string) ([][]float32, []int, []int, error) {
+	return loadCIFAR100File(cfg, filepath.Join(dataDir, "train.bin"))
+}
+
+// loadCIFAR100File reads a CIFAR-100 binary file of any number of records of the configured
+// image size, rejecting partial records and labels outside the CIFAR-100 class ranges
+func loadCIFAR100File(cfg BenchmarkConfig, filePath string) ([][]float32, []int, []int, error) {
+	fmt.Fprintf(os.Stderr, "Loading file: %s\n", filePath)
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	imageSize := cfg.ImageSize()
+	recordSize := imageSize + 2
+	if len(data) == 0 || len(data)%recordSize != 0 {
+		return nil, nil, nil, fmt.Errorf("file %s is not a whole number of %d-byte records: got %d bytes",
+			filePath, recordSize, len(data))
+	}
+
+	numImages := len(data) / recordSize
+	images := make([][]float32, 0, numImages)
+	coarseLabels := make([]int, 0, numImages)
+	fineLabels := make([]int, 0, numImages)
+	for j := 0; j < numImages; j++ {
+		record := data[j*recordSize : (j+1)*recordSize]
+		coarse, fine := int(record[0]), int(record[1])
+		if coarse >= CIFAR100CoarseClasses || fine >= CIFAR100FineClasses {
+			return nil, nil, nil, fmt.Errorf("record %d in %s has labels %d/%d outside the %d coarse and %d fine classes",
+				j, filePath, coarse, fine, CIFAR100CoarseClasses, CIFAR100FineClasses)
+		}
+
+		image := make([]float32, imageSize)
+		for k, p := range record[2:] {
+			image[k] = float32(p) / 255.0
+		}
+		images = append(images, image)
+		coarseLabels = append(coarseLabels, coarse)
+		fineLabels = append(fineLabels, fine)
+	}
+	return images, coarseLabels, fineLabels, nil
+}
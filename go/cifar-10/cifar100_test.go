@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeCIFAR100File writes numImages CIFAR-100 records for cfg to path, with coarse label
+// i%20 and fine label i%100
+func writeCIFAR100File(t *testing.T, cfg BenchmarkConfig, path string, numImages int) {
+	t.Helper()
+	var data []byte
+	for i := 0; i < numImages; i++ {
+		data = append(data, byte(i%CIFAR100CoarseClasses), byte(i%CIFAR100FineClasses))
+		for k := 0; k < cfg.ImageSize(); k++ {
+			data = append(data, 255)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write CIFAR-100 file: %v", err)
+	}
+}
+
+// checkCIFAR100Labels fails the test if any label is outside the CIFAR-100 class ranges
+func checkCIFAR100Labels(t *testing.T, coarse, fine []int) {
+	t.Helper()
+	for i := range coarse {
+		if coarse[i] < 0 || coarse[i] >= CIFAR100CoarseClasses {
+			t.Errorf("Image %d coarse label %d outside 0-19", i, coarse[i])
+		}
+		if fine[i] < 0 || fine[i] >= CIFAR100FineClasses {
+			t.Errorf("Image %d fine label %d outside 0-99", i, fine[i])
+		}
+	}
+}
+
+func TestLoadCIFAR100Synthetic(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 2, ImageWidth: 2, Channels: 3, BatchSize: 50, NumRuns: 1}
+	dataDir := t.TempDir()
+	writeCIFAR100File(t, cfg, filepath.Join(dataDir, "train.bin"), 200)
+
+	images, coarse, fine, err := LoadCIFAR100(cfg, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to load CIFAR-100: %v", err)
+	}
+	if len(images) != 200 || len(coarse) != 200 || len(fine) != 200 {
+		t.Fatalf("Expected 200 images and labels, got %d, %d, %d", len(images), len(coarse), len(fine))
+	}
+	checkCIFAR100Labels(t, coarse, fine)
+	if coarse[21] != 1 || fine[199] != 99 {
+		t.Errorf("Labels out of order: coarse[21]=%d, fine[199]=%d", coarse[21], fine[199])
+	}
+	if images[0][0] != 1.0 {
+		t.Errorf("Expected pixels normalized to 1.0, got %.2f", images[0][0])
+	}
+
+	_, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, fine)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 200 {
+		t.Errorf("Expected 200 images processed, got %d", imagesProcessed)
+	}
+}
+
+func TestLoadCIFAR100Invalid(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 2, ImageWidth: 2, Channels: 3}
+	dataDir := t.TempDir()
+
+	// A trailing partial record
+	path := filepath.Join(dataDir, "train.bin")
+	writeCIFAR100File(t, cfg, path, 2)
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, data[:len(data)-1], 0644); err != nil {
+		t.Fatalf("Failed to truncate file: %v", err)
+	}
+	if _, _, _, err := LoadCIFAR100(cfg, dataDir); err == nil {
+		t.Errorf("Expected an error for a partial record")
+	}
+
+	// A fine label of 100
+	data = append([]byte{0, 100}, make([]byte, cfg.ImageSize())...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, _, _, err := LoadCIFAR100(cfg, dataDir); err == nil {
+		t.Errorf("Expected an error for an out-of-range fine label")
+	}
+}
+
+func TestLoadCIFAR100(t *testing.T) {
+	dataDir := cifar100DataDir
+	requireDataset(t, filepath.Join(dataDir, "train.bin"))
+
+	images, coarse, fine, err := LoadCIFAR100(DefaultConfig(), dataDir)
+	if err != nil {
+		t.Fatalf("Failed to load CIFAR-100 dataset: %v", err)
+	}
+	if len(images) != 50000 {
+		t.Errorf("Expected 50000 images, got %d", len(images))
+	}
+	checkCIFAR100Labels(t, coarse, fine)
+}
+
+// cifar100Testdata holds five 32x32x3 CIFAR-100 records. Record i has coarse label 4i, fine
+// label 20i+3 and pixel bytes (7i+k)%256.
+const cifar100Testdata = "testdata/cifar100"
+
+func TestLoadCIFAR100Testdata(t *testing.T) {
+	images, coarse, fine, err := LoadCIFAR100(DefaultConfig(), cifar100Testdata)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", cifar100Testdata, err)
+	}
+	if len(images) != 5 {
+		t.Fatalf("Expected 5 images, got %d", len(images))
+	}
+	for i := range images {
+		if coarse[i] != 4*i || fine[i] != 20*i+3 {
+			t.Errorf("Image %d: expected labels %d/%d, got %d/%d", i, 4*i, 20*i+3, coarse[i], fine[i])
+		}
+		if expected := float32((7*i+1)%256) / 255.0; images[i][1] != expected {
+			t.Errorf("Image %d: expected second pixel %v, got %v", i, expected, images[i][1])
+		}
+	}
+}
+
+func TestLoadDatasetsCIFAR100(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Dataset = DatasetCIFAR100
+	cfg.Limit = 3
+	datasets, err := loadDatasets(cfg, cifar100Testdata)
+	if err != nil {
+		t.Fatalf("Failed to load datasets: %v", err)
+	}
+	if len(datasets) != 1 || datasets[0].Split != SplitTrain {
+		t.Fatalf("Expected one train dataset, got %+v", datasets)
+	}
+	dataset := datasets[0]
+	if len(dataset.Images) != 3 || len(dataset.Labels) != 3 {
+		t.Fatalf("Expected 3 images and labels, got %d and %d", len(dataset.Images), len(dataset.Labels))
+	}
+	if dataset.Labels[2] != 43 {
+		t.Errorf("Expected the fine label 43 for image 2, got %d", dataset.Labels[2])
+	}
+
+	cfg.BatchSize = 1
+	_, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, dataset.Images, dataset.Labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 3 {
+		t.Errorf("Expected 3 images processed, got %d", imagesProcessed)
+	}
+
+	cfg.Split = SplitTest
+	if _, err := loadDatasets(cfg, cifar100Testdata); err == nil {
+		t.Errorf("Expected an error for the CIFAR-100 test split")
+	}
+}
+
+func TestValidateDataset(t *testing.T) {
+	for _, name := range []string{DatasetCIFAR10, DatasetCIFAR100} {
+		if err := validateDataset(name); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", name, err)
+		}
+	}
+	if err := validateDataset("imagenet"); err == nil {
+		t.Errorf("Expected an error for an unknown dataset")
+	}
+	if dataDirFor(DatasetCIFAR100) != cifar100DataDir || dataDirFor(DatasetCIFAR10) != defaultDataDir {
+		t.Errorf("Expected per-dataset data directories, got %q and %q", dataDirFor(DatasetCIFAR100), dataDirFor(DatasetCIFAR10))
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "math"
+
+// float32One is the IEEE 754 bit pattern of 1.0
+const float32One = 0x3f800000
+
+// ClampPixel clamps a pixel value to [0, 1] using math.Min and math.Max
+func ClampPixel(v float32) float32 {
+	return float32(math.Max(0, math.Min(1, float64(v))))
+}
+
+// ClampPixelBranchless clamps a pixel value to [0, 1] by operating on its bit pattern.
+// Negative values have the sign bit set and are masked to zero. Non-negative floats order
+// the same way as their bit patterns, so anything above 1.0 is replaced by 1.0 using a mask
+// derived from the sign of the difference. NaNs follow their sign bit like other values.
+func ClampPixelBranchless(v float32) float32 {
+	bits := math.Float32bits(v)
+	bits &^= uint32(int32(bits) >> 31)
+	aboveOne := uint32((int32(float32One) - int32(bits)) >> 31)
+	bits = bits&^aboveOne | float32One&aboveOne
+	return math.Float32frombits(bits)
+}
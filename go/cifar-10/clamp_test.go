@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/cpu"
+
+	"golang/internal/synthetic"
+)
+
+func TestClampPixel(t *testing.T) {
+	tests := []struct {
+		in, want float32
+	}{
+		{-1.5, 0},
+		{float32(math.Copysign(0, -1)), 0},
+		{0, 0},
+		{math.SmallestNonzeroFloat32, math.SmallestNonzeroFloat32},
+		{0.25, 0.25},
+		{1, 1},
+		{math.Nextafter32(1, 2), 1},
+		{2, 1},
+		{float32(math.Inf(1)), 1},
+		{float32(math.Inf(-1)), 0},
+	}
+
+	for _, tt := range tests {
+		if got := ClampPixel(tt.in); got != tt.want || math.Signbit(float64(got)) {
+			t.Errorf("ClampPixel(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+		if got := ClampPixelBranchless(tt.in); got != tt.want || math.Signbit(float64(got)) {
+			t.Errorf("ClampPixelBranchless(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// clampDatasetImages is the CIFAR-10 training set size used by BenchmarkClampPixel
+const clampDatasetImages = 50000
+
+func BenchmarkClampPixel(b *testing.B) {
+	cfg := DefaultConfig()
+	numImages := clampDatasetImages
+	if testing.Short() {
+		numImages = 500
+	}
+	images, _ := synthetic.GenerateSyntheticImages(numImages, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, 1)
+	// Spread values over [-0.5, 1.5) so roughly half the pixels need clamping
+	for _, image := range images {
+		for i := range image {
+			image[i] = image[i]*2 - 0.5
+		}
+	}
+
+	var sink float32
+	clampers := []struct {
+		name  string
+		clamp func(float32) float32
+	}{
+		{"math", ClampPixel},
+		{"branchless", ClampPixelBranchless},
+	}
+	perOp := make(map[string]time.Duration)
+
+	for _, c := range clampers {
+		b.Run(c.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for _, image := range images {
+					for _, v := range image {
+						sink += c.clamp(v)
+					}
+				}
+			}
+			perOp[c.name] = b.Elapsed() / time.Duration(b.N)
+		})
+	}
+
+	faster := "math"
+	if perOp["branchless"] < perOp["math"] {
+		faster = "branchless"
+	}
+	b.Logf("%s clamping is faster on %s (AVX2: %v): math %v/op, branchless %v/op over %d images",
+		faster, runtime.GOARCH, cpu.X86.HasAVX2, perOp["math"], perOp["branchless"], numImages)
+	_ = sink
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	cfg := DefaultConfig()
+	fs := flag.NewFlagSet("cifar-10", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+
+	args := []string{"-height", "8", "-width", "4", "-channels", "1", "-batch-size", "16", "-num-runs", "3", "-warmup", "2", "-gc-between-runs", "-free-os-memory", "-cooldown", "500ms", "-max-inflight", "6", "-tile-rows", "2", "-num-seeds", "4", "-maxprocs-sweep", "1,2", "-kernel", "blur", "-gpu-transfer-latency", "1.5", "-dram-bandwidth", "25.6", "-synthetic", "64", "-seed", "7", "-dataset", "cifar100", "-split", "both", "-limit", "5", "-baseline", "-per-class", "-once", "-dry-run", "-hash-dataset", "-json", "-", "-csv", "runs.csv", "-influx", "runs.lp", "-batch-timings", "batches.csv", "-save-grid", "grid.png", "-dump-dir", "dump", "-dump-labels", "binary", "-cpuprofile", "cpu.pprof", "-memprofile", "mem.pprof", "-listen", ":9090", "-collectors", "loadavg,goroutines", "-pipeline", "-pipeline-workers", "3", "-pipeline-buffer", "2"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	expected := BenchmarkConfig{ImageHeight: 8, ImageWidth: 4, Channels: 1, ImagesPerBatch: 10000, BatchSize: 16, NumRuns: 3, Warmup: 2, GCBetweenRuns: true, FreeOSMemory: true, Cooldown: 500 * time.Millisecond, MaxInFlight: 6, TileRows: 2, NumSeeds: 4, MaxProcsSweep: "1,2", Kernel: KernelBlur, GPUTransferLatency: 1.5, DRAMBandwidth: 25.6, SyntheticImages: 64, Seed: 7, Dataset: DatasetCIFAR100, Split: SplitBoth, Limit: 5, Baseline: true, PerClass: true, Once: true, DryRun: true, HashDataset: true, JSONPath: "-", CSVPath: "runs.csv", InfluxPath: "runs.lp", BatchTimingsPath: "batches.csv", GridPath: "grid.png", DumpDir: "dump", DumpLabels: "binary", CPUProfilePath: "cpu.pprof", MemProfilePath: "mem.pprof", ListenAddr: ":9090", Collectors: "loadavg,goroutines", Pipeline: true, PipelineWorkers: 3, PipelineBuffer: 2}
+	if cfg != expected {
+		t.Errorf("Config mismatch: expected %+v, got %+v", expected, cfg)
+	}
+	if cfg.ImageSize() != 32 {
+		t.Errorf("Image size mismatch: expected 32, got %d", cfg.ImageSize())
+	}
+}
+
+func TestRegisterFlagsDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	fs := flag.NewFlagSet("cifar-10", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if cfg != DefaultConfig() {
+		t.Errorf("Defaults changed after parsing no flags: %+v", cfg)
+	}
+}
+
+func TestRegisterFlagsForceGCAlias(t *testing.T) {
+	cfg := DefaultConfig()
+	fs := flag.NewFlagSet("cifar-10", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"-force-gc"}); err != nil {
+		t.Fatalf("Failed to parse -force-gc: %v", err)
+	}
+	if !cfg.GCBetweenRuns {
+		t.Errorf("Expected -force-gc to enable -gc-between-runs")
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"time"
+
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// logDryRun writes the outcome of a -dry-run, which loads the dataset and stops before any
+// processing, so the loading time reflects storage and decoding alone
+func logDryRun(logger *metricslog.Logger, numImages int, loadingTime time.Duration) {
+	logger.Printf("Dry Run: loaded %d images in %s seconds (%.2f images/second), processing skipped",
+		numImages, metrics.FormatDuration(loadingTime), throughput(numImages, loadingTime))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"golang/internal/dump"
+	"golang/internal/labels"
+)
+
+// writeDump writes every image of dataset after the cfg.Kernel transform to cfg.DumpDir, with its
+// label in the cfg.DumpLabels format. With several -num-seeds the images are dumped in the order
+// of the first seed's shuffle, the order that seed's runs process them in.
+func writeDump(cfg BenchmarkConfig, dataset Dataset, classNames []string) error {
+	format, err := labels.ParseFormat(cfg.DumpLabels)
+	if err != nil {
+		return err
+	}
+	images, l := dataset.Images, labels.FromIndices(dataset.Labels, classNames)
+	if cfg.NumSeeds > 1 {
+		order := labels.ShuffleOrder(len(images), cfg.Seed)
+		l = l.Permute(order)
+		shuffled := make([][]float32, len(order))
+		for i, src := range order {
+			shuffled[i] = images[src]
+		}
+		images = shuffled
+	}
+
+	processed := make([][]float32, len(images))
+	for i, image := range images {
+		// The double kernel works in place, so it gets a copy of the dataset image
+		processed[i], err = processImage(cfg, append([]float32(nil), image...))
+		if err != nil {
+			return fmt.Errorf("failed to process image %d for the dump: %w", i, err)
+		}
+	}
+	return dump.Write(cfg.DumpDir, dataset.Split, processed, l, format)
+}
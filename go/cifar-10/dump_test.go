@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"golang/internal/dump"
+)
+
+func TestWriteDumpFollowsShuffleOrder(t *testing.T) {
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.NumSeeds, cfg.Seed = 20, 2, 3
+	cfg.DumpDir, cfg.DumpLabels = t.TempDir(), "csv"
+	images, classes, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	first := images[0][0]
+
+	if err := writeDump(cfg, Dataset{Split: "synthetic", Images: images, Labels: classes}, CIFAR10LabelNames[:]); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	if images[0][0] != first {
+		t.Errorf("Expected the dump to leave the dataset untouched, got %v from %v", images[0][0], first)
+	}
+
+	// The dump lines up with the images the first seed's runs process
+	shuffledImages, shuffledLabels := shuffleImages(images, classes, cfg.Seed)
+	dumped, err := dump.ReadLabels(cfg.DumpDir, "synthetic")
+	if err != nil {
+		t.Fatalf("Failed to read dumped labels: %v", err)
+	}
+	for i, label := range shuffledLabels {
+		if int(dumped[i]) != label {
+			t.Fatalf("Image %d: expected label %d, got %d", i, label, dumped[i])
+		}
+	}
+	pixels, err := dump.ReadPixels(cfg.DumpDir, "synthetic")
+	if err != nil {
+		t.Fatalf("Failed to read dumped pixels: %v", err)
+	}
+	if len(pixels) != len(images)*cfg.ImageSize() || pixels[cfg.ImageSize()] != shuffledImages[1][0]*2 {
+		t.Errorf("Expected the doubled pixels of the shuffled images, got %d values", len(pixels))
+	}
+
+	cfg.DumpLabels = "json"
+	if err := writeDump(cfg, Dataset{Split: "synthetic", Images: images, Labels: classes}, nil); err == nil {
+		t.Errorf("Expected an error for an unknown -dump-labels format")
+	}
+}
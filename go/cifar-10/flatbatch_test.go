@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestImageBatchFlat(t *testing.T) {
+	for _, kernel := range []string{KernelDouble, KernelBlur} {
+		cfg := DefaultConfig()
+		cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Kernel = 4, 4, 3, kernel
+		images := make([][]float32, 5)
+		for i := range images {
+			images[i] = make([]float32, cfg.ImageSize())
+			for j := range images[i] {
+				images[i][j] = float32(i*100 + j)
+			}
+		}
+		flat := NewImageBatchFlat(cfg, ImageBatch{Images: images, Labels: []int{0, 1, 2, 3, 4}})
+		if flat.Len() != len(images) || len(flat.Data) != len(images)*cfg.ImageSize() {
+			t.Fatalf("Expected %d images in %d values, got %d in %d", len(images), len(images)*cfg.ImageSize(), flat.Len(), len(flat.Data))
+		}
+		if image := flat.Image(2); image[0] != 200 || len(image) != cfg.ImageSize() || cap(image) != cfg.ImageSize() {
+			t.Errorf("Expected image 2 to start at 200 with length and capacity %d, got %v (cap %d)", cfg.ImageSize(), image[0], cap(image))
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go ProcessBatchFlat(cfg, flat, &wg)
+		go ProcessBatch(cfg, ImageBatch{Images: images}, &wg)
+		wg.Wait()
+		for i := range images {
+			for j, expected := range images[i] {
+				if got := flat.Image(i)[j]; got != expected {
+					t.Fatalf("%s: image %d pixel %d: expected %v as in the jagged batch, got %v", kernel, i, j, expected, got)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkProcessBatchLayout processes the same batch stored as one allocation per image and as
+// one flat buffer, to show what memory layout costs the kernel
+func BenchmarkProcessBatchLayout(b *testing.B) {
+	for _, kernel := range []string{KernelDouble, KernelBlur} {
+		cfg := DefaultConfig()
+		cfg.SyntheticImages, cfg.Kernel = cfg.BatchSize, kernel
+		images, labels, err := syntheticDataset(cfg)
+		if err != nil {
+			b.Fatalf("Failed to build synthetic dataset: %v", err)
+		}
+		jagged := ImageBatch{Images: images, Labels: labels}
+		flat := NewImageBatchFlat(cfg, jagged)
+		bytes := int64(len(flat.Data) * 4)
+
+		b.Run(fmt.Sprintf("%s/jagged", kernel), func(b *testing.B) {
+			b.SetBytes(bytes)
+			for i := 0; i < b.N; i++ {
+				var wg sync.WaitGroup
+				wg.Add(1)
+				ProcessBatch(cfg, jagged, &wg)
+			}
+		})
+		b.Run(fmt.Sprintf("%s/flat", kernel), func(b *testing.B) {
+			b.SetBytes(bytes)
+			for i := 0; i < b.N; i++ {
+				var wg sync.WaitGroup
+				wg.Add(1)
+				ProcessBatchFlat(cfg, flat, &wg)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// GPUTransferSimulator models copying each batch from host to GPU memory before it is processed.
+// Transfers share one simulated bus, so concurrent batches wait for each other and the bus
+// bandwidth becomes a bottleneck as it would on a real device.
+type GPUTransferSimulator struct {
+	TransferLatencyPerMB float64 // Milliseconds per MB of float32 pixel data, 0 disables the delay
+
+	bus sync.Mutex
+}
+
+// TransferTime returns the simulated time to copy batch to the GPU
+func (s *GPUTransferSimulator) TransferTime(batch ImageBatch) time.Duration {
+	values := 0
+	for _, image := range batch.Images {
+		values += len(image)
+	}
+	mb := float64(values*4) / (1024 * 1024)
+	return time.Duration(mb * s.TransferLatencyPerMB * float64(time.Millisecond))
+}
+
+// Transfer blocks for the transfer time of batch once the bus is free
+func (s *GPUTransferSimulator) Transfer(batch ImageBatch) {
+	d := s.TransferTime(batch)
+	if d <= 0 {
+		return
+	}
+	s.bus.Lock()
+	defer s.bus.Unlock()
+	time.Sleep(d)
+}
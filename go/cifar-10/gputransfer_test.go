@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGPUTransferTime(t *testing.T) {
+	// 4 images of 65536 float32 values are exactly 1 MB
+	batch := ImageBatch{Images: make([][]float32, 4), Labels: make([]int, 4)}
+	for i := range batch.Images {
+		batch.Images[i] = make([]float32, 65536)
+	}
+
+	gpu := &GPUTransferSimulator{TransferLatencyPerMB: 2.5}
+	if got := gpu.TransferTime(batch); got != 2500*time.Microsecond {
+		t.Errorf("Expected 2.5ms for 1 MB, got %v", got)
+	}
+	disabled := &GPUTransferSimulator{}
+	if got := disabled.TransferTime(batch); got != 0 {
+		t.Errorf("Expected no transfer time when disabled, got %v", got)
+	}
+}
+
+func TestGPUTransferSerializesBatches(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 32, 32, 4
+	cfg.BatchSize, cfg.SyntheticImages = 64, 256
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	// Each batch is 64 x 4096 float32 values, 1 MB, so the four transfers take 4 x 20ms on one bus
+	cfg.GPUTransferLatency = 20
+
+	executionTime, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 256 {
+		t.Errorf("Expected 256 images processed, got %d", imagesProcessed)
+	}
+	if executionTime < 80*time.Millisecond {
+		t.Errorf("Expected the transfers to take at least 80ms in total, got %v", executionTime)
+	}
+}
+
+// BenchmarkGPUTransferLatency measures how simulated transfer latency limits throughput on the
+// CIFAR-10 layout
+func BenchmarkGPUTransferLatency(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 10 * cfg.BatchSize
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		b.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	for _, latency := range []float64{0, 1, 5} {
+		cfg.GPUTransferLatency = latency
+		b.Run(fmt.Sprintf("%gms-per-MB", latency), func(b *testing.B) {
+			var processed int
+			var elapsed time.Duration
+			for i := 0; i < b.N; i++ {
+				executionTime, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+				if err != nil {
+					b.Fatalf("RunProcessingTask failed: %v", err)
+				}
+				processed += imagesProcessed
+				elapsed += executionTime
+			}
+			b.ReportMetric(throughput(processed, elapsed), "images/s")
+		})
+	}
+}
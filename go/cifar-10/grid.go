@@ -0,0 +1,21 @@
+package main
+
+import "golang/internal/grid"
+
+// gridSamples is the number of images rendered by -save-grid
+const gridSamples = 4
+
+// saveGrid renders the first gridSamples images before and after the cfg.Kernel transform to path,
+// one sample per row
+func saveGrid(cfg BenchmarkConfig, images [][]float32, path string) error {
+	g := grid.Grid{Stages: []string{"original", cfg.Kernel}}
+	for i := 0; i < len(images) && i < gridSamples; i++ {
+		// The double kernel works in place, so it gets a copy of the dataset image
+		processed := ProcessImage(cfg, append([]float32(nil), images[i]...))
+		g.Rows = append(g.Rows, []grid.Cell{
+			{Pixels: images[i], Height: cfg.ImageHeight, Width: cfg.ImageWidth, Channels: cfg.Channels},
+			{Pixels: processed, Height: cfg.ImageHeight, Width: cfg.ImageWidth, Channels: cfg.Channels},
+		})
+	}
+	return g.WritePNG(path)
+}
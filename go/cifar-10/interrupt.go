@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang/internal/metricslog"
+)
+
+// interruptedExitCode is the status an interrupted benchmark exits with, 128 + SIGINT as shells
+// report a Ctrl-C
+const interruptedExitCode = 130
+
+// interruptContext returns a context that is cancelled on SIGINT or SIGTERM. The first signal
+// stops the in-flight run; default handling is then restored, so a second one exits at once.
+func interruptContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		fmt.Fprintln(os.Stderr, "Interrupted, stopping the current run; interrupt again to exit immediately")
+	}()
+	return ctx
+}
+
+// interrupted reports whether err comes from a run cancelled through its context
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// logInterrupted writes how many measured runs completed before the interrupt and their averages
+func logInterrupted(cfg BenchmarkConfig, logger *metricslog.Logger, summary runSummary) {
+	logger.Printf("\nInterrupted after %d of %d runs", summary.Runs, cfg.NumRuns)
+	if summary.Runs > 0 {
+		logger.Printf("\nAverage Metrics (%d completed runs):", summary.Runs)
+		logAverages(logger, summary)
+	}
+}
+
+// exitInterrupted logs the partial summary, flushes the log and exits with interruptedExitCode
+func exitInterrupted(cfg BenchmarkConfig, logger *metricslog.Logger, logFilePath string, summary runSummary) {
+	logInterrupted(cfg, logger, summary)
+	if err := logger.Close(); err != nil {
+		log.Fatalf("Error writing metrics log: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Interrupted after %d of %d runs; partial results written to %s\n", summary.Runs, cfg.NumRuns, logFilePath)
+	os.Exit(interruptedExitCode)
+}
+
+// exitFailed logs the error that aborted the benchmark after the completed runs, flushes the log
+// and exits, so a failed run is never averaged in as if it had succeeded
+func exitFailed(logger *metricslog.Logger, summary runSummary, err error) {
+	logger.Printf("\nBenchmark aborted after %d completed runs: %v", summary.Runs, err)
+	logger.Fatalf("Error running benchmark: %v", err)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestRunProcessingTaskStopsWhenCancelled(t *testing.T) {
+	cfg := syntheticConfig()
+	cfg.BatchSize = 10
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := runProcessingTask(ctx, cfg, images, labels)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if result.ImagesProcessed != 0 || result.PixelsProcessed != 0 {
+		t.Errorf("Expected no images processed after cancellation, got %d images and %d pixels", result.ImagesProcessed, result.PixelsProcessed)
+	}
+}
+
+func TestInterruptedBenchmarkReportsCompletedRuns(t *testing.T) {
+	cfg := syntheticConfig()
+	cfg.BatchSize = 10
+	cfg.Warmup, cfg.NumRuns = 1, 5
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+
+	// The interrupt arrives while the third measured run is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	summary, err := runBenchmark(cfg, logger, func() (runResult, error) {
+		calls++
+		if calls == cfg.Warmup+3 {
+			cancel()
+		}
+		return measureRunWithContext(ctx, cfg, images, labels)
+	})
+	if !interrupted(err) {
+		t.Fatalf("Expected an interrupted benchmark, got %v", err)
+	}
+	if summary.Runs != 2 {
+		t.Errorf("Expected 2 completed runs, got %d", summary.Runs)
+	}
+	logInterrupted(cfg, logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{"Interrupted after 2 of 5 runs", "Average Metrics (2 completed runs):", "Average Execution Time:"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
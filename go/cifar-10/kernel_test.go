@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+)
+
+func TestProcessImageKernels(t *testing.T) {
+	cfg := DefaultConfig()
+	image := make([]float32, cfg.ImageSize())
+	for i := range image {
+		image[i] = 1
+	}
+
+	cfg.Kernel = KernelBlur
+	blurred := ProcessImage(cfg, image)
+	if blurred[0] != 1 || image[0] != 1 {
+		t.Errorf("Expected blur to keep a constant image and leave the input alone, got %.2f and %.2f", blurred[0], image[0])
+	}
+
+	cfg.Kernel = KernelSobel
+	edges := ProcessImage(cfg, image)
+	if edges[0] != 0 || edges[1] != 1 || image[0] != 1 {
+		t.Errorf("Expected Sobel to find no edges in a constant image and leave the input alone, got %.2f, %.2f and %.2f", edges[0], edges[1], image[0])
+	}
+
+	cfg.Kernel = KernelDouble
+	doubled := ProcessImage(cfg, image)
+	if doubled[0] != 2 {
+		t.Errorf("Expected double to give 2, got %.2f", doubled[0])
+	}
+}
+
+func TestValidateKernel(t *testing.T) {
+	for _, name := range []string{KernelDouble, KernelBlur, KernelSobel} {
+		if err := validateKernel(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	if err := validateKernel("sharpen"); err == nil {
+		t.Errorf("Expected an error for an unknown kernel")
+	}
+}
+
+// failingKernel is the name of a kernel that fails on any image whose first pixel is failMarker
+// and spends a millisecond on every other image, so the remaining batches are still running
+// when the failure cancels them
+const (
+	failingKernel = "fail-on-marker"
+	failMarker    = -1
+)
+
+func registerFailingKernel(t *testing.T) {
+	t.Helper()
+	kernelFuncs[failingKernel] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		if image[0] == failMarker {
+			return nil, fmt.Errorf("%w: marked image", ErrBadImage)
+		}
+		time.Sleep(time.Millisecond)
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, failingKernel) })
+}
+
+func TestRunProcessingTaskPropagatesKernelErrors(t *testing.T) {
+	registerFailingKernel(t)
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.BatchSize = 500, 50
+	cfg.Kernel = failingKernel
+	if err := validateKernel(cfg.Kernel); err != nil {
+		t.Fatalf("Expected the registered kernel to be valid, got %v", err)
+	}
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	images[0][0] = failMarker
+
+	result, err := runProcessingTask(context.Background(), cfg, images, labels)
+	if !errors.Is(err, ErrBadImage) {
+		t.Fatalf("Expected the kernel error, got %v", err)
+	}
+	// The nine healthy batches take 50 ms each, so cancellation must cut them short
+	if result.ImagesProcessed >= len(images)/2 {
+		t.Errorf("Expected the failure to stop the other batches, but %d of %d images were processed", result.ImagesProcessed, len(images))
+	}
+
+	if _, err := measureRun(cfg, images, labels); !errors.Is(err, ErrBadImage) {
+		t.Errorf("Expected measureRun to report the kernel error, got %v", err)
+	}
+}
+
+func TestProcessImageRejectsWrongSize(t *testing.T) {
+	cfg := DefaultConfig()
+	if _, err := processImage(cfg, make([]float32, cfg.ImageSize()-1)); !errors.Is(err, ErrBadImage) {
+		t.Errorf("Expected ErrBadImage for a short image, got %v", err)
+	}
+	if _, err := processImage(cfg, make([]float32, cfg.ImageSize())); err != nil {
+		t.Errorf("Expected a full-size image to process, got %v", err)
+	}
+}
+
+// BenchmarkKernels compares the double, blur and Sobel kernels on the CIFAR-10 and Tiny ImageNet
+// layouts
+func BenchmarkKernels(b *testing.B) {
+	for _, shape := range [][3]int{{32, 32, 3}, {64, 64, 3}} {
+		for _, kernel := range []string{KernelDouble, KernelBlur, KernelSobel} {
+			cfg := DefaultConfig()
+			cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = shape[0], shape[1], shape[2]
+			cfg.Kernel = kernel
+			image := make([]float32, cfg.ImageSize())
+			for i := range image {
+				image[i] = float32(i%256) / 255
+			}
+
+			b.Run(fmt.Sprintf("%s/%dx%dx%d", kernel, shape[0], shape[1], shape[2]), func(b *testing.B) {
+				b.SetBytes(int64(len(image) * 4))
+				for i := 0; i < b.N; i++ {
+					ProcessImage(cfg, image)
+				}
+			})
+		}
+	}
+}
+
+func TestNormalizeKernelUsesDatasetStats(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth = 8, 8
+	cfg.SyntheticImages = 50
+	cfg.Kernel = KernelNormalize
+	images, _, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	var logOutput bytes.Buffer
+	logger := metricslog.NewStream(&logOutput)
+	cfg = withChannelStats(cfg, logger, images)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+	if !strings.Contains(logOutput.String(), "Channel Statistics: mean [") || !strings.Contains(logOutput.String(), "over 50 images, reduced in") {
+		t.Errorf("Expected the channel statistics and reduction time in the log, got:\n%s", logOutput.String())
+	}
+
+	// Normalized with its own statistics, the dataset has a mean of 0 in every channel
+	var sums [3]float64
+	for _, image := range images {
+		normalized := ProcessImage(cfg, image)
+		for i, v := range normalized {
+			sums[i%3] += float64(v)
+		}
+	}
+	pixels := float64(len(images) * cfg.ImageHeight * cfg.ImageWidth)
+	for c, sum := range sums {
+		if mean := sum / pixels; mean > 1e-4 || mean < -1e-4 {
+			t.Errorf("Expected channel %d to have mean 0 after normalizing, got %v", c, mean)
+		}
+	}
+
+	// Other kernels need no statistics
+	cfg.Kernel, cfg.ChannelStd = KernelDouble, [3]float32{}
+	if withChannelStats(cfg, metricslog.NewStream(io.Discard), images).ChannelStd != ([3]float32{}) {
+		t.Errorf("Expected the config unchanged for the double kernel")
+	}
+}
+
+func TestTiledProcessImageMatchesUntiled(t *testing.T) {
+	cfg := DefaultConfig()
+	rng := rand.New(rand.NewSource(1))
+	image := make([]float32, cfg.ImageSize())
+	for i := range image {
+		image[i] = rng.Float32()
+	}
+	tiled := cfg
+	tiled.TileRows = 5 // Does not divide the image height, so the tiles differ in size
+
+	// Doubling is done in place, so each configuration gets its own copy
+	for _, kernel := range []string{KernelDouble, KernelBlur, KernelSobel} {
+		cfg.Kernel, tiled.Kernel = kernel, kernel
+		expected := ProcessImage(cfg, append([]float32(nil), image...))
+		got := ProcessImage(tiled, append([]float32(nil), image...))
+		for i := range expected {
+			switch kernel {
+			case KernelDouble:
+				if math.Float32bits(got[i]) != math.Float32bits(expected[i]) {
+					t.Fatalf("%s: pixel %d not bit-identical: expected %v, got %v", kernel, i, expected[i], got[i])
+				}
+			default:
+				if math.Abs(float64(got[i]-expected[i])) > 1e-6 {
+					t.Fatalf("%s: pixel %d mismatch: expected %v, got %v", kernel, i, expected[i], got[i])
+				}
+			}
+		}
+	}
+
+	var logOutput bytes.Buffer
+	logger := metricslog.NewStream(&logOutput)
+	tiled.MaxInFlight = 4
+	logTiling(tiled, logger, 10)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+	expected := fmt.Sprintf("Kernel Goroutines: up to 4 batches x 5 tiles = 20 on GOMAXPROCS %d", runtime.GOMAXPROCS(0))
+	if !strings.Contains(logOutput.String(), "Tile Rows per Image: 5") || !strings.Contains(logOutput.String(), expected) {
+		t.Errorf("Expected the tiling and batch settings in the log, got:\n%s", logOutput.String())
+	}
+}
@@ -1,26 +1,30 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/shirou/gopsutil/cpu"
-)
+	"golang.org/x/sync/errgroup"
 
-const (
-	numImagesPerBatch = 10000
-	imageHeight       = 32
-	imageWidth        = 32
-	channels          = 3
-	imageSize         = imageHeight * imageWidth * channels
-	batchSize         = 500 // Processing batch size
-	numRuns           = 100 // Number of times to repeat the task for averaging
+	"golang/internal/energy"
+	"golang/internal/kernels"
+	"golang/internal/labels"
+	"golang/internal/metricslog"
+	"golang/internal/monitor"
+	"golang/internal/result"
+	"golang/internal/subset"
+	"golang/internal/synthetic"
+	"golang/internal/sysinfo"
 )
 
 // ImageBatch represents a batch of images
@@ -29,61 +33,363 @@ type ImageBatch struct {
 	Labels []int
 }
 
-// LoadCIFAR10 loads all CIFAR-10 dataset batches
-func LoadCIFAR10(dataDir string) ([][]float32, []int, error) {
-	var allImages [][]float32
-	var allLabels []int
+// Names of the CIFAR-10 splits that can be selected with the -split flag
+const (
+	SplitTrain = "train"
+	SplitTest  = "test"
+	SplitBoth  = "both"
+)
+
+// CIFAR10LabelNames maps each CIFAR-10 label to its class name, in the order of batches.meta.txt
+var CIFAR10LabelNames = [10]string{"airplane", "automobile", "bird", "cat", "deer", "dog", "frog", "horse", "ship", "truck"}
+
+// labelName returns the class name of label from names, or the label number for labels
+// without a name
+func labelName(names []string, label int) string {
+	if label < 0 || label >= len(names) {
+		return strconv.Itoa(label)
+	}
+	return names[label]
+}
+
+// Dataset holds the images and labels of one CIFAR-10 split
+type Dataset struct {
+	Split  string
+	Images [][]float32
+	Labels []int
+}
+
+// LoadCIFAR10 loads all CIFAR-10 training batches
+func LoadCIFAR10(cfg BenchmarkConfig, dataDir string) ([][]float32, []int, error) {
+	dataset, err := LoadCIFAR10Split(cfg, dataDir, SplitTrain)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataset.Images, dataset.Labels, nil
+}
 
-	// Load all 5 batches
-	for i := 1; i <= 5; i++ {
-		filePath := filepath.Join(dataDir, fmt.Sprintf("data_batch_%d.bin", i))
-		fmt.Printf("Loading batch: %s\n", filePath)
+// LoadCIFAR10Split loads either the five training batches or the test batch, stopping once
+// cfg.Limit images have been read when a limit is set
+func LoadCIFAR10Split(cfg BenchmarkConfig, dataDir, split string) (Dataset, error) {
+	fileNames, err := splitFileNames(split)
+	if err != nil {
+		return Dataset{}, err
+	}
 
-		data, err := ioutil.ReadFile(filePath)
+	dataset := Dataset{Split: split}
+	for _, fileName := range fileNames {
+		images, labels, err := loadCIFAR10Batch(cfg, filepath.Join(dataDir, fileName))
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+			return Dataset{}, err
+		}
+		dataset.Images = append(dataset.Images, images...)
+		dataset.Labels = append(dataset.Labels, labels...)
+		if cfg.Limit > 0 && len(dataset.Images) >= cfg.Limit {
+			dataset.Images, dataset.Labels = dataset.Images[:cfg.Limit], dataset.Labels[:cfg.Limit]
+			break
+		}
+	}
+	return dataset, nil
+}
+
+// splitFileNames returns the CIFAR-10 binary files that make up split
+func splitFileNames(split string) ([]string, error) {
+	switch split {
+	case SplitTrain:
+		var fileNames []string
+		for i := 1; i <= 5; i++ {
+			fileNames = append(fileNames, fmt.Sprintf("data_batch_%d.bin", i))
 		}
+		return fileNames, nil
+	case SplitTest:
+		return []string{"test_batch.bin"}, nil
+	}
+	return nil, fmt.Errorf("unknown split %q: expected %q or %q", split, SplitTrain, SplitTest)
+}
 
-		for j := 0; j < numImagesPerBatch; j++ {
-			label := int(data[j*(imageSize+1)])
-			imageData := data[j*(imageSize+1)+1 : (j+1)*(imageSize+1)]
-			image := make([]float32, imageSize)
-			for k := 0; k < imageSize; k++ {
-				image[k] = float32(imageData[k]) / 255.0
-			}
+// LoadCIFAR10TrainTest loads the training and test splits and returns them separately
+// so they can be processed in different phases
+func LoadCIFAR10TrainTest(cfg BenchmarkConfig, dataDir string) (Dataset, Dataset, error) {
+	train, err := LoadCIFAR10Split(cfg, dataDir, SplitTrain)
+	if err != nil {
+		return Dataset{}, Dataset{}, err
+	}
+	test, err := LoadCIFAR10Split(cfg, dataDir, SplitTest)
+	if err != nil {
+		return Dataset{}, Dataset{}, err
+	}
+	return train, test, nil
+}
+
+// loadDatasets returns the datasets selected by cfg: a synthetic set when cfg.SyntheticImages
+// is set, the CIFAR-100 training set for DatasetCIFAR100, otherwise the requested CIFAR-10
+// split or both splits, each cut down by -limit and -sample-fraction
+func loadDatasets(cfg BenchmarkConfig, dataDir string) ([]Dataset, error) {
+	datasets, err := loadSplits(cfg, dataDir)
+	if err != nil {
+		return nil, err
+	}
+	for i, dataset := range datasets {
+		datasets[i].Images, datasets[i].Labels = subset.Apply(dataset.Images, dataset.Labels, cfg.Limit, cfg.SampleFraction, cfg.Seed)
+	}
+	return datasets, nil
+}
+
+// loadSplits loads the splits of loadDatasets before any -sample-fraction is applied
+func loadSplits(cfg BenchmarkConfig, dataDir string) ([]Dataset, error) {
+	if cfg.SyntheticImages > 0 {
+		numImages := cfg.SyntheticImages
+		if cfg.Limit > 0 && cfg.Limit < numImages {
+			numImages = cfg.Limit
+		}
+		images, labels := synthetic.GenerateSyntheticDataset(numImages, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+		return []Dataset{{Split: "synthetic", Images: images, Labels: labels}}, nil
+	}
+
+	if cfg.Dataset == DatasetCIFAR100 {
+		dataset, err := loadCIFAR100Dataset(cfg, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		return []Dataset{dataset}, nil
+	}
 
-			allImages = append(allImages, image)
-			allLabels = append(allLabels, label)
+	if cfg.Split == SplitBoth {
+		train, test, err := LoadCIFAR10TrainTest(cfg, dataDir)
+		if err != nil {
+			return nil, err
 		}
+		return []Dataset{train, test}, nil
 	}
-	return allImages, allLabels, nil
+
+	dataset, err := LoadCIFAR10Split(cfg, dataDir, cfg.Split)
+	if err != nil {
+		return nil, err
+	}
+	return []Dataset{dataset}, nil
 }
 
-// SimulateImageProcessing performs dummy image transformations
-func SimulateImageProcessing(image []float32) []float32 {
-	for i := range image {
-		image[i] = image[i] * 2
+// loadCIFAR10Batch reads a single CIFAR-10 binary file after checking that it holds
+// exactly cfg.ImagesPerBatch records of the configured image size
+func loadCIFAR10Batch(cfg BenchmarkConfig, filePath string) ([][]float32, []int, error) {
+	fmt.Fprintf(os.Stderr, "Loading batch: %s\n", filePath)
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	imageSize := cfg.ImageSize()
+	expectedSize := cfg.ImagesPerBatch * (imageSize + 1)
+	if len(data) < expectedSize {
+		return nil, nil, fmt.Errorf("file %s is truncated: expected %d bytes (%d records of %d bytes), got %d",
+			filePath, expectedSize, cfg.ImagesPerBatch, imageSize+1, len(data))
+	}
+	if len(data) > expectedSize {
+		return nil, nil, fmt.Errorf("file %s is larger than expected: expected %d bytes (%d records of %d bytes), got %d",
+			filePath, expectedSize, cfg.ImagesPerBatch, imageSize+1, len(data))
 	}
+
+	images := make([][]float32, 0, cfg.ImagesPerBatch)
+	labels := make([]int, 0, cfg.ImagesPerBatch)
+	for j := 0; j < cfg.ImagesPerBatch; j++ {
+		label := int(data[j*(imageSize+1)])
+		imageData := data[j*(imageSize+1)+1 : (j+1)*(imageSize+1)]
+		image := make([]float32, imageSize)
+		for k := 0; k < imageSize; k++ {
+			image[k] = float32(imageData[k]) / 255.0
+		}
+
+		images = append(images, image)
+		labels = append(labels, label)
+	}
+	return images, labels, nil
+}
+
+// SimulateImageProcessing performs dummy image transformations on an image of the configured shape.
+// It fails with ErrInjected on a random cfg.ErrorRate of the images, leaving them untouched, so
+// the error handling of the benchmark can be exercised without a kernel that really fails.
+func SimulateImageProcessing(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+	if cfg.ErrorRate > 0 && rand.Float64() < cfg.ErrorRate {
+		return nil, ErrInjected
+	}
+	return doubleImage(cfg, image), nil
+}
+
+// doubleImage doubles every pixel of image in place, with the rows split among cfg.TileRows
+// goroutines when it is above 1
+func doubleImage(cfg BenchmarkConfig, image []float32) []float32 {
+	if cfg.TileRows <= 1 {
+		doubleRows(cfg, image, 0, cfg.ImageHeight)
+		return image
+	}
+	kernels.Tile(cfg.ImageHeight, cfg.TileRows, func(y0, y1 int) {
+		doubleRows(cfg, image, y0, y1)
+	})
 	return image
 }
 
+// doubleRows doubles the pixels of rows [y0, y1) of image in place, in one linear pass over
+// their span of the flat slice
+func doubleRows(cfg BenchmarkConfig, image []float32, y0, y1 int) {
+	rowSize := cfg.ImageWidth * cfg.Channels
+	rows := image[y0*rowSize : y1*rowSize]
+	for i := range rows {
+		rows[i] = rows[i] * 2
+	}
+}
+
+// SimulateImageProcessingReadOnly visits the same pixels as SimulateImageProcessing, in the same
+// order, but only reads them, returning their sum. Comparing the two separates the cost of
+// writing pixels back to memory from the cost of scheduling the work.
+func SimulateImageProcessingReadOnly(cfg BenchmarkConfig, image []float32) float32 {
+	var sum float32
+	for _, pixel := range image[:cfg.ImageSize()] {
+		sum += pixel
+	}
+	return sum
+}
+
+// ProcessAnyImage applies SimulateImageProcessing to an image held in an interface{}.
+// Raw []uint8 pixels are normalized to float32 first; unsupported types return an error
+// rather than panicking on a failed type assertion.
+func ProcessAnyImage(cfg BenchmarkConfig, img interface{}) ([]float32, error) {
+	switch pixels := img.(type) {
+	case []float32:
+		return SimulateImageProcessing(cfg, pixels)
+	case []uint8:
+		image := make([]float32, len(pixels))
+		for i, p := range pixels {
+			image[i] = float32(p) / 255.0
+		}
+		return SimulateImageProcessing(cfg, image)
+	default:
+		return nil, fmt.Errorf("unsupported image type %T", img)
+	}
+}
+
 // ProcessBatch processes a batch of images concurrently
-func ProcessBatch(batch ImageBatch, wg *sync.WaitGroup) {
+func ProcessBatch(cfg BenchmarkConfig, batch ImageBatch, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for i, image := range batch.Images {
+		batch.Images[i] = ProcessImage(cfg, image)
+	}
+}
+
+// ProcessBatchReadOnly checksums a batch of images with SimulateImageProcessingReadOnly, leaving
+// the pixels untouched, and stores the sum of the image checksums in checksum
+func ProcessBatchReadOnly(cfg BenchmarkConfig, batch ImageBatch, wg *sync.WaitGroup, checksum *float32) {
+	defer wg.Done()
+	var sum float32
+	for _, image := range batch.Images {
+		sum += SimulateImageProcessingReadOnly(cfg, image)
+	}
+	*checksum = sum
+}
+
+// ProcessBatchAtomic processes a batch of images like ProcessBatch, adding one to processed after
+// each image. Every worker increments the same counter, so comparing it with ProcessBatch measures
+// the cost of contended atomic increments on a hot path.
+func ProcessBatchAtomic(cfg BenchmarkConfig, batch ImageBatch, wg *sync.WaitGroup, processed *atomic.Int64) {
 	defer wg.Done()
 	for i, image := range batch.Images {
-		batch.Images[i] = SimulateImageProcessing(image)
+		batch.Images[i] = ProcessImage(cfg, image)
+		processed.Add(1)
 	}
 }
 
-// RunProcessingTask runs the preprocessing task once and returns execution time and concurrency overhead
-func RunProcessingTask(images [][]float32, labels []int) (time.Duration, time.Duration) {
+// ProcessBatchWithContext processes a batch of images like ProcessBatch, but stops before
+// the next image once ctx is done. It returns the number of images processed, with ctx.Err() if
+// processing stopped early or the kernel's error, naming the image, if an image failed.
+func ProcessBatchWithContext(ctx context.Context, cfg BenchmarkConfig, batch ImageBatch) (int, error) {
+	for i, image := range batch.Images {
+		select {
+		case <-ctx.Done():
+			return i, ctx.Err()
+		default:
+		}
+		processed, err := processImage(cfg, image)
+		if err != nil {
+			return i, fmt.Errorf("image %d of batch: %w", i, err)
+		}
+		batch.Images[i] = processed
+	}
+	return len(batch.Images), nil
+}
+
+// RunProcessingTaskWithContext runs the preprocessing task once like RunProcessingTask, returning
+// early once ctx is done. Timings then cover the partial run and the error wraps ctx.Err(), so
+// callers can detect a timeout with errors.Is(err, context.DeadlineExceeded). An image the kernel
+// fails on stops the run too, with an error wrapping the kernel's.
+func RunProcessingTaskWithContext(ctx context.Context, cfg BenchmarkConfig, images [][]float32, labels []int) (time.Duration, time.Duration, error) {
+	result, err := runProcessingTask(ctx, cfg, images, labels)
+	return result.ExecutionTime, result.ConcurrencyOverhead, err
+}
+
+// RunProcessingTask runs the preprocessing task once and returns its timings and the amount of work done.
+// Concurrency overhead spans the whole call, starting before the images are partitioned into batches,
+// while execution time starts once the batches are ready and goroutines are about to be launched.
+// The difference between the two is the cost of setting up the concurrent work.
+// Goroutine spawn duration runs from the first go statement until the last goroutine has started
+// executing, isolating the scheduler's spawn overhead from the processing itself.
+// The image and pixel counts cover only full batches; a trailing partial batch is not processed.
+// An image the kernel fails on stops the run early with lower counts and an error wrapping the
+// kernel's, such as ErrInjected.
+// Batch durations holds the time each batch goroutine took from starting to finishing, indexed by
+// batch, to expose load imbalance that the execution time hides. With cfg.MaxInFlight set, a
+// goroutine waits for one of that many slots before processing, and its duration starts once it
+// has one.
+func RunProcessingTask(cfg BenchmarkConfig, images [][]float32, labels []int) (executionTime, concurrencyOverhead, goroutineSpawnDuration time.Duration, imagesProcessed, pixelsProcessed int, batchDurations []time.Duration, err error) {
+	result, err := runProcessingTask(context.Background(), cfg, images, labels)
+	return result.ExecutionTime, result.ConcurrencyOverhead, result.GoroutineSpawnDuration, result.ImagesProcessed, result.PixelsProcessed, result.BatchDurations, err
+}
+
+// RunProcessingTaskTTFB runs the preprocessing task once like RunProcessingTask and returns its
+// time to first batch: from entry until the first batch has finished processing, covering the
+// partitioning, the goroutine launches and one batch of work that the total time hides. The total
+// spans the whole call, like the concurrency overhead of RunProcessingTask. The time to first
+// batch is 0 when no batch completed.
+func RunProcessingTaskTTFB(cfg BenchmarkConfig, images [][]float32, labels []int) (ttfb time.Duration, total time.Duration) {
+	result, _ := runProcessingTask(context.Background(), cfg, images, labels)
+	return result.TimeToFirstBatch, result.ConcurrencyOverhead
+}
+
+// RunProcessingTaskErrgroup runs the preprocessing task once like RunProcessingTask, under ctx,
+// and returns only the first error a batch fails with. The batches run in an errgroup, so
+// that error cancels the batches still waiting or running, which stop before their next image.
+// The error wraps the kernel's, such as ErrInjected, or ctx.Err() when ctx is done first.
+func RunProcessingTaskErrgroup(ctx context.Context, cfg BenchmarkConfig, images [][]float32, labels []int) error {
+	_, err := runProcessingTask(ctx, cfg, images, labels)
+	return err
+}
+
+// taskResult holds the timings and work counts of one pass of the preprocessing task, as
+// described on RunProcessingTask, and its time to first batch as described on RunProcessingTaskTTFB
+type taskResult struct {
+	ExecutionTime          time.Duration
+	ConcurrencyOverhead    time.Duration
+	GoroutineSpawnDuration time.Duration
+	ImagesProcessed        int
+	PixelsProcessed        int
+	BatchDurations         []time.Duration
+	TimeToFirstBatch       time.Duration
+}
+
+// runProcessingTask is RunProcessingTask with cancellation and error propagation. Batch workers
+// check ctx between images, so a cancelled run stops promptly, and the first image a kernel
+// fails on cancels the remaining batches. The counts then cover only the images processed, and
+// the error wraps ctx.Err() or the kernel's error. Time to first batch is that of
+// RunProcessingTaskTTFB.
+func runProcessingTask(ctx context.Context, cfg BenchmarkConfig, images [][]float32, labels []int) (result taskResult, err error) {
+	startOverhead := time.Now()
+
 	// Divide into batches
 	totalImages := len(images)
-	numBatches := totalImages / batchSize
+	numBatches := totalImages / cfg.BatchSize
 	batches := make([]ImageBatch, numBatches)
 	for i := 0; i < numBatches; i++ {
-		start := i * batchSize
-		end := start + batchSize
+		start := i * cfg.BatchSize
+		end := start + cfg.BatchSize
 		batches[i] = ImageBatch{
 			Images: images[start:end],
 			Labels: labels[start:end],
@@ -91,19 +397,66 @@ func RunProcessingTask(images [][]float32, labels []int) (time.Duration, time.Du
 	}
 
 	// Start concurrent processing
-	startOverhead := time.Now()
 	startExecution := time.Now()
 
-	var wg sync.WaitGroup
-	for _, batch := range batches {
-		wg.Add(1)
-		go ProcessBatch(batch, &wg)
+	// Each goroutine reports its start time on the barrier channel before processing
+	started := make(chan time.Time, numBatches)
+	gpu := &GPUTransferSimulator{TransferLatencyPerMB: cfg.GPUTransferLatency}
+	group, groupCtx := errgroup.WithContext(ctx)
+	var processed atomic.Int64
+	// Nanoseconds from startOverhead until the first batch finished, set once by that batch
+	var firstBatch atomic.Int64
+	// Each goroutine writes only its own batch's slot, so no locking is needed
+	result.BatchDurations = make([]time.Duration, numBatches)
+	// Every batch still gets its goroutine, but it holds a slot of the semaphore while processing,
+	// so at most cfg.MaxInFlight batches run at once; a nil semaphore leaves them unbounded
+	var inFlight chan struct{}
+	if cfg.MaxInFlight > 0 {
+		inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+	for i, batch := range batches {
+		group.Go(func() error {
+			started <- time.Now()
+			if inFlight != nil {
+				select {
+				case inFlight <- struct{}{}:
+					defer func() { <-inFlight }()
+				case <-groupCtx.Done():
+					return nil
+				}
+			}
+			start := time.Now()
+			gpu.Transfer(batch)
+			n, err := ProcessBatchWithContext(groupCtx, cfg, batch)
+			result.BatchDurations[i] = time.Since(start)
+			processed.Add(int64(n))
+			if err == nil {
+				firstBatch.CompareAndSwap(0, int64(time.Since(startOverhead)))
+			}
+			return err
+		})
 	}
-	wg.Wait()
+	groupErr := group.Wait()
 
-	executionTime := time.Since(startExecution)
-	concurrencyOverhead := time.Since(startOverhead)
-	return executionTime, concurrencyOverhead
+	result.ExecutionTime = time.Since(startExecution)
+	result.ConcurrencyOverhead = time.Since(startOverhead)
+
+	close(started)
+	for startTime := range started {
+		if spawn := startTime.Sub(startExecution); spawn > result.GoroutineSpawnDuration {
+			result.GoroutineSpawnDuration = spawn
+		}
+	}
+	result.TimeToFirstBatch = time.Duration(firstBatch.Load())
+	result.ImagesProcessed = int(processed.Load())
+	result.PixelsProcessed = result.ImagesProcessed * cfg.ImageHeight * cfg.ImageWidth
+	switch {
+	case ctx.Err() != nil && result.ImagesProcessed < numBatches*cfg.BatchSize:
+		err = fmt.Errorf("processing stopped after %d of %d images: %w", result.ImagesProcessed, numBatches*cfg.BatchSize, ctx.Err())
+	case groupErr != nil:
+		err = fmt.Errorf("processing failed after %d of %d images: %w", result.ImagesProcessed, numBatches*cfg.BatchSize, groupErr)
+	}
+	return result, err
 }
 
 // AppendToLogFile appends a string to the specified log file
@@ -120,70 +473,411 @@ func AppendToLogFile(filePath, message string) error {
 	return nil
 }
 
-// calculateCPUUsage calculates average CPU utilization during a processing window
-func calculateCPUUsage(duration time.Duration) (float64, error) {
-	percentages, err := cpu.Percent(duration, false)
-	if err != nil {
-		return 0, err
+// throughput returns count per second over the given duration, or 0 for an empty duration
+func throughput(count int, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(count) / duration.Seconds()
+}
+
+// logClasses writes the number of distinct classes and the images per class, so an imbalanced
+// load such as a -limit cutting into the last classes is visible. Classes are named from names
+// where it has an entry for the label.
+func logClasses(logger *metricslog.Logger, imageLabels []int, names []string) {
+	histogram := labels.Histogram(imageLabels)
+	logger.Printf("Number of Classes: %d\n", len(histogram))
+	if len(histogram) == 0 {
+		return
+	}
+	min, max := labels.CountRange(histogram)
+	logger.Printf("Images per Class: min %d, max %d", min, max)
+	for _, c := range histogram {
+		logger.Printf("  class %d (%s): %d", c.Label, labelName(names, c.Label), c.Count)
 	}
-	return percentages[0], nil
 }
 
 func main() {
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if cfg.NumSeeds < 1 {
+		log.Fatalf("-num-seeds must be at least 1, got %d", cfg.NumSeeds)
+	}
+	if err := validateKernel(cfg.Kernel); err != nil {
+		log.Fatalf("Invalid -kernel: %v", err)
+	}
+	if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		log.Fatalf("-error-rate must be between 0 and 1, got %v", cfg.ErrorRate)
+	}
+	if cfg.ErrorRate > 0 && cfg.Kernel != KernelDouble {
+		log.Fatalf("-error-rate only applies to the double kernel, got -kernel %s", cfg.Kernel)
+	}
+	if err := validateDataset(cfg.Dataset); err != nil {
+		log.Fatalf("Invalid -dataset: %v", err)
+	}
+	if _, err := labels.ParseFormat(cfg.DumpLabels); err != nil {
+		log.Fatalf("Invalid -dump-labels: %v", err)
+	}
+	if cfg.Dataset == DatasetCIFAR100 && cfg.Pipeline && cfg.SyntheticImages == 0 {
+		log.Fatalf("-pipeline streams CIFAR-10 batch files and cannot load -dataset %s", DatasetCIFAR100)
+	}
+	if cfg.GPUTransferLatency < 0 {
+		log.Fatalf("-gpu-transfer-latency must not be negative, got %v", cfg.GPUTransferLatency)
+	}
+	if cfg.DRAMBandwidth < 0 {
+		log.Fatalf("-dram-bandwidth must not be negative, got %v", cfg.DRAMBandwidth)
+	}
+	if cfg.Baseline && cfg.Kernel != KernelDouble {
+		log.Fatalf("-baseline only supports -kernel %s", KernelDouble)
+	}
+	if cfg.MaxInFlight < 0 {
+		log.Fatalf("-max-inflight must not be negative, got %d", cfg.MaxInFlight)
+	}
+	if cfg.Cooldown < 0 {
+		log.Fatalf("-cooldown must not be negative, got %v", cfg.Cooldown)
+	}
+	if cfg.FreeOSMemory && !cfg.GCBetweenRuns {
+		log.Fatalf("-free-os-memory requires -gc-between-runs")
+	}
+	if cfg.TileRows < 1 {
+		log.Fatalf("-tile-rows must be at least 1, got %d", cfg.TileRows)
+	}
+	if cfg.TileRows > 1 && cfg.Kernel == KernelNormalize {
+		log.Fatalf("-tile-rows only applies to the double, blur and sobel kernels, got -kernel %s", cfg.Kernel)
+	}
+	if cfg.PipelineWorkers < 1 || cfg.PipelineBuffer < 1 {
+		log.Fatalf("-pipeline-workers and -pipeline-buffer must be at least 1, got %d and %d", cfg.PipelineWorkers, cfg.PipelineBuffer)
+	}
+	var sweep []int
+	if cfg.MaxProcsSweep != "" {
+		settings, err := parseMaxProcsSweep(cfg.MaxProcsSweep)
+		if err != nil {
+			log.Fatalf("Invalid -maxprocs-sweep: %v", err)
+		}
+		if cfg.Once || cfg.NumSeeds > 1 {
+			log.Fatalf("-maxprocs-sweep cannot be combined with -once or -num-seeds")
+		}
+		sweep = settings
+	}
+	if cfg.InfluxPath != "" && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-influx cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.BatchTimingsPath != "" && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-batch-timings cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.DryRun && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-dry-run cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.PerClass && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-per-class cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.SampleFraction < 0 || cfg.SampleFraction > 1 {
+		log.Fatalf("-sample-fraction must be between 0 and 1, got %v", cfg.SampleFraction)
+	}
+	if cfg.Kernel == KernelNormalize && cfg.Pipeline {
+		log.Fatalf("-kernel normalize cannot be combined with -pipeline, which streams the dataset before its statistics are known")
+	}
+	if cfg.SampleFraction > 0 && cfg.Pipeline {
+		log.Fatalf("-sample-fraction cannot be combined with -pipeline, which streams the dataset")
+	}
+	if cfg.MemProfilePath != "" && (cfg.Once || cfg.Pipeline) {
+		log.Fatalf("-memprofile cannot be combined with -once or -pipeline")
+	}
+	if cfg.MutexProfilePath != "" && (cfg.Once || cfg.Pipeline) {
+		log.Fatalf("-mutexprofile cannot be combined with -once or -pipeline")
+	}
+	if cfg.JSONPath != "" && !cfg.Once && sweep == nil {
+		log.Fatalf("-json is only supported together with -once or -maxprocs-sweep")
+	}
+
+	// runtime.NumCPU sees the host's CPUs, not a container's quota, so GOMAXPROCS is lowered
+	// to the quota before anything runs
+	cpuLimitSummary := sysinfo.ApplyContainerCPULimit()
+
+	dataDir := dataDirFor(cfg.Dataset)
+	if cfg.Once {
+		if cfg.JSONPath == "" || cfg.JSONPath == "-" {
+			if err := runOnce(cfg, dataDir, os.Stdout, os.Stderr); err != nil {
+				log.Fatalf("Error running one-shot benchmark: %v", err)
+			}
+			return
+		}
+		file, err := os.Create(cfg.JSONPath)
+		if err != nil {
+			log.Fatalf("Error creating JSON output: %v", err)
+		}
+		if err := runOnce(cfg, dataDir, file, os.Stderr); err != nil {
+			log.Fatalf("Error running one-shot benchmark: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			log.Fatalf("Error writing JSON output: %v", err)
+		}
+		return
+	}
+
 	logFilePath := "go_cifar10_metrics_result.log"
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		log.Fatalf("Error opening metrics log: %v", err)
+	}
+	defer func() {
+		if err := logger.Close(); err != nil {
+			log.Fatalf("Error writing metrics log: %v", err)
+		}
+	}()
 
 	// Load CIFAR-10 dataset
-	err := AppendToLogFile(logFilePath, "Loading CIFAR-10 dataset...")
-	dataDir := "../../cifar-10-batches-bin/"
-	images, labels, err := LoadCIFAR10(dataDir)
+	logMetadata(logger, result.NewMetadata(cfg.Dataset))
+	if err := logger.Printf("Loading %s dataset...", datasetTitle(cfg.Dataset)); err != nil {
+		log.Fatalf("Error writing metrics log: %v", err)
+	}
+	// Multi-socket hosts add NUMA effects that make runs hard to compare
+	if sockets, err := sysinfo.DetectCPUSockets(); err != nil {
+		logger.Printf("CPU Sockets: unknown (%v)", err)
+	} else {
+		logger.Printf("CPU Sockets: %d", sockets)
+		if warning := sysinfo.SocketWarning(sockets); warning != "" {
+			logger.Printf("%s", warning)
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+	logger.Printf("%s", cpuLimitSummary)
+	meter := newEnergyMeter(logger, energy.DefaultRoot)
+	var progress *monitor.Monitor
+	if cfg.ListenAddr != "" {
+		m, addr, stop, err := startMonitor(cfg.ListenAddr)
+		if err != nil {
+			logger.Fatalf("Error starting -listen server: %v", err)
+		}
+		defer stop()
+		progress = m
+		logger.Printf("Serving progress metrics on http://%s/metrics", addr)
+	}
+	memProfiles := newMemProfiler(cfg, logger)
+	collectors, err := newCollectorSet(cfg, logger)
 	if err != nil {
-		log.Fatalf("Error loading CIFAR-10: %v", err)
+		logger.Fatalf("Invalid -collectors: %v", err)
+	}
+
+	if cfg.Pipeline {
+		stopProfile, err := startBenchmarkProfile(cfg, logger)
+		if err != nil {
+			logger.Fatalf("Error starting -cpuprofile: %v", err)
+		}
+		if err := runPipelineMode(cfg, logger, dataDir); err != nil {
+			logger.Fatalf("Error running pipeline benchmark: %v", err)
+		}
+		if err := stopProfile(); err != nil {
+			logger.Fatalf("Error writing -cpuprofile: %v", err)
+		}
+		return
 	}
-	err = AppendToLogFile(logFilePath, "Dataset loaded successfully.")
 
-	err = AppendToLogFile(logFilePath, "\nDataset Parameters:")
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Total Images: %d\n", len(images)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Image Shape: %d x %d x %d (Height x Width x Channels)\n", imageHeight, imageWidth, channels))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Number of Classes: %d\n", 10))
+	startLoading := time.Now()
+	datasets, err := loadDatasets(cfg, dataDir)
+	if err != nil {
+		logger.Fatalf("Error loading %s: %v", datasetTitle(cfg.Dataset), err)
+	}
+	loadingTime := time.Since(startLoading)
+	logger.Printf("Dataset loaded successfully.")
+	for _, dataset := range datasets {
+		logSubset(cfg, logger, dataset.Split, len(dataset.Images))
+	}
+	if cfg.DryRun {
+		totalImages := 0
+		for _, dataset := range datasets {
+			totalImages += len(dataset.Images)
+		}
+		logDryRun(logger, totalImages, loadingTime)
+		return
+	}
+	// With -split both the statistics come from the training split, as real preprocessing does
+	cfg = withChannelStats(cfg, logger, datasets[0].Images)
+	if cfg.GridPath != "" {
+		if err := saveGrid(cfg, datasets[0].Images, cfg.GridPath); err != nil {
+			logger.Fatalf("Error saving sample grid: %v", err)
+		}
+		logger.Printf("Sample grid saved to %s", cfg.GridPath)
+	}
+	var classNames []string
+	if cfg.Dataset == DatasetCIFAR10 {
+		classNames = CIFAR10LabelNames[:]
+	}
+	if cfg.DumpDir != "" {
+		for _, dataset := range datasets {
+			if err := writeDump(cfg, dataset, classNames); err != nil {
+				logger.Fatalf("Error writing -dump-dir: %v", err)
+			}
+		}
+		logger.Printf("Processed images and %s labels dumped to %s", cfg.DumpLabels, cfg.DumpDir)
+	}
 
-	var totalExecutionTime, totalConcurrencyOverhead time.Duration
-	var totalMemoryUsage uint64
-	var totalCPUUsage float64
+	totalImages := 0
+	for _, dataset := range datasets {
+		totalImages += len(dataset.Images)
+	}
 
-	for i := 0; i < numRuns; i++ {
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("\nRun %d/%d...\n", i+1, numRuns))
+	logger.Printf("\nDataset Parameters:")
+	logger.Printf("Split: %s", cfg.Split)
+	logger.Printf("Total Images: %d\n", totalImages)
+	logger.Printf("Image Shape: %d x %d x %d (Height x Width x Channels)\n", cfg.ImageHeight, cfg.ImageWidth, cfg.Channels)
+	if cfg.MaxInFlight > 0 {
+		logger.Printf("Max In-Flight Batches: %d", cfg.MaxInFlight)
+	} else {
+		logger.Printf("Max In-Flight Batches: unlimited")
+	}
+	logTiling(cfg, logger, totalImages/cfg.BatchSize)
+	logBetweenRuns(cfg, logger)
+	logGoroutineCreationRate(logger, sysinfo.GoroutineCreationRate(sysinfo.GoroutineRateSamples), totalImages/cfg.BatchSize)
+	if cfg.GPUTransferLatency > 0 {
+		logger.Printf("Simulated GPU Transfer Latency: %.2f ms/MB", cfg.GPUTransferLatency)
+	}
+	if cfg.DRAMBandwidth > 0 {
+		logger.Printf("DRAM Bandwidth: %.2f GB/s (memory-bound maximum %.2f images/second)", cfg.DRAMBandwidth, maxThroughput(cfg))
+	}
+	var allLabels []int
+	for _, dataset := range datasets {
+		allLabels = append(allLabels, dataset.Labels...)
+	}
+	logClasses(logger, allLabels, classNames)
+	if cfg.PerClass {
+		for _, dataset := range datasets {
+			logger.Printf("\nPer-Class Throughput (%s):", dataset.Split)
+			if _, err := RunPerClassBenchmark(cfg, logger, groupByClass(dataset.Images, dataset.Labels)); err != nil {
+				logger.Fatalf("Error running per-class benchmark: %v", err)
+			}
+		}
+		return
+	}
+	if cfg.MutexProfilePath != "" {
+		if err := CollectMutexProfile(cfg, logger, datasets[0].Images, datasets[0].Labels, cfg.MutexProfilePath); err != nil {
+			logger.Fatalf("Error collecting -mutexprofile: %v", err)
+		}
+	}
 
-		var memStatsBefore runtime.MemStats
-		runtime.ReadMemStats(&memStatsBefore)
-		memoryBefore := memStatsBefore.Alloc
+	jsonOut, closeJSON, err := createSweepOutput(cfg.JSONPath)
+	if err != nil {
+		logger.Fatalf("Error creating JSON output: %v", err)
+	}
 
-		executionTime, concurrencyOverhead := RunProcessingTask(images, labels)
+	stopProfile, err := startBenchmarkProfile(cfg, logger)
+	if err != nil {
+		logger.Fatalf("Error starting -cpuprofile: %v", err)
+	}
 
-		var memStatsAfter runtime.MemStats
-		runtime.ReadMemStats(&memStatsAfter)
-		memoryAfter := memStatsAfter.Alloc
-		memoryUsage := memoryAfter - memoryBefore
+	// From here on Ctrl-C stops the current run and reports the completed ones
+	ctx := interruptContext()
 
-		startCPUTime := time.Now()
-		cpuUsage, err := calculateCPUUsage(time.Since(startCPUTime))
-		if err != nil {
-			log.Fatalf("Error calculating CPU usage: %v", err)
+	// Each split is processed in its own phase with separate averages
+	var records []result.MetricRecord
+	var influxResults []BenchmarkResult
+	var batchTimings []result.BatchTimingRecord
+	for _, dataset := range datasets {
+		images, labels := dataset.Images, dataset.Labels
+		logger.Printf("\nPhase: %s (%d images)", dataset.Split, len(images))
+		var checksum string
+		if cfg.HashDataset {
+			checksum = hashDataset(logger, images)
 		}
 
-		totalExecutionTime += executionTime
-		totalConcurrencyOverhead += concurrencyOverhead
-		totalMemoryUsage += memoryUsage
-		totalCPUUsage += cpuUsage
+		// With several seeds the loop is repeated over differently shuffled copies of the split
+		var seeds []int64
+		var seedAverages []time.Duration
+		for i := 0; i < cfg.NumSeeds; i++ {
+			runImages, runLabels := images, labels
+			datasetName := cfg.Dataset + "-" + dataset.Split
+			if cfg.NumSeeds > 1 {
+				seed := cfg.Seed + int64(i)
+				logger.Printf("\nSeed %d (%d/%d)", seed, i+1, cfg.NumSeeds)
+				runImages, runLabels = shuffleImages(images, labels, seed)
+				seeds = append(seeds, seed)
+				datasetName = fmt.Sprintf("%s-seed%d", datasetName, seed)
+			}
+
+			run := func() (runResult, error) {
+				return measureRunWithContext(ctx, cfg, runImages, runLabels)
+			}
+			if collectors != nil {
+				run = withCollectors(cfg, cfg.Dataset, collectors, run)
+			}
+			if meter != nil {
+				run = withEnergy(meter, run)
+			}
+			if progress != nil {
+				run = withMonitor(cfg, progress, run)
+			}
+			if memProfiles != nil {
+				run = memProfiles.wrap(run)
+			}
+
+			if sweep != nil {
+				sweepDataset := result.Dataset{Split: dataset.Split, Images: len(images), SHA256: checksum}
+				sweepRecords, err := runMaxProcsSweep(cfg, logger, datasetName, sweepDataset, sweep, run, jsonOut)
+				if err != nil {
+					logger.Fatalf("Error running GOMAXPROCS sweep: %v", err)
+				}
+				records = append(records, sweepRecords...)
+				continue
+			}
 
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("Execution Time for Run %d: %.2f seconds", i+1, executionTime.Seconds()))
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("Concurrency Overhead for Run %d: %.2f seconds", i+1, concurrencyOverhead.Seconds()))
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("Memory Usage for Run %d: %.2f MB", i+1, float64(memoryUsage)/(1024*1024)))
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("CPU Utilization for Run %d: %.2f%%", i+1, cpuUsage*100))
+			summary, err := runBenchmark(cfg, logger, run)
+			if err != nil {
+				if interrupted(err) {
+					exitInterrupted(cfg, logger, logFilePath, summary)
+				}
+				exitFailed(logger, summary, err)
+			}
+
+			logger.Printf("\nAverage Metrics (%s):", dataset.Split)
+			logAverages(logger, summary)
+			logBandwidthEfficiency(cfg, logger, "Average Memory Bandwidth Efficiency", throughput(summary.Total.ImagesProcessed, summary.Total.ExecutionTime))
+			seedAverages = append(seedAverages, summary.averages().ExecutionTime)
+			records = append(records, metricRecords(datasetName, summary)...)
+			batchTimings = append(batchTimings, batchTimingRecords(datasetName, summary)...)
+			if cfg.InfluxPath != "" {
+				influxResults = append(influxResults, newBenchmarkResult(cfg.Dataset, cfg, dataset.Split, len(runImages), summary))
+			}
+		}
+		if cfg.NumSeeds > 1 {
+			logSeedVariance(logger, seeds, seedAverages)
+		}
+		if cfg.Baseline {
+			baselines, err := runBaselines(cfg, logger, images, labels)
+			if err != nil {
+				logger.Fatalf("Error running baselines: %v", err)
+			}
+			baselineName := cfg.Dataset + "-" + dataset.Split
+			records = append(records, metricRecords(baselineName+"-sequential", baselines.Sequential)...)
+			records = append(records, metricRecords(baselineName+"-bare-loop", baselines.BareLoop)...)
+		}
 	}
 
-	err = AppendToLogFile(logFilePath, "\nAverage Metrics:")
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average Execution Time: %.2f seconds", totalExecutionTime.Seconds()/float64(numRuns)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average Concurrency Overhead: %.2f seconds", totalConcurrencyOverhead.Seconds()/float64(numRuns)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average Memory Usage: %.2f MB", float64(totalMemoryUsage)/(float64(numRuns)*1024*1024)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average CPU Utilization: %.2f%%", (totalCPUUsage/float64(numRuns))*100))
+	if err := stopProfile(); err != nil {
+		logger.Fatalf("Error writing -cpuprofile: %v", err)
+	}
+	if err := closeJSON(); err != nil {
+		logger.Fatalf("Error writing JSON output: %v", err)
+	}
+	if collectors != nil {
+		logCollectorOverhead(logger, collectors)
+	}
+	if cfg.CSVPath != "" {
+		if err := result.WriteCSV(cfg.CSVPath, records); err != nil {
+			logger.Fatalf("Error writing CSV results: %v", err)
+		}
+		logger.Printf("\nPer-run results written to %s", cfg.CSVPath)
+	}
+	if cfg.BatchTimingsPath != "" {
+		if err := result.WriteBatchTimingsCSV(cfg.BatchTimingsPath, batchTimings); err != nil {
+			logger.Fatalf("Error writing batch timings: %v", err)
+		}
+		logger.Printf("Per-batch timings written to %s", cfg.BatchTimingsPath)
+	}
+	if cfg.InfluxPath != "" {
+		if err := writeInflux(cfg.InfluxPath, influxResults); err != nil {
+			logger.Fatalf("Error writing line protocol results: %v", err)
+		}
+		logger.Printf("Line protocol results written to %s", cfg.InfluxPath)
+	}
 }
@@ -1,16 +1,46 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+	"golang/internal/synthetic"
 )
 
+// runMain makes the test binary run main() instead of the tests; TestMainIntegration
+// re-executes itself with it to exercise main() in a subprocess
+var runMain = flag.Bool("test.run-main", false, "run main() with the arguments after --")
+
+// requireDataset skips a test that reads a downloaded dataset in -short mode or when dataDir
+// does not exist, so the suite passes without the multi-gigabyte downloads
+func requireDataset(t *testing.T, dataDir string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping real dataset test in -short mode")
+	}
+	if _, err := os.Stat(dataDir); err != nil {
+		t.Skipf("Dataset not available: %v", err)
+	}
+}
+
 func TestLoadCIFAR10(t *testing.T) {
-	dataDir := "../../cifar-10-batches-bin/"
-	images, labels, err := LoadCIFAR10(dataDir)
+	cfg := DefaultConfig()
+	dataDir := defaultDataDir
+	requireDataset(t, dataDir)
+	images, labels, err := LoadCIFAR10(cfg, dataDir)
 	if err != nil {
 		t.Fatalf("Failed to load CIFAR-10 dataset: %v", err)
 	}
@@ -24,19 +54,23 @@ func TestLoadCIFAR10(t *testing.T) {
 	}
 
 	for i, img := range images {
-		if len(img) != imageSize {
-			t.Errorf("Image %d size mismatch: expected %d, got %d", i, imageSize, len(img))
+		if len(img) != cfg.ImageSize() {
+			t.Errorf("Image %d size mismatch: expected %d, got %d", i, cfg.ImageSize(), len(img))
 		}
 	}
 }
 
 func TestSimulateImageProcessing(t *testing.T) {
-	image := make([]float32, imageSize)
+	cfg := DefaultConfig()
+	image := make([]float32, cfg.ImageSize())
 	for i := range image {
 		image[i] = 1.0
 	}
 
-	processedImage := SimulateImageProcessing(image)
+	processedImage, err := SimulateImageProcessing(cfg, image)
+	if err != nil {
+		t.Fatalf("Expected no error without -error-rate, got %v", err)
+	}
 	for i, val := range processedImage {
 		if val != 2.0 {
 			t.Errorf("Pixel %d value mismatch: expected 2.0, got %.2f", i, val)
@@ -44,15 +78,36 @@ func TestSimulateImageProcessing(t *testing.T) {
 	}
 }
 
+// BenchmarkSimulateImageProcessing shows how the throughput of the doubling loop scales from the
+// CIFAR-10 and Tiny ImageNet shapes up to images that no longer fit in the CPU caches
+func BenchmarkSimulateImageProcessing(b *testing.B) {
+	for _, size := range []int{32, 64, 224, 512} {
+		cfg := DefaultConfig()
+		cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = size, size, 3
+		image := make([]float32, cfg.ImageSize())
+		for i := range image {
+			image[i] = 1.0
+		}
+
+		b.Run(fmt.Sprintf("%dx%dx%d", size, size, cfg.Channels), func(b *testing.B) {
+			b.SetBytes(int64(len(image) * 4))
+			for i := 0; i < b.N; i++ {
+				SimulateImageProcessing(cfg, image)
+			}
+		})
+	}
+}
+
 func TestProcessBatch(t *testing.T) {
+	cfg := DefaultConfig()
 	batch := ImageBatch{
-		Images: make([][]float32, batchSize),
-		Labels: make([]int, batchSize),
+		Images: make([][]float32, cfg.BatchSize),
+		Labels: make([]int, cfg.BatchSize),
 	}
 
-	for i := 0; i < batchSize; i++ {
-		image := make([]float32, imageSize)
-		for j := 0; j < imageSize; j++ {
+	for i := 0; i < cfg.BatchSize; i++ {
+		image := make([]float32, cfg.ImageSize())
+		for j := 0; j < cfg.ImageSize(); j++ {
 			image[j] = 1.0
 		}
 		batch.Images[i] = image
@@ -61,7 +116,7 @@ func TestProcessBatch(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	go ProcessBatch(batch, &wg)
+	go ProcessBatch(cfg, batch, &wg)
 	wg.Wait()
 
 	for i, img := range batch.Images {
@@ -101,13 +156,37 @@ func contains(data, substring string) bool {
 }
 
 func TestRunProcessingTask(t *testing.T) {
-	dataDir := "../../cifar-10-batches-bin/"
-	images, labels, err := LoadCIFAR10(dataDir)
+	cfg := DefaultConfig()
+	images, labels := synthetic.GenerateSyntheticDataset(2000, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+
+	executionTime, concurrencyOverhead, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if executionTime == 0 {
+		t.Errorf("Execution time should not be zero")
+	}
+	if concurrencyOverhead < executionTime {
+		t.Errorf("Concurrency overhead should be greater than or equal to execution time")
+	}
+	if imagesProcessed != len(images) {
+		t.Errorf("Expected %d images processed, got %d", len(images), imagesProcessed)
+	}
+}
+
+func TestRunProcessingTaskRealDataset(t *testing.T) {
+	cfg := DefaultConfig()
+	dataDir := defaultDataDir
+	requireDataset(t, dataDir)
+	images, labels, err := LoadCIFAR10(cfg, dataDir)
 	if err != nil {
 		t.Fatalf("Failed to load CIFAR-10 dataset: %v", err)
 	}
 
-	executionTime, concurrencyOverhead := RunProcessingTask(images, labels)
+	executionTime, concurrencyOverhead, _, _, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
 	if executionTime == 0 {
 		t.Errorf("Execution time should not be zero")
 	}
@@ -115,3 +194,775 @@ func TestRunProcessingTask(t *testing.T) {
 		t.Errorf("Concurrency overhead should be greater than or equal to execution time")
 	}
 }
+
+// BenchmarkRunProcessingTask measures one processing run, as a single measured run of the benchmark
+// does, over the dataset when it is downloaded and 10000 synthetic images otherwise
+func BenchmarkRunProcessingTask(b *testing.B) {
+	cfg := DefaultConfig()
+	images, labels, err := LoadCIFAR10(cfg, defaultDataDir)
+	if err != nil {
+		images, labels = synthetic.GenerateSyntheticDataset(10000, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+	}
+	b.SetBytes(int64(len(images) * cfg.ImageSize() * 4))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RunProcessingTask(cfg, images, labels)
+	}
+	b.ReportMetric(float64(len(images)*b.N)/b.Elapsed().Seconds(), "images/s")
+}
+
+func TestProcessAnyImage(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 1, ImageWidth: 1, Channels: 2}
+	floatImage := []float32{0.5, 1.0}
+	processed, err := ProcessAnyImage(cfg, floatImage)
+	if err != nil {
+		t.Fatalf("Failed to process []float32 image: %v", err)
+	}
+	if processed[0] != 1.0 || processed[1] != 2.0 {
+		t.Errorf("[]float32 result mismatch: got %v", processed)
+	}
+
+	byteImage := []uint8{0, 255}
+	processed, err = ProcessAnyImage(cfg, byteImage)
+	if err != nil {
+		t.Fatalf("Failed to process []uint8 image: %v", err)
+	}
+	if processed[0] != 0.0 || processed[1] != 2.0 {
+		t.Errorf("[]uint8 result mismatch: got %v", processed)
+	}
+
+	for _, img := range []interface{}{nil, []int{1, 2}, "not an image"} {
+		if _, err := ProcessAnyImage(cfg, img); err == nil {
+			t.Errorf("Expected an error for unsupported type %T", img)
+		}
+	}
+}
+
+func BenchmarkTypeAssertionCost(b *testing.B) {
+	cfg := DefaultConfig()
+	image := make([]float32, cfg.ImageSize())
+	var boxed interface{} = image
+
+	b.Run("float32", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			SimulateImageProcessing(cfg, image)
+		}
+	})
+
+	b.Run("interface", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ProcessAnyImage(cfg, boxed); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// writeSyntheticBatches writes the five CIFAR-10 training batch files for cfg into dir,
+// with each label equal to the image's index within its batch
+func writeSyntheticBatches(t *testing.T, cfg BenchmarkConfig, dir string) {
+	t.Helper()
+	for i := 1; i <= 5; i++ {
+		var data []byte
+		for j := 0; j < cfg.ImagesPerBatch; j++ {
+			data = append(data, byte(j))
+			for k := 0; k < cfg.ImageSize(); k++ {
+				data = append(data, 255)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("data_batch_%d.bin", i)), data, 0644); err != nil {
+			t.Fatalf("Failed to write synthetic batch: %v", err)
+		}
+	}
+}
+
+func TestLoadCIFAR10SyntheticConfig(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 2, ImageWidth: 2, Channels: 3, ImagesPerBatch: 4, BatchSize: 2, NumRuns: 1}
+	dataDir := t.TempDir()
+	writeSyntheticBatches(t, cfg, dataDir)
+
+	images, labels, err := LoadCIFAR10(cfg, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to load synthetic dataset: %v", err)
+	}
+
+	if len(images) != 5*cfg.ImagesPerBatch {
+		t.Fatalf("Expected %d images, got %d", 5*cfg.ImagesPerBatch, len(images))
+	}
+
+	for i, img := range images {
+		if labels[i] != i%cfg.ImagesPerBatch {
+			t.Errorf("Image %d label mismatch: expected %d, got %d", i, i%cfg.ImagesPerBatch, labels[i])
+		}
+		if len(img) != cfg.ImageSize() || img[0] != 1.0 {
+			t.Errorf("Image %d not normalized to the configured shape: %v", i, img)
+		}
+	}
+
+	executionTime, _, _, _, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if executionTime == 0 {
+		t.Errorf("Execution time should not be zero")
+	}
+	if images[0][0] != 2.0 {
+		t.Errorf("Expected processed pixel value 2.0, got %.2f", images[0][0])
+	}
+}
+
+// testdataConfig matches testdata/test_batch.bin: 10 full-size images, where image i has
+// label i and every pixel set to i*25
+func testdataConfig() BenchmarkConfig {
+	cfg := DefaultConfig()
+	cfg.ImagesPerBatch = 10
+	return cfg
+}
+
+func TestLoadCIFAR10TestSplit(t *testing.T) {
+	cfg := testdataConfig()
+	dataset, err := LoadCIFAR10Split(cfg, "testdata", SplitTest)
+	if err != nil {
+		t.Fatalf("Failed to load test split: %v", err)
+	}
+
+	if len(dataset.Images) != 10 || len(dataset.Labels) != 10 {
+		t.Fatalf("Expected 10 images and labels, got %d and %d", len(dataset.Images), len(dataset.Labels))
+	}
+
+	for i, img := range dataset.Images {
+		if dataset.Labels[i] != i {
+			t.Errorf("Image %d label mismatch: expected %d, got %d", i, i, dataset.Labels[i])
+		}
+		expected := float32(i*25) / 255.0
+		if img[0] != expected || img[len(img)-1] != expected {
+			t.Errorf("Image %d pixel mismatch: expected %.4f, got %.4f", i, expected, img[0])
+		}
+	}
+}
+
+// TestNormalizationDenominator checks that CIFAR-10 pixels, stored as 8-bit bytes, are divided by
+// 255 so that 255 maps to 1.0. Dividing by the 16-bit 65535 of Tiny ImageNet would squash them
+// below 0.004.
+func TestNormalizationDenominator(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 16, ImageWidth: 16, Channels: 1, ImagesPerBatch: 1}
+	data := []byte{3}
+	for k := 0; k < cfg.ImageSize(); k++ {
+		data = append(data, byte(k))
+	}
+	path := filepath.Join(t.TempDir(), "data_batch_1.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+
+	images, _, err := loadCIFAR10Batch(cfg, path)
+	if err != nil {
+		t.Fatalf("Failed to load batch: %v", err)
+	}
+	for k, got := range images[0] {
+		expected := float32(k) / 255.0
+		if math.Abs(float64(got-expected)) > 1.0/(1<<23) {
+			t.Errorf("Pixel %d: expected %d/255 = %v, got %v", k, k, expected, got)
+		}
+	}
+	if got := images[0][255]; got != 1.0 {
+		t.Errorf("Expected the raw value 255 to normalize to 1.0, got %v", got)
+	}
+}
+
+func TestLoadCIFAR10WithTestBatch(t *testing.T) {
+	// A full-size test_batch.bin in the training batch format: 10,000 records of a label byte
+	// followed by 3072 pixel bytes, with labels cycling through the 10 classes
+	cfg := DefaultConfig()
+	recordSize := cfg.ImageSize() + 1
+	data := make([]byte, 0, cfg.ImagesPerBatch*recordSize)
+	for j := 0; j < cfg.ImagesPerBatch; j++ {
+		data = append(data, byte(j%10))
+		for k := 0; k < cfg.ImageSize(); k++ {
+			data = append(data, byte(j+k))
+		}
+	}
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "test_batch.bin"), data, 0644); err != nil {
+		t.Fatalf("Failed to write test batch: %v", err)
+	}
+
+	dataset, err := LoadCIFAR10Split(cfg, dataDir, SplitTest)
+	if err != nil {
+		t.Fatalf("Failed to load test batch: %v", err)
+	}
+	if len(dataset.Images) != 10000 || len(dataset.Labels) != 10000 {
+		t.Fatalf("Expected 10000 images and labels, got %d and %d", len(dataset.Images), len(dataset.Labels))
+	}
+	for i, label := range dataset.Labels {
+		if label < 0 || label > 9 {
+			t.Fatalf("Image %d: expected a label in [0, 9], got %d", i, label)
+		}
+	}
+	for i, img := range dataset.Images {
+		for j, pixel := range img {
+			if pixel < 0 || pixel > 1 {
+				t.Fatalf("Image %d pixel %d: expected a value in [0.0, 1.0], got %v", i, j, pixel)
+			}
+		}
+	}
+}
+
+func TestLoadCIFAR10TrainTest(t *testing.T) {
+	cfg := testdataConfig()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "test_batch.bin"))
+	if err != nil {
+		t.Fatalf("Failed to read testdata: %v", err)
+	}
+
+	dataDir := t.TempDir()
+	for _, name := range []string{"data_batch_1.bin", "data_batch_2.bin", "data_batch_3.bin", "data_batch_4.bin", "data_batch_5.bin", "test_batch.bin"} {
+		if err := os.WriteFile(filepath.Join(dataDir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	train, test, err := LoadCIFAR10TrainTest(cfg, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to load train and test splits: %v", err)
+	}
+	if train.Split != SplitTrain || len(train.Images) != 50 {
+		t.Errorf("Expected 50 train images, got %d in split %q", len(train.Images), train.Split)
+	}
+	if test.Split != SplitTest || len(test.Images) != 10 {
+		t.Errorf("Expected 10 test images, got %d in split %q", len(test.Images), test.Split)
+	}
+}
+
+func TestLoadCIFAR10TruncatedFile(t *testing.T) {
+	cfg := testdataConfig()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "test_batch.bin"))
+	if err != nil {
+		t.Fatalf("Failed to read testdata: %v", err)
+	}
+
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "test_batch.bin"), data[:len(data)-100], 0644); err != nil {
+		t.Fatalf("Failed to write truncated file: %v", err)
+	}
+
+	_, err = LoadCIFAR10Split(cfg, dataDir, SplitTest)
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("Expected a truncation error, got %v", err)
+	}
+}
+
+func TestLoadCIFAR10UnknownSplit(t *testing.T) {
+	if _, err := LoadCIFAR10Split(testdataConfig(), "testdata", "validation"); err == nil {
+		t.Errorf("Expected an error for an unknown split")
+	}
+}
+
+func TestLoadDatasetsSynthetic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 2 * cfg.BatchSize
+
+	datasets, err := loadDatasets(cfg, "does-not-exist")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	if len(datasets) != 1 || len(datasets[0].Images) != cfg.SyntheticImages {
+		t.Fatalf("Expected one dataset of %d images, got %d datasets", cfg.SyntheticImages, len(datasets))
+	}
+
+	images, labels := datasets[0].Images, datasets[0].Labels
+	expected := make([]float32, len(images[0]))
+	copy(expected, images[0])
+
+	RunProcessingTask(cfg, images, labels)
+	for j, val := range images[0] {
+		if val != expected[j]*2 {
+			t.Fatalf("Pixel %d mismatch after processing: expected %.4f, got %.4f", j, expected[j]*2, val)
+		}
+	}
+	for i, label := range labels {
+		if label != i%10 {
+			t.Errorf("Image %d label mismatch: expected %d, got %d", i, i%10, label)
+		}
+	}
+}
+
+func TestRunProcessingTaskTTFB(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 8 * cfg.BatchSize
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	ttfb, total := RunProcessingTaskTTFB(cfg, images, labels)
+	if ttfb <= 0 {
+		t.Errorf("Time to first batch should be positive, got %v", ttfb)
+	}
+	if ttfb > total {
+		t.Errorf("Time to first batch %v should not exceed total time %v", ttfb, total)
+	}
+
+	// Without a full batch nothing completes
+	if ttfb, _ := RunProcessingTaskTTFB(cfg, images[:cfg.BatchSize-1], labels[:cfg.BatchSize-1]); ttfb != 0 {
+		t.Errorf("Expected no time to first batch without a full batch, got %v", ttfb)
+	}
+}
+
+func TestSimulateImageProcessingInjectsErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ErrorRate = 1
+	image := make([]float32, cfg.ImageSize())
+	for i := range image {
+		image[i] = 1.0
+	}
+	if _, err := SimulateImageProcessing(cfg, image); !errors.Is(err, ErrInjected) {
+		t.Fatalf("Expected ErrInjected at an error rate of 1, got %v", err)
+	}
+	if image[0] != 1.0 {
+		t.Errorf("Expected a failed image to be left untouched, got %.2f", image[0])
+	}
+}
+
+func TestRunProcessingTaskErrgroup(t *testing.T) {
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.BatchSize = 500, 10
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	if err := RunProcessingTaskErrgroup(context.Background(), cfg, images, labels); err != nil {
+		t.Fatalf("Expected no error without -error-rate, got %v", err)
+	}
+
+	// With one batch at a time the run stops at the first failure, about 20 images in. Had the
+	// other batches carried on to their own first failure, most of the 500 would be processed.
+	cfg.ErrorRate, cfg.MaxInFlight = 0.05, 1
+	result, err := runProcessingTask(context.Background(), cfg, images, labels)
+	if !errors.Is(err, ErrInjected) {
+		t.Fatalf("Expected the injected error, got %v", err)
+	}
+	if result.ImagesProcessed >= len(images)/2 {
+		t.Errorf("Expected the first error to cancel the remaining batches, got %d of %d images processed", result.ImagesProcessed, len(images))
+	}
+	if err := RunProcessingTaskErrgroup(context.Background(), cfg, images, labels); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected the injected error, got %v", err)
+	}
+	if _, _, _, _, _, _, err := RunProcessingTask(cfg, images, labels); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected RunProcessingTask to return the injected error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg.ErrorRate = 0
+	if err := RunProcessingTaskErrgroup(ctx, cfg, images, labels); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled for a cancelled context, got %v", err)
+	}
+}
+
+func TestRunProcessingTaskGoroutineSpawn(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 8 * cfg.BatchSize
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	executionTime, concurrencyOverhead, goroutineSpawnDuration, _, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if goroutineSpawnDuration <= 0 {
+		t.Errorf("Goroutine spawn duration should be positive, got %v", goroutineSpawnDuration)
+	}
+	if goroutineSpawnDuration > executionTime {
+		t.Errorf("Goroutine spawn duration %v should not exceed execution time %v", goroutineSpawnDuration, executionTime)
+	}
+	if concurrencyOverhead < executionTime {
+		t.Errorf("Concurrency overhead should be greater than or equal to execution time")
+	}
+}
+
+// syntheticDataset returns the images and labels of the single synthetic dataset for cfg
+func syntheticDataset(cfg BenchmarkConfig) ([][]float32, []int, error) {
+	datasets, err := loadDatasets(cfg, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return datasets[0].Images, datasets[0].Labels, nil
+}
+
+func TestRunProcessingTaskCounts(t *testing.T) {
+	cfg := DefaultConfig()
+	// Three full batches plus a partial batch that is not processed
+	cfg.SyntheticImages = 3*cfg.BatchSize + 7
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	_, _, _, imagesProcessed, pixelsProcessed, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 3*cfg.BatchSize {
+		t.Errorf("Expected %d images processed, got %d", 3*cfg.BatchSize, imagesProcessed)
+	}
+	if pixelsProcessed != imagesProcessed*cfg.ImageHeight*cfg.ImageWidth {
+		t.Errorf("Expected %d pixels processed, got %d", imagesProcessed*cfg.ImageHeight*cfg.ImageWidth, pixelsProcessed)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	if got := throughput(500, 250*time.Millisecond); got != 2000 {
+		t.Errorf("Expected 2000 per second, got %.2f", got)
+	}
+	if got := throughput(500, 0); got != 0 {
+		t.Errorf("Expected 0 for an empty duration, got %.2f", got)
+	}
+}
+
+func TestRunProcessingTaskWithContextDeadline(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 4 * cfg.BatchSize
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	original := images[0][0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	start := time.Now()
+	_, _, err = RunProcessingTaskWithContext(ctx, cfg, images, labels)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Cancelled run took %v, expected it to return promptly", elapsed)
+	}
+	if images[0][0] != original {
+		t.Errorf("Expected no images to be processed after the deadline")
+	}
+}
+
+func TestRunProcessingTaskWithContextCompletes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 4 * cfg.BatchSize
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	expected := images[len(images)-1][0] * 2
+
+	executionTime, concurrencyOverhead, err := RunProcessingTaskWithContext(context.Background(), cfg, images, labels)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if concurrencyOverhead < executionTime {
+		t.Errorf("Concurrency overhead should be greater than or equal to execution time")
+	}
+	if images[len(images)-1][0] != expected {
+		t.Errorf("Expected the last image to be processed")
+	}
+}
+
+func TestRunProcessingTaskWithContextKernelError(t *testing.T) {
+	registerFailingKernel(t)
+	cfg := syntheticConfig()
+	cfg.BatchSize = 10
+	cfg.Kernel = failingKernel
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	images[15][0] = failMarker
+
+	if _, _, err := RunProcessingTaskWithContext(context.Background(), cfg, images, labels); !errors.Is(err, ErrBadImage) {
+		t.Errorf("Expected the kernel error, got %v", err)
+	}
+}
+
+func TestMainIntegration(t *testing.T) {
+	if *runMain {
+		// Hand main() only the benchmark arguments, with a fresh flag set for it to register on
+		args := flag.Args()
+		os.Args = append([]string{os.Args[0]}, args...)
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		main()
+		return
+	}
+
+	dir := runMainInTempDir(t, "-synthetic", "64", "-height", "8", "-width", "8", "-batch-size", "16", "-num-runs", "2", "-warmup", "1", "-csv", "runs.csv")
+
+	content := readMetricsLog(t, dir)
+	if !strings.Contains(string(content), "Average Execution Time") {
+		t.Errorf("Log file is missing the averages:\n%s", content)
+	}
+
+	csvContent, err := os.ReadFile(filepath.Join(dir, "runs.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read CSV results: %v", err)
+	}
+	if rows := strings.Count(string(csvContent), "\n"); rows != 3 {
+		t.Errorf("Expected a header and 2 run rows in the CSV, got %d lines:\n%s", rows, csvContent)
+	}
+}
+
+// runMainInTempDir runs main() with args in a subprocess, through TestMainIntegration, inside a
+// fresh temporary directory that it returns
+func runMainInTempDir(t *testing.T, args ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestMainIntegration$", "-test.run-main", "--"}, args...)...)
+	cmd.Dir = dir
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("main() failed: %v\nstdout:\n%s\nstderr:\n%s", err, stdout.String(), stderr.String())
+	}
+	return dir
+}
+
+// readMetricsLog returns the metrics log main() wrote into dir, failing the test if it is empty
+func readMetricsLog(t *testing.T, dir string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, "go_cifar10_metrics_result.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if len(content) == 0 {
+		t.Fatalf("Log file is empty")
+	}
+	return content
+}
+
+func TestDryRun(t *testing.T) {
+	dir := runMainInTempDir(t, "-synthetic", "64", "-height", "8", "-width", "8", "-batch-size", "16", "-dry-run", "-csv", "runs.csv")
+	content := string(readMetricsLog(t, dir))
+
+	if !strings.Contains(content, "Dry Run: loaded 64 images in") || !strings.Contains(content, "processing skipped") {
+		t.Errorf("Expected the dry run to log the image count and loading time:\n%s", content)
+	}
+	for _, unexpected := range []string{"Run 1/", "Average Metrics"} {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("Expected no processing with -dry-run, found %q:\n%s", unexpected, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "runs.csv")); err == nil {
+		t.Errorf("Expected no CSV results without runs")
+	}
+}
+
+func TestNumRunsZero(t *testing.T) {
+	dir := runMainInTempDir(t, "-synthetic", "64", "-height", "8", "-width", "8", "-batch-size", "16", "-num-runs", "0", "-warmup", "0")
+	content := string(readMetricsLog(t, dir))
+
+	if !strings.Contains(content, "Dataset Parameters") {
+		t.Errorf("Expected the dataset parameters to be logged:\n%s", content)
+	}
+	if strings.Contains(content, "Execution Time for Run") {
+		t.Errorf("Expected no run entries with -num-runs 0:\n%s", content)
+	}
+	if !strings.Contains(content, "Average Metrics") {
+		t.Fatalf("Expected the average metrics section:\n%s", content)
+	}
+	averages := content[strings.Index(content, "Average Metrics"):]
+	for _, line := range strings.Split(averages, "\n") {
+		for _, metric := range []string{"Average Execution Time:", "Average Memory Usage:", "Average Throughput:"} {
+			if i := strings.Index(line, metric); i >= 0 {
+				value := line[i+len(metric):]
+				if strings.ContainsAny(value, "123456789") && !strings.Contains(value, "N/A") {
+					t.Errorf("Expected zero or N/A averages without runs, got %q", line)
+				}
+			}
+		}
+		if strings.Contains(line, "NaN") || strings.Contains(line, "Inf") {
+			t.Errorf("Expected no NaN or Inf without runs, got %q", line)
+		}
+	}
+}
+
+func TestProcessBatchAtomic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 2, 2, 3
+	images := make([][]float32, 6)
+	expected := make([][]float32, len(images))
+	for i := range images {
+		images[i] = make([]float32, cfg.ImageSize())
+		for j := range images[i] {
+			images[i][j] = float32(i + j)
+		}
+		expected[i] = ProcessImage(cfg, append([]float32(nil), images[i]...))
+	}
+
+	var processed atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go ProcessBatchAtomic(cfg, ImageBatch{Images: images[:3]}, &wg, &processed)
+	go ProcessBatchAtomic(cfg, ImageBatch{Images: images[3:]}, &wg, &processed)
+	wg.Wait()
+
+	if processed.Load() != int64(len(images)) {
+		t.Errorf("Expected %d processed images, got %d", len(images), processed.Load())
+	}
+	for i := range images {
+		for j := range images[i] {
+			if images[i][j] != expected[i][j] {
+				t.Fatalf("Image %d pixel %d: expected %v, got %v", i, j, expected[i][j], images[i][j])
+			}
+		}
+	}
+}
+
+// BenchmarkProcessBatchAtomic runs every batch of the same synthetic dataset on its own goroutine,
+// with and without a shared atomic counter, to show the cost of incrementing a hot counter
+func BenchmarkProcessBatchAtomic(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 10000
+	cfg.BatchSize = 100
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		b.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	numBatches := len(images) / cfg.BatchSize
+	batches := make([]ImageBatch, numBatches)
+	for i := range batches {
+		start, end := i*cfg.BatchSize, (i+1)*cfg.BatchSize
+		batches[i] = ImageBatch{Images: images[start:end], Labels: labels[start:end]}
+	}
+
+	b.Run("plain", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			wg.Add(len(batches))
+			for _, batch := range batches {
+				go ProcessBatch(cfg, batch, &wg)
+			}
+			wg.Wait()
+		}
+	})
+
+	b.Run("atomic", func(b *testing.B) {
+		var processed atomic.Int64
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			wg.Add(len(batches))
+			for _, batch := range batches {
+				go ProcessBatchAtomic(cfg, batch, &wg, &processed)
+			}
+			wg.Wait()
+		}
+		if processed.Load() != int64(b.N*len(images)) {
+			b.Fatalf("Expected %d processed images, got %d", b.N*len(images), processed.Load())
+		}
+	})
+}
+
+func TestLogClassesCountsDistinctLabels(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logClasses(logger, []int{0, 1, 1, 9, 1, 0}, CIFAR10LabelNames[:])
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{"Number of Classes: 3", "Images per Class: min 1, max 3", "class 1 (automobile): 3", "class 9 (truck): 1"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestLabelNameFallsBackToNumber(t *testing.T) {
+	for label, expected := range map[int]string{0: "airplane", 3: "cat", 9: "truck", 10: "10", -1: "-1"} {
+		if got := labelName(CIFAR10LabelNames[:], label); got != expected {
+			t.Errorf("Expected label %d to be named %q, got %q", label, expected, got)
+		}
+	}
+}
+
+func TestProcessBatchReadOnly(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 2, 2, 3
+	images := make([][]float32, 3)
+	var expected float32
+	for i := range images {
+		images[i] = make([]float32, cfg.ImageSize())
+		for j := range images[i] {
+			images[i][j] = float32(i*len(images[i]) + j)
+			expected += images[i][j]
+		}
+	}
+
+	var checksum float32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go ProcessBatchReadOnly(cfg, ImageBatch{Images: images}, &wg, &checksum)
+	wg.Wait()
+
+	if checksum != expected {
+		t.Errorf("Expected checksum %v, got %v", expected, checksum)
+	}
+	for i := range images {
+		for j, p := range images[i] {
+			if p != float32(i*len(images[i])+j) {
+				t.Fatalf("Image %d pixel %d was modified: got %v", i, j, p)
+			}
+		}
+	}
+}
+
+// BenchmarkProcessBatchReadOnly runs every batch of the same synthetic dataset on its own
+// goroutine, once doubling the pixels in place and once only summing them. The gap between the
+// two is the cost of writing pixels back; what remains in the read-only run is scheduling and reads.
+func BenchmarkProcessBatchReadOnly(b *testing.B) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 10000
+	cfg.BatchSize = 100
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		b.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	batches := make([]ImageBatch, len(images)/cfg.BatchSize)
+	for i := range batches {
+		start, end := i*cfg.BatchSize, (i+1)*cfg.BatchSize
+		batches[i] = ImageBatch{Images: images[start:end], Labels: labels[start:end]}
+	}
+
+	b.Run("mutating", func(b *testing.B) {
+		b.SetBytes(int64(len(images) * cfg.ImageSize() * 4))
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			wg.Add(len(batches))
+			for _, batch := range batches {
+				go ProcessBatch(cfg, batch, &wg)
+			}
+			wg.Wait()
+		}
+	})
+
+	b.Run("read-only", func(b *testing.B) {
+		b.SetBytes(int64(len(images) * cfg.ImageSize() * 4))
+		checksums := make([]float32, len(batches))
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			wg.Add(len(batches))
+			for j, batch := range batches {
+				go ProcessBatchReadOnly(cfg, batch, &wg, &checksums[j])
+			}
+			wg.Wait()
+		}
+	})
+}
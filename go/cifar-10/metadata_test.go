@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+	"golang/internal/result"
+)
+
+func TestLogMetadata(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logMetadata(logger, result.Metadata{
+		Timestamp: time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC), Hostname: "bench-1", GoVersion: "go1.23.3",
+		GOOS: "linux", GOARCH: "amd64", NumCPU: 16, GOMAXPROCS: 8, CPUModel: "Test CPU @ 3.00GHz",
+		TotalMemoryBytes: 32 * 1024 * 1024 * 1024,
+	})
+	logMetadata(logger, result.Metadata{})
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{
+		"Timestamp: 2026-04-01T12:00:00Z",
+		"Host: bench-1 (linux/amd64)",
+		"CPU Model: Test CPU @ 3.00GHz",
+		"CPUs: 16 (GOMAXPROCS 8)",
+		"Total Memory: 32.00 GB",
+		"Go Version: go1.23.3",
+		"Total Memory: unknown",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+// fetchGauges reads the Prometheus gauges served at url
+func fetchGauges(t *testing.T, url string) map[string]float64 {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to fetch metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+
+	gauges := make(map[string]float64)
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			t.Fatalf("Malformed metric line %q", line)
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			t.Fatalf("Invalid value in metric line %q: %v", line, err)
+		}
+		gauges[fields[0]] = value
+	}
+	return gauges
+}
+
+func TestMonitorDuringRun(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.BatchSize, cfg.SyntheticImages = 16, 64
+	cfg.Warmup, cfg.NumRuns = 1, 3
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	progress, addr, stop, err := startMonitor("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+	defer stop()
+	url := "http://" + addr.String() + "/metrics"
+
+	run := withMonitor(cfg, progress, func() (runResult, error) {
+		return measureRun(cfg, images, labels)
+	})
+	// Scrape the endpoint after every run, while the benchmark is still going
+	var scrapes []map[string]float64
+	var executionTimes []float64
+	scraped := func() (runResult, error) {
+		result, err := run()
+		scrapes = append(scrapes, fetchGauges(t, url))
+		executionTimes = append(executionTimes, result.ExecutionTime.Seconds())
+		return result, err
+	}
+	if _, err := runBenchmark(cfg, metricslog.NewStream(io.Discard), scraped); err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+
+	if len(scrapes) != cfg.Warmup+cfg.NumRuns {
+		t.Fatalf("Expected %d scrapes, got %d", cfg.Warmup+cfg.NumRuns, len(scrapes))
+	}
+	var total float64
+	for i, gauges := range scrapes {
+		measured := i + 1 - cfg.Warmup
+		if measured < 0 {
+			measured = 0
+		}
+		if got := gauges["benchmark_run_index"]; got != float64(measured) {
+			t.Errorf("Scrape %d: expected run index %d, got %v", i, measured, got)
+		}
+		if _, ok := gauges["benchmark_cpu_utilization_percent"]; !ok {
+			t.Errorf("Scrape %d: expected the CPU utilization gauge", i)
+		}
+		if measured == 0 {
+			continue
+		}
+		if gauges["benchmark_memory_bytes"] <= 0 {
+			t.Errorf("Scrape %d: expected the current memory to be reported, got %v", i, gauges["benchmark_memory_bytes"])
+		}
+		total += executionTimes[i]
+		if got := gauges["benchmark_last_run_execution_seconds"]; got != executionTimes[i] {
+			t.Errorf("Scrape %d: expected last execution %v, got %v", i, executionTimes[i], got)
+		}
+		if got, want := gauges["benchmark_average_execution_seconds"], total/float64(measured); got < want*0.999999 || got > want*1.000001 {
+			t.Errorf("Scrape %d: expected average execution %v, got %v", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"golang/internal/datahash"
+	"golang/internal/result"
+)
+
+func TestRunOnceWritesSingleJSONRecord(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.BatchSize = 16
+	cfg.SyntheticImages = 256
+	cfg.Limit = 64
+
+	var stdout, stderr bytes.Buffer
+	if err := runOnce(cfg, "", &stdout, &stderr); err != nil {
+		t.Fatalf("runOnce failed: %v", err)
+	}
+
+	decoder := json.NewDecoder(&stdout)
+	var record struct {
+		Benchmark string
+		GoVersion string
+		NumCPU    int
+		Config    map[string]interface{}
+		Dataset   struct {
+			Split  string
+			Images int
+		}
+		Warmup int
+		Runs   int
+		Run    struct {
+			ExecutionSeconds float64
+			ImagesProcessed  int
+		}
+	}
+	if err := decoder.Decode(&record); err != nil {
+		t.Fatalf("stdout is not a JSON document: %v", err)
+	}
+	var extra interface{}
+	if err := decoder.Decode(&extra); err != io.EOF {
+		t.Errorf("Expected exactly one JSON document on stdout, found more (%v)", err)
+	}
+
+	if record.Benchmark != "cifar10" || record.GoVersion == "" || record.NumCPU == 0 {
+		t.Errorf("Record is missing metadata: %+v", record)
+	}
+	if record.Warmup != 1 || record.Runs != 1 {
+		t.Errorf("Expected 1 warmup and 1 measured run, got %d and %d", record.Warmup, record.Runs)
+	}
+	if record.Dataset.Images != 64 || record.Run.ImagesProcessed != 64 {
+		t.Errorf("Expected the dataset to be limited to 64 images, got %+v", record)
+	}
+	if record.Config["BatchSize"] != float64(16) {
+		t.Errorf("Expected the config in the record, got %v", record.Config)
+	}
+	if !strings.Contains(stderr.String(), "Execution Time for Run 1") {
+		t.Errorf("Expected human-readable output on stderr, got %q", stderr.String())
+	}
+}
+
+func TestRunOnceHashesDataset(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.BatchSize = 16
+	cfg.SyntheticImages = 256
+	cfg.Limit = 64
+	cfg.HashDataset = true
+
+	var stdout, stderr bytes.Buffer
+	if err := runOnce(cfg, "", &stdout, &stderr); err != nil {
+		t.Fatalf("runOnce failed: %v", err)
+	}
+	var record result.Record
+	if err := json.Unmarshal(stdout.Bytes(), &record); err != nil {
+		t.Fatalf("stdout is not a JSON record: %v", err)
+	}
+
+	datasets, err := loadDatasets(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to load synthetic dataset: %v", err)
+	}
+	expected := datahash.SHA256(datasets[0].Images)
+	if record.Dataset.SHA256 != expected {
+		t.Errorf("Expected dataset checksum %s, got %q", expected, record.Dataset.SHA256)
+	}
+	if !strings.Contains(stderr.String(), "Dataset SHA-256: "+expected) {
+		t.Errorf("Expected the checksum in the log, got:\n%s", stderr.String())
+	}
+	if record.Benchmark != "cifar10" || record.CPUModel == "" || record.Hostname == "" {
+		t.Errorf("Record is missing metadata: %+v", record.Metadata)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang/internal/backpressure"
+	"golang/internal/benchmark"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// batchProducer loads batches and passes each one to emit as soon as it is ready. emit blocks
+// while the pipeline buffer is full.
+type batchProducer func(emit func(ImageBatch)) error
+
+// pipelineResult holds the stage timings of one pass through the pipeline. Load is wall time on
+// the single producer; LoadWait, Process and Collect are summed over batches, so with several
+// processors they can exceed Elapsed.
+type pipelineResult struct {
+	Batches         int
+	ImagesProcessed int
+	Elapsed         time.Duration
+	Load            time.Duration // Producer time spent loading, excluding waits for buffer space
+	LoadWait        time.Duration // Time processors spent waiting for the producer
+	Process         time.Duration // Time processors spent running the kernel
+	Collect         time.Duration // Time processed batches waited for the collector
+	Backpressure    backpressure.Stats
+}
+
+// processedBatch is the per-batch report a processor sends to the collector
+type processedBatch struct {
+	images   int
+	loadWait time.Duration
+	process  time.Duration
+	sent     time.Time
+	err      error // The kernel's error when an image of the batch failed
+}
+
+// runPipeline streams the batches of produce through cfg.PipelineWorkers processors over a queue
+// buffering cfg.PipelineBuffer batches, while a collector aggregates the per-batch timings. The
+// queue's back-pressure stats are returned with the timings.
+func runPipeline(cfg BenchmarkConfig, produce batchProducer) (pipelineResult, error) {
+	start := time.Now()
+	batches := backpressure.NewQueue[ImageBatch](cfg.PipelineBuffer)
+
+	var load time.Duration
+	var produceErr error
+	go func() {
+		defer batches.Close()
+		last := time.Now()
+		produceErr = produce(func(batch ImageBatch) {
+			load += time.Since(last)
+			batches.Put(batch)
+			last = time.Now()
+		})
+		load += time.Since(last)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	workers := cfg.PipelineWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	processed := make(chan processedBatch, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				waitStart := time.Now()
+				batch, ok := batches.Get()
+				if !ok {
+					return
+				}
+				// After a failure the remaining batches are drained unprocessed, so the producer
+				// never blocks on a full buffer
+				if ctx.Err() != nil {
+					continue
+				}
+				processStart := time.Now()
+				images, err := ProcessBatchWithContext(ctx, cfg, batch)
+				if ctx.Err() != nil {
+					err = nil // Cut short by another processor's failure, which is the one reported
+				} else if err != nil {
+					cancel()
+				}
+				processed <- processedBatch{
+					images:   images,
+					loadWait: processStart.Sub(waitStart),
+					process:  time.Since(processStart),
+					sent:     time.Now(),
+					err:      err,
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(processed)
+	}()
+
+	var result pipelineResult
+	var processErr error
+	for p := range processed {
+		if p.err != nil && processErr == nil {
+			processErr = p.err
+		}
+		result.Collect += time.Since(p.sent)
+		result.Batches++
+		result.ImagesProcessed += p.images
+		result.LoadWait += p.loadWait
+		result.Process += p.process
+	}
+	result.Elapsed = time.Since(start)
+	// The producer has returned: batches was closed before the processors could finish
+	result.Load = load
+	result.Backpressure = batches.Stats()
+	if processErr != nil {
+		return result, processErr
+	}
+	return result, produceErr
+}
+
+// sliceProducer emits images already held in memory in batches of cfg.BatchSize
+func sliceProducer(cfg BenchmarkConfig, images [][]float32, labels []int) batchProducer {
+	return func(emit func(ImageBatch)) error {
+		for start := 0; start < len(images); start += cfg.BatchSize {
+			end := start + cfg.BatchSize
+			if end > len(images) {
+				end = len(images)
+			}
+			emit(ImageBatch{Images: images[start:end], Labels: labels[start:end]})
+		}
+		return nil
+	}
+}
+
+// fileProducer streams the files of split from disk record by record and emits their images in
+// batches of cfg.BatchSize, so processing starts before the first file is fully read. It stops
+// after cfg.Limit images when a limit is set.
+func fileProducer(cfg BenchmarkConfig, dataDir, split string) batchProducer {
+	return func(emit func(ImageBatch)) error {
+		return streamCIFAR10(cfg, dataDir, split, emit)
+	}
+}
+
+// runPipelineBenchmark repeats the pipeline cfg.Warmup + cfg.NumRuns times, reloading the data on
+// every pass, and logs the stage timings and queue back-pressure of the measured runs
+func runPipelineBenchmark(cfg BenchmarkConfig, logger *metricslog.Logger, produce batchProducer) error {
+	pass := func() (pipelineResult, error) {
+		return runPipeline(cfg, produce)
+	}
+	warmups := 0
+	if _, err := benchmark.TryRunN(cfg.Warmup, pass, func(pipelineResult) {
+		warmups++
+		logger.Printf("Warmup Run %d/%d completed (excluded from averages)", warmups, cfg.Warmup)
+	}); err != nil {
+		return err
+	}
+
+	var total pipelineResult
+	runs := 0
+	if _, err := benchmark.TryRunN(cfg.NumRuns, func() (pipelineResult, error) {
+		logger.Printf("\nRun %d/%d...\n", runs+1, cfg.NumRuns)
+		return pass()
+	}, func(result pipelineResult) {
+		runs++
+		logPipelineResult(cfg, logger, fmt.Sprintf("for Run %d", runs), result)
+		for _, line := range result.Backpressure.Report() {
+			logger.Printf("%s", line)
+		}
+
+		total.Batches += result.Batches
+		total.ImagesProcessed += result.ImagesProcessed
+		total.Elapsed += result.Elapsed
+		total.Load += result.Load
+		total.LoadWait += result.LoadWait
+		total.Process += result.Process
+		total.Collect += result.Collect
+	}); err != nil {
+		return err
+	}
+	if cfg.NumRuns == 0 {
+		return nil
+	}
+
+	n := time.Duration(cfg.NumRuns)
+	logger.Printf("\nAverage Pipeline Metrics:")
+	logPipelineResult(cfg, logger, "(Average)", pipelineResult{
+		Batches:         total.Batches / cfg.NumRuns,
+		ImagesProcessed: total.ImagesProcessed / cfg.NumRuns,
+		Elapsed:         total.Elapsed / n,
+		Load:            total.Load / n,
+		LoadWait:        total.LoadWait / n,
+		Process:         total.Process / n,
+		Collect:         total.Collect / n,
+	})
+	return nil
+}
+
+// logPipelineResult writes the stage timings of one pipeline pass. The sequential estimate is how
+// long loading everything and then processing it on the same processors would take; the overlap
+// savings are its gap to the execution time, negative when the pipeline costs more than it hides.
+func logPipelineResult(cfg BenchmarkConfig, logger *metricslog.Logger, label string, r pipelineResult) {
+	workers := cfg.PipelineWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	sequential := r.Load + r.Process/time.Duration(workers)
+	logger.Printf("Execution Time %s: %s seconds (%d images in %d batches)", label, metrics.FormatDuration(r.Elapsed), r.ImagesProcessed, r.Batches)
+	logger.Printf("Load Time %s: %s seconds", label, metrics.FormatDuration(r.Load))
+	logger.Printf("Load Wait Time %s: %s seconds (summed over processors)", label, metrics.FormatDuration(r.LoadWait))
+	logger.Printf("Process Time %s: %s seconds (summed over processors)", label, metrics.FormatDuration(r.Process))
+	logger.Printf("Collect Time %s: %s seconds (summed over batches)", label, metrics.FormatDuration(r.Collect))
+	logger.Printf("Sequential Estimate %s: %s seconds (load, then process)", label, metrics.FormatDuration(sequential))
+	savings := sequential - r.Elapsed
+	var savingsPercent float64
+	if sequential > 0 {
+		savingsPercent = 100 * savings.Seconds() / sequential.Seconds()
+	}
+	logger.Printf("Overlap Savings %s: %s seconds hidden by overlapping load and processing (%.1f%% of the sequential estimate)",
+		label, metrics.FormatDuration(savings), savingsPercent)
+	logger.Printf("Throughput %s: %.2f images/second", label, throughput(r.ImagesProcessed, r.Elapsed))
+}
+
+// runPipelineMode benchmarks the streaming pipeline on synthetic images, or on each selected split
+// streamed from disk
+func runPipelineMode(cfg BenchmarkConfig, logger *metricslog.Logger, dataDir string) error {
+	logger.Printf("\nPipeline Mode: %d processors, buffer of %d batches", cfg.PipelineWorkers, cfg.PipelineBuffer)
+	if cfg.SyntheticImages > 0 {
+		datasets, err := loadDatasets(cfg, dataDir)
+		if err != nil {
+			return err
+		}
+		logger.Printf("\nPhase: synthetic (%d images in memory)", len(datasets[0].Images))
+		return runPipelineBenchmark(cfg, logger, sliceProducer(cfg, datasets[0].Images, datasets[0].Labels))
+	}
+
+	splits := []string{cfg.Split}
+	if cfg.Split == SplitBoth {
+		splits = []string{SplitTrain, SplitTest}
+	}
+	for _, split := range splits {
+		logger.Printf("\nPhase: %s (streamed from disk)", split)
+		if err := runPipelineBenchmark(cfg, logger, fileProducer(cfg, dataDir, split)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+)
+
+func TestRunPipelineSyntheticProducer(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 8, 100
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 3, 2
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	first := images[0][0]
+
+	result, err := runPipeline(cfg, sliceProducer(cfg, images, labels))
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	// 12 full batches and a trailing batch of 4
+	if result.Batches != 13 || result.ImagesProcessed != 100 {
+		t.Errorf("Expected 13 batches and 100 images, got %d and %d", result.Batches, result.ImagesProcessed)
+	}
+	if images[0][0] != first*2 {
+		t.Errorf("Expected the kernel to be applied, got %v from %v", images[0][0], first)
+	}
+	if result.Elapsed <= 0 || result.Process <= 0 {
+		t.Errorf("Expected positive elapsed and process times, got %v and %v", result.Elapsed, result.Process)
+	}
+}
+
+func TestRunPipelineStageTimings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 8, 40
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 2, 1
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	// A slow loader: processors spend most of the run waiting for it
+	const loadDelay = 10 * time.Millisecond
+	inMemory := sliceProducer(cfg, images, labels)
+	slow := func(emit func(ImageBatch)) error {
+		return inMemory(func(batch ImageBatch) {
+			time.Sleep(loadDelay)
+			emit(batch)
+		})
+	}
+
+	result, err := runPipeline(cfg, slow)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.Load < 5*loadDelay {
+		t.Errorf("Expected load time of at least %v, got %v", 5*loadDelay, result.Load)
+	}
+	if result.LoadWait < loadDelay {
+		t.Errorf("Expected processors to wait for the loader, got load wait %v", result.LoadWait)
+	}
+	if result.LoadWait < result.Process {
+		t.Errorf("Expected load wait (%v) to dominate process time (%v) with a slow loader", result.LoadWait, result.Process)
+	}
+}
+
+func TestRunPipelineBlockedProducerNotCountedAsLoad(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 64, 64, 3
+	cfg.Kernel = KernelBlur
+	cfg.BatchSize, cfg.SyntheticImages = 8, 80
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 1, 1
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	// An in-memory loader with a one-batch queue spends the run blocked on the processor
+	result, err := runPipeline(cfg, sliceProducer(cfg, images, labels))
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.Load*2 > result.Elapsed {
+		t.Errorf("Expected load time (%v) to exclude time blocked on the processor (elapsed %v)", result.Load, result.Elapsed)
+	}
+}
+
+func TestRunPipelineBackpressureVerdict(t *testing.T) {
+	const name = "slow-consumer"
+	kernelFuncs[name] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		time.Sleep(time.Millisecond)
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, name) })
+
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 4, 40
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 1, 2
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	inMemory := sliceProducer(cfg, images, labels)
+
+	// A loader slower than the kernel leaves the queue empty
+	slowProducer := func(emit func(ImageBatch)) error {
+		return inMemory(func(batch ImageBatch) {
+			time.Sleep(10 * time.Millisecond)
+			emit(batch)
+		})
+	}
+	result, err := runPipeline(cfg, slowProducer)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if verdict := result.Backpressure.Verdict(); !strings.HasPrefix(verdict, "producer-bound") {
+		t.Errorf("Expected a producer-bound verdict with a slow loader, got %q", verdict)
+	}
+
+	// A kernel slower than the in-memory loader keeps the queue full
+	cfg.Kernel = name
+	result, err = runPipeline(cfg, inMemory)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if verdict := result.Backpressure.Verdict(); !strings.HasPrefix(verdict, "consumer-bound") {
+		t.Errorf("Expected a consumer-bound verdict with a slow kernel, got %q", verdict)
+	}
+	if result.Backpressure.ProducerBlocked <= 0 {
+		t.Errorf("Expected the loader to block on the full queue, got %v", result.Backpressure.ProducerBlocked)
+	}
+}
+
+func TestRunPipelineProducerError(t *testing.T) {
+	cfg := DefaultConfig()
+	failing := errors.New("disk gone")
+	_, err := runPipeline(cfg, func(emit func(ImageBatch)) error {
+		emit(ImageBatch{Images: [][]float32{make([]float32, cfg.ImageSize())}, Labels: []int{0}})
+		return failing
+	})
+	if !errors.Is(err, failing) {
+		t.Errorf("Expected the producer error, got %v", err)
+	}
+}
+
+func TestRunPipelineKernelError(t *testing.T) {
+	registerFailingKernel(t)
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.BatchSize = 200, 8
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 2, 1
+	cfg.Kernel = failingKernel
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	images[20][0] = failMarker
+
+	// The failure must reach the caller, and the batches after it must not wait for the kernel
+	result, err := runPipeline(cfg, sliceProducer(cfg, images, labels))
+	if !errors.Is(err, ErrBadImage) {
+		t.Fatalf("Expected the kernel error, got %v", err)
+	}
+	if result.ImagesProcessed >= len(images)/2 {
+		t.Errorf("Expected the failure to stop processing, but %d of %d images were processed", result.ImagesProcessed, len(images))
+	}
+}
+
+func TestFileProducer(t *testing.T) {
+	cfg := BenchmarkConfig{ImageHeight: 2, ImageWidth: 2, Channels: 3, ImagesPerBatch: 4, BatchSize: 3, PipelineWorkers: 2, PipelineBuffer: 1}
+	dataDir := t.TempDir()
+	writeSyntheticBatches(t, cfg, dataDir)
+
+	// Batches of 3 span the 4-image files; the 20 images end with a partial batch of 2
+	result, err := runPipeline(cfg, fileProducer(cfg, dataDir, SplitTrain))
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.Batches != 7 || result.ImagesProcessed != 20 {
+		t.Errorf("Expected 7 batches and 20 images, got %d and %d", result.Batches, result.ImagesProcessed)
+	}
+
+	cfg.Limit = 10
+	result, err = runPipeline(cfg, fileProducer(cfg, dataDir, SplitTrain))
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.ImagesProcessed != 10 {
+		t.Errorf("Expected -limit to stop after 10 images, got %d", result.ImagesProcessed)
+	}
+
+	if _, err := runPipeline(cfg, fileProducer(cfg, dataDir, SplitTest)); err == nil {
+		t.Errorf("Expected an error for the missing test batch")
+	}
+}
+
+func TestRunPipelineBenchmarkLogsStages(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 8, 64
+	cfg.Warmup, cfg.NumRuns = 1, 2
+
+	path := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(path)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	if err := runPipelineMode(cfg, logger, ""); err != nil {
+		t.Fatalf("Pipeline mode failed: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, want := range []string{"Load Time for Run 2", "Load Wait Time for Run 2", "Process Time for Run 2", "Collect Time for Run 2", "Sequential Estimate (Average)", "Overlap Savings (Average)", "Back-pressure Verdict"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected %q in the log:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "for Run 3") {
+		t.Errorf("Expected the warmup run to be left out of the measured runs")
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"golang/internal/benchmark"
+	"golang/internal/energy"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+	"golang/internal/result"
+	"golang/internal/stats"
+)
+
+// runResult holds the metrics collected from a single processing run
+type runResult struct {
+	ExecutionTime       time.Duration
+	ConcurrencyOverhead time.Duration
+	GoroutineSpawn      time.Duration
+	TimeToFirstBatch    time.Duration // From entering the task until its first batch finished; 0 for tasks without batches
+	ImagesProcessed     int
+	PixelsProcessed     int
+	NumWorkers          int // Goroutines the images were split across
+	metrics.RunMetrics
+	Energy         energy.Measurement // Empty when RAPL counters are unavailable
+	Collected      map[string]float64 // Metrics from -collectors, keyed "collector.metric"
+	BatchSize      int                // Images per batch of BatchDurations
+	BatchDurations []time.Duration    // Time each batch goroutine took, indexed by batch; empty for runs not split into batches
+	ForcedGC       bool               // A -gc-between-runs collection ran after the run
+	GCDuration     time.Duration      // Time that collection took, outside the run's timings
+}
+
+// runSummary accumulates the results of the measured runs; warmup runs are never added
+type runSummary struct {
+	Runs    int
+	Total   runResult
+	Results []runResult // Every measured run, in order, for distribution statistics
+}
+
+// add includes a measured run in the totals
+func (s *runSummary) add(r runResult) {
+	s.Runs++
+	s.Results = append(s.Results, r)
+	s.Total.ExecutionTime += r.ExecutionTime
+	s.Total.ConcurrencyOverhead += r.ConcurrencyOverhead
+	s.Total.GoroutineSpawn += r.GoroutineSpawn
+	s.Total.TimeToFirstBatch += r.TimeToFirstBatch
+	s.Total.ImagesProcessed += r.ImagesProcessed
+	s.Total.PixelsProcessed += r.PixelsProcessed
+	s.Total.RunMetrics = s.Total.RunMetrics.Add(r.RunMetrics)
+	s.Total.BatchDurations = append(s.Total.BatchDurations, r.BatchDurations...)
+	s.Total.Energy = s.Total.Energy.Add(r.Energy)
+	for key, value := range r.Collected {
+		if s.Total.Collected == nil {
+			s.Total.Collected = make(map[string]float64)
+		}
+		s.Total.Collected[key] += value
+	}
+}
+
+// measureRun runs the processing task once over images and collects its metrics
+func measureRun(cfg BenchmarkConfig, images [][]float32, labels []int) (runResult, error) {
+	return measureRunWithContext(context.Background(), cfg, images, labels)
+}
+
+// measureRunWithContext is measureRun for a run that stops early once ctx is done, in which case
+// the error wraps ctx.Err()
+func measureRunWithContext(ctx context.Context, cfg BenchmarkConfig, images [][]float32, labels []int) (runResult, error) {
+	var task taskResult
+	var taskErr error
+	result, err := measureTask(len(images)/cfg.BatchSize, func() (time.Duration, time.Duration, time.Duration, int, int) {
+		task, taskErr = runProcessingTask(ctx, cfg, images, labels)
+		return task.ExecutionTime, task.ConcurrencyOverhead, task.GoroutineSpawnDuration, task.ImagesProcessed, task.PixelsProcessed
+	})
+	result.BatchDurations = task.BatchDurations
+	result.TimeToFirstBatch = task.TimeToFirstBatch
+	result.BatchSize = cfg.BatchSize
+	if taskErr != nil {
+		return result, taskErr
+	}
+	return result, err
+}
+
+// measureTask runs task once, on numWorkers goroutines, and collects its metrics. task returns
+// the timings and work counts of RunProcessingTask.
+func measureTask(numWorkers int, task func() (time.Duration, time.Duration, time.Duration, int, int)) (runResult, error) {
+	var collector metrics.Collector
+	if err := collector.BeforeRun(); err != nil {
+		return runResult{}, err
+	}
+	executionTime, concurrencyOverhead, goroutineSpawnDuration, imagesProcessed, pixelsProcessed := task()
+	rm, err := collector.AfterRun()
+	if err != nil {
+		return runResult{}, err
+	}
+
+	return runResult{
+		ExecutionTime:       executionTime,
+		ConcurrencyOverhead: concurrencyOverhead,
+		GoroutineSpawn:      goroutineSpawnDuration,
+		ImagesProcessed:     imagesProcessed,
+		PixelsProcessed:     pixelsProcessed,
+		NumWorkers:          numWorkers,
+		RunMetrics:          rm,
+	}, nil
+}
+
+// collectAfterRun runs a full garbage collection after a run when cfg.GCBetweenRuns is set, with
+// debug.FreeOSMemory, which collects as well, when cfg.FreeOSMemory is set too, so that the run's
+// garbage does not land in the next run's timings. It reports whether it collected and how long
+// that took.
+func collectAfterRun(cfg BenchmarkConfig) (bool, time.Duration) {
+	if !cfg.GCBetweenRuns {
+		return false, 0
+	}
+	start := time.Now()
+	if cfg.FreeOSMemory {
+		debug.FreeOSMemory()
+	} else {
+		runtime.GC()
+	}
+	return true, time.Since(start)
+}
+
+// logBetweenRuns records the cooldown and forced collections that separate the runs, when set
+func logBetweenRuns(cfg BenchmarkConfig, logger *metricslog.Logger) {
+	if cfg.Cooldown > 0 {
+		logger.Printf("Cooldown Before Each Measured Run: %s seconds", metrics.FormatDuration(cfg.Cooldown))
+	}
+	if cfg.GCBetweenRuns {
+		collector := "runtime.GC"
+		if cfg.FreeOSMemory {
+			collector = "debug.FreeOSMemory"
+		}
+		logger.Printf("Forced GC After Each Run: %s", collector)
+	}
+}
+
+// runBenchmark performs cfg.Warmup warmup runs followed by cfg.NumRuns measured runs of run.
+// Warmup runs are logged but left out of the returned summary.
+func runBenchmark(cfg BenchmarkConfig, logger *metricslog.Logger, run func() (runResult, error)) (runSummary, error) {
+	// The collection after a run and the cooldown before the next stay outside the timed window
+	cleanRun := func() (runResult, error) {
+		result, err := run()
+		if err == nil {
+			result.ForcedGC, result.GCDuration = collectAfterRun(cfg)
+		}
+		return result, err
+	}
+	warmups := 0
+	if _, err := benchmark.TryRunN(cfg.Warmup, cleanRun, func(runResult) {
+		warmups++
+		logger.Printf("Warmup Run %d/%d completed (excluded from averages)", warmups, cfg.Warmup)
+	}); err != nil {
+		return runSummary{}, err
+	}
+
+	var summary runSummary
+	_, err := benchmark.TryRunN(cfg.NumRuns, func() (runResult, error) {
+		time.Sleep(cfg.Cooldown)
+		logger.Printf("\nRun %d/%d...\n", summary.Runs+1, cfg.NumRuns)
+		return cleanRun()
+	}, func(result runResult) {
+		summary.add(result)
+		i := summary.Runs - 1
+
+		logger.Printf("Execution Time for Run %d: %s seconds", i+1, metrics.FormatDuration(result.ExecutionTime))
+		logger.Printf("Concurrency Overhead for Run %d: %s seconds", i+1, metrics.FormatDuration(result.ConcurrencyOverhead))
+		logger.Printf("Goroutine Spawn Time for Run %d: %.3f ms", i+1, result.GoroutineSpawn.Seconds()*1000)
+		if result.TimeToFirstBatch > 0 {
+			logger.Printf("Time to First Batch for Run %d: %s seconds (of %s seconds total)", i+1,
+				metrics.FormatDuration(result.TimeToFirstBatch), metrics.FormatDuration(result.ConcurrencyOverhead))
+		}
+		metrics.Log(logger, i+1, result.RunMetrics)
+		logBatchDurations(logger, fmt.Sprintf("Batch Durations for Run %d", i+1), result.BatchDurations)
+		logger.Printf("Throughput for Run %d: %.2f images/second, %.2f megapixels/second", i+1,
+			throughput(result.ImagesProcessed, result.ExecutionTime), throughput(result.PixelsProcessed, result.ExecutionTime)/1e6)
+		logBandwidthEfficiency(cfg, logger, fmt.Sprintf("Memory Bandwidth Efficiency for Run %d", i+1), throughput(result.ImagesProcessed, result.ExecutionTime))
+		logEnergy(logger, fmt.Sprintf("Energy for Run %d", i+1), result.Energy, 1, result.ImagesProcessed)
+		if len(result.Collected) > 0 {
+			logger.Printf("Collector Metrics for Run %d: %s", i+1, formatCollected(result.Collected))
+		}
+		if result.ForcedGC {
+			logger.Printf("Forced GC After Run %d: yes, %s seconds", i+1, metrics.FormatDuration(result.GCDuration))
+		} else {
+			logger.Printf("Forced GC After Run %d: no", i+1)
+		}
+	})
+	return summary, err
+}
+
+// gcCyclesPerRun returns the mean number of GC cycles per measured run
+func (s runSummary) gcCyclesPerRun() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Total.NumGC) / float64(s.Runs)
+}
+
+// logGC writes the average GC cycles and pause times per run, and the percentiles of the
+// individual pauses across all runs, to line up against JVM GC logs
+func logGC(logger *metricslog.Logger, summary runSummary) {
+	avg := summary.averages()
+	logger.Printf("Average GC per Run: %.2f cycles, total pause %.3f ms, max pause %.3f ms",
+		summary.gcCyclesPerRun(), avg.GCPause.Seconds()*1000, avg.GCMaxPause.Seconds()*1000)
+	if len(avg.GCPauses) == 0 {
+		return
+	}
+	pauses := make([]float64, len(avg.GCPauses))
+	for i, p := range avg.GCPauses {
+		pauses[i] = p.Seconds() * 1000
+	}
+	d := stats.Summarize(pauses)
+	logger.Printf("GC Pause Percentiles (ms): p50 %.3f, p95 %.3f, p99 %.3f, max %.3f over %d pauses",
+		d.Median, d.P95, d.P99, d.Max, len(pauses))
+}
+
+// heapFragmentationGrowth is the factor by which the heap fragmentation of the last run may exceed
+// that of the first before the summary warns about it
+const heapFragmentationGrowth = 1.5
+
+// logHeapFragmentation writes the average heap fragmentation and its first and last values, with a
+// warning when it grew by more than heapFragmentationGrowth over the runs. HeapInuse counts whole
+// spans, so the ratio sits above 1 even for a compact heap; its growth is what points to
+// fragmentation.
+func logHeapFragmentation(logger *metricslog.Logger, summary runSummary) {
+	if summary.Runs == 0 {
+		return
+	}
+	first, last := summary.Results[0].HeapFragmentation, summary.Results[summary.Runs-1].HeapFragmentation
+	logger.Printf("Average Heap Fragmentation: %.3f (HeapInuse/HeapAlloc; first run %.3f, last run %.3f)",
+		summary.averages().HeapFragmentation, first, last)
+	if first > 0 && last > first*heapFragmentationGrowth {
+		logger.Printf("WARNING: Heap fragmentation grew from %.3f in the first run to %.3f in the last (%.2fx over %d runs)",
+			first, last, last/first, summary.Runs)
+	}
+}
+
+// logBatchDurations writes the spread of the batch durations of a run, or of several runs, and
+// the imbalance between the slowest batch and the mean. Nothing is written without batches.
+func logBatchDurations(logger *metricslog.Logger, prefix string, durations []time.Duration) {
+	if len(durations) == 0 {
+		return
+	}
+	ms := make([]float64, len(durations))
+	for i, d := range durations {
+		ms[i] = d.Seconds() * 1000
+	}
+	d := stats.Summarize(ms)
+	logger.Printf("%s: min %.3f ms, median %.3f ms, max %.3f ms, imbalance %.2fx (max/mean) over %d batches",
+		prefix, d.Min, d.Median, d.Max, d.Imbalance(), d.Count)
+}
+
+// batchTimingRecords converts the batch durations of the measured runs of summary into CSV
+// records for dataset
+func batchTimingRecords(dataset string, summary runSummary) []result.BatchTimingRecord {
+	var records []result.BatchTimingRecord
+	for i, r := range summary.Results {
+		for batch, d := range r.BatchDurations {
+			records = append(records, result.BatchTimingRecord{Dataset: dataset, Run: i + 1, Batch: batch, Images: r.BatchSize, Duration: d})
+		}
+	}
+	return records
+}
+
+// megabytes converts bytes to MB
+func megabytes(bytes float64) float64 {
+	return bytes / (1024 * 1024)
+}
+
+// averages returns the mean of each metric over the measured runs
+func (s runSummary) averages() runResult {
+	if s.Runs == 0 {
+		return runResult{}
+	}
+	n := time.Duration(s.Runs)
+	var collected map[string]float64
+	if len(s.Total.Collected) > 0 {
+		collected = make(map[string]float64, len(s.Total.Collected))
+		for key, total := range s.Total.Collected {
+			collected[key] = total / float64(s.Runs)
+		}
+	}
+	return runResult{
+		ExecutionTime:       s.Total.ExecutionTime / n,
+		ConcurrencyOverhead: s.Total.ConcurrencyOverhead / n,
+		GoroutineSpawn:      s.Total.GoroutineSpawn / n,
+		TimeToFirstBatch:    s.Total.TimeToFirstBatch / n,
+		ImagesProcessed:     s.Total.ImagesProcessed / s.Runs,
+		PixelsProcessed:     s.Total.PixelsProcessed / s.Runs,
+		RunMetrics:          s.Total.RunMetrics.Average(s.Runs),
+		BatchDurations:      s.Total.BatchDurations, // Every batch of every run, for the spread
+		Collected:           collected,
+	}
+}
+
+// logAverages writes the average metrics of the measured runs
+func logAverages(logger *metricslog.Logger, summary runSummary) {
+	avg := summary.averages()
+	logger.Printf("Average Execution Time: %s seconds", metrics.FormatDuration(avg.ExecutionTime))
+	logger.Printf("Average Concurrency Overhead: %s seconds", metrics.FormatDuration(avg.ConcurrencyOverhead))
+	logger.Printf("Average Goroutine Spawn Time: %.3f ms", avg.GoroutineSpawn.Seconds()*1000)
+	if avg.TimeToFirstBatch > 0 {
+		logger.Printf("Average Time to First Batch: %s seconds", metrics.FormatDuration(avg.TimeToFirstBatch))
+	}
+	logger.Printf("Average Memory Usage: %s MB", metrics.FormatMB(float64(avg.MemoryUsage)))
+	logger.Printf("Average Allocations: AllocCount %d, FreeCount %d", avg.AllocCount, avg.FreeCount)
+	metrics.LogSampledMemory(logger, "Sampled Memory across Runs", avg.RunMetrics)
+	logGC(logger, summary)
+	logHeapFragmentation(logger, summary)
+	logBatchDurations(logger, "Batch Durations across Runs", avg.BatchDurations)
+	logger.Printf("Average CPU Utilization: %s%%", metrics.FormatPercent(avg.CPUUsage))
+	logger.Printf("Average CPU Utilization per Core: %s", metrics.FormatPerCore(avg.PerCoreCPU))
+	metrics.LogCPUTime(logger, "Average CPU Time", avg.RunMetrics)
+	logger.Printf("Average Throughput: %.2f images/second, %.2f megapixels/second",
+		throughput(summary.Total.ImagesProcessed, summary.Total.ExecutionTime), throughput(summary.Total.PixelsProcessed, summary.Total.ExecutionTime)/1e6)
+	logEnergy(logger, "Average Energy", summary.Total.Energy, summary.Runs, summary.Total.ImagesProcessed)
+	if len(avg.Collected) > 0 {
+		logger.Printf("Average Collector Metrics: %s", formatCollected(avg.Collected))
+	}
+	logDistribution(logger, summary)
+	logVariability(logger, summary)
+}
+
+// logDistribution writes the spread of each metric over the measured runs, since the mean alone
+// hides the variance between runs
+func logDistribution(logger *metricslog.Logger, summary runSummary) {
+	figures := []struct {
+		name   string
+		unit   string
+		value  func(runResult) float64
+		format func(float64) string
+	}{
+		{"Execution Time", "seconds", func(r runResult) float64 { return r.ExecutionTime.Seconds() }, metrics.FormatSeconds},
+		{"Concurrency Overhead", "seconds", func(r runResult) float64 { return r.ConcurrencyOverhead.Seconds() }, metrics.FormatSeconds},
+		{"Memory Usage", "MB", func(r runResult) float64 { return float64(r.MemoryUsage) }, metrics.FormatMB},
+		{"CPU Utilization", "%", func(r runResult) float64 { return r.CPUUsage }, metrics.FormatPercent},
+	}
+
+	samples := make([]float64, len(summary.Results))
+	for _, m := range figures {
+		for i, r := range summary.Results {
+			samples[i] = m.value(r)
+		}
+		d := stats.Summarize(samples)
+		f := m.format
+		logger.Printf("%s Distribution (%s): min %s, max %s, median %s, p95 %s, p99 %s, stddev %s, CV %.2f%%",
+			m.name, m.unit, f(d.Min), f(d.Max), f(d.Median), f(d.P95), f(d.P99), f(d.StdDev), d.CV*100)
+	}
+}
+
+// highVariabilityCV is the coefficient of variation above which the spread between runs is
+// large enough to question the averages
+const highVariabilityCV = 0.1
+
+// logVariability writes the coefficient of variation of the execution time and memory usage over
+// the measured runs, with a warning for each above highVariabilityCV
+func logVariability(logger *metricslog.Logger, summary runSummary) {
+	if summary.Runs < 2 {
+		return
+	}
+	executionTimes := make([]float64, len(summary.Results))
+	memoryUsages := make([]float64, len(summary.Results))
+	for i, r := range summary.Results {
+		executionTimes[i] = r.ExecutionTime.Seconds()
+		memoryUsages[i] = float64(r.MemoryUsage)
+	}
+	for _, m := range []struct {
+		name string
+		cv   float64
+	}{
+		{"Execution Time", stats.CoefficientOfVariation(executionTimes)},
+		{"Memory Usage", stats.CoefficientOfVariation(memoryUsages)},
+	} {
+		logger.Printf("%s Coefficient of Variation: %.2f%%", m.name, m.cv*100)
+		if m.cv > highVariabilityCV {
+			logger.Printf("WARNING: High variability detected in %s (CV %.2f%% over %d runs); consider increasing -num-runs or enabling -gc-between-runs.", m.name, m.cv*100, summary.Runs)
+		}
+	}
+}
+
+// logGoroutineCreationRate writes how many goroutines per second this machine can create and
+// retire, and the least time it takes to start the numBatches goroutines of a run at that rate.
+// It bounds the batch throughput of one goroutine per batch, for sizing batches and worker pools.
+func logGoroutineCreationRate(logger *metricslog.Logger, rate float64, numBatches int) {
+	if rate <= 0 {
+		return
+	}
+	logger.Printf("Goroutine Creation Rate: %.0f goroutines/second (%.3f ms to start the %d batch goroutines of a run)",
+		rate, float64(numBatches)/rate*1000, numBatches)
+}
+
+// metricRecords converts the measured runs of summary into CSV records for dataset
+func metricRecords(dataset string, summary runSummary) []result.MetricRecord {
+	records := make([]result.MetricRecord, len(summary.Results))
+	for i, r := range summary.Results {
+		records[i] = result.MetricRecord{
+			Dataset:             dataset,
+			Run:                 i + 1,
+			NumWorkers:          r.NumWorkers,
+			BatchSize:           r.BatchSize,
+			ExecutionTime:       r.ExecutionTime,
+			ConcurrencyOverhead: r.ConcurrencyOverhead,
+			MemoryMB:            float64(r.MemoryUsage) / (1024 * 1024),
+			CPUPercent:          r.CPUUsage,
+			GCPause:             r.GCPause,
+			NumGC:               r.NumGC,
+			GCMaxPause:          r.GCMaxPause,
+		}
+	}
+	return records
+}
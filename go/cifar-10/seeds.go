@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"golang/internal/labels"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// seedSensitivityThreshold is the standard deviation of the per-seed averages, as a fraction of
+// their mean, above which results are considered sensitive to data ordering
+const seedSensitivityThreshold = 0.10
+
+// shuffleImages returns copies of images and their class labels reordered by a permutation drawn
+// from seed, keeping each label with its image. The permutation is the one -dump-dir dumps in.
+func shuffleImages(images [][]float32, classes []int, seed int64) ([][]float32, []int) {
+	order := labels.ShuffleOrder(len(images), seed)
+	shuffledImages := make([][]float32, len(images))
+	shuffledLabels := make([]int, len(classes))
+	for i, j := range order {
+		shuffledImages[i] = images[j]
+		if j < len(classes) {
+			shuffledLabels[i] = classes[j]
+		}
+	}
+	return shuffledImages, shuffledLabels
+}
+
+// seedVariance returns the mean (seconds) and sample variance (seconds squared) of the per-seed
+// average execution times
+func seedVariance(averages []time.Duration) (mean, variance float64) {
+	if len(averages) == 0 {
+		return 0, 0
+	}
+	for _, avg := range averages {
+		mean += avg.Seconds()
+	}
+	mean /= float64(len(averages))
+	if len(averages) < 2 {
+		return mean, 0
+	}
+	for _, avg := range averages {
+		d := avg.Seconds() - mean
+		variance += d * d
+	}
+	return mean, variance / float64(len(averages)-1)
+}
+
+// logSeedVariance logs the spread of the per-seed average execution times and warns when the
+// standard deviation exceeds seedSensitivityThreshold of the mean. Variance is in seconds
+// squared, so its square root is what gets compared against the mean.
+func logSeedVariance(logger *metricslog.Logger, seeds []int64, averages []time.Duration) {
+	mean, variance := seedVariance(averages)
+	stddev := math.Sqrt(variance)
+
+	logger.Printf("\nSeed Variance (%d seeds):", len(averages))
+	for i, avg := range averages {
+		logger.Printf("Seed %d Average Execution Time: %s seconds", seeds[i], metrics.FormatDuration(avg))
+	}
+	logger.Printf("Mean of Seed Averages: %s seconds", metrics.FormatSeconds(mean))
+	logger.Printf("Variance of Seed Averages: %.9f seconds^2", variance)
+	logger.Printf("Standard Deviation of Seed Averages: %s seconds", metrics.FormatSeconds(stddev))
+	if mean > 0 && stddev > seedSensitivityThreshold*mean {
+		logger.Printf("WARNING: seed averages vary by %.1f%% of the mean; results are sensitive to data ordering", stddev/mean*100)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// slowKernel is the name of a kernel that sleeps slowImageTime per image, standing in for an
+// expensive transform whose cost does not depend on the CPU, so the speedup only measures how
+// well the batches overlap
+const (
+	slowKernel    = "slow-processor"
+	slowImageTime = 10 * time.Millisecond
+)
+
+func TestConcurrencySpeedup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Sleeps for about half a second")
+	}
+	kernelFuncs[slowKernel] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		time.Sleep(slowImageTime)
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, slowKernel) })
+
+	cfg := syntheticConfig()
+	cfg.SyntheticImages = 40
+	cfg.Kernel = slowKernel
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	// One batch of 40 images runs on a single goroutine
+	cfg.BatchSize = 40
+	sequentialTime, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 40 {
+		t.Fatalf("Expected 40 images processed sequentially, got %d", imagesProcessed)
+	}
+
+	// 4 batches of 10 on 4 workers should take about a quarter as long
+	cfg.BatchSize, cfg.MaxInFlight = 10, 4
+	concurrentTime, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 40 {
+		t.Fatalf("Expected 40 images processed concurrently, got %d", imagesProcessed)
+	}
+
+	if concurrentTime >= sequentialTime/2 {
+		t.Errorf("Expected 4 workers to take under half the sequential %v, got %v", sequentialTime, concurrentTime)
+	}
+}
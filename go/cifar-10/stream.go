@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// streamReadBufferSize is the read buffer in front of each batch file: large enough to amortize
+// the read calls, small next to the 30MB files
+const streamReadBufferSize = 1 << 20
+
+// StreamCIFAR10 reads the CIFAR-10 training batches record by record and sends their images on out
+// in batches of batchSize, so processing can start while the files are still being read. Images
+// have the standard 32x32x3 shape. out is left open for the caller to close.
+func StreamCIFAR10(dataDir string, out chan<- ImageBatch, batchSize int) error {
+	if batchSize < 1 {
+		return fmt.Errorf("invalid batch size %d: must be at least 1", batchSize)
+	}
+	cfg := DefaultConfig()
+	cfg.BatchSize = batchSize
+	return streamCIFAR10(cfg, dataDir, SplitTrain, func(batch ImageBatch) {
+		out <- batch
+	})
+}
+
+// streamCIFAR10 reads the files of split through a buffered reader and passes their images to
+// emit in batches of cfg.BatchSize, converting one record at a time instead of reading whole
+// files. It stops after cfg.Limit images when a limit is set. Batches emitted before a read error
+// are not taken back.
+func streamCIFAR10(cfg BenchmarkConfig, dataDir, split string, emit func(ImageBatch)) error {
+	fileNames, err := splitFileNames(split)
+	if err != nil {
+		return err
+	}
+	imageSize := cfg.ImageSize()
+	record := make([]byte, imageSize+1)
+	remaining := cfg.Limit
+	var pending ImageBatch
+	for _, fileName := range fileNames {
+		filePath := filepath.Join(dataDir, fileName)
+		fmt.Fprintf(os.Stderr, "Streaming batch: %s\n", filePath)
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %v", filePath, err)
+		}
+		reader := bufio.NewReaderSize(file, streamReadBufferSize)
+		for records := 0; cfg.Limit == 0 || remaining > 0; records++ {
+			_, err := io.ReadFull(reader, record)
+			if err == io.EOF {
+				break
+			}
+			if err == io.ErrUnexpectedEOF {
+				file.Close()
+				return fmt.Errorf("file %s is truncated: record %d has fewer than %d bytes", filePath, records+1, imageSize+1)
+			}
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("failed to read file %s: %v", filePath, err)
+			}
+
+			image := make([]float32, imageSize)
+			for k := 0; k < imageSize; k++ {
+				image[k] = float32(record[k+1]) / 255.0
+			}
+			pending.Images = append(pending.Images, image)
+			pending.Labels = append(pending.Labels, int(record[0]))
+			if len(pending.Images) == cfg.BatchSize {
+				emit(pending)
+				pending = ImageBatch{}
+			}
+			remaining--
+		}
+		file.Close()
+		if cfg.Limit > 0 && remaining == 0 {
+			break
+		}
+	}
+	if len(pending.Images) > 0 {
+		emit(pending)
+	}
+	return nil
+}
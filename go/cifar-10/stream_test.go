@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeNumberedBatches writes the five training files with perFile records of the standard
+// image size. Record i has label i%10 and first pixel i, so the order of arrival can be checked.
+func writeNumberedBatches(t *testing.T, dir string, perFile int) {
+	t.Helper()
+	imageSize := DefaultConfig().ImageSize()
+	for f := 0; f < 5; f++ {
+		var data []byte
+		for j := 0; j < perFile; j++ {
+			record := make([]byte, imageSize+1)
+			record[0] = byte((f*perFile + j) % 10)
+			record[1] = byte(f*perFile + j)
+			data = append(data, record...)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("data_batch_%d.bin", f+1)), data, 0644); err != nil {
+			t.Fatalf("Failed to write synthetic batch: %v", err)
+		}
+	}
+}
+
+func TestStreamCIFAR10(t *testing.T) {
+	dataDir := t.TempDir()
+	writeNumberedBatches(t, dataDir, 7)
+
+	out := make(chan ImageBatch)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- StreamCIFAR10(dataDir, out, 4)
+		close(out)
+	}()
+
+	// 35 records in batches of 4 spanning the 7-record files: 8 full batches and one of 3
+	var sizes []int
+	next := 0
+	for batch := range out {
+		sizes = append(sizes, len(batch.Images))
+		for i, image := range batch.Images {
+			if len(image) != DefaultConfig().ImageSize() {
+				t.Fatalf("Expected images of %d values, got %d", DefaultConfig().ImageSize(), len(image))
+			}
+			if got := int(image[0]*255 + 0.5); got != next {
+				t.Fatalf("Expected record %d next, got record %d", next, got)
+			}
+			if batch.Labels[i] != next%10 {
+				t.Errorf("Expected label %d for record %d, got %d", next%10, next, batch.Labels[i])
+			}
+			next++
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Failed to stream dataset: %v", err)
+	}
+	if next != 35 {
+		t.Errorf("Expected all 35 records exactly once, got %d", next)
+	}
+	if len(sizes) != 9 || sizes[0] != 4 || sizes[8] != 3 {
+		t.Errorf("Expected 8 batches of 4 and one of 3, got %v", sizes)
+	}
+}
+
+func TestStreamCIFAR10Errors(t *testing.T) {
+	if err := StreamCIFAR10(t.TempDir(), make(chan ImageBatch), 0); err == nil {
+		t.Errorf("Expected an error for a batch size of 0")
+	}
+
+	dataDir := t.TempDir()
+	writeNumberedBatches(t, dataDir, 2)
+	path := filepath.Join(dataDir, "data_batch_3.bin")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read synthetic batch: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-1], 0644); err != nil {
+		t.Fatalf("Failed to truncate synthetic batch: %v", err)
+	}
+
+	var received int
+	err = streamCIFAR10(DefaultConfig(), dataDir, SplitTrain, func(batch ImageBatch) {
+		received += len(batch.Images)
+	})
+	if err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("Expected a truncated record error, got %v", err)
+	}
+	if received != 0 {
+		t.Errorf("Expected no batch before the error with a batch size of 500, got %d images", received)
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "golang/internal/metricslog"
+
+// logSubset writes the effective size of split after -limit and -sample-fraction, with the seed
+// the sample was drawn with, when either is set
+func logSubset(cfg BenchmarkConfig, logger *metricslog.Logger, split string, numImages int) {
+	if cfg.Limit == 0 && cfg.SampleFraction == 0 {
+		return
+	}
+	logger.Printf("Dataset Subset (%s): %d images (limit %d, sample fraction %g, seed %d)",
+		split, numImages, cfg.Limit, cfg.SampleFraction, cfg.Seed)
+}
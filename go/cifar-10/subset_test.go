@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestLoadDatasetsSampleFraction(t *testing.T) {
+	cfg := syntheticConfig()
+	cfg.SyntheticImages = 1000
+	cfg.SampleFraction = 0.1
+	datasets, err := loadDatasets(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to load synthetic dataset: %v", err)
+	}
+	// 10 classes of 100 images keep 10 each
+	if n := len(datasets[0].Images); n != 100 || len(datasets[0].Labels) != n {
+		t.Errorf("Expected 100 sampled images and labels, got %d and %d", n, len(datasets[0].Labels))
+	}
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logSubset(cfg, logger, datasets[0].Split, len(datasets[0].Images))
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	expected := "Dataset Subset (synthetic): 100 images (limit 0, sample fraction 0.1, seed 1)"
+	if !strings.Contains(string(content), expected) {
+		t.Errorf("Expected %q in log, got:\n%s", expected, content)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+	"golang/internal/result"
+)
+
+func TestParseMaxProcsSweep(t *testing.T) {
+	settings, err := parseMaxProcsSweep("1, 2,4,")
+	if err != nil {
+		t.Fatalf("Failed to parse sweep: %v", err)
+	}
+	if len(settings) != 3 || settings[0] != 1 || settings[1] != 2 || settings[2] != 4 {
+		t.Errorf("Expected [1 2 4], got %v", settings)
+	}
+	for _, list := range []string{"", "0", "1,x", "-2"} {
+		if _, err := parseMaxProcsSweep(list); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestScalingPoints(t *testing.T) {
+	summaryOf := func(d time.Duration) runSummary {
+		var s runSummary
+		s.add(runResult{ExecutionTime: d, ImagesProcessed: 100})
+		return s
+	}
+	points := scalingPoints([]int{4, 1, 2}, []runSummary{summaryOf(time.Second), summaryOf(4 * time.Second), summaryOf(2 * time.Second)})
+	expected := []struct{ speedup, efficiency float64 }{{4, 1}, {1, 1}, {2, 1}}
+	for i, p := range points {
+		if p.Speedup != expected[i].speedup || p.Efficiency != expected[i].efficiency {
+			t.Errorf("GOMAXPROCS=%d: expected speedup %v and efficiency %v, got %v and %v",
+				p.GOMAXPROCS, expected[i].speedup, expected[i].efficiency, p.Speedup, p.Efficiency)
+		}
+	}
+}
+
+func TestRunMaxProcsSweep(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.BatchSize = 10
+	cfg.SyntheticImages = 100
+	cfg.Warmup, cfg.NumRuns = 1, 2
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	previous := runtime.GOMAXPROCS(0)
+	var jsonOut bytes.Buffer
+	records, err := runMaxProcsSweep(cfg, logger, "sweep", result.Dataset{Split: "synthetic", Images: len(images)}, []int{1, 2}, func() (runResult, error) {
+		return measureRun(cfg, images, labels)
+	}, &jsonOut)
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	if runtime.GOMAXPROCS(0) != previous {
+		t.Errorf("Expected GOMAXPROCS to be restored to %d, got %d", previous, runtime.GOMAXPROCS(0))
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	log := string(content)
+	for _, expected := range []string{"GOMAXPROCS Sweep: 1 (1/2)", "GOMAXPROCS Sweep: 2 (2/2)", "Average Metrics (GOMAXPROCS=2)", "Scaling Table (relative to GOMAXPROCS=1)"} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("Expected %q in log", expected)
+		}
+	}
+	if count := strings.Count(log, "Execution Time for Run"); count != 4 {
+		t.Errorf("Expected 4 measured runs in the log, got %d", count)
+	}
+
+	perGroup := make(map[string]int)
+	for _, r := range records {
+		perGroup[r.Dataset]++
+	}
+	if perGroup["sweep-gomaxprocs1"] != 2 || perGroup["sweep-gomaxprocs2"] != 2 {
+		t.Errorf("Expected 2 records per setting, got %v", perGroup)
+	}
+
+	var sweep result.Sweep
+	if err := json.Unmarshal(jsonOut.Bytes(), &sweep); err != nil {
+		t.Fatalf("Sweep output is not JSON: %v", err)
+	}
+	if sweep.Baseline != 1 || len(sweep.Points) != 2 {
+		t.Fatalf("Expected 2 points relative to GOMAXPROCS=1, got %+v", sweep)
+	}
+	for i, p := range sweep.Points {
+		if p.GOMAXPROCS != i+1 || p.Runs != cfg.NumRuns {
+			t.Errorf("Point %d: expected GOMAXPROCS %d with %d runs, got %d with %d", i, i+1, cfg.NumRuns, p.GOMAXPROCS, p.Runs)
+		}
+	}
+	if sweep.Points[0].Speedup != 1 {
+		t.Errorf("Expected the baseline speedup to be 1, got %v", sweep.Points[0].Speedup)
+	}
+}
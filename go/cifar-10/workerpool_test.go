@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// poolBatches splits a synthetic dataset into batches of cfg.BatchSize
+func poolBatches(t *testing.T, cfg BenchmarkConfig) []ImageBatch {
+	t.Helper()
+	images, labels, err := syntheticDataset(cfg)
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	var batches []ImageBatch
+	for start := 0; start+cfg.BatchSize <= len(images); start += cfg.BatchSize {
+		batches = append(batches, ImageBatch{Images: images[start : start+cfg.BatchSize], Labels: labels[start : start+cfg.BatchSize]})
+	}
+	return batches
+}
+
+func TestBoundedWorkerPoolProcessesAll(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 8, 80
+	batches := poolBatches(t, cfg)
+	first := batches[0].Images[0][0]
+
+	pool := NewBoundedWorkerPool(cfg, 3, 2)
+	for _, batch := range batches {
+		if err := pool.Submit(batch); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	result := pool.Wait()
+
+	if result.Batches != len(batches) || result.ImagesProcessed != cfg.SyntheticImages {
+		t.Errorf("Expected %d batches and %d images, got %d and %d", len(batches), cfg.SyntheticImages, result.Batches, result.ImagesProcessed)
+	}
+	if got := batches[0].Images[0][0]; got != first*2 {
+		t.Errorf("Expected the double kernel to be applied, got %v from %v", got, first)
+	}
+	if result.Elapsed <= 0 || result.ProcessingTime <= 0 {
+		t.Errorf("Expected positive timings, got elapsed %v and processing %v", result.Elapsed, result.ProcessingTime)
+	}
+}
+
+func TestBoundedWorkerPoolBackpressure(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 64, 64, 3
+	cfg.Kernel = KernelBlur
+	cfg.BatchSize, cfg.SyntheticImages = 8, 80
+	batches := poolBatches(t, cfg)
+
+	// One slow worker and room for one waiting batch: submitting ten at once must block
+	pool := NewBoundedWorkerPool(cfg, 1, 1)
+	for _, batch := range batches {
+		if err := pool.Submit(batch); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	result := pool.Wait()
+
+	if result.BackpressureEvents == 0 || result.BlockedTime <= 0 {
+		t.Errorf("Expected Submit to block, got %d events and %v blocked", result.BackpressureEvents, result.BlockedTime)
+	}
+	if result.BackpressureEvents > len(batches) {
+		t.Errorf("Expected at most one backpressure event per batch, got %d", result.BackpressureEvents)
+	}
+}
+
+func TestBoundedWorkerPoolConcurrentSubmit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 4, 160
+	batches := poolBatches(t, cfg)
+
+	pool := NewBoundedWorkerPool(cfg, 4, 2)
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		wg.Add(1)
+		go func(batch ImageBatch) {
+			defer wg.Done()
+			if err := pool.Submit(batch); err != nil {
+				t.Errorf("Submit failed: %v", err)
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	if result := pool.Wait(); result.Batches != len(batches) {
+		t.Errorf("Expected %d batches, got %d", len(batches), result.Batches)
+	}
+}
+
+func TestBoundedWorkerPoolSubmitAfterWait(t *testing.T) {
+	pool := NewBoundedWorkerPool(DefaultConfig(), 1, 1)
+	pool.Wait()
+	if err := pool.Submit(ImageBatch{}); !errors.Is(err, errPoolClosed) {
+		t.Errorf("Expected errPoolClosed, got %v", err)
+	}
+	if result := pool.Wait(); result.Batches != 0 {
+		t.Errorf("Expected a second Wait to report no batches, got %d", result.Batches)
+	}
+}
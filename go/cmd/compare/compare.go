@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"golang/internal/stats"
+)
+
+// Row is the comparison of one metric on one dataset
+type Row struct {
+	Metric  string
+	Go      stats.Summary
+	Java    stats.Summary
+	Ratio   float64 // Go mean over Java mean, NaN when the Java mean is 0
+	Flagged bool    // The ratio is further from 1 than the threshold
+}
+
+// DatasetComparison holds the rows of one dataset measured by both implementations
+type DatasetComparison struct {
+	Dataset       string
+	GoBatchSize   int
+	JavaBatchSize int
+	GoRuns        int
+	JavaRuns      int
+	Rows          []Row
+}
+
+// Comparison is the outcome of comparing two result files
+type Comparison struct {
+	Threshold float64 // Largest relative difference of the means left unflagged
+	Datasets  []DatasetComparison
+	Warnings  []string
+}
+
+// Compare summarizes every metric of the datasets present in both result files. Datasets measured
+// with a different batch size or number of runs are an error unless force is set, in which case
+// they are compared with a warning. Datasets only one side measured are skipped with a warning.
+func Compare(goResults, javaResults *Results, threshold float64, force bool) (*Comparison, error) {
+	c := &Comparison{Threshold: threshold}
+	for _, g := range goResults.Datasets {
+		j := javaResults.Dataset(g.Name)
+		if j == nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("dataset %s has no Java results", g.Name))
+			continue
+		}
+		var mismatches []string
+		if g.BatchSize != 0 && j.BatchSize != 0 && g.BatchSize != j.BatchSize {
+			mismatches = append(mismatches, fmt.Sprintf("batch size %d in Go results but %d in Java results", g.BatchSize, j.BatchSize))
+		}
+		if g.Runs != j.Runs {
+			mismatches = append(mismatches, fmt.Sprintf("%d runs in Go results but %d in Java results", g.Runs, j.Runs))
+		}
+		for _, m := range mismatches {
+			if !force {
+				return nil, fmt.Errorf("dataset %s: %s; pass -force to compare anyway", g.Name, m)
+			}
+			c.Warnings = append(c.Warnings, fmt.Sprintf("dataset %s: %s", g.Name, m))
+		}
+
+		d := DatasetComparison{
+			Dataset:       g.Name,
+			GoBatchSize:   g.BatchSize,
+			JavaBatchSize: j.BatchSize,
+			GoRuns:        g.Runs,
+			JavaRuns:      j.Runs,
+		}
+		for _, m := range metrics {
+			goSamples, javaSamples := g.Samples[m.Column], j.Samples[m.Column]
+			if len(goSamples) == 0 || len(javaSamples) == 0 {
+				continue
+			}
+			row := Row{Metric: m.Name, Go: stats.Summarize(goSamples), Java: stats.Summarize(javaSamples), Ratio: math.NaN()}
+			if row.Java.Mean != 0 {
+				row.Ratio = row.Go.Mean / row.Java.Mean
+				row.Flagged = math.Abs(row.Ratio-1) > threshold
+			}
+			d.Rows = append(d.Rows, row)
+		}
+		c.Datasets = append(c.Datasets, d)
+	}
+	for _, j := range javaResults.Datasets {
+		if goResults.Dataset(j.Name) == nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("dataset %s has no Go results", j.Name))
+		}
+	}
+	return c, nil
+}
+
+// WriteTable writes one side-by-side table per dataset, marking the flagged rows, followed by the
+// warnings
+func (c *Comparison) WriteTable(w io.Writer) {
+	for i, d := range c.Datasets {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Dataset %s (batch size %s, %s runs)\n", d.Dataset,
+			sideBySide(d.GoBatchSize, d.JavaBatchSize), sideBySide(d.GoRuns, d.JavaRuns))
+		fmt.Fprintf(w, "  %-24s  %12s  %12s  %12s  %12s  %12s  %12s  %8s\n",
+			"Metric", "Go Mean", "Go Median", "Go StdDev", "Java Mean", "Java Median", "Java StdDev", "Go/Java")
+		for _, r := range d.Rows {
+			ratio := "-"
+			if !math.IsNaN(r.Ratio) {
+				ratio = fmt.Sprintf("%.2fx", r.Ratio)
+			}
+			fmt.Fprintf(w, "  %-24s  %12.4f  %12.4f  %12.4f  %12.4f  %12.4f  %12.4f  %8s", r.Metric,
+				r.Go.Mean, r.Go.Median, r.Go.StdDev, r.Java.Mean, r.Java.Median, r.Java.StdDev, ratio)
+			if r.Flagged {
+				fmt.Fprintf(w, "  * differs by %+.1f%%", (r.Ratio-1)*100)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(c.Datasets) == 0 {
+		fmt.Fprintln(w, "No dataset appears in both result files")
+	}
+	for _, warning := range c.Warnings {
+		fmt.Fprintf(w, "WARNING: %s\n", warning)
+	}
+}
+
+// sideBySide formats a setting of both sides as a single value when they agree and as go/java
+// otherwise, with 0 shown as unknown
+func sideBySide(goValue, javaValue int) string {
+	format := func(v int) string {
+		if v == 0 {
+			return "unknown"
+		}
+		return fmt.Sprint(v)
+	}
+	if goValue == javaValue {
+		return format(goValue)
+	}
+	return format(goValue) + " Go / " + format(javaValue) + " Java"
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// readFixtures reads the Go and Java result fixtures from testdata
+func readFixtures(t *testing.T, goFile, javaFile string) (*Results, *Results) {
+	t.Helper()
+	goResults, err := ReadResultsFile("testdata/" + goFile)
+	if err != nil {
+		t.Fatalf("Failed to read Go results: %v", err)
+	}
+	javaResults, err := ReadResultsFile("testdata/" + javaFile)
+	if err != nil {
+		t.Fatalf("Failed to read Java results: %v", err)
+	}
+	return goResults, javaResults
+}
+
+func TestCompareFlagsDifferencesAboveThreshold(t *testing.T) {
+	goResults, javaResults := readFixtures(t, "go_results.csv", "java_results.csv")
+	comparison, err := Compare(goResults, javaResults, 0.05, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if len(comparison.Datasets) != 2 {
+		t.Fatalf("Expected 2 datasets, got %d", len(comparison.Datasets))
+	}
+
+	// The Java fixture has no GC columns, so only the four shared metrics are compared
+	tiny := comparison.Datasets[1]
+	if len(tiny.Rows) != 4 {
+		t.Fatalf("Expected 4 metrics, got %d", len(tiny.Rows))
+	}
+	flagged := map[string]bool{}
+	for _, r := range tiny.Rows {
+		flagged[r.Metric] = r.Flagged
+	}
+	if flagged["Execution Time (s)"] || flagged["CPU Utilization (%)"] || !flagged["Concurrency Overhead (s)"] {
+		t.Errorf("Expected only the concurrency overhead flagged at 5%%, got %v", flagged)
+	}
+	if ratio := tiny.Rows[1].Ratio; ratio < 0.199 || ratio > 0.201 {
+		t.Errorf("Expected a concurrency overhead ratio of 0.20, got %v", ratio)
+	}
+}
+
+func TestCompareRejectsMismatchedSettings(t *testing.T) {
+	goResults, javaResults := readFixtures(t, "go_results.csv", "java_batch250.csv")
+	_, err := Compare(goResults, javaResults, 0.1, false)
+	if err == nil || !strings.Contains(err.Error(), "batch size 500 in Go results but 250 in Java results") {
+		t.Fatalf("Expected a batch size mismatch error, got %v", err)
+	}
+
+	comparison, err := Compare(goResults, javaResults, 0.1, true)
+	if err != nil {
+		t.Fatalf("Expected -force to compare anyway, got %v", err)
+	}
+	var buf bytes.Buffer
+	comparison.WriteTable(&buf)
+	output := buf.String()
+	for _, expected := range []string{
+		"Dataset cifar10-train (batch size 500 Go / 250 Java, 4 runs)",
+		"WARNING: dataset cifar10-train: batch size 500 in Go results but 250 in Java results",
+		"WARNING: dataset tinyimagenet has no Java results",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestCompareRejectsDifferentRunCounts(t *testing.T) {
+	goResults, javaResults := readFixtures(t, "go_once.json", "java_results.csv")
+	_, err := Compare(goResults, javaResults, 0.1, false)
+	if err == nil || !strings.Contains(err.Error(), "2 runs in Go results but 4 in Java results") {
+		t.Errorf("Expected a run count mismatch error, got %v", err)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	goResults, javaResults := readFixtures(t, "go_results.csv", "java_results.csv")
+	comparison, err := Compare(goResults, javaResults, 0.05, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	var buf bytes.Buffer
+	comparison.WriteTable(&buf)
+
+	expected, err := os.ReadFile("testdata/table.golden")
+	if err != nil {
+		t.Fatalf("Failed to read golden table: %v", err)
+	}
+	if buf.String() != string(expected) {
+		t.Errorf("Table mismatch, expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
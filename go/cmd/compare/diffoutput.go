@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"golang/internal/dump"
+)
+
+// maxListedMismatches caps the label mismatches listed per split
+const maxListedMismatches = 10
+
+// DiffOutputs compares every split dumped with -dump-dir in goDir against the same split in
+// javaDir, writing the label check and then the pixel comparison of each split to w. It reports
+// whether all splits agree.
+func DiffOutputs(goDir, javaDir string, tolerance float64, w io.Writer) (bool, error) {
+	splits, err := dump.Splits(goDir)
+	if err != nil {
+		return false, err
+	}
+	if len(splits) == 0 {
+		return false, fmt.Errorf("no dumped images found in %s", goDir)
+	}
+
+	agree := true
+	for _, split := range splits {
+		diff, err := dump.Compare(goDir, javaDir, split, tolerance)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(w, "Split %s (%d images)\n", split, diff.Images)
+		if len(diff.LabelMismatches) > 0 {
+			agree = false
+			fmt.Fprintf(w, "  Labels: %d mismatches, pixels not compared\n", len(diff.LabelMismatches))
+			for i, m := range diff.LabelMismatches {
+				if i == maxListedMismatches {
+					fmt.Fprintf(w, "    ... %d more\n", len(diff.LabelMismatches)-maxListedMismatches)
+					break
+				}
+				fmt.Fprintf(w, "    image %d: go %d, java %d\n", m.Index, m.Expected, m.Actual)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "  Labels: all match\n")
+		if diff.PixelMismatches > 0 {
+			agree = false
+		}
+		fmt.Fprintf(w, "  Pixels: %d of %d differ by more than %g (max difference %g)\n",
+			diff.PixelMismatches, diff.Pixels, tolerance, diff.MaxAbsDiff)
+	}
+	return agree, nil
+}
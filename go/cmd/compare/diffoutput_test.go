@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang/internal/dump"
+	"golang/internal/labels"
+)
+
+func TestDiffOutputsReportsLabelsBeforePixels(t *testing.T) {
+	images := [][]float32{{0.1, 0.2}, {0.3, 0.4}, {0.5, 0.6}}
+	goDir, javaDir := t.TempDir(), t.TempDir()
+	if err := dump.Write(goDir, "train", images, labels.FromIndices([]int{0, 1, 2}, nil), labels.FormatBinary); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	if err := dump.Write(javaDir, "train", images, labels.FromIndices([]int{0, 1, 2}, nil), labels.FormatCSV); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+
+	var out bytes.Buffer
+	agree, err := DiffOutputs(goDir, javaDir, 0, &out)
+	if err != nil || !agree {
+		t.Fatalf("Expected identical dumps to agree, got %v %v:\n%s", agree, err, out.String())
+	}
+	if !strings.Contains(out.String(), "Labels: all match") || !strings.Contains(out.String(), "Pixels: 0 of 6") {
+		t.Errorf("Unexpected report:\n%s", out.String())
+	}
+
+	// Swapped labels are reported on their own, without a pixel comparison
+	if err := dump.Write(javaDir, "train", images, labels.FromIndices([]int{1, 0, 2}, nil), labels.FormatBinary); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	out.Reset()
+	agree, err = DiffOutputs(goDir, javaDir, 0, &out)
+	if err != nil || agree {
+		t.Fatalf("Expected swapped labels to disagree, got %v %v", agree, err)
+	}
+	report := out.String()
+	if !strings.Contains(report, "Labels: 2 mismatches") || !strings.Contains(report, "image 0: go 0, java 1") {
+		t.Errorf("Expected the label mismatches in the report:\n%s", report)
+	}
+	if strings.Contains(report, "Pixels:") {
+		t.Errorf("Expected pixels to be left uncompared after a label mismatch:\n%s", report)
+	}
+}
@@ -0,0 +1,74 @@
+// Command compare reports Go and Java benchmark results side by side: the mean, median and
+// standard deviation of every metric on each dataset, and the ratio of the Go mean to the Java one.
+//
+//	go run ./cmd/compare -go go_results.csv -java java_results.csv
+//
+// Both files use the schema the Go benchmarks write with -csv, or are -once JSON records when
+// their name ends in .json.
+//
+// With -diff-output, -go and -java name directories written with -dump-dir instead. The labels of
+// every dumped split are checked first, and the pixels are compared only when they agree.
+//
+//	go run ./cmd/compare -diff-output -go go_dump -java java_dump
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	goPath := flag.String("go", "", "Go results file, CSV as written by -csv or JSON as written by -once")
+	javaPath := flag.String("java", "", "Java results file in the same schema")
+	threshold := flag.Float64("threshold", 10, "flag metrics whose Go and Java means differ by more than this percentage")
+	force := flag.Bool("force", false, "compare results taken with different batch sizes or numbers of runs")
+	diffOutput := flag.Bool("diff-output", false, "treat -go and -java as -dump-dir directories and compare their labels, then their pixels")
+	tolerance := flag.Float64("tolerance", 1e-6, "with -diff-output, largest difference between two pixel values that still counts as a match")
+	flag.Parse()
+
+	if *goPath == "" || *javaPath == "" {
+		fmt.Fprintln(os.Stderr, "Both -go and -java are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *threshold < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid -threshold %v: must not be negative\n", *threshold)
+		os.Exit(2)
+	}
+
+	if *diffOutput {
+		agree, err := DiffOutputs(*goPath, *javaPath, *tolerance, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !agree {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(*goPath, *javaPath, *threshold/100, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run compares the result files at goPath and javaPath and prints the tables to stdout
+func run(goPath, javaPath string, threshold float64, force bool) error {
+	goResults, err := ReadResultsFile(goPath)
+	if err != nil {
+		return err
+	}
+	javaResults, err := ReadResultsFile(javaPath)
+	if err != nil {
+		return err
+	}
+	comparison, err := Compare(goResults, javaResults, threshold, force)
+	if err != nil {
+		return err
+	}
+	comparison.WriteTable(os.Stdout)
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang/internal/result"
+)
+
+// metric is a figure compared between the two result files, with the CSV column it is read from
+type metric struct {
+	Name   string
+	Column string
+	Scale  float64 // Factor converting the column's unit to the unit shown in the table
+}
+
+// metrics lists the compared figures in table order. GC metrics are skipped when a file has no
+// such column, as the JVM reports its collections elsewhere.
+var metrics = []metric{
+	{"Execution Time (s)", "execution_time_seconds", 1},
+	{"Concurrency Overhead (s)", "concurrency_overhead_seconds", 1},
+	{"Memory Usage (MB)", "memory_mb", 1},
+	{"CPU Utilization (%)", "cpu_percent", 1},
+	{"GC Pause (ms)", "gc_pause_ns", 1e-6},
+	{"GC Cycles", "num_gc", 1},
+}
+
+// DatasetResults holds the samples of every metric measured on one dataset
+type DatasetResults struct {
+	Name      string
+	BatchSize int // Images per batch, 0 when the file does not record it
+	Runs      int // Measured runs behind the samples
+	Samples   map[string][]float64
+}
+
+// Results holds the datasets of one result file in the order they first appear
+type Results struct {
+	Datasets []*DatasetResults
+}
+
+// Dataset returns the results for the named dataset, nil when the file has none
+func (r *Results) Dataset(name string) *DatasetResults {
+	for _, d := range r.Datasets {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// dataset returns the results for the named dataset, adding them when missing
+func (r *Results) dataset(name string) *DatasetResults {
+	if d := r.Dataset(name); d != nil {
+		return d
+	}
+	d := &DatasetResults{Name: name, Samples: make(map[string][]float64)}
+	r.Datasets = append(r.Datasets, d)
+	return d
+}
+
+// setBatchSize records the batch size of a sample, rejecting a file that mixes batch sizes
+// within one dataset. A batch size of 0 is unknown and matches anything.
+func (d *DatasetResults) setBatchSize(batchSize int) error {
+	if batchSize == 0 {
+		return nil
+	}
+	if d.BatchSize != 0 && d.BatchSize != batchSize {
+		return fmt.Errorf("dataset %s mixes batch sizes %d and %d", d.Name, d.BatchSize, batchSize)
+	}
+	d.BatchSize = batchSize
+	return nil
+}
+
+// ReadResultsFile reads a result file, as JSON records when its name ends in .json and as CSV
+// rows in the -csv schema otherwise
+func ReadResultsFile(path string) (*Results, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results file: %v", err)
+	}
+	defer file.Close()
+
+	var results *Results
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		results, err = ReadJSONResults(file)
+	} else {
+		results, err = ReadCSVResults(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return results, nil
+}
+
+// ReadCSVResults reads rows in the schema the benchmarks write with -csv, one measured run per
+// row. Only the dataset column is required; a missing metric column leaves that metric out, and a
+// missing batch_size column leaves the batch size unknown.
+func ReadCSVResults(r io.Reader) (*Results, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	datasetColumn, ok := columns["dataset"]
+	if !ok {
+		return nil, fmt.Errorf("CSV header has no dataset column")
+	}
+
+	results := &Results{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %v", err)
+		}
+		d := results.dataset(row[datasetColumn])
+		d.Runs++
+		if i, ok := columns["batch_size"]; ok {
+			batchSize, err := strconv.Atoi(row[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid batch_size %q", line, row[i])
+			}
+			if err := d.setBatchSize(batchSize); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		for _, m := range metrics {
+			i, ok := columns[m.Column]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(row[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, m.Column, row[i])
+			}
+			d.Samples[m.Column] = append(d.Samples[m.Column], value*m.Scale)
+		}
+	}
+	return results, nil
+}
+
+// ReadJSONResults reads one or more -once records written one after the other. Each record is the
+// average of its runs and counts as one sample of its split; the run counts add up.
+func ReadJSONResults(r io.Reader) (*Results, error) {
+	decoder := json.NewDecoder(r)
+	results := &Results{}
+	for {
+		var record struct {
+			result.Record
+			Config struct{ BatchSize int }
+		}
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode result record: %v", err)
+		}
+		d := results.dataset(record.Dataset.Split)
+		d.Runs += record.Runs
+		if err := d.setBatchSize(record.Config.BatchSize); err != nil {
+			return nil, err
+		}
+		run := record.Run
+		for column, value := range map[string]float64{
+			"execution_time_seconds":       run.ExecutionSeconds,
+			"concurrency_overhead_seconds": run.ConcurrencyOverheadSeconds,
+			"memory_mb":                    float64(run.MemoryBytes) / (1024 * 1024),
+			"cpu_percent":                  run.CPUPercent,
+			"gc_pause_ns":                  run.GCPauseSeconds * 1e9,
+			"num_gc":                       run.NumGC,
+		} {
+			d.Samples[column] = append(d.Samples[column], value*metricScale(column))
+		}
+	}
+	if len(results.Datasets) == 0 {
+		return nil, fmt.Errorf("no result records")
+	}
+	return results, nil
+}
+
+// metricScale returns the table scale of the metric read from column
+func metricScale(column string) float64 {
+	for _, m := range metrics {
+		if m.Column == column {
+			return m.Scale
+		}
+	}
+	return 1
+}
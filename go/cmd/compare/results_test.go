@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVResults(t *testing.T) {
+	results, err := ReadResultsFile("testdata/go_results.csv")
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	if len(results.Datasets) != 2 || results.Datasets[0].Name != "cifar10-train" || results.Datasets[1].Name != "tinyimagenet" {
+		t.Fatalf("Expected datasets cifar10-train and tinyimagenet in file order, got %+v", results.Datasets)
+	}
+	d := results.Dataset("cifar10-train")
+	if d.BatchSize != 500 || d.Runs != 4 {
+		t.Errorf("Expected batch size 500 and 4 runs, got %d and %d", d.BatchSize, d.Runs)
+	}
+	expected := []float64{0.031, 0.029, 0.030, 0.034}
+	if got := d.Samples["execution_time_seconds"]; len(got) != len(expected) || got[3] != expected[3] {
+		t.Errorf("Expected execution times %v, got %v", expected, got)
+	}
+	if got := d.Samples["gc_pause_ns"]; len(got) != 4 || math.Abs(got[0]-0.12) > 1e-9 {
+		t.Errorf("Expected the first GC pause converted to 0.12 ms, got %v", got)
+	}
+}
+
+func TestReadCSVResultsWithoutOptionalColumns(t *testing.T) {
+	results, err := ReadCSVResults(strings.NewReader("dataset,execution_time_seconds\ncifar10-train,0.5\ncifar10-train,0.7\n"))
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	d := results.Dataset("cifar10-train")
+	if d.BatchSize != 0 || d.Runs != 2 {
+		t.Errorf("Expected an unknown batch size and 2 runs, got %d and %d", d.BatchSize, d.Runs)
+	}
+	if _, ok := d.Samples["memory_mb"]; ok {
+		t.Errorf("Expected no memory samples without a memory_mb column")
+	}
+}
+
+func TestReadCSVResultsErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"no dataset column":  "run,execution_time_seconds\n1,0.5\n",
+		"invalid value":      "dataset,execution_time_seconds\ncifar10-train,fast\n",
+		"mixed batch sizes":  "dataset,batch_size\ncifar10-train,500\ncifar10-train,250\n",
+		"invalid batch size": "dataset,batch_size\ncifar10-train,large\n",
+		"empty file":         "",
+	} {
+		if _, err := ReadCSVResults(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReadJSONResults(t *testing.T) {
+	results, err := ReadResultsFile("testdata/go_once.json")
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	d := results.Dataset("cifar10-train")
+	if d == nil {
+		t.Fatalf("Expected a cifar10-train dataset, got %+v", results.Datasets)
+	}
+	if d.BatchSize != 500 || d.Runs != 2 {
+		t.Errorf("Expected batch size 500 and 2 runs over both records, got %d and %d", d.BatchSize, d.Runs)
+	}
+	if got := d.Samples["memory_mb"]; len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Errorf("Expected memory samples [2 1] MB, got %v", got)
+	}
+	if got := d.Samples["gc_pause_ns"]; len(got) != 2 || math.Abs(got[0]-0.12) > 1e-9 {
+		t.Errorf("Expected the first GC pause converted to 0.12 ms, got %v", got)
+	}
+
+	if _, err := ReadJSONResults(strings.NewReader("")); err == nil {
+		t.Errorf("Expected an error for a file without records")
+	}
+}
@@ -2,15 +2,19 @@ module golang
 
 go 1.23.3
 
-require gorgonia.org/gorgonia v0.9.18
+require (
+	github.com/google/pprof v0.0.0-20241210010833-40e02aabc2ad
+	github.com/shirou/gopsutil v3.21.11+incompatible
+	golang.org/x/sync v0.10.0
+	gorgonia.org/gorgonia v0.9.18
+)
 
 require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
-	github.com/shirou/gopsutil v3.21.11+incompatible // indirect
 	github.com/tklauser/go-sysconf v0.3.14 // indirect
 	github.com/tklauser/numcpus v0.8.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
-	golang.org/x/sys v0.19.0 // indirect
+	golang.org/x/sys v0.19.0
 )
 
 require (
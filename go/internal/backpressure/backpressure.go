@@ -0,0 +1,168 @@
+// Package backpressure provides a bounded queue that records how long producers and
+// consumers spend waiting on each other, for use between streaming loaders and workers.
+package backpressure
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue is a bounded channel instrumented with occupancy, blocked-time and starved-time tracking
+type Queue[T any] struct {
+	ch chan T
+
+	mu              sync.Mutex
+	start           time.Time
+	lastChange      time.Time
+	lastLen         int
+	occupancy       []time.Duration
+	producerBlocked time.Duration
+	blockedPuts     int
+	consumerStarved time.Duration
+}
+
+// Stats is a snapshot of the queue's back-pressure metrics
+type Stats struct {
+	Capacity        int
+	Elapsed         time.Duration
+	ProducerBlocked time.Duration   // Total time producers waited for free space
+	BlockedPuts     int             // Number of Put calls that found the queue full
+	ConsumerStarved time.Duration   // Total time consumers waited for an item
+	Occupancy       []time.Duration // Time spent at each queue length, indexed 0..Capacity
+}
+
+// NewQueue creates a queue holding at most capacity items. A capacity below 1 is raised to 1:
+// an unbuffered queue has no occupancy to tell a full queue from an empty one.
+func NewQueue[T any](capacity int) *Queue[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	now := time.Now()
+	return &Queue[T]{
+		ch:         make(chan T, capacity),
+		start:      now,
+		lastChange: now,
+		occupancy:  make([]time.Duration, capacity+1),
+	}
+}
+
+// Put adds an item, blocking while the queue is full
+func (q *Queue[T]) Put(item T) {
+	select {
+	case q.ch <- item:
+	default:
+		waitStart := time.Now()
+		q.ch <- item
+		q.mu.Lock()
+		q.producerBlocked += time.Since(waitStart)
+		q.blockedPuts++
+		q.mu.Unlock()
+	}
+	q.recordOccupancy()
+}
+
+// Get removes an item, blocking while the queue is empty. It returns false once the
+// queue is closed and drained.
+func (q *Queue[T]) Get() (T, bool) {
+	var item T
+	var ok bool
+	select {
+	case item, ok = <-q.ch:
+	default:
+		waitStart := time.Now()
+		item, ok = <-q.ch
+		q.mu.Lock()
+		q.consumerStarved += time.Since(waitStart)
+		q.mu.Unlock()
+	}
+	if ok {
+		q.recordOccupancy()
+	}
+	return item, ok
+}
+
+// Close signals that no more items will be added
+func (q *Queue[T]) Close() {
+	close(q.ch)
+}
+
+// recordOccupancy attributes the time since the last change to the previous queue length
+func (q *Queue[T]) recordOccupancy() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	q.occupancy[q.lastLen] += now.Sub(q.lastChange)
+	q.lastChange = now
+	q.lastLen = len(q.ch)
+}
+
+// Stats returns the metrics accumulated so far
+func (q *Queue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	occupancy := make([]time.Duration, len(q.occupancy))
+	copy(occupancy, q.occupancy)
+	occupancy[q.lastLen] += now.Sub(q.lastChange)
+	return Stats{
+		Capacity:        cap(q.ch),
+		Elapsed:         now.Sub(q.start),
+		ProducerBlocked: q.producerBlocked,
+		BlockedPuts:     q.blockedPuts,
+		ConsumerStarved: q.consumerStarved,
+		Occupancy:       occupancy,
+	}
+}
+
+// fraction returns the share of the run spent at the given queue length
+func (s Stats) fraction(length int) float64 {
+	var total time.Duration
+	for _, d := range s.Occupancy {
+		total += d
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Occupancy[length]) / float64(total)
+}
+
+// ProducerBoundFraction is the share of the run the queue was empty, i.e. consumers were waiting on the producer
+func (s Stats) ProducerBoundFraction() float64 {
+	return s.fraction(0)
+}
+
+// ConsumerBoundFraction is the share of the run the queue was full, i.e. the producer was waiting on consumers
+func (s Stats) ConsumerBoundFraction() float64 {
+	return s.fraction(s.Capacity)
+}
+
+// Verdict summarizes which side of the queue was the bottleneck
+func (s Stats) Verdict() string {
+	producer, consumer := s.ProducerBoundFraction(), s.ConsumerBoundFraction()
+	switch {
+	case producer == 0 && consumer == 0:
+		return "balanced for the whole run"
+	case producer >= consumer:
+		return fmt.Sprintf("producer-bound for %.0f%% of the run", producer*100)
+	default:
+		return fmt.Sprintf("consumer-bound for %.0f%% of the run", consumer*100)
+	}
+}
+
+// Report formats the stats as log lines, including the time-weighted occupancy histogram
+func (s Stats) Report() []string {
+	lines := []string{
+		fmt.Sprintf("Queue Capacity: %d", s.Capacity),
+		fmt.Sprintf("Producer Blocked Time: %.9f seconds", s.ProducerBlocked.Seconds()),
+		fmt.Sprintf("Consumer Starved Time: %.9f seconds", s.ConsumerStarved.Seconds()),
+		"Queue Occupancy (time-weighted):",
+	}
+	for length := range s.Occupancy {
+		share := s.fraction(length)
+		lines = append(lines, fmt.Sprintf("  %3d: %6.2f%% %s", length, share*100, strings.Repeat("#", int(share*50))))
+	}
+	lines = append(lines, "Back-pressure Verdict: "+s.Verdict())
+	return lines
+}
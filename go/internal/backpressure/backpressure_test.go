@@ -0,0 +1,145 @@
+package backpressure
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// runScenario pushes numItems through a queue with the given per-item producer and consumer delays
+func runScenario(producerDelay, consumerDelay time.Duration) Stats {
+	const numItems = 30
+	q := NewQueue[int](4)
+
+	go func() {
+		for i := 0; i < numItems; i++ {
+			time.Sleep(producerDelay)
+			q.Put(i)
+		}
+		q.Close()
+	}()
+
+	for {
+		if _, ok := q.Get(); !ok {
+			break
+		}
+		time.Sleep(consumerDelay)
+	}
+	return q.Stats()
+}
+
+func TestVerdictSlowProducer(t *testing.T) {
+	stats := runScenario(3*time.Millisecond, 0)
+	if !strings.HasPrefix(stats.Verdict(), "producer-bound") {
+		t.Errorf("Expected producer-bound verdict, got %q", stats.Verdict())
+	}
+	if stats.ConsumerStarved <= stats.ProducerBlocked {
+		t.Errorf("Expected consumer starved time (%v) to exceed producer blocked time (%v)", stats.ConsumerStarved, stats.ProducerBlocked)
+	}
+}
+
+func TestVerdictSlowConsumer(t *testing.T) {
+	stats := runScenario(0, 3*time.Millisecond)
+	if !strings.HasPrefix(stats.Verdict(), "consumer-bound") {
+		t.Errorf("Expected consumer-bound verdict, got %q", stats.Verdict())
+	}
+	if stats.ProducerBlocked <= stats.ConsumerStarved {
+		t.Errorf("Expected producer blocked time (%v) to exceed consumer starved time (%v)", stats.ProducerBlocked, stats.ConsumerStarved)
+	}
+	if stats.BlockedPuts == 0 {
+		t.Errorf("Expected blocked puts to be counted")
+	}
+}
+
+func TestReportIncludesHistogram(t *testing.T) {
+	stats := runScenario(0, 0)
+	lines := stats.Report()
+	// Header lines, one line per occupancy level 0..capacity, and the verdict
+	if len(lines) != 4+stats.Capacity+1+1 {
+		t.Errorf("Unexpected report length %d: %v", len(lines), lines)
+	}
+}
+
+// blockWindow is how long a call must stay pending to count as blocked, and unblockTimeout how
+// soon it must return once the other side makes progress
+const (
+	blockWindow    = 20 * time.Millisecond
+	unblockTimeout = 100 * time.Millisecond
+)
+
+func TestBoundedQueueBlocking(t *testing.T) {
+	const capacity = 3
+	q := NewQueue[int](capacity)
+	for i := 0; i < capacity; i++ {
+		q.Put(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.Put(capacity)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Expected Put on a full queue to block")
+	case <-time.After(blockWindow):
+	}
+
+	if item, ok := q.Get(); !ok || item != 0 {
+		t.Fatalf("Expected to dequeue 0, got %d, %v", item, ok)
+	}
+	select {
+	case <-done:
+	case <-time.After(unblockTimeout):
+		t.Fatalf("Expected the blocked Put to complete within %v of a Get", unblockTimeout)
+	}
+
+	for want := 1; want <= capacity; want++ {
+		if item, _ := q.Get(); item != want {
+			t.Errorf("Expected to dequeue %d, got %d", want, item)
+		}
+	}
+	if stats := q.Stats(); stats.BlockedPuts != 1 || stats.ProducerBlocked < blockWindow {
+		t.Errorf("Expected 1 blocked put of at least %v, got %d of %v", blockWindow, stats.BlockedPuts, stats.ProducerBlocked)
+	}
+}
+
+func TestBoundedQueueGetBlocksWhenEmpty(t *testing.T) {
+	q := NewQueue[int](2)
+
+	got := make(chan int)
+	go func() {
+		item, _ := q.Get()
+		got <- item
+	}()
+	select {
+	case item := <-got:
+		t.Fatalf("Expected Get on an empty queue to block, got %d", item)
+	case <-time.After(blockWindow):
+	}
+
+	q.Put(42)
+	select {
+	case item := <-got:
+		if item != 42 {
+			t.Errorf("Expected to dequeue 42, got %d", item)
+		}
+	case <-time.After(unblockTimeout):
+		t.Fatalf("Expected the blocked Get to complete within %v of a Put", unblockTimeout)
+	}
+	if stats := q.Stats(); stats.ConsumerStarved < blockWindow {
+		t.Errorf("Expected the consumer to be starved for at least %v, got %v", blockWindow, stats.ConsumerStarved)
+	}
+}
+
+func TestBoundedQueueGetAfterClose(t *testing.T) {
+	q := NewQueue[int](2)
+	q.Put(7)
+	q.Close()
+	if item, ok := q.Get(); !ok || item != 7 {
+		t.Errorf("Expected the queued 7 before the close, got %d, %v", item, ok)
+	}
+	if _, ok := q.Get(); ok {
+		t.Errorf("Expected Get on a closed, drained queue to return false")
+	}
+}
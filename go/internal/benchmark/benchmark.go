@@ -0,0 +1,45 @@
+// Package benchmark runs a task repeatedly and averages its wall time, leaving what a run
+// measures to the task and how runs add up to the caller
+package benchmark
+
+import "time"
+
+// AverageMetrics is the wall time of the task calls of RunN, measured around each call
+type AverageMetrics struct {
+	Runs    int           // Task calls completed
+	Elapsed time.Duration // Wall time summed over the calls
+	Mean    time.Duration // Elapsed over Runs, 0 without runs
+}
+
+// RunN calls task n times in sequence and passes every result to collect before the next call.
+// The result type is the caller's own, such as a struct of timings; collect accumulates it.
+func RunN[T any](n int, task func() T, collect func(T)) AverageMetrics {
+	avg, _ := TryRunN(n, func() (T, error) { return task(), nil }, collect)
+	return avg
+}
+
+// TryRunN is RunN for a task that can fail. The first error stops the runs and is returned
+// unwrapped, with the metrics of the runs collected before it.
+func TryRunN[T any](n int, task func() (T, error), collect func(T)) (AverageMetrics, error) {
+	var avg AverageMetrics
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		result, err := task()
+		elapsed := time.Since(start)
+		if err != nil {
+			return avg.average(), err
+		}
+		avg.Runs++
+		avg.Elapsed += elapsed
+		collect(result)
+	}
+	return avg.average(), nil
+}
+
+// average sets the mean of the runs so far
+func (m AverageMetrics) average() AverageMetrics {
+	if m.Runs > 0 {
+		m.Mean = m.Elapsed / time.Duration(m.Runs)
+	}
+	return m
+}
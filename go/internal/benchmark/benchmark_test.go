@@ -0,0 +1,50 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunN(t *testing.T) {
+	type timing struct{ run int }
+	calls := 0
+	var collected []int
+	avg := RunN(3, func() timing {
+		calls++
+		time.Sleep(time.Millisecond)
+		return timing{run: calls}
+	}, func(r timing) {
+		collected = append(collected, r.run)
+	})
+
+	if len(collected) != 3 || collected[0] != 1 || collected[2] != 3 {
+		t.Errorf("Expected runs [1 2 3] collected in order, got %v", collected)
+	}
+	if avg.Runs != 3 || avg.Elapsed < 3*time.Millisecond || avg.Mean != avg.Elapsed/3 {
+		t.Errorf("Expected 3 runs of at least 1ms each, got %+v", avg)
+	}
+
+	if avg := RunN(0, func() int { return 1 }, func(int) { t.Errorf("Expected no runs to collect") }); avg != (AverageMetrics{}) {
+		t.Errorf("Expected empty metrics without runs, got %+v", avg)
+	}
+}
+
+func TestTryRunNStopsAtFirstError(t *testing.T) {
+	failure := errors.New("kernel failed")
+	calls, collected := 0, 0
+	avg, err := TryRunN(5, func() (int, error) {
+		calls++
+		if calls == 3 {
+			return 0, failure
+		}
+		return calls, nil
+	}, func(int) { collected++ })
+
+	if err != failure {
+		t.Errorf("Expected the task's error, got %v", err)
+	}
+	if calls != 3 || collected != 2 || avg.Runs != 2 {
+		t.Errorf("Expected 3 calls and 2 collected runs, got %d calls, %d collected and %+v", calls, collected, avg)
+	}
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/load"
+)
+
+func init() {
+	Register("loadavg", func() Collector { return &loadAverage{} })
+	Register("goroutines", func() Collector { return &goroutinePeak{interval: time.Millisecond} })
+}
+
+// loadAverage reports the 1-minute system load average at the start and end of a run
+type loadAverage struct {
+	start float64
+}
+
+// Start records the load average before the run
+func (l *loadAverage) Start(ctx context.Context, info RunInfo) error {
+	avg, err := load.Avg()
+	if err != nil {
+		return err
+	}
+	l.start = avg.Load1
+	return nil
+}
+
+// Stop reports the load average before and after the run
+func (l *loadAverage) Stop() (map[string]float64, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]float64{"load1_start": l.start, "load1_end": avg.Load1}, nil
+}
+
+// goroutinePeak samples runtime.NumGoroutine during a run and reports the highest count seen
+type goroutinePeak struct {
+	interval time.Duration
+	mu       sync.Mutex
+	peak     int
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// Start begins sampling the goroutine count in the background
+func (g *goroutinePeak) Start(ctx context.Context, info RunInfo) error {
+	g.peak = runtime.NumGoroutine()
+	g.done = make(chan struct{})
+	g.stopped = make(chan struct{})
+	go g.sample(ctx)
+	return nil
+}
+
+// sample raises the peak until Stop is called or ctx is done
+func (g *goroutinePeak) sample(ctx context.Context) {
+	defer close(g.stopped)
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.observe(runtime.NumGoroutine())
+		}
+	}
+}
+
+// observe raises the peak to n if it is higher
+func (g *goroutinePeak) observe(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n > g.peak {
+		g.peak = n
+	}
+}
+
+// Stop ends sampling and reports the peak goroutine count
+func (g *goroutinePeak) Stop() (map[string]float64, error) {
+	close(g.done)
+	<-g.stopped
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return map[string]float64{"peak": float64(g.peak)}, nil
+}
@@ -0,0 +1,243 @@
+// Package collector gathers extra per-run metrics from pluggable collectors, so site-specific
+// measurements such as off-CPU time or temperatures can be added without changing the benchmarks.
+//
+// Collectors register a factory by name and are selected at run time. A Set brackets every
+// measured run with Start and Stop on each selected collector, isolating failures: a collector
+// that errors, panics or stops too slowly is reported and disabled, and the run carries on.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStopTimeout is how long a Set waits for a collector's Stop before disabling it
+const DefaultStopTimeout = 2 * time.Second
+
+// RunInfo identifies the measured run a collector is started for
+type RunInfo struct {
+	Benchmark string
+	Run       int // 1-based index of the measured run
+}
+
+// Collector measures something over the span of a measured run. Start is called right before
+// the run and Stop right after it; Stop returns the metrics of the run keyed by metric name.
+// ctx is cancelled once Stop has been called, so background sampling can watch it.
+type Collector interface {
+	Start(ctx context.Context, info RunInfo) error
+	Stop() (map[string]float64, error)
+}
+
+// Factory creates a fresh collector for one benchmark session
+type Factory func() Collector
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a collector available under name. It panics if name is empty, contains a
+// dot (used to namespace metrics) or is already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" || strings.Contains(name, ".") {
+		panic(fmt.Sprintf("collector: invalid name %q", name))
+	}
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("collector: %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Names returns the registered collector names in sorted order
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseNames splits a comma-separated list of collector names, dropping blanks
+func ParseNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// entry is a selected collector and its state within a Set
+type entry struct {
+	name      string
+	collector Collector
+	started   bool
+	disabled  bool
+	overhead  time.Duration // Time spent in Start and Stop across all runs
+	runCost   time.Duration // Time spent in Start and Stop for the current run
+}
+
+// Set runs the selected collectors around each measured run
+type Set struct {
+	StopTimeout time.Duration // How long Stop may take before the collector is disabled
+
+	entries []*entry
+	warn    func(format string, args ...interface{})
+	cancel  context.CancelFunc
+}
+
+// New creates a collector from each of the named factories. warn reports collectors that fail
+// and are disabled. An unknown name is an error, so typos are caught before the benchmark starts.
+func New(names []string, warn func(format string, args ...interface{})) (*Set, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	s := &Set{StopTimeout: DefaultStopTimeout, warn: warn}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q (available: %s)", name, strings.Join(sortedKeys(registry), ", "))
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		s.entries = append(s.entries, &entry{name: name, collector: factory()})
+	}
+	return s, nil
+}
+
+// sortedKeys returns the names in registry in sorted order; the caller holds registryMu
+func sortedKeys(registry map[string]Factory) []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start starts every enabled collector for the run described by info
+func (s *Set) Start(ctx context.Context, info RunInfo) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, e := range s.entries {
+		if e.disabled {
+			continue
+		}
+		begin := time.Now()
+		err := safeCall(func() error { return e.collector.Start(ctx, info) })
+		e.runCost = time.Since(begin)
+		e.overhead += e.runCost
+		if err != nil {
+			s.disable(e, "start", err)
+			continue
+		}
+		e.started = true
+	}
+}
+
+// Stop stops every collector started by Start and returns their metrics, each namespaced as
+// "collector.metric". The time a collector spent in Start and Stop for this run is included as
+// "collector.overhead_seconds".
+func (s *Set) Stop() map[string]float64 {
+	if s.cancel != nil {
+		defer s.cancel()
+	}
+	metrics := make(map[string]float64)
+	for _, e := range s.entries {
+		if !e.started {
+			continue
+		}
+		e.started = false
+		begin := time.Now()
+		values, err := s.stopWithTimeout(e)
+		stopCost := time.Since(begin)
+		e.runCost += stopCost
+		e.overhead += stopCost
+		if err != nil {
+			s.disable(e, "stop", err)
+			continue
+		}
+		for metric, value := range values {
+			metrics[e.name+"."+metric] = value
+		}
+		metrics[e.name+".overhead_seconds"] = e.runCost.Seconds()
+	}
+	return metrics
+}
+
+// stopResult carries the outcome of a collector's Stop across goroutines
+type stopResult struct {
+	values map[string]float64
+	err    error
+}
+
+// stopWithTimeout calls Stop on its own goroutine so a collector that hangs cannot stall the
+// benchmark. A collector that misses the deadline is left to finish in the background.
+func (s *Set) stopWithTimeout(e *entry) (map[string]float64, error) {
+	done := make(chan stopResult, 1)
+	go func() {
+		var values map[string]float64
+		err := safeCall(func() error {
+			var err error
+			values, err = e.collector.Stop()
+			return err
+		})
+		done <- stopResult{values, err}
+	}()
+	select {
+	case r := <-done:
+		return r.values, r.err
+	case <-time.After(s.StopTimeout):
+		return nil, fmt.Errorf("did not return within %v", s.StopTimeout)
+	}
+}
+
+// disable turns a failing collector off for the rest of the session
+func (s *Set) disable(e *entry, phase string, err error) {
+	e.disabled = true
+	e.started = false
+	if s.warn != nil {
+		s.warn("WARNING: collector %q failed to %s and is disabled: %v", e.name, phase, err)
+	}
+}
+
+// Overhead returns the total time each collector spent in Start and Stop, by name
+func (s *Set) Overhead() map[string]time.Duration {
+	overhead := make(map[string]time.Duration, len(s.entries))
+	for _, e := range s.entries {
+		overhead[e.name] = e.overhead
+	}
+	return overhead
+}
+
+// Active returns the names of the collectors that have not been disabled, in selection order
+func (s *Set) Active() []string {
+	var names []string
+	for _, e := range s.entries {
+		if !e.disabled {
+			names = append(names, e.name)
+		}
+	}
+	return names
+}
+
+// safeCall runs fn, turning a panic into an error
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
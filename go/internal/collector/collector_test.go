@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fake is a scriptable collector; each field selects a failure mode
+type fake struct {
+	startErr   error
+	stopErr    error
+	panicStart bool
+	panicStop  bool
+	stopDelay  time.Duration
+	starts     int
+	lastRun    RunInfo
+}
+
+func (f *fake) Start(ctx context.Context, info RunInfo) error {
+	f.starts++
+	f.lastRun = info
+	if f.panicStart {
+		panic("start exploded")
+	}
+	return f.startErr
+}
+
+func (f *fake) Stop() (map[string]float64, error) {
+	if f.panicStop {
+		panic("stop exploded")
+	}
+	time.Sleep(f.stopDelay)
+	if f.stopErr != nil {
+		return nil, f.stopErr
+	}
+	return map[string]float64{"value": float64(f.starts)}, nil
+}
+
+// registerFake registers f under a name unique to the test and returns the name
+func registerFake(t *testing.T, f *fake) string {
+	t.Helper()
+	name := strings.NewReplacer("/", "-", ".", "-").Replace(t.Name())
+	Register(name, func() Collector { return f })
+	return name
+}
+
+// warnings collects warn calls
+type warnings []string
+
+func (w *warnings) warn(format string, args ...interface{}) {
+	*w = append(*w, fmt.Sprintf(format, args...))
+}
+
+func runOnce(s *Set, run int) map[string]float64 {
+	s.Start(context.Background(), RunInfo{Benchmark: "test", Run: run})
+	return s.Stop()
+}
+
+func TestSetNamespacesMetrics(t *testing.T) {
+	f := &fake{}
+	name := registerFake(t, f)
+	var w warnings
+	s, err := New([]string{name}, w.warn)
+	if err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+
+	metrics := runOnce(s, 1)
+	metrics = runOnce(s, 2)
+	if metrics[name+".value"] != 2 {
+		t.Errorf("Expected %s.value 2, got %v", name, metrics)
+	}
+	if _, ok := metrics[name+".overhead_seconds"]; !ok {
+		t.Errorf("Expected %s.overhead_seconds in %v", name, metrics)
+	}
+	if f.lastRun.Run != 2 || f.lastRun.Benchmark != "test" {
+		t.Errorf("Expected run info for run 2, got %+v", f.lastRun)
+	}
+	if len(w) != 0 {
+		t.Errorf("Expected no warnings, got %v", w)
+	}
+	if s.Overhead()[name] <= 0 {
+		t.Errorf("Expected a positive overhead for %s", name)
+	}
+}
+
+func TestSetIsolatesFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fake *fake
+	}{
+		{"start error", &fake{startErr: errors.New("no sensor")}},
+		{"stop error", &fake{stopErr: errors.New("read failed")}},
+		{"start panic", &fake{panicStart: true}},
+		{"stop panic", &fake{panicStop: true}},
+		{"slow stop", &fake{stopDelay: time.Second}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			healthy := &fake{}
+			broken := registerFake(t, tc.fake)
+			good := broken + "-healthy"
+			Register(good, func() Collector { return healthy })
+
+			var w warnings
+			s, err := New([]string{broken, good}, w.warn)
+			if err != nil {
+				t.Fatalf("Failed to create set: %v", err)
+			}
+			s.StopTimeout = 50 * time.Millisecond
+
+			for run := 1; run <= 3; run++ {
+				metrics := runOnce(s, run)
+				if metrics[good+".value"] != float64(run) {
+					t.Errorf("Run %d: expected the healthy collector's metric, got %v", run, metrics)
+				}
+				for key := range metrics {
+					if strings.HasPrefix(key, broken+".") {
+						t.Errorf("Run %d: unexpected metric %q from the failing collector", run, key)
+					}
+				}
+			}
+			if len(w) != 1 || !strings.Contains(w[0], broken) {
+				t.Errorf("Expected one warning naming %s, got %v", broken, w)
+			}
+			if tc.fake.starts != 1 {
+				t.Errorf("Expected the failing collector to be started once, got %d", tc.fake.starts)
+			}
+			if active := s.Active(); len(active) != 1 || active[0] != good {
+				t.Errorf("Expected only %s active, got %v", good, active)
+			}
+		})
+	}
+}
+
+func TestNewRejectsUnknownCollector(t *testing.T) {
+	if _, err := New([]string{"no-such-collector"}, nil); err == nil {
+		t.Error("Expected an error for an unknown collector")
+	}
+}
+
+func TestRegisterRejectsInvalidNames(t *testing.T) {
+	for _, name := range []string{"", "with.dot", "loadavg"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register(%q) to panic", name)
+				}
+			}()
+			Register(name, func() Collector { return &fake{} })
+		}()
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	names := ParseNames(" loadavg, ,goroutines,")
+	if len(names) != 2 || names[0] != "loadavg" || names[1] != "goroutines" {
+		t.Errorf("Expected [loadavg goroutines], got %v", names)
+	}
+}
+
+func TestBuiltinCollectors(t *testing.T) {
+	var w warnings
+	s, err := New([]string{"loadavg", "goroutines"}, w.warn)
+	if err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+
+	s.Start(context.Background(), RunInfo{Benchmark: "test", Run: 1})
+	release := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		go func() { <-release }()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	metrics := s.Stop()
+
+	if metrics["goroutines.peak"] < 50 {
+		t.Errorf("Expected a goroutine peak of at least 50, got %v", metrics["goroutines.peak"])
+	}
+	// load.Avg is unsupported on some platforms; that must disable the collector, not fail the run
+	if _, ok := metrics["loadavg.load1_end"]; !ok && len(w) == 0 {
+		t.Errorf("Expected loadavg metrics or a warning, got %v", metrics)
+	}
+}
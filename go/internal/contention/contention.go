@@ -0,0 +1,122 @@
+// Package contention summarizes the runtime's mutex profile: the call sites where goroutines
+// held a lock that others were waiting for, and how long the waiting took
+package contention
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Site is a call site that kept other goroutines waiting on a lock it held. The mutex profile
+// records where a contended lock was released, so Delay is the waiting its holders caused.
+type Site struct {
+	Function string // First function of the stack outside the runtime and sync packages
+	File     string
+	Line     int
+	Count    int64         // Contention events, scaled up from the sampled ones
+	Delay    time.Duration // Time goroutines spent waiting, scaled up from the sampled events
+}
+
+// Enable samples 1 in rate contention events into the mutex profile and returns a function
+// that restores the previous rate
+func Enable(rate int) func() {
+	previous := runtime.SetMutexProfileFraction(rate)
+	return func() { runtime.SetMutexProfileFraction(previous) }
+}
+
+// Sites returns the call sites of the current mutex profile, merged by site and ordered by
+// decreasing delay. rate is the sampling rate the profile was recorded at, which scales the
+// sampled events up to estimates of all of them.
+func Sites(rate int) []Site {
+	var records []runtime.BlockProfileRecord
+	n, _ := runtime.MutexProfile(nil)
+	for {
+		// Room for records added since the size was read
+		records = make([]runtime.BlockProfileRecord, n+16)
+		var ok bool
+		if n, ok = runtime.MutexProfile(records); ok {
+			records = records[:n]
+			break
+		}
+	}
+
+	perSecond := cyclesPerSecond()
+	bySite := map[Site]*Site{}
+	var order []*Site
+	for _, r := range records {
+		key := callSite(r.Stack())
+		site, ok := bySite[key]
+		if !ok {
+			site = &Site{Function: key.Function, File: key.File, Line: key.Line}
+			bySite[key] = site
+			order = append(order, site)
+		}
+		site.Count += r.Count * int64(rate)
+		if perSecond > 0 {
+			site.Delay += time.Duration(float64(r.Cycles) / perSecond * float64(rate) * float64(time.Second))
+		}
+	}
+
+	sites := make([]Site, len(order))
+	for i, site := range order {
+		sites[i] = *site
+	}
+	sort.SliceStable(sites, func(i, j int) bool { return sites[i].Delay > sites[j].Delay })
+	return sites
+}
+
+// TotalDelay returns the waiting of all sites together
+func TotalDelay(sites []Site) time.Duration {
+	var total time.Duration
+	for _, s := range sites {
+		total += s.Delay
+	}
+	return total
+}
+
+// callSite returns the first frame of stack outside the runtime and sync packages, which is
+// where the benchmark itself released the lock, with the counts left empty
+func callSite(stack []uintptr) Site {
+	frames := runtime.CallersFrames(stack)
+	var first Site
+	for {
+		frame, more := frames.Next()
+		site := Site{Function: frame.Function, File: frame.File, Line: frame.Line}
+		if first.Function == "" {
+			first = site
+		}
+		if !strings.HasPrefix(frame.Function, "runtime.") && !strings.HasPrefix(frame.Function, "sync.") &&
+			!strings.HasPrefix(frame.Function, "internal/") {
+			return site
+		}
+		if !more {
+			return first
+		}
+	}
+}
+
+// cyclesPerSecond returns the rate of the CPU tick counter the mutex profile measures delays
+// in, as pprof writes it in the profile's text header, or 0 when it cannot be read
+func cyclesPerSecond() float64 {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("mutex").WriteTo(&buf, 1); err != nil {
+		return 0
+	}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "cycles/second="); ok {
+			perSecond, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0
+			}
+			return perSecond
+		}
+	}
+	return 0
+}
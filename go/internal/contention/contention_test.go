@@ -0,0 +1,60 @@
+package contention
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// holdLock keeps mu for a millisecond at a time while other goroutines wait for it
+func holdLock(mu *sync.Mutex) {
+	mu.Lock()
+	time.Sleep(time.Millisecond)
+	mu.Unlock()
+}
+
+func TestSitesFindsContendedLock(t *testing.T) {
+	restore := Enable(1)
+	defer restore()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				holdLock(&mu)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sites := Sites(1)
+	var found *Site
+	for i, s := range sites {
+		if strings.HasSuffix(s.Function, "contention.holdLock") {
+			found = &sites[i]
+		}
+		if i > 0 && s.Delay > sites[i-1].Delay {
+			t.Errorf("Expected sites ordered by decreasing delay, got %v after %v", s.Delay, sites[i-1].Delay)
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected holdLock among the contended sites, got %+v", sites)
+	}
+	if found.Count == 0 || found.Delay <= 0 || found.Line == 0 {
+		t.Errorf("Expected contention events, a delay and a line for holdLock, got %+v", *found)
+	}
+	if TotalDelay(sites) < found.Delay {
+		t.Errorf("Expected the total delay to cover holdLock's %v, got %v", found.Delay, TotalDelay(sites))
+	}
+}
+
+func TestTotalDelay(t *testing.T) {
+	sites := []Site{{Delay: time.Second}, {Delay: 2 * time.Millisecond}}
+	if got := TotalDelay(sites); got != time.Second+2*time.Millisecond {
+		t.Errorf("Expected 1.002s, got %v", got)
+	}
+}
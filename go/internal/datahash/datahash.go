@@ -0,0 +1,26 @@
+// Package datahash fingerprints a loaded dataset, so runs of the Go and Java benchmarks can be
+// checked to have processed the same pixels
+package datahash
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+)
+
+// SHA256 returns the hex SHA-256 of images, hashed in order as the IEEE 754 bits of every pixel
+// value in little-endian byte order. Any implementation that normalizes the same bytes the same
+// way, and streams them in the same order, gets the same checksum.
+func SHA256(images [][]float32) string {
+	h := sha256.New()
+	var buf []byte
+	for _, image := range images {
+		buf = buf[:0]
+		for _, v := range image {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		}
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
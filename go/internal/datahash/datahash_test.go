@@ -0,0 +1,44 @@
+package datahash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"golang/internal/synthetic"
+)
+
+func TestSHA256(t *testing.T) {
+	// 1.0 is 0x3f800000 and 0.5 is 0x3f000000 in IEEE 754, written little-endian
+	expected := sha256.Sum256([]byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0x3f})
+	if got := SHA256([][]float32{{1.0}, {0.5}}); got != hex.EncodeToString(expected[:]) {
+		t.Errorf("Expected %x, got %s", expected, got)
+	}
+	if got := SHA256([][]float32{{1.0, 0.5}}); got != hex.EncodeToString(expected[:]) {
+		t.Errorf("Expected the image boundaries not to change the checksum, got %s", got)
+	}
+}
+
+func TestSHA256SyntheticData(t *testing.T) {
+	images, _ := synthetic.GenerateSyntheticDataset(100, 8, 8, 3, 1)
+	again, _ := synthetic.GenerateSyntheticDataset(100, 8, 8, 3, 1)
+	other, _ := synthetic.GenerateSyntheticDataset(100, 8, 8, 3, 2)
+
+	checksum := SHA256(images)
+	if len(checksum) != 64 {
+		t.Errorf("Expected 64 hex digits, got %q", checksum)
+	}
+	if SHA256(again) != checksum {
+		t.Errorf("Expected the same images to hash alike")
+	}
+	if SHA256(other) == checksum {
+		t.Errorf("Expected differently seeded images to hash differently")
+	}
+	again[99][191] += 0.25
+	if SHA256(again) == checksum {
+		t.Errorf("Expected a change to one pixel to change the checksum")
+	}
+	if SHA256(images[:50]) == checksum {
+		t.Errorf("Expected a subset to hash differently")
+	}
+}
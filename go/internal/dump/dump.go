@@ -0,0 +1,195 @@
+// Package dump writes processed images together with their labels, so the outputs of the Go and
+// Java implementations can be cross-checked image by image, and compares two such dumps.
+//
+// A dump directory holds three files per split, all in the same image order:
+//   - <split>_images.bin: the pixels of every image as little-endian float32, one image after another
+//   - <split>_labels.bin or <split>_labels.csv: the labels in the binary or CSV format of package labels
+//   - <split>_classes.csv: the class map, written alongside the binary labels with the classmap format
+package dump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang/internal/labels"
+)
+
+// imagesSuffix ends the name of the pixel file of every split in a dump directory
+const imagesSuffix = "_images.bin"
+
+// Write dumps the images of split with their labels to dir, creating dir if needed. Labels must
+// describe the images in the order they are given, so a shuffled or subsetted dataset dumps in
+// the order it was processed.
+func Write(dir, split string, images [][]float32, l labels.Labels, format labels.Format) error {
+	if len(l.Indices) != len(images) {
+		return fmt.Errorf("dump of %s has %d images but %d labels", split, len(images), len(l.Indices))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create dump directory %s: %v", dir, err)
+	}
+	if err := writeFile(filepath.Join(dir, split+imagesSuffix), func(w io.Writer) error {
+		for _, image := range images {
+			if err := binary.Write(w, binary.LittleEndian, image); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	switch format {
+	case labels.FormatCSV:
+		return writeLabels(filepath.Join(dir, split+"_labels.csv"), l, labels.FormatCSV)
+	case labels.FormatClassMap:
+		if err := writeLabels(filepath.Join(dir, split+"_classes.csv"), l, labels.FormatClassMap); err != nil {
+			return err
+		}
+	}
+	return writeLabels(filepath.Join(dir, split+"_labels.bin"), l, labels.FormatBinary)
+}
+
+// writeLabels encodes l in format to path
+func writeLabels(path string, l labels.Labels, format labels.Format) error {
+	return writeFile(path, func(w io.Writer) error {
+		return l.Write(w, format)
+	})
+}
+
+// writeFile creates path and fills it through a buffered writer with write
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	buffered := bufio.NewWriter(file)
+	if err := write(buffered); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// Splits returns the names of the splits dumped to dir, in sorted order
+func Splits(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+imagesSuffix))
+	if err != nil {
+		return nil, err
+	}
+	splits := make([]string, 0, len(paths))
+	for _, path := range paths {
+		splits = append(splits, strings.TrimSuffix(filepath.Base(path), imagesSuffix))
+	}
+	sort.Strings(splits)
+	return splits, nil
+}
+
+// ReadLabels reads the class indices dumped for split, from whichever of the binary and CSV
+// label files dir holds
+func ReadLabels(dir, split string) ([]int32, error) {
+	binaryPath := filepath.Join(dir, split+"_labels.bin")
+	if file, err := os.Open(binaryPath); err == nil {
+		defer file.Close()
+		return labels.ReadBinary(file)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open %s: %v", binaryPath, err)
+	}
+
+	csvPath := filepath.Join(dir, split+"_labels.csv")
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("no labels dumped for %s in %s: %v", split, dir, err)
+	}
+	defer file.Close()
+	_, indices, err := labels.ReadCSV(file)
+	return indices, err
+}
+
+// ReadPixels reads the pixel values dumped for split
+func ReadPixels(dir, split string) ([]float32, error) {
+	path := filepath.Join(dir, split+imagesSuffix)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("%s has %d bytes, not a multiple of 4", path, len(data))
+	}
+	pixels := make([]float32, len(data)/4)
+	for i := range pixels {
+		pixels[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return pixels, nil
+}
+
+// Diff is the outcome of comparing the dumps of one split. Label mismatches are reported apart
+// from pixel differences, since a label mismatch means the images are not aligned and their
+// pixel differences say nothing about the kernels.
+type Diff struct {
+	Split           string
+	Images          int
+	LabelMismatches []labels.Mismatch
+	Pixels          int     // Number of pixel values compared, 0 when the labels disagree
+	PixelMismatches int     // Number of pixel values differing by more than the tolerance
+	MaxAbsDiff      float64 // Largest absolute difference between two pixel values
+}
+
+// Compare checks that the dumps of split in expectedDir and actualDir agree: first that their
+// labels match image by image, then, only when they do, that no pixel value differs by more than
+// tolerance
+func Compare(expectedDir, actualDir, split string, tolerance float64) (Diff, error) {
+	diff := Diff{Split: split}
+	expectedLabels, err := ReadLabels(expectedDir, split)
+	if err != nil {
+		return diff, err
+	}
+	actualLabels, err := ReadLabels(actualDir, split)
+	if err != nil {
+		return diff, err
+	}
+	diff.Images = len(expectedLabels)
+	diff.LabelMismatches, err = labels.Compare(expectedLabels, actualLabels)
+	if err != nil {
+		return diff, fmt.Errorf("%s: %w", split, err)
+	}
+	if len(diff.LabelMismatches) > 0 {
+		return diff, nil
+	}
+
+	expectedPixels, err := ReadPixels(expectedDir, split)
+	if err != nil {
+		return diff, err
+	}
+	actualPixels, err := ReadPixels(actualDir, split)
+	if err != nil {
+		return diff, err
+	}
+	if len(expectedPixels) != len(actualPixels) {
+		return diff, fmt.Errorf("%s: pixel count mismatch: expected %d, got %d", split, len(expectedPixels), len(actualPixels))
+	}
+	diff.Pixels = len(expectedPixels)
+	for i := range expectedPixels {
+		d := math.Abs(float64(expectedPixels[i]) - float64(actualPixels[i]))
+		if d > diff.MaxAbsDiff {
+			diff.MaxAbsDiff = d
+		}
+		if d > tolerance {
+			diff.PixelMismatches++
+		}
+	}
+	return diff, nil
+}
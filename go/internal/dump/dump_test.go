@@ -0,0 +1,127 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang/internal/labels"
+)
+
+var classNames = []string{"cat", "dog", "frog"}
+
+func sampleImages() ([][]float32, []int) {
+	images := [][]float32{{0.1, 0.2}, {0.3, 0.4}, {0.5, 0.6}, {0.7, 0.8}}
+	return images, []int{2, 0, 1, 2}
+}
+
+func TestWriteReadEachFormat(t *testing.T) {
+	images, classes := sampleImages()
+	l := labels.FromIndices(classes, classNames)
+	for _, format := range []labels.Format{labels.FormatBinary, labels.FormatCSV, labels.FormatClassMap} {
+		dir := t.TempDir()
+		if err := Write(dir, "train", images, l, format); err != nil {
+			t.Fatalf("Failed to write %s dump: %v", format, err)
+		}
+
+		indices, err := ReadLabels(dir, "train")
+		if err != nil {
+			t.Fatalf("Failed to read %s labels: %v", format, err)
+		}
+		if mismatches, err := labels.Compare(l.Indices, indices); err != nil || len(mismatches) != 0 {
+			t.Errorf("%s labels did not round-trip: %v %v", format, mismatches, err)
+		}
+		pixels, err := ReadPixels(dir, "train")
+		if err != nil {
+			t.Fatalf("Failed to read %s pixels: %v", format, err)
+		}
+		if len(pixels) != 8 || pixels[2] != 0.3 || pixels[7] != 0.8 {
+			t.Errorf("%s pixels did not round-trip: %v", format, pixels)
+		}
+
+		_, err = os.Stat(filepath.Join(dir, "train_classes.csv"))
+		if format == labels.FormatClassMap && err != nil {
+			t.Errorf("Expected a class map with the classmap format: %v", err)
+		}
+		if format != labels.FormatClassMap && err == nil {
+			t.Errorf("Expected no class map with the %s format", format)
+		}
+	}
+}
+
+func TestWriteLabelCountMismatch(t *testing.T) {
+	images, _ := sampleImages()
+	if err := Write(t.TempDir(), "train", images, labels.FromIndices([]int{0}, classNames), labels.FormatBinary); err == nil {
+		t.Errorf("Expected an error for fewer labels than images")
+	}
+}
+
+func TestCompareAfterSeededShuffle(t *testing.T) {
+	images, classes := sampleImages()
+	l := labels.FromIndices(classes, classNames)
+	order := labels.ShuffleOrder(len(images), 7)
+	shuffled := make([][]float32, len(images))
+	for i, src := range order {
+		shuffled[i] = images[src]
+	}
+
+	// Two sides shuffling with the same seed dump identical files
+	goDir, javaDir := t.TempDir(), t.TempDir()
+	if err := Write(goDir, "train", shuffled, l.Permute(order), labels.FormatBinary); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	if err := Write(javaDir, "train", shuffled, l.Permute(order), labels.FormatCSV); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	diff, err := Compare(goDir, javaDir, "train", 0)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(diff.LabelMismatches) != 0 || diff.PixelMismatches != 0 || diff.Pixels != 8 {
+		t.Errorf("Expected identical dumps, got %+v", diff)
+	}
+
+	// Labels left in load order no longer line up with the shuffled images
+	if err := Write(javaDir, "train", shuffled, l, labels.FormatBinary); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	diff, err = Compare(goDir, javaDir, "train", 0)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(diff.LabelMismatches) == 0 {
+		t.Errorf("Expected label mismatches for unshuffled labels")
+	}
+	if diff.Pixels != 0 {
+		t.Errorf("Expected pixels to be left uncompared after a label mismatch, got %d compared", diff.Pixels)
+	}
+}
+
+func TestComparePixelTolerance(t *testing.T) {
+	images, classes := sampleImages()
+	l := labels.FromIndices(classes, classNames)
+	goDir, javaDir := t.TempDir(), t.TempDir()
+	if err := Write(goDir, "test", images, l, labels.FormatBinary); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	changed := [][]float32{{0.1, 0.2}, {0.3, 0.41}, {0.5, 0.6}, {0.7, 0.8}}
+	if err := Write(javaDir, "test", changed, l, labels.FormatBinary); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+
+	diff, err := Compare(goDir, javaDir, "test", 1e-3)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if diff.PixelMismatches != 1 || diff.MaxAbsDiff < 0.009 {
+		t.Errorf("Expected one pixel off by 0.01, got %+v", diff)
+	}
+	if diff, _ := Compare(goDir, javaDir, "test", 0.1); diff.PixelMismatches != 0 {
+		t.Errorf("Expected no mismatches within a 0.1 tolerance, got %d", diff.PixelMismatches)
+	}
+
+	splits, err := Splits(goDir)
+	if err != nil || len(splits) != 1 || splits[0] != "test" {
+		t.Errorf("Expected the test split, got %v %v", splits, err)
+	}
+}
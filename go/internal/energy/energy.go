@@ -0,0 +1,254 @@
+// Package energy reads Intel RAPL energy counters through the Linux powercap interface
+package energy
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRoot is where Linux exposes the powercap zones
+const DefaultRoot = "/sys/class/powercap"
+
+// ErrUnavailable is returned when the host exposes no RAPL package or DRAM zones
+var ErrUnavailable = errors.New("no RAPL energy counters found")
+
+// Domain is one RAPL zone that is counted: a CPU package or the DRAM attached to it.
+// Core and uncore zones are skipped because the package zone already includes them.
+type Domain struct {
+	Name     string // "package-0", "dram", ...
+	Dir      string // Zone directory relative to the powercap root
+	MaxRange uint64 // Value at which energy_uj wraps back to 0, in microjoules
+}
+
+// IsDRAM reports whether the domain measures memory rather than a CPU package
+func (d Domain) IsDRAM() bool {
+	return d.Name == "dram"
+}
+
+// Discover finds the package zones and their DRAM subzones under fsys, the powercap root
+func Discover(fsys fs.FS) ([]Domain, error) {
+	packages, err := fs.Glob(fsys, "intel-rapl:*")
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []Domain
+	for _, dir := range packages {
+		// Subzones are named intel-rapl:<package>:<n> and live inside their package zone
+		if strings.Count(dir, ":") != 1 {
+			continue
+		}
+		zones, err := fs.Glob(fsys, dir+"/intel-rapl:*")
+		if err != nil {
+			return nil, err
+		}
+		for _, zone := range append([]string{dir}, zones...) {
+			domain, ok, err := readDomain(fsys, zone)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	if len(domains) == 0 {
+		return nil, ErrUnavailable
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Dir < domains[j].Dir })
+	return domains, nil
+}
+
+// readDomain reads the name and wrap range of a zone, reporting whether it should be counted
+func readDomain(fsys fs.FS, dir string) (Domain, bool, error) {
+	name, err := fs.ReadFile(fsys, path.Join(dir, "name"))
+	if err != nil {
+		return Domain{}, false, fmt.Errorf("failed to read RAPL zone name in %s: %w", dir, err)
+	}
+	domain := Domain{Name: strings.TrimSpace(string(name)), Dir: dir}
+	if !strings.HasPrefix(domain.Name, "package") && !domain.IsDRAM() {
+		return Domain{}, false, nil
+	}
+
+	maxRange, err := readCounter(fsys, path.Join(dir, "max_energy_range_uj"))
+	if err != nil {
+		return Domain{}, false, err
+	}
+	domain.MaxRange = maxRange
+	return domain, true, nil
+}
+
+// readCounter parses a single unsigned integer file. Permission errors are wrapped so callers
+// can tell them apart with errors.Is(err, fs.ErrPermission).
+func readCounter(fsys fs.FS, name string) (uint64, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid counter in %s: %v", name, err)
+	}
+	return value, nil
+}
+
+// delta returns the microjoules consumed between two counter readings, allowing for one wrap
+func delta(before, after, maxRange uint64) uint64 {
+	if after >= before {
+		return after - before
+	}
+	return maxRange - before + after
+}
+
+// DomainEnergy is the energy one domain consumed during a measurement
+type DomainEnergy struct {
+	Name   string
+	Joules float64
+}
+
+// Measurement is the energy consumed between Meter.Start and Meter.Stop
+type Measurement struct {
+	Duration time.Duration
+	Domains  []DomainEnergy
+}
+
+// Available reports whether the measurement holds any readings
+func (m Measurement) Available() bool {
+	return len(m.Domains) > 0
+}
+
+// PackageJoules returns the energy of all CPU packages
+func (m Measurement) PackageJoules() float64 {
+	var total float64
+	for _, d := range m.Domains {
+		if d.Name != "dram" {
+			total += d.Joules
+		}
+	}
+	return total
+}
+
+// DRAMJoules returns the energy of all DRAM domains
+func (m Measurement) DRAMJoules() float64 {
+	var total float64
+	for _, d := range m.Domains {
+		if d.Name == "dram" {
+			total += d.Joules
+		}
+	}
+	return total
+}
+
+// Joules returns the energy of the packages and DRAM together
+func (m Measurement) Joules() float64 {
+	return m.PackageJoules() + m.DRAMJoules()
+}
+
+// Watts returns the average power over the measurement
+func (m Measurement) Watts() float64 {
+	if m.Duration <= 0 {
+		return 0
+	}
+	return m.Joules() / m.Duration.Seconds()
+}
+
+// Meter samples the RAPL counters at the start and end of a run
+type Meter struct {
+	fsys    fs.FS
+	domains []Domain
+	start   []uint64
+	started time.Time
+}
+
+// NewMeter discovers the RAPL domains under root and checks that their counters can be read.
+// On hosts without RAPL it returns ErrUnavailable; when energy_uj is restricted to root it
+// returns an error matching fs.ErrPermission.
+func NewMeter(root string) (*Meter, error) {
+	return newMeter(os.DirFS(root))
+}
+
+// newMeter builds a meter over any powercap tree, so fixtures can stand in for /sys
+func newMeter(fsys fs.FS) (*Meter, error) {
+	domains, err := Discover(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m := &Meter{fsys: fsys, domains: domains}
+	if _, err := m.read(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// read samples every domain's counter
+func (m *Meter) read() ([]uint64, error) {
+	values := make([]uint64, len(m.domains))
+	for i, d := range m.domains {
+		value, err := readCounter(m.fsys, path.Join(d.Dir, "energy_uj"))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// Start records the counters at the beginning of a run
+func (m *Meter) Start() error {
+	values, err := m.read()
+	if err != nil {
+		return err
+	}
+	m.start, m.started = values, time.Now()
+	return nil
+}
+
+// Stop reads the counters again and returns the energy consumed since Start
+func (m *Meter) Stop() (Measurement, error) {
+	if m.start == nil {
+		return Measurement{}, fmt.Errorf("energy meter stopped before it was started")
+	}
+	values, err := m.read()
+	if err != nil {
+		return Measurement{}, err
+	}
+	measurement := Measurement{Duration: time.Since(m.started)}
+	for i, d := range m.domains {
+		microjoules := delta(m.start[i], values[i], d.MaxRange)
+		measurement.Domains = append(measurement.Domains, DomainEnergy{Name: d.Name, Joules: float64(microjoules) / 1e6})
+	}
+	m.start = nil
+	return measurement, nil
+}
+
+// Add returns the sum of two measurements taken with the same meter
+func (m Measurement) Add(other Measurement) Measurement {
+	sum := Measurement{Duration: m.Duration + other.Duration}
+	if len(m.Domains) == 0 {
+		sum.Domains = append(sum.Domains, other.Domains...)
+		return sum
+	}
+	sum.Domains = append(sum.Domains, m.Domains...)
+	for i := range sum.Domains {
+		if i < len(other.Domains) {
+			sum.Domains[i].Joules += other.Domains[i].Joules
+		}
+	}
+	return sum
+}
+
+// Domains returns the names of the domains the meter reads, for logging
+func (m *Meter) Domains() []string {
+	names := make([]string, len(m.domains))
+	for i, d := range m.domains {
+		names[i] = d.Name
+	}
+	return names
+}
@@ -0,0 +1,157 @@
+package energy
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// zone adds a powercap zone with the given name and counters to tree
+func zone(tree fstest.MapFS, dir, name, energy, maxRange string) {
+	tree[dir+"/name"] = &fstest.MapFile{Data: []byte(name + "\n")}
+	tree[dir+"/energy_uj"] = &fstest.MapFile{Data: []byte(energy + "\n")}
+	tree[dir+"/max_energy_range_uj"] = &fstest.MapFile{Data: []byte(maxRange + "\n")}
+}
+
+// twoPackageTree is a dual-socket host with core and DRAM subzones and a psys zone
+func twoPackageTree() fstest.MapFS {
+	tree := fstest.MapFS{}
+	zone(tree, "intel-rapl:0", "package-0", "1000000", "262143328850")
+	zone(tree, "intel-rapl:0/intel-rapl:0:0", "core", "500000", "262143328850")
+	zone(tree, "intel-rapl:0/intel-rapl:0:1", "dram", "200000", "65712999613")
+	zone(tree, "intel-rapl:1", "package-1", "3000000", "262143328850")
+	zone(tree, "intel-rapl:1/intel-rapl:1:0", "dram", "400000", "65712999613")
+	zone(tree, "intel-rapl:2", "psys", "9000000", "262143328850")
+	return tree
+}
+
+// setEnergy overwrites the energy counter of a zone
+func setEnergy(tree fstest.MapFS, dir, energy string) {
+	tree[dir+"/energy_uj"] = &fstest.MapFile{Data: []byte(energy + "\n")}
+}
+
+func TestDiscover(t *testing.T) {
+	domains, err := Discover(twoPackageTree())
+	if err != nil {
+		t.Fatalf("Failed to discover domains: %v", err)
+	}
+
+	var names []string
+	for _, d := range domains {
+		names = append(names, d.Name)
+	}
+	if got := strings.Join(names, ","); got != "package-0,dram,package-1,dram" {
+		t.Errorf("Expected packages and DRAM only, got %s", got)
+	}
+	if domains[1].MaxRange != 65712999613 {
+		t.Errorf("Expected the DRAM wrap range to be read, got %d", domains[1].MaxRange)
+	}
+}
+
+func TestDiscoverUnavailable(t *testing.T) {
+	if _, err := Discover(fstest.MapFS{}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable for an empty powercap tree, got %v", err)
+	}
+}
+
+func TestMeterMultiPackage(t *testing.T) {
+	tree := twoPackageTree()
+	meter, err := newMeter(tree)
+	if err != nil {
+		t.Fatalf("Failed to create meter: %v", err)
+	}
+	if err := meter.Start(); err != nil {
+		t.Fatalf("Failed to start meter: %v", err)
+	}
+
+	setEnergy(tree, "intel-rapl:0", "3000000")               // +2 J
+	setEnergy(tree, "intel-rapl:0/intel-rapl:0:1", "700000") // +0.5 J
+	setEnergy(tree, "intel-rapl:1", "4000000")               // +1 J
+	setEnergy(tree, "intel-rapl:1/intel-rapl:1:0", "650000") // +0.25 J
+	setEnergy(tree, "intel-rapl:2", "99000000")              // psys is not counted
+
+	m, err := meter.Stop()
+	if err != nil {
+		t.Fatalf("Failed to stop meter: %v", err)
+	}
+	if !almostEqual(m.PackageJoules(), 3) || !almostEqual(m.DRAMJoules(), 0.75) || !almostEqual(m.Joules(), 3.75) {
+		t.Errorf("Expected 3 J package and 0.75 J DRAM, got %v and %v", m.PackageJoules(), m.DRAMJoules())
+	}
+}
+
+func TestMeterWraparound(t *testing.T) {
+	tree := fstest.MapFS{}
+	zone(tree, "intel-rapl:0", "package-0", "999000", "1000000")
+	meter, err := newMeter(tree)
+	if err != nil {
+		t.Fatalf("Failed to create meter: %v", err)
+	}
+	if err := meter.Start(); err != nil {
+		t.Fatalf("Failed to start meter: %v", err)
+	}
+
+	// The counter passed its 1 J range and wrapped: 0.001 J before the wrap and 0.002 J after
+	setEnergy(tree, "intel-rapl:0", "2000")
+	m, err := meter.Stop()
+	if err != nil {
+		t.Fatalf("Failed to stop meter: %v", err)
+	}
+	if !almostEqual(m.Joules(), 0.003) {
+		t.Errorf("Expected 0.003 J across the wrap, got %v", m.Joules())
+	}
+}
+
+// deniedFS refuses to open energy_uj, like /sys on kernels that restrict RAPL to root
+type deniedFS struct {
+	fstest.MapFS
+}
+
+func (d deniedFS) Open(name string) (fs.File, error) {
+	if strings.HasSuffix(name, "/energy_uj") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return d.MapFS.Open(name)
+}
+
+func (d deniedFS) ReadFile(name string) ([]byte, error) {
+	if strings.HasSuffix(name, "/energy_uj") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return d.MapFS.ReadFile(name)
+}
+
+func TestMeterPermissionDenied(t *testing.T) {
+	_, err := newMeter(deniedFS{twoPackageTree()})
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+}
+
+func TestMeasurementWatts(t *testing.T) {
+	m := Measurement{Duration: 2e9, Domains: []DomainEnergy{{Name: "package-0", Joules: 10}}}
+	if m.Watts() != 5 {
+		t.Errorf("Expected 5 W, got %v", m.Watts())
+	}
+	if (Measurement{}).Available() {
+		t.Errorf("Expected an empty measurement to be unavailable")
+	}
+}
+
+// almostEqual compares joule values that went through float conversion
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMeasurementAdd(t *testing.T) {
+	a := Measurement{Duration: 1e9, Domains: []DomainEnergy{{"package-0", 1}, {"dram", 0.5}}}
+	sum := Measurement{}.Add(a).Add(a)
+	if sum.Duration != 2e9 || sum.PackageJoules() != 2 || sum.DRAMJoules() != 1 {
+		t.Errorf("Unexpected sum: %+v", sum)
+	}
+	if a.Domains[0].Joules != 1 {
+		t.Errorf("Add modified its receiver's domains: %+v", a)
+	}
+}
@@ -0,0 +1,133 @@
+package gcaccount
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Sample records how much GC work the measured runs did for one dataset size
+type Sample struct {
+	Limit          int // Value of -limit for the invocation, 0 for the full dataset
+	RetainedBytes  int64
+	GCCyclesPerRun float64
+	GCPausePerRun  time.Duration
+}
+
+// sampleHeader is the header row of the samples CSV
+var sampleHeader = []string{"limit", "retained_bytes", "gc_cycles_per_run", "gc_pause_ns_per_run"}
+
+// AppendSample adds a sample to the CSV file at path, writing the header if the file is new
+func AppendSample(path string, sample Sample) error {
+	info, statErr := os.Stat(path)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open GC samples file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if statErr != nil || info.Size() == 0 {
+		writer.Write(sampleHeader)
+	}
+	writer.Write([]string{
+		strconv.Itoa(sample.Limit),
+		strconv.FormatInt(sample.RetainedBytes, 10),
+		strconv.FormatFloat(sample.GCCyclesPerRun, 'f', -1, 64),
+		strconv.FormatInt(int64(sample.GCPausePerRun), 10),
+	})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write GC sample: %v", err)
+	}
+	return nil
+}
+
+// ReadSamples parses a samples CSV written by AppendSample
+func ReadSamples(r io.Reader) ([]Sample, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GC samples: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	samples := make([]Sample, 0, len(records)-1)
+	for row, record := range records[1:] {
+		if len(record) != len(sampleHeader) {
+			return nil, fmt.Errorf("GC sample row %d has %d columns, expected %d", row, len(record), len(sampleHeader))
+		}
+		limit, err1 := strconv.Atoi(record[0])
+		retained, err2 := strconv.ParseInt(record[1], 10, 64)
+		cycles, err3 := strconv.ParseFloat(record[2], 64)
+		pause, err4 := strconv.ParseInt(record[3], 10, 64)
+		for _, err := range []error{err1, err2, err3, err4} {
+			if err != nil {
+				return nil, fmt.Errorf("invalid GC sample row %d: %v", row, err)
+			}
+		}
+		samples = append(samples, Sample{Limit: limit, RetainedBytes: retained, GCCyclesPerRun: cycles, GCPausePerRun: time.Duration(pause)})
+	}
+	return samples, nil
+}
+
+// pearson returns the Pearson correlation coefficient of xs and ys
+func pearson(xs, ys []float64) (float64, error) {
+	if len(xs) < 2 {
+		return 0, fmt.Errorf("need at least 2 samples, got %d", len(xs))
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, fmt.Errorf("samples have no variance")
+	}
+	return cov / math.Sqrt(varX*varY), nil
+}
+
+// CorrelationReport relates retained dataset size to GC pause time and cycle count across samples
+func CorrelationReport(samples []Sample) []string {
+	lines := []string{fmt.Sprintf("GC Correlation Samples: %d", len(samples))}
+	for _, s := range samples {
+		lines = append(lines, fmt.Sprintf("  limit %d: %.2f MB retained, %.2f GC cycles/run, %.3f ms GC pause/run",
+			s.Limit, megabytes(s.RetainedBytes), s.GCCyclesPerRun, s.GCPausePerRun.Seconds()*1000))
+	}
+
+	retained := make([]float64, len(samples))
+	pauses := make([]float64, len(samples))
+	cycles := make([]float64, len(samples))
+	for i, s := range samples {
+		retained[i] = float64(s.RetainedBytes)
+		pauses[i] = float64(s.GCPausePerRun)
+		cycles[i] = s.GCCyclesPerRun
+	}
+
+	if r, err := pearson(retained, pauses); err != nil {
+		lines = append(lines, fmt.Sprintf("Retained Size vs GC Pause: unavailable (%v)", err))
+	} else {
+		lines = append(lines, fmt.Sprintf("Retained Size vs GC Pause: r = %.3f", r))
+	}
+	if r, err := pearson(retained, cycles); err != nil {
+		lines = append(lines, fmt.Sprintf("Retained Size vs GC Cycles: unavailable (%v)", err))
+	} else {
+		lines = append(lines, fmt.Sprintf("Retained Size vs GC Cycles: r = %.3f", r))
+	}
+	return lines
+}
@@ -0,0 +1,72 @@
+package gcaccount
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndReadSamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gc.csv")
+	samples := []Sample{
+		{Limit: 1000, RetainedBytes: 1 << 20, GCCyclesPerRun: 0.5, GCPausePerRun: 100 * time.Microsecond},
+		{Limit: 0, RetainedBytes: 4 << 20, GCCyclesPerRun: 2, GCPausePerRun: 400 * time.Microsecond},
+	}
+	for _, s := range samples {
+		if err := AppendSample(path, s); err != nil {
+			t.Fatalf("Failed to append sample: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open samples: %v", err)
+	}
+	defer file.Close()
+	got, err := ReadSamples(file)
+	if err != nil {
+		t.Fatalf("Failed to read samples: %v", err)
+	}
+	if len(got) != len(samples) {
+		t.Fatalf("Expected %d samples, got %d", len(samples), len(got))
+	}
+	for i := range samples {
+		if got[i] != samples[i] {
+			t.Errorf("Sample %d mismatch: expected %+v, got %+v", i, samples[i], got[i])
+		}
+	}
+}
+
+func TestPearson(t *testing.T) {
+	r, err := pearson([]float64{1, 2, 3, 4}, []float64{2, 4, 6, 8})
+	if err != nil || math.Abs(r-1) > 1e-9 {
+		t.Errorf("Expected r = 1, got %v (%v)", r, err)
+	}
+	r, err = pearson([]float64{1, 2, 3, 4}, []float64{8, 6, 4, 2})
+	if err != nil || math.Abs(r+1) > 1e-9 {
+		t.Errorf("Expected r = -1, got %v (%v)", r, err)
+	}
+	if _, err := pearson([]float64{1}, []float64{1}); err == nil {
+		t.Errorf("Expected an error for a single sample")
+	}
+	if _, err := pearson([]float64{1, 1}, []float64{1, 2}); err == nil {
+		t.Errorf("Expected an error for samples without variance")
+	}
+}
+
+func TestCorrelationReport(t *testing.T) {
+	samples := []Sample{
+		{Limit: 1000, RetainedBytes: 1 << 20, GCCyclesPerRun: 1, GCPausePerRun: 100 * time.Microsecond},
+		{Limit: 2000, RetainedBytes: 2 << 20, GCCyclesPerRun: 1, GCPausePerRun: 200 * time.Microsecond},
+		{Limit: 4000, RetainedBytes: 4 << 20, GCCyclesPerRun: 1, GCPausePerRun: 400 * time.Microsecond},
+	}
+	report := strings.Join(CorrelationReport(samples), "\n")
+	for _, want := range []string{"GC Correlation Samples: 3", "Retained Size vs GC Pause: r = 1.000", "Retained Size vs GC Cycles: unavailable"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Package gcaccount separates the heap retained by a loaded dataset from the allocations
+// made by the measured workload, so GC work can be attributed to one or the other.
+package gcaccount
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// profileHeader matches the first line of the profile, which carries twice the sampling rate
+var profileHeader = regexp.MustCompile(`^heap profile: .*@ heap/(\d+)`)
+
+// recordHeader matches "inuse_objects: inuse_bytes [alloc_objects: alloc_bytes] @ pcs"
+var recordHeader = regexp.MustCompile(`^(\d+): (\d+) \[(\d+): (\d+)\] @`)
+
+// Site is the heap usage attributed to one allocation site
+type Site struct {
+	Name         string // Allocating function and source location
+	InUseObjects int64
+	InUseBytes   int64
+	AllocObjects int64
+	AllocBytes   int64
+}
+
+// Profile is a heap profile aggregated by allocation site
+type Profile struct {
+	Sites map[string]*Site
+}
+
+// CaptureHeapProfile forces a full GC so only retained objects count as in use,
+// then reads the runtime heap profile
+func CaptureHeapProfile() (Profile, error) {
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 1); err != nil {
+		return Profile{}, fmt.Errorf("failed to write heap profile: %v", err)
+	}
+	return ParseHeapProfile(&buf)
+}
+
+// ParseHeapProfile parses the text heap profile written by pprof with debug=1. Each record
+// is attributed to its first non-runtime frame, which is where the program asked for memory.
+// Sampled counts are scaled back up to estimates of the real totals, the same way pprof does.
+func ParseHeapProfile(r io.Reader) (Profile, error) {
+	profile := Profile{Sites: make(map[string]*Site)}
+	var samplingRate float64
+	var current *Site
+	siteNamed := false
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if !siteNamed {
+			current.Name = "unknown"
+		}
+		site, ok := profile.Sites[current.Name]
+		if !ok {
+			site = &Site{Name: current.Name}
+			profile.Sites[current.Name] = site
+		}
+		site.InUseObjects += current.InUseObjects
+		site.InUseBytes += current.InUseBytes
+		site.AllocObjects += current.AllocObjects
+		site.AllocBytes += current.AllocBytes
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case profileHeader.MatchString(line):
+			rate, err := strconv.ParseFloat(profileHeader.FindStringSubmatch(line)[1], 64)
+			if err != nil {
+				return Profile{}, fmt.Errorf("invalid heap profile header %q: %v", line, err)
+			}
+			samplingRate = rate / 2
+		case strings.HasPrefix(line, "# runtime.MemStats"):
+			flush()
+			return profile, nil
+		case recordHeader.MatchString(line):
+			flush()
+			values := recordHeader.FindStringSubmatch(line)
+			parsed := make([]int64, 4)
+			for i := range parsed {
+				n, err := strconv.ParseInt(values[i+1], 10, 64)
+				if err != nil {
+					return Profile{}, fmt.Errorf("invalid heap profile record %q: %v", line, err)
+				}
+				parsed[i] = n
+			}
+			inUseObjects, inUseBytes := scaleSample(parsed[0], parsed[1], samplingRate)
+			allocObjects, allocBytes := scaleSample(parsed[2], parsed[3], samplingRate)
+			current = &Site{InUseObjects: inUseObjects, InUseBytes: inUseBytes, AllocObjects: allocObjects, AllocBytes: allocBytes}
+			siteNamed = false
+		case current != nil && !siteNamed && strings.HasPrefix(line, "#"):
+			// Frame lines look like "#\t0xpc\tpkg.func+0xoff\tfile:line"
+			fields := strings.Fields(strings.TrimPrefix(line, "#"))
+			if len(fields) < 3 {
+				continue
+			}
+			function := fields[1]
+			if i := strings.LastIndex(function, "+0x"); i >= 0 {
+				function = function[:i]
+			}
+			if strings.HasPrefix(function, "runtime.") {
+				continue
+			}
+			current.Name = fmt.Sprintf("%s (%s)", function, filepath.Base(fields[2]))
+			siteNamed = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Profile{}, fmt.Errorf("failed to read heap profile: %v", err)
+	}
+	flush()
+	return profile, nil
+}
+
+// scaleSample estimates the real counts behind a sampled record, given the average
+// number of bytes between samples. Objects larger than the rate are almost always sampled.
+func scaleSample(objects, bytes int64, rate float64) (int64, int64) {
+	if objects == 0 || bytes == 0 || rate <= 1 {
+		return objects, bytes
+	}
+	avgSize := float64(bytes) / float64(objects)
+	scale := 1 / (1 - math.Exp(-avgSize/rate))
+	return int64(float64(objects) * scale), int64(float64(bytes) * scale)
+}
+
+// InUseBytes returns the retained bytes across all sites
+func (p Profile) InUseBytes() int64 {
+	var total int64
+	for _, site := range p.Sites {
+		total += site.InUseBytes
+	}
+	return total
+}
+
+// AllocBytes returns the cumulative allocated bytes across all sites
+func (p Profile) AllocBytes() int64 {
+	var total int64
+	for _, site := range p.Sites {
+		total += site.AllocBytes
+	}
+	return total
+}
+
+// Top returns the n sites retaining the most bytes
+func (p Profile) Top(n int) []Site {
+	sites := make([]Site, 0, len(p.Sites))
+	for _, site := range p.Sites {
+		if site.InUseBytes > 0 {
+			sites = append(sites, *site)
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].InUseBytes != sites[j].InUseBytes {
+			return sites[i].InUseBytes > sites[j].InUseBytes
+		}
+		return sites[i].Name < sites[j].Name
+	})
+	if n < len(sites) {
+		sites = sites[:n]
+	}
+	return sites
+}
+
+// Breakdown splits the heap retained after a measured run into the dataset retained since
+// loading and whatever the run itself left behind
+type Breakdown struct {
+	DatasetBytes      int64   // Retained right after loading
+	RunRetainedBytes  int64   // Retained growth between loading and the end of the run
+	RunAllocatedBytes int64   // Allocated during the run, mostly transient
+	DatasetShare      float64 // Fraction of the retained heap after the run that belongs to the dataset
+	TopAfterLoad      []Site
+	TopAfterRun       []Site
+}
+
+// Compare builds a breakdown from the profiles captured after loading and after the first measured run
+func Compare(afterLoad, afterRun Profile, topN int) Breakdown {
+	breakdown := Breakdown{
+		DatasetBytes:      afterLoad.InUseBytes(),
+		RunAllocatedBytes: afterRun.AllocBytes() - afterLoad.AllocBytes(),
+		TopAfterLoad:      afterLoad.Top(topN),
+		TopAfterRun:       afterRun.Top(topN),
+	}
+	for name, site := range afterRun.Sites {
+		growth := site.InUseBytes
+		if before, ok := afterLoad.Sites[name]; ok {
+			growth -= before.InUseBytes
+		}
+		if growth > 0 {
+			breakdown.RunRetainedBytes += growth
+		}
+	}
+	if retained := afterRun.InUseBytes(); retained > 0 {
+		breakdown.DatasetShare = float64(breakdown.DatasetBytes) / float64(retained)
+		if breakdown.DatasetShare > 1 {
+			breakdown.DatasetShare = 1
+		}
+	}
+	return breakdown
+}
+
+// Report formats the breakdown as log lines
+func (b Breakdown) Report() []string {
+	lines := []string{
+		fmt.Sprintf("Dataset Retained Heap: %.2f MB", megabytes(b.DatasetBytes)),
+		fmt.Sprintf("Run Retained Heap: %.2f MB", megabytes(b.RunRetainedBytes)),
+		fmt.Sprintf("Run Allocated (transient): %.2f MB", megabytes(b.RunAllocatedBytes)),
+		fmt.Sprintf("Dataset Share of Scanned Heap: %.2f%%", b.DatasetShare*100),
+		"Top Retaining Sites After Loading:",
+	}
+	for _, site := range b.TopAfterLoad {
+		lines = append(lines, fmt.Sprintf("  %10.2f MB  %s", megabytes(site.InUseBytes), site.Name))
+	}
+	lines = append(lines, "Top Retaining Sites After First Measured Run:")
+	for _, site := range b.TopAfterRun {
+		lines = append(lines, fmt.Sprintf("  %10.2f MB  %s", megabytes(site.InUseBytes), site.Name))
+	}
+	return lines
+}
+
+// megabytes converts a byte count to MB
+func megabytes(n int64) float64 {
+	return float64(n) / (1024 * 1024)
+}
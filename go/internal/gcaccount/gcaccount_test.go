@@ -0,0 +1,142 @@
+package gcaccount
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// sampleProfile is a debug=1 heap profile recorded with MemProfileRate=1, so no scaling applies
+const sampleProfile = `heap profile: 6: 3072 [10: 5120] @ heap/2
+2: 2048 [2: 2048] @ 0x1 0x2 0x3
+#	0x1	runtime.mallocgc+0x10	/usr/local/go/src/runtime/malloc.go:1000
+#	0x2	main.loadImage+0x20	/src/tinyimagenet/main.go:120
+#	0x3	main.main+0x30	/src/tinyimagenet/main.go:300
+
+3: 768 [3: 768] @ 0x4 0x3
+#	0x4	main.loadImage+0x24	/src/tinyimagenet/main.go:120
+#	0x3	main.main+0x30	/src/tinyimagenet/main.go:300
+
+1: 256 [5: 2304] @ 0x5
+#	0x5	main.ProcessBatch+0x40	/src/tinyimagenet/main.go:200
+
+
+# runtime.MemStats
+# Alloc = 123
+`
+
+func TestParseHeapProfile(t *testing.T) {
+	profile, err := ParseHeapProfile(strings.NewReader(sampleProfile))
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	if len(profile.Sites) != 2 {
+		t.Fatalf("Expected 2 sites, got %d: %+v", len(profile.Sites), profile.Sites)
+	}
+
+	load := profile.Sites["main.loadImage (main.go:120)"]
+	if load == nil {
+		t.Fatalf("Expected runtime frames to be skipped, got sites %+v", profile.Sites)
+	}
+	if load.InUseObjects != 5 || load.InUseBytes != 2816 || load.AllocBytes != 2816 {
+		t.Errorf("Expected loadImage records to be merged, got %+v", *load)
+	}
+	if profile.InUseBytes() != 3072 {
+		t.Errorf("Expected 3072 in-use bytes, got %d", profile.InUseBytes())
+	}
+	if profile.AllocBytes() != 5120 {
+		t.Errorf("Expected 5120 allocated bytes, got %d", profile.AllocBytes())
+	}
+
+	top := profile.Top(1)
+	if len(top) != 1 || top[0].Name != "main.loadImage (main.go:120)" {
+		t.Errorf("Expected loadImage as the top site, got %+v", top)
+	}
+}
+
+func TestParseHeapProfileScalesSamples(t *testing.T) {
+	// With a 512 KB sampling rate a single 512 KB object stands for about 1.58 objects
+	text := "heap profile: 1: 524288 [1: 524288] @ heap/1048576\n1: 524288 [1: 524288] @ 0x1\n#\t0x1\tmain.f+0x1\t/src/f.go:1\n"
+	profile, err := ParseHeapProfile(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	if got := profile.InUseBytes(); got < 820000 || got > 840000 {
+		t.Errorf("Expected about 829 KB after scaling, got %d", got)
+	}
+}
+
+// retained keeps allocations from retain reachable across GCs
+var retained [][]byte
+
+//go:noinline
+func retain(n, size int) {
+	for i := 0; i < n; i++ {
+		retained = append(retained, make([]byte, size))
+	}
+}
+
+//go:noinline
+func churn(n, size int) {
+	for i := 0; i < n; i++ {
+		buf := make([]byte, size)
+		buf[0] = byte(i)
+	}
+}
+
+func TestCaptureHeapProfile(t *testing.T) {
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	defer func() { retained = nil }()
+
+	retain(64, 64*1024)
+	afterLoad, err := CaptureHeapProfile()
+	if err != nil {
+		t.Fatalf("Failed to capture profile: %v", err)
+	}
+	churn(64, 64*1024)
+	afterRun, err := CaptureHeapProfile()
+	if err != nil {
+		t.Fatalf("Failed to capture profile: %v", err)
+	}
+
+	var site *Site
+	for name, s := range afterLoad.Sites {
+		if strings.HasPrefix(name, "golang/internal/gcaccount.retain ") {
+			site = s
+		}
+	}
+	if site == nil {
+		t.Fatalf("Expected a site for retain, got %d sites", len(afterLoad.Sites))
+	}
+	if site.InUseBytes < 64*64*1024 {
+		t.Errorf("Expected at least %d retained bytes for retain, got %d", 64*64*1024, site.InUseBytes)
+	}
+
+	breakdown := Compare(afterLoad, afterRun, 3)
+	if breakdown.DatasetBytes < 64*64*1024 {
+		t.Errorf("Expected the dataset to include retain, got %d bytes", breakdown.DatasetBytes)
+	}
+	if breakdown.RunAllocatedBytes < 64*64*1024 {
+		t.Errorf("Expected the churn allocations to be counted as run allocations, got %d bytes", breakdown.RunAllocatedBytes)
+	}
+	if breakdown.RunRetainedBytes >= 64*1024*8 {
+		t.Errorf("Expected churn to leave little retained, got %d bytes", breakdown.RunRetainedBytes)
+	}
+	if len(breakdown.TopAfterLoad) == 0 || !strings.Contains(breakdown.TopAfterLoad[0].Name, "retain") {
+		t.Errorf("Expected retain as the top site after loading, got %+v", breakdown.TopAfterLoad)
+	}
+}
+
+func TestBreakdownReport(t *testing.T) {
+	profile, err := ParseHeapProfile(strings.NewReader(sampleProfile))
+	if err != nil {
+		t.Fatalf("Failed to parse profile: %v", err)
+	}
+	report := strings.Join(Compare(profile, profile, 5).Report(), "\n")
+	for _, want := range []string{"Dataset Retained Heap", "Dataset Share of Scanned Heap: 100.00%", "main.loadImage (main.go:120)"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package gcaccount
+
+import (
+	"runtime"
+	"time"
+)
+
+// Cycles is the garbage collection work done between two runtime.MemStats snapshots
+type Cycles struct {
+	NumGC      uint32          // Completed GC cycles
+	PauseTotal time.Duration   // Stop-the-world pause time summed over the cycles
+	Pauses     []time.Duration // Pause of each cycle, oldest first; the runtime keeps only the last 256
+}
+
+// Between returns the GC cycles that completed after before was read and up to after. PauseNs
+// is a ring buffer indexed by cycle number, so the pauses of cycles that have since been
+// overwritten are missing from Pauses but still counted in PauseTotal.
+func Between(before, after *runtime.MemStats) Cycles {
+	cycles := Cycles{
+		NumGC:      after.NumGC - before.NumGC,
+		PauseTotal: time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+	}
+	first := before.NumGC
+	if ring := uint32(len(after.PauseNs)); cycles.NumGC > ring {
+		first = after.NumGC - ring
+	}
+	for n := first; n < after.NumGC; n++ {
+		cycles.Pauses = append(cycles.Pauses, time.Duration(after.PauseNs[n%uint32(len(after.PauseNs))]))
+	}
+	return cycles
+}
+
+// MaxPause returns the longest pause in c.Pauses, or 0 without cycles
+func (c Cycles) MaxPause() time.Duration {
+	var longest time.Duration
+	for _, p := range c.Pauses {
+		longest = max(longest, p)
+	}
+	return longest
+}
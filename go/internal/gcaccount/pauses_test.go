@@ -0,0 +1,78 @@
+package gcaccount
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// gcSink keeps the test allocations reachable until they are dropped on purpose
+var gcSink [][]byte
+
+func TestBetweenDifferencesForcedCycles(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 64; j++ {
+			gcSink = append(gcSink, make([]byte, 64<<10))
+		}
+		gcSink = nil
+		runtime.GC()
+	}
+	runtime.ReadMemStats(&after)
+
+	cycles := Between(&before, &after)
+	if cycles.NumGC < 3 || cycles.NumGC != after.NumGC-before.NumGC {
+		t.Errorf("Expected at least 3 cycles, the difference %d, got %d", after.NumGC-before.NumGC, cycles.NumGC)
+	}
+	if cycles.PauseTotal <= 0 || cycles.PauseTotal != time.Duration(after.PauseTotalNs-before.PauseTotalNs) {
+		t.Errorf("Expected the pause difference %v, got %v", time.Duration(after.PauseTotalNs-before.PauseTotalNs), cycles.PauseTotal)
+	}
+	if len(cycles.Pauses) != int(cycles.NumGC) {
+		t.Fatalf("Expected %d pauses, got %d", cycles.NumGC, len(cycles.Pauses))
+	}
+	var sum time.Duration
+	for _, p := range cycles.Pauses {
+		sum += p
+	}
+	if sum != cycles.PauseTotal {
+		t.Errorf("Expected the pauses to sum to %v, got %v", cycles.PauseTotal, sum)
+	}
+	if cycles.MaxPause() <= 0 || cycles.MaxPause() > cycles.PauseTotal {
+		t.Errorf("Expected a max pause within (0, %v], got %v", cycles.PauseTotal, cycles.MaxPause())
+	}
+}
+
+func TestBetweenReadsThePauseRing(t *testing.T) {
+	var before, after runtime.MemStats
+	for n := range after.PauseNs {
+		after.PauseNs[n] = uint64(n + 1)
+	}
+
+	// Cycles 254 to 257 wrap around the end of the ring
+	before.NumGC, after.NumGC = 254, 258
+	cycles := Between(&before, &after)
+	expected := []time.Duration{255, 256, 1, 2}
+	if len(cycles.Pauses) != len(expected) {
+		t.Fatalf("Expected pauses %v, got %v", expected, cycles.Pauses)
+	}
+	for i := range expected {
+		if cycles.Pauses[i] != expected[i] {
+			t.Errorf("Expected pauses %v, got %v", expected, cycles.Pauses)
+			break
+		}
+	}
+	if cycles.MaxPause() != 256 {
+		t.Errorf("Expected max pause 256, got %v", cycles.MaxPause())
+	}
+
+	// Only the last 256 of 300 cycles are still in the ring
+	before.NumGC, after.NumGC = 0, 300
+	if cycles := Between(&before, &after); cycles.NumGC != 300 || len(cycles.Pauses) != len(after.PauseNs) {
+		t.Errorf("Expected 300 cycles with %d pauses, got %d with %d", len(after.PauseNs), cycles.NumGC, len(cycles.Pauses))
+	}
+
+	if cycles := Between(&after, &after); cycles.NumGC != 0 || cycles.Pauses != nil || cycles.MaxPause() != 0 {
+		t.Errorf("Expected no cycles between equal snapshots, got %+v", cycles)
+	}
+}
@@ -0,0 +1,87 @@
+package grid
+
+import (
+	"image"
+	"image/color"
+	"unicode"
+)
+
+// Glyphs are 3x5 pixel bitmaps, drawn one pixel per bit with one blank column between glyphs
+const (
+	glyphWidth   = 3
+	glyphHeight  = 5
+	glyphSpacing = 1
+)
+
+// font holds the glyphs of the label alphabet; lowercase letters are drawn as uppercase and any
+// other rune as '?'
+var font = map[rune][glyphHeight]string{
+	'A': {"###", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {"###", "#..", "#..", "#..", "###"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {"###", "#..", "#.#", "#.#", "###"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", "###"},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {"###", "#.#", "#.#", "#.#", "###"},
+	'P': {"###", "#.#", "###", "#..", "#.."},
+	'Q': {"###", "#.#", "#.#", "###", "..#"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {"###", "#..", "###", "..#", "###"},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	' ': {"...", "...", "...", "...", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+	'_': {"...", "...", "...", "...", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'?': {"###", "..#", ".##", "...", ".#."},
+}
+
+// textWidth returns the width in pixels of s when drawn with drawText
+func textWidth(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return n*(glyphWidth+glyphSpacing) - glyphSpacing
+}
+
+// drawText draws s onto img with its top-left corner at (x, y)
+func drawText(img *image.RGBA, x, y int, s string, c color.Color) {
+	for _, r := range s {
+		glyph, ok := font[unicode.ToUpper(r)]
+		if !ok {
+			glyph = font['?']
+		}
+		for gy, row := range glyph {
+			for gx, bit := range row {
+				if bit == '#' {
+					img.Set(x+gx, y+gy, c)
+				}
+			}
+		}
+		x += glyphWidth + glyphSpacing
+	}
+}
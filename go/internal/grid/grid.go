@@ -0,0 +1,153 @@
+// Package grid renders sample images after each stage of a processing pipeline as one PNG,
+// with a row per sample and a labelled column per stage
+package grid
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+// padding is the space in pixels around every cell and label
+const padding = 2
+
+var (
+	background = color.RGBA{R: 32, G: 32, B: 32, A: 255}
+	labelColor = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// Cell is one image of the grid, a height x width x channels image stored row-major with
+// interleaved channels like the benchmark images. Channels must be 1 or 3, and pixel values
+// are expected in [0, 1]; values outside are clamped.
+type Cell struct {
+	Pixels   []float32
+	Height   int
+	Width    int
+	Channels int
+}
+
+// Grid is a set of samples traced through a pipeline: Rows[r][s] is sample r after stage s,
+// and Stages[s] is the label drawn above column s
+type Grid struct {
+	Stages []string
+	Rows   [][]Cell
+}
+
+// Layout records where Render placed the cells. Every cell has the size of the largest image
+// or label in the grid; smaller images sit in the top-left corner and the rest is padding.
+type Layout struct {
+	CellWidth  int
+	CellHeight int
+}
+
+// labelHeight is the height of the stage label strip above the first row
+const labelHeight = glyphHeight + 2*padding
+
+// Cell returns the area reserved for sample row after stage
+func (l Layout) Cell(row, stage int) image.Rectangle {
+	x := padding + stage*(l.CellWidth+padding)
+	y := labelHeight + row*(l.CellHeight+padding)
+	return image.Rect(x, y, x+l.CellWidth, y+l.CellHeight)
+}
+
+// Render draws the grid, returning the image and the layout of its cells
+func (g Grid) Render() (*image.RGBA, Layout, error) {
+	var layout Layout
+	for _, name := range g.Stages {
+		if w := textWidth(name); w > layout.CellWidth {
+			layout.CellWidth = w
+		}
+	}
+	for r, row := range g.Rows {
+		if len(row) != len(g.Stages) {
+			return nil, Layout{}, fmt.Errorf("row %d has %d cells, expected one per stage (%d)", r, len(row), len(g.Stages))
+		}
+		for s, cell := range row {
+			if err := cell.validate(); err != nil {
+				return nil, Layout{}, fmt.Errorf("row %d, stage %q: %v", r, g.Stages[s], err)
+			}
+			if cell.Width > layout.CellWidth {
+				layout.CellWidth = cell.Width
+			}
+			if cell.Height > layout.CellHeight {
+				layout.CellHeight = cell.Height
+			}
+		}
+	}
+
+	width := padding + len(g.Stages)*(layout.CellWidth+padding)
+	height := labelHeight + len(g.Rows)*(layout.CellHeight+padding)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, background.A
+	}
+
+	for s, name := range g.Stages {
+		drawText(img, layout.Cell(0, s).Min.X, padding, name, labelColor)
+	}
+	for r, row := range g.Rows {
+		for s, cell := range row {
+			cell.draw(img, layout.Cell(r, s).Min)
+		}
+	}
+	return img, layout, nil
+}
+
+// validate checks that the pixel count matches the cell's shape
+func (c Cell) validate() error {
+	if c.Channels != 1 && c.Channels != 3 {
+		return fmt.Errorf("unsupported channel count %d: expected 1 or 3", c.Channels)
+	}
+	if len(c.Pixels) != c.Height*c.Width*c.Channels {
+		return fmt.Errorf("expected %d pixel values for %d x %d x %d, got %d",
+			c.Height*c.Width*c.Channels, c.Height, c.Width, c.Channels, len(c.Pixels))
+	}
+	return nil
+}
+
+// draw copies the cell onto img with its top-left corner at origin
+func (c Cell) draw(img *image.RGBA, origin image.Point) {
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			i := (y*c.Width + x) * c.Channels
+			var px color.RGBA
+			if c.Channels == 1 {
+				v := toByte(c.Pixels[i])
+				px = color.RGBA{R: v, G: v, B: v, A: 255}
+			} else {
+				px = color.RGBA{R: toByte(c.Pixels[i]), G: toByte(c.Pixels[i+1]), B: toByte(c.Pixels[i+2]), A: 255}
+			}
+			img.SetRGBA(origin.X+x, origin.Y+y, px)
+		}
+	}
+}
+
+// toByte maps a pixel value in [0, 1] to [0, 255], clamping values outside the range
+func toByte(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return uint8(v*255 + 0.5)
+}
+
+// WritePNG renders g and writes it to path as a PNG
+func (g Grid) WritePNG(path string) error {
+	img, _, err := g.Render()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode %s: %v", path, err)
+	}
+	return file.Close()
+}
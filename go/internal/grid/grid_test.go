@@ -0,0 +1,196 @@
+package grid
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stage is one step of the synthetic test pipeline
+type stage struct {
+	name     string
+	identity bool
+	apply    func(Cell) Cell
+}
+
+// testPipeline inverts, halves the resolution and then passes the image through unchanged
+var testPipeline = []stage{
+	{"invert", false, func(c Cell) Cell {
+		out := Cell{Pixels: make([]float32, len(c.Pixels)), Height: c.Height, Width: c.Width, Channels: c.Channels}
+		for i, v := range c.Pixels {
+			out.Pixels[i] = 1 - v
+		}
+		return out
+	}},
+	{"resize", false, func(c Cell) Cell {
+		out := Cell{Height: c.Height / 2, Width: c.Width / 2, Channels: c.Channels}
+		for y := 0; y < out.Height; y++ {
+			for x := 0; x < out.Width; x++ {
+				i := (2*y*c.Width + 2*x) * c.Channels
+				out.Pixels = append(out.Pixels, c.Pixels[i:i+c.Channels]...)
+			}
+		}
+		return out
+	}},
+	{"identity", true, func(c Cell) Cell { return c }},
+}
+
+// testGrid traces rows samples of 8x8 gradients through testPipeline
+func testGrid(rows int) Grid {
+	g := Grid{Stages: []string{"original"}}
+	for _, s := range testPipeline {
+		g.Stages = append(g.Stages, s.name)
+	}
+	for r := 0; r < rows; r++ {
+		cell := Cell{Height: 8, Width: 8, Channels: 3}
+		for i := 0; i < 8*8*3; i++ {
+			cell.Pixels = append(cell.Pixels, float32((i+r*17)%97)/96)
+		}
+		row := []Cell{cell}
+		for _, s := range testPipeline {
+			cell = s.apply(cell)
+			row = append(row, cell)
+		}
+		g.Rows = append(g.Rows, row)
+	}
+	return g
+}
+
+// cellPixels returns the RGBA bytes of rect in img
+func cellPixels(img *image.RGBA, rect image.Rectangle) []byte {
+	var pix []byte
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		start := img.PixOffset(rect.Min.X, y)
+		pix = append(pix, img.Pix[start:start+4*rect.Dx()]...)
+	}
+	return pix
+}
+
+func TestRenderDimensions(t *testing.T) {
+	g := testGrid(3)
+	img, layout, err := g.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// "original" and "identity" are 8 glyphs wide, wider than the 8 pixel images
+	if layout.CellWidth != textWidth("original") || layout.CellHeight != 8 {
+		t.Errorf("Expected cells of %d x 8, got %d x %d", textWidth("original"), layout.CellWidth, layout.CellHeight)
+	}
+	width := padding + len(g.Stages)*(layout.CellWidth+padding)
+	height := labelHeight + len(g.Rows)*(layout.CellHeight+padding)
+	if got := img.Bounds(); got.Dx() != width || got.Dy() != height {
+		t.Errorf("Expected a %d x %d image, got %d x %d", width, height, got.Dx(), got.Dy())
+	}
+	last := layout.Cell(len(g.Rows)-1, len(g.Stages)-1)
+	if !last.In(img.Bounds()) {
+		t.Errorf("Expected the last cell %v to lie inside the image %v", last, img.Bounds())
+	}
+}
+
+func TestRenderCellPlacement(t *testing.T) {
+	g := testGrid(2)
+	img, layout, err := g.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	for r, row := range g.Rows {
+		for s, cell := range row {
+			origin := layout.Cell(r, s).Min
+			for y := 0; y < layout.CellHeight; y++ {
+				for x := 0; x < layout.CellWidth; x++ {
+					got := img.RGBAAt(origin.X+x, origin.Y+y)
+					if x >= cell.Width || y >= cell.Height {
+						if got != background {
+							t.Fatalf("Row %d, stage %s: expected padding at (%d, %d), got %v", r, g.Stages[s], x, y, got)
+						}
+						continue
+					}
+					i := (y*cell.Width + x) * cell.Channels
+					if got.R != toByte(cell.Pixels[i]) || got.G != toByte(cell.Pixels[i+1]) || got.B != toByte(cell.Pixels[i+2]) {
+						t.Fatalf("Row %d, stage %s: pixel (%d, %d) is %v, expected %v", r, g.Stages[s], x, y, got, cell.Pixels[i:i+3])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRenderStagesDiffer(t *testing.T) {
+	g := testGrid(2)
+	img, layout, err := g.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	for r := range g.Rows {
+		for s, st := range testPipeline {
+			prev := cellPixels(img, layout.Cell(r, s))
+			cur := cellPixels(img, layout.Cell(r, s+1))
+			same := string(prev) == string(cur)
+			if same != st.identity {
+				t.Errorf("Row %d, stage %s: expected cell identical to the previous stage %v, got %v", r, st.name, st.identity, same)
+			}
+		}
+	}
+}
+
+func TestRenderLabels(t *testing.T) {
+	g := testGrid(1)
+	img, layout, err := g.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for s, name := range g.Stages {
+		strip := image.Rect(layout.Cell(0, s).Min.X, padding, layout.Cell(0, s).Min.X+textWidth(name), padding+glyphHeight)
+		lit := 0
+		for y := strip.Min.Y; y < strip.Max.Y; y++ {
+			for x := strip.Min.X; x < strip.Max.X; x++ {
+				if img.RGBAAt(x, y) == labelColor {
+					lit++
+				}
+			}
+		}
+		if lit == 0 {
+			t.Errorf("Expected the label %q to be drawn above column %d", name, s)
+		}
+	}
+}
+
+func TestRenderInvalid(t *testing.T) {
+	cases := map[string]Grid{
+		"missing cell":   {Stages: []string{"a", "b"}, Rows: [][]Cell{{{Pixels: make([]float32, 3), Height: 1, Width: 1, Channels: 3}}}},
+		"wrong channels": {Stages: []string{"a"}, Rows: [][]Cell{{{Pixels: make([]float32, 2), Height: 1, Width: 1, Channels: 2}}}},
+		"short pixels":   {Stages: []string{"a"}, Rows: [][]Cell{{{Pixels: make([]float32, 5), Height: 2, Width: 1, Channels: 3}}}},
+	}
+	for name, g := range cases {
+		if _, _, err := g.Render(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	g := testGrid(2)
+	path := filepath.Join(t.TempDir(), "grid.png")
+	if err := g.WritePNG(path); err != nil {
+		t.Fatalf("WritePNG failed: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open PNG: %v", err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	img, _, _ := g.Render()
+	if decoded.Bounds() != img.Bounds() {
+		t.Errorf("Expected bounds %v, got %v", img.Bounds(), decoded.Bounds())
+	}
+}
@@ -0,0 +1,60 @@
+// Package kernels holds image transforms that operate on flattened HWC float32 images
+package kernels
+
+// gaussian3x3 is the 3x3 binomial approximation of a Gaussian, normalized by gaussianSum
+var gaussian3x3 = [3][3]float32{
+	{1, 2, 1},
+	{2, 4, 2},
+	{1, 2, 1},
+}
+
+const gaussianSum = 16
+
+// GaussianBlur returns a 3x3 Gaussian blur of image, a height x width x channels image stored
+// row-major with interleaved channels. Each channel is blurred independently, and pixels beyond
+// the border are clamped to the nearest edge pixel. image is left unchanged.
+func GaussianBlur(image []float32, height, width, channels int) []float32 {
+	out := make([]float32, len(image))
+	blurRows(out, image, height, width, channels, 0, height)
+	return out
+}
+
+// GaussianBlurTiled is GaussianBlur with the rows split among tiles goroutines by Tile. Each
+// output pixel is computed exactly as GaussianBlur computes it.
+func GaussianBlurTiled(image []float32, height, width, channels, tiles int) []float32 {
+	out := make([]float32, len(image))
+	Tile(height, tiles, func(y0, y1 int) {
+		blurRows(out, image, height, width, channels, y0, y1)
+	})
+	return out
+}
+
+// blurRows writes the blurred rows [y0, y1) of image to out
+func blurRows(out, image []float32, height, width, channels, y0, y1 int) {
+	for y := y0; y < y1; y++ {
+		for x := 0; x < width; x++ {
+			for c := 0; c < channels; c++ {
+				var sum float32
+				for dy := -1; dy <= 1; dy++ {
+					sy := clampIndex(y+dy, height)
+					for dx := -1; dx <= 1; dx++ {
+						sx := clampIndex(x+dx, width)
+						sum += gaussian3x3[dy+1][dx+1] * image[(sy*width+sx)*channels+c]
+					}
+				}
+				out[(y*width+x)*channels+c] = sum / gaussianSum
+			}
+		}
+	}
+}
+
+// clampIndex limits i to [0, n-1]
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,45 @@
+package kernels
+
+import "testing"
+
+func TestGaussianBlurHandComputed(t *testing.T) {
+	image := []float32{
+		1, 2, 3,
+		4, 5, 6,
+		7, 8, 9,
+	}
+	// Borders are clamped, so the corner (0,0) sees 1 1 2 / 1 1 2 / 4 4 5
+	expected := []float32{
+		2, 2.75, 3.5,
+		4.25, 5, 5.75,
+		6.5, 7.25, 8,
+	}
+
+	blurred := GaussianBlur(image, 3, 3, 1)
+	for i := range expected {
+		if blurred[i] != expected[i] {
+			t.Errorf("Pixel %d mismatch: expected %.4f, got %.4f", i, expected[i], blurred[i])
+		}
+	}
+	if image[0] != 1 || image[8] != 9 {
+		t.Errorf("GaussianBlur modified its input: %v", image)
+	}
+}
+
+func TestGaussianBlurChannelsIndependent(t *testing.T) {
+	for _, shape := range [][3]int{{32, 32, 3}, {64, 64, 3}} {
+		height, width, channels := shape[0], shape[1], shape[2]
+		image := make([]float32, height*width*channels)
+		for i := range image {
+			image[i] = float32(i % channels)
+		}
+
+		// A constant channel stays constant, including at the borders
+		blurred := GaussianBlur(image, height, width, channels)
+		for i, v := range blurred {
+			if v != float32(i%channels) {
+				t.Fatalf("%dx%dx%d: pixel %d expected %d, got %.4f", height, width, channels, i, i%channels, v)
+			}
+		}
+	}
+}
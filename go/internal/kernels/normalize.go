@@ -0,0 +1,114 @@
+package kernels
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// MaxStatChannels is the number of channels ComputeChannelStats reports; channels beyond it
+// are left out of the statistics and left unchanged by Normalize
+const MaxStatChannels = 3
+
+// welford accumulates the running mean and sum of squared deviations of one channel, which stays
+// accurate where summing squares would cancel catastrophically on large datasets
+type welford struct {
+	count float64
+	mean  float64
+	m2    float64
+}
+
+// add includes value x
+func (w *welford) add(x float64) {
+	w.count++
+	delta := x - w.mean
+	w.mean += delta / w.count
+	w.m2 += delta * (x - w.mean)
+}
+
+// merge combines the accumulators of two disjoint parts of the data (Chan et al.)
+func (w welford) merge(o welford) welford {
+	if o.count == 0 {
+		return w
+	}
+	if w.count == 0 {
+		return o
+	}
+	count := w.count + o.count
+	delta := o.mean - w.mean
+	return welford{
+		count: count,
+		mean:  w.mean + delta*o.count/count,
+		m2:    w.m2 + o.m2 + delta*delta*w.count*o.count/count,
+	}
+}
+
+// ComputeChannelStats returns the mean and population standard deviation of every channel over
+// all pixels of images, stored with interleaved channels. The images are split across one
+// goroutine per CPU, each running Welford's algorithm over its share, and the partial results
+// are merged once all have finished. Only the first MaxStatChannels channels are reported.
+func ComputeChannelStats(images [][]float32, channels int) (mean, std [MaxStatChannels]float32) {
+	return computeChannelStats(images, channels, runtime.NumCPU())
+}
+
+// computeChannelStats is ComputeChannelStats on at most workers goroutines
+func computeChannelStats(images [][]float32, channels, workers int) (mean, std [MaxStatChannels]float32) {
+	if workers > len(images) {
+		workers = len(images)
+	}
+	stats := min(channels, MaxStatChannels)
+	partials := make([][MaxStatChannels]welford, workers)
+	chunk := (len(images) + workers - 1) / max(workers, 1)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each goroutine writes only its own partial, so no locking is needed
+			acc := &partials[w]
+			for _, image := range images[w*chunk : min((w+1)*chunk, len(images))] {
+				for i := 0; i+channels <= len(image); i += channels {
+					for c := 0; c < stats; c++ {
+						acc[c].add(float64(image[i+c]))
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total [MaxStatChannels]welford
+	for _, partial := range partials {
+		for c := range total {
+			total[c] = total[c].merge(partial[c])
+		}
+	}
+	for c := 0; c < stats; c++ {
+		if total[c].count > 0 {
+			mean[c] = float32(total[c].mean)
+			std[c] = float32(math.Sqrt(total[c].m2 / total[c].count))
+		}
+	}
+	return mean, std
+}
+
+// Normalize returns image with every channel shifted by its mean and scaled by its standard
+// deviation, (x - mean) / std, for an image stored with interleaved channels. A channel with a
+// standard deviation of 0 is only shifted, and channels past MaxStatChannels are copied
+// unchanged. image is left unchanged.
+func Normalize(image []float32, channels int, mean, std [MaxStatChannels]float32) []float32 {
+	out := make([]float32, len(image))
+	copy(out, image)
+	stats := min(channels, MaxStatChannels)
+	for i := 0; i+channels <= len(out); i += channels {
+		for c := 0; c < stats; c++ {
+			x := out[i+c] - mean[c]
+			if std[c] != 0 {
+				x /= std[c]
+			}
+			out[i+c] = x
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package kernels
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestComputeChannelStatsHandComputed(t *testing.T) {
+	// Two 1x2 RGB images: red 1, 3, 5, 7; green constant 2; blue -1, 1, -1, 1
+	images := [][]float32{
+		{1, 2, -1, 3, 2, 1},
+		{5, 2, -1, 7, 2, 1},
+	}
+	mean, std := ComputeChannelStats(images, 3)
+	expectedMean := [3]float32{4, 2, 0}
+	expectedStd := [3]float32{float32(math.Sqrt(5)), 0, 1}
+	for c := 0; c < 3; c++ {
+		if math.Abs(float64(mean[c]-expectedMean[c])) > 1e-6 || math.Abs(float64(std[c]-expectedStd[c])) > 1e-6 {
+			t.Errorf("Channel %d: expected mean %v and std %v, got %v and %v", c, expectedMean[c], expectedStd[c], mean[c], std[c])
+		}
+	}
+}
+
+func TestComputeChannelStatsSingleChannel(t *testing.T) {
+	mean, std := ComputeChannelStats([][]float32{{2, 4}, {4, 6}}, 1)
+	if mean != [3]float32{4, 0, 0} || math.Abs(float64(std[0])-math.Sqrt(2)) > 1e-6 || std[1] != 0 {
+		t.Errorf("Expected mean 4 and std sqrt(2) on the only channel, got %v and %v", mean, std)
+	}
+	if mean, std := ComputeChannelStats(nil, 3); mean != ([3]float32{}) || std != ([3]float32{}) {
+		t.Errorf("Expected zero statistics without images, got %v and %v", mean, std)
+	}
+}
+
+func TestComputeChannelStatsParallelMatchesSequential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	images := make([][]float32, 1001)
+	for i := range images {
+		images[i] = make([]float32, 8*8*3)
+		for j := range images[i] {
+			// An offset far from 0 is where a naive sum of squares loses precision
+			images[i][j] = 1000 + rng.Float32()*float32(j%3+1)
+		}
+	}
+
+	// Two-pass reference on one goroutine
+	var sum, squares [3]float64
+	var count float64
+	for _, image := range images {
+		for i := 0; i < len(image); i += 3 {
+			for c := 0; c < 3; c++ {
+				sum[c] += float64(image[i+c])
+			}
+		}
+		count += float64(len(image) / 3)
+	}
+	for _, image := range images {
+		for i := 0; i < len(image); i += 3 {
+			for c := 0; c < 3; c++ {
+				d := float64(image[i+c]) - sum[c]/count
+				squares[c] += d * d
+			}
+		}
+	}
+
+	mean, std := computeChannelStats(images, 3, 8)
+	for c := 0; c < 3; c++ {
+		expectedMean, expectedStd := sum[c]/count, math.Sqrt(squares[c]/count)
+		if math.Abs(float64(mean[c])-expectedMean) > 1e-5*expectedMean || math.Abs(float64(std[c])-expectedStd) > 1e-5 {
+			t.Errorf("Channel %d: expected mean %v and std %v, got %v and %v", c, expectedMean, expectedStd, mean[c], std[c])
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	image := []float32{5, 2, 0, 1, 2, 4}
+	out := Normalize(image, 3, [3]float32{3, 2, 2}, [3]float32{2, 0, 4})
+	expected := []float32{1, 0, -0.5, -1, 0, 0.5}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, out)
+		}
+	}
+	if image[0] != 5 {
+		t.Errorf("Expected the input image unchanged, got %v", image)
+	}
+}
@@ -0,0 +1,68 @@
+package kernels
+
+import "math"
+
+// sobelX and sobelY are the 3x3 Sobel operators for the horizontal and vertical gradient
+var (
+	sobelX = [3][3]float32{
+		{-1, 0, 1},
+		{-2, 0, 2},
+		{-1, 0, 1},
+	}
+	sobelY = [3][3]float32{
+		{-1, -2, -1},
+		{0, 0, 0},
+		{1, 2, 1},
+	}
+)
+
+// Rec. 601 luma weights of the red, green and blue channels
+const (
+	lumaRed   = 0.299
+	lumaGreen = 0.587
+	lumaBlue  = 0.114
+)
+
+// Sobel returns image with its red channel replaced by the Sobel gradient magnitude of the
+// image's luminance, for a height x width x channels image stored row-major with interleaved
+// channels. Images with fewer than three channels use the first channel as the luminance.
+// Pixels beyond the border are clamped to the nearest edge pixel. image is left unchanged.
+func Sobel(image []float32, height, width, channels int) []float32 {
+	return SobelTiled(image, height, width, channels, 1)
+}
+
+// SobelTiled is Sobel with the rows split among tiles goroutines by Tile. The gradients of a row
+// read the luminance of its neighbours, so all of it is computed first, behind a barrier of its own.
+func SobelTiled(image []float32, height, width, channels, tiles int) []float32 {
+	luma := make([]float32, height*width)
+	Tile(height, tiles, func(y0, y1 int) {
+		for i := y0 * width; i < y1*width; i++ {
+			pixel := image[i*channels : (i+1)*channels]
+			if channels >= 3 {
+				luma[i] = lumaRed*pixel[0] + lumaGreen*pixel[1] + lumaBlue*pixel[2]
+			} else {
+				luma[i] = pixel[0]
+			}
+		}
+	})
+
+	out := make([]float32, len(image))
+	Tile(height, tiles, func(y0, y1 int) {
+		copy(out[y0*width*channels:y1*width*channels], image[y0*width*channels:y1*width*channels])
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				var gx, gy float32
+				for dy := -1; dy <= 1; dy++ {
+					sy := clampIndex(y+dy, height)
+					for dx := -1; dx <= 1; dx++ {
+						v := luma[sy*width+clampIndex(x+dx, width)]
+						gx += sobelX[dy+1][dx+1] * v
+						gy += sobelY[dy+1][dx+1] * v
+					}
+				}
+				out[(y*width+x)*channels] = float32(math.Sqrt(float64(gx*gx + gy*gy)))
+			}
+		}
+	})
+	return out
+}
@@ -0,0 +1,94 @@
+package kernels
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSobelHorizontalRamp(t *testing.T) {
+	// Luminance rises by 1 per column, so the gradient is purely horizontal: 4 per unit step
+	// across the two-column span of the operator inside, and 4 across the one clamped step at
+	// either border
+	height, width := 4, 5
+	image := make([]float32, height*width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			image[y*width+x] = float32(x)
+		}
+	}
+	expectedRow := []float32{4, 8, 8, 8, 4}
+
+	edges := Sobel(image, height, width, 1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if got := edges[y*width+x]; got != expectedRow[x] {
+				t.Errorf("Pixel (%d,%d) mismatch: expected %.4f, got %.4f", x, y, expectedRow[x], got)
+			}
+		}
+	}
+	if image[1] != 1 {
+		t.Errorf("Sobel modified its input: %v", image)
+	}
+}
+
+func TestSobelVerticalStep(t *testing.T) {
+	// A step from 0 to 1 between rows 1 and 2 gives a response of 4 on the two rows beside it
+	// and none elsewhere
+	height, width := 4, 3
+	image := make([]float32, height*width)
+	for i := 2 * width; i < len(image); i++ {
+		image[i] = 1
+	}
+	expected := []float32{0, 4, 4, 0}
+
+	edges := Sobel(image, height, width, 1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if got := edges[y*width+x]; got != expected[y] {
+				t.Errorf("Pixel (%d,%d) mismatch: expected %.4f, got %.4f", x, y, expected[y], got)
+			}
+		}
+	}
+}
+
+func TestSobelWritesLuminanceEdgesToRed(t *testing.T) {
+	for _, shape := range [][3]int{{32, 32, 3}, {64, 64, 3}} {
+		height, width, channels := shape[0], shape[1], shape[2]
+		// A grey horizontal ramp has luminance x, since the luma weights sum to 1
+		image := make([]float32, height*width*channels)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				for c := 0; c < channels; c++ {
+					image[(y*width+x)*channels+c] = float32(x)
+				}
+			}
+		}
+
+		edges := Sobel(image, height, width, channels)
+		name := fmt.Sprintf("%dx%dx%d", height, width, channels)
+		for y := 0; y < height; y++ {
+			for x := 1; x < width-1; x++ {
+				idx := (y*width + x) * channels
+				if math.Abs(float64(edges[idx]-8)) > 1e-3 {
+					t.Fatalf("%s: pixel (%d,%d) expected an edge response of 8, got %.4f", name, x, y, edges[idx])
+				}
+				if edges[idx+1] != float32(x) || edges[idx+2] != float32(x) {
+					t.Fatalf("%s: pixel (%d,%d) expected green and blue to be kept, got %.4f and %.4f", name, x, y, edges[idx+1], edges[idx+2])
+				}
+			}
+		}
+	}
+}
+
+func TestSobelConstantImageHasNoEdges(t *testing.T) {
+	image := make([]float32, 8*8*3)
+	for i := range image {
+		image[i] = 0.5
+	}
+	for i, v := range Sobel(image, 8, 8, 3) {
+		if i%3 == 0 && v != 0 {
+			t.Fatalf("Pixel %d expected no edge, got %.4f", i/3, v)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package kernels
+
+import "sync"
+
+// Tile splits the rows [0, height) of an image into tiles contiguous ranges of near-equal size
+// and calls fn on each range from its own goroutine, returning once every range is done, so the
+// caller sees a barrier per image. With one tile or fewer, fn runs on the calling goroutine, and
+// there are never more tiles than rows.
+func Tile(height, tiles int, fn func(y0, y1 int)) {
+	if tiles > height {
+		tiles = height
+	}
+	if tiles <= 1 {
+		fn(0, height)
+		return
+	}
+	var wg sync.WaitGroup
+	for t := 0; t < tiles; t++ {
+		y0, y1 := t*height/tiles, (t+1)*height/tiles
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(y0, y1)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package kernels
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTileCoversEveryRowOnce(t *testing.T) {
+	for _, c := range []struct{ height, tiles int }{{32, 1}, {32, 4}, {64, 7}, {5, 8}, {1, 3}} {
+		visits := make([]atomic.Int32, c.height)
+		var calls atomic.Int32
+		Tile(c.height, c.tiles, func(y0, y1 int) {
+			calls.Add(1)
+			for y := y0; y < y1; y++ {
+				visits[y].Add(1)
+			}
+		})
+		for y := range visits {
+			if n := visits[y].Load(); n != 1 {
+				t.Errorf("Height %d, %d tiles: expected row %d visited once, got %d", c.height, c.tiles, y, n)
+			}
+		}
+		if expected := int32(min(c.height, c.tiles)); calls.Load() != expected {
+			t.Errorf("Height %d, %d tiles: expected %d calls, got %d", c.height, c.tiles, expected, calls.Load())
+		}
+	}
+}
+
+// randomImage returns a height x width x channels image of values in [0, 1)
+func randomImage(height, width, channels int) []float32 {
+	rng := rand.New(rand.NewSource(1))
+	image := make([]float32, height*width*channels)
+	for i := range image {
+		image[i] = rng.Float32()
+	}
+	return image
+}
+
+func TestTiledKernelsMatchUntiled(t *testing.T) {
+	height, width, channels := 37, 29, 3
+	image := randomImage(height, width, channels)
+	for _, tiles := range []int{2, 4, 9} {
+		for name, pair := range map[string][2][]float32{
+			"blur":  {GaussianBlur(image, height, width, channels), GaussianBlurTiled(image, height, width, channels, tiles)},
+			"sobel": {Sobel(image, height, width, channels), SobelTiled(image, height, width, channels, tiles)},
+		} {
+			for i := range pair[0] {
+				if pair[0][i] != pair[1][i] {
+					t.Fatalf("%s with %d tiles: pixel %d mismatch: expected %v, got %v", name, tiles, i, pair[0][i], pair[1][i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package labels
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ClassCount is the number of images carrying one label
+type ClassCount[T cmp.Ordered] struct {
+	Label T
+	Count int
+}
+
+// Histogram counts the images per distinct label, in sorted label order. Its length is the
+// number of classes present, which for a full dataset is far smaller than the number of images.
+func Histogram[T cmp.Ordered](labels []T) []ClassCount[T] {
+	counts := make(map[T]int)
+	for _, label := range labels {
+		counts[label]++
+	}
+	histogram := make([]ClassCount[T], 0, len(counts))
+	for label, count := range counts {
+		histogram = append(histogram, ClassCount[T]{Label: label, Count: count})
+	}
+	slices.SortFunc(histogram, func(a, b ClassCount[T]) int { return cmp.Compare(a.Label, b.Label) })
+	return histogram
+}
+
+// Distinct returns the distinct labels in sorted order
+func Distinct[T cmp.Ordered](labels []T) []T {
+	histogram := Histogram(labels)
+	distinct := make([]T, len(histogram))
+	for i, c := range histogram {
+		distinct[i] = c.Label
+	}
+	return distinct
+}
+
+// CountRange returns the smallest and largest per-class counts of histogram, both 0 when it is empty
+func CountRange[T cmp.Ordered](histogram []ClassCount[T]) (min, max int) {
+	for i, c := range histogram {
+		if i == 0 || c.Count < min {
+			min = c.Count
+		}
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	return min, max
+}
+
+// ReadWnids decodes a Tiny ImageNet wnids.txt, one WordNet ID per line, into a map from wnid to
+// class index in file order. Blank lines are ignored and a repeated wnid is an error.
+func ReadWnids(r io.Reader) (map[string]int, error) {
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		wnid := strings.TrimSpace(scanner.Text())
+		if wnid == "" {
+			continue
+		}
+		if _, exists := index[wnid]; exists {
+			return nil, fmt.Errorf("wnid %q on line %d is listed twice", wnid, line)
+		}
+		index[wnid] = len(index)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wnids: %v", err)
+	}
+	return index, nil
+}
+
+// ReadWords decodes a Tiny ImageNet words.txt into a map from wnid to English class name. Each
+// line holds a wnid, a tab and a comma-separated list of synonyms such as "goldfish, Carassius
+// auratus"; the first synonym is used as the name. Lines separated by spaces instead of a tab
+// are accepted, and blank lines and lines without a name are skipped. When a wnid repeats, the
+// first name is kept.
+func ReadWords(r io.Reader) (map[string]string, error) {
+	names := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		wnid, synonyms, found := strings.Cut(line, "\t")
+		if !found {
+			wnid, synonyms, _ = strings.Cut(line, " ")
+		}
+		wnid = strings.TrimSpace(wnid)
+		name, _, _ := strings.Cut(synonyms, ",")
+		name = strings.TrimSpace(name)
+		if wnid == "" || name == "" {
+			continue
+		}
+		if _, exists := names[wnid]; !exists {
+			names[wnid] = name
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read words: %v", err)
+	}
+	return names, nil
+}
+
+// ValAnnotation is the class of one Tiny ImageNet validation image
+type ValAnnotation struct {
+	Filename string
+	Wnid     string
+}
+
+// ReadValAnnotations decodes a Tiny ImageNet val_annotations.txt in file order. Each line holds
+// an image filename, its wnid and the four bounding box coordinates, separated by tabs; the
+// bounding box is ignored. Blank lines are skipped, and a line without a wnid is an error.
+func ReadValAnnotations(r io.Reader) ([]ValAnnotation, error) {
+	var annotations []ValAnnotation
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 2 || strings.TrimSpace(fields[0]) == "" || strings.TrimSpace(fields[1]) == "" {
+			return nil, fmt.Errorf("line %d: expected a filename and a wnid separated by a tab, got %q", line, text)
+		}
+		annotations = append(annotations, ValAnnotation{Filename: strings.TrimSpace(fields[0]), Wnid: strings.TrimSpace(fields[1])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read val annotations: %v", err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,113 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistogramCountsDuplicateLabels(t *testing.T) {
+	histogram := Histogram([]int{3, 1, 3, 3, 0, 1})
+	expected := []ClassCount[int]{{0, 1}, {1, 2}, {3, 3}}
+	if len(histogram) != len(expected) {
+		t.Fatalf("Expected %d classes, got %v", len(expected), histogram)
+	}
+	for i, c := range histogram {
+		if c != expected[i] {
+			t.Errorf("Class %d: expected %+v, got %+v", i, expected[i], c)
+		}
+	}
+	if min, max := CountRange(histogram); min != 1 || max != 3 {
+		t.Errorf("Expected count range 1-3, got %d-%d", min, max)
+	}
+}
+
+func TestDistinctStrings(t *testing.T) {
+	distinct := Distinct([]string{"n02", "n01", "n02", "n01", "n03"})
+	if strings.Join(distinct, ",") != "n01,n02,n03" {
+		t.Errorf("Expected [n01 n02 n03], got %v", distinct)
+	}
+}
+
+func TestHistogramEmptyInput(t *testing.T) {
+	if histogram := Histogram([]string(nil)); len(histogram) != 0 {
+		t.Errorf("Expected no classes, got %v", histogram)
+	}
+	if distinct := Distinct([]int{}); len(distinct) != 0 {
+		t.Errorf("Expected no classes, got %v", distinct)
+	}
+	if min, max := CountRange([]ClassCount[int]{}); min != 0 || max != 0 {
+		t.Errorf("Expected count range 0-0, got %d-%d", min, max)
+	}
+}
+
+func TestReadWnids(t *testing.T) {
+	index, err := ReadWnids(strings.NewReader("n01443537\nn01629819\n\nn01641577\n"))
+	if err != nil {
+		t.Fatalf("Failed to read wnids: %v", err)
+	}
+	expected := map[string]int{"n01443537": 0, "n01629819": 1, "n01641577": 2}
+	if len(index) != len(expected) {
+		t.Fatalf("Expected %d wnids, got %v", len(expected), index)
+	}
+	for wnid, i := range expected {
+		if index[wnid] != i {
+			t.Errorf("Expected %s at class index %d, got %d", wnid, i, index[wnid])
+		}
+	}
+
+	if _, err := ReadWnids(strings.NewReader("n01443537\nn01443537\n")); err == nil {
+		t.Error("Expected an error for a repeated wnid")
+	}
+}
+
+func TestReadWords(t *testing.T) {
+	words := "n01443537\tgoldfish, Carassius auratus\n" +
+		"n01629819\tEuropean fire salamander, Salamandra salamandra\n" +
+		"\n" +
+		"n01641577 bullfrog, Rana catesbeiana\n" +
+		"n00000001\t\n" +
+		"n01443537\tcarp\n" +
+		"  n02123045\t  tabby , tabby cat  \n"
+	names, err := ReadWords(strings.NewReader(words))
+	if err != nil {
+		t.Fatalf("Failed to read words: %v", err)
+	}
+	expected := map[string]string{
+		"n01443537": "goldfish",
+		"n01629819": "European fire salamander",
+		"n01641577": "bullfrog",
+		"n02123045": "tabby",
+	}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %d names, got %v", len(expected), names)
+	}
+	for wnid, name := range expected {
+		if names[wnid] != name {
+			t.Errorf("Expected %s to be named %q, got %q", wnid, name, names[wnid])
+		}
+	}
+}
+
+func TestReadValAnnotations(t *testing.T) {
+	annotations := "val_0.JPEG\tn03444034\t0\t32\t44\t62\n" +
+		"\n" +
+		"val_1.JPEG\tn04067472\t52\t55\t57\t59\n" +
+		"val_2.JPEG\tn03444034\n"
+	got, err := ReadValAnnotations(strings.NewReader(annotations))
+	if err != nil {
+		t.Fatalf("Failed to read val annotations: %v", err)
+	}
+	expected := []ValAnnotation{{"val_0.JPEG", "n03444034"}, {"val_1.JPEG", "n04067472"}, {"val_2.JPEG", "n03444034"}}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d annotations, got %v", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Annotation %d: expected %+v, got %+v", i, expected[i], got[i])
+		}
+	}
+
+	if _, err := ReadValAnnotations(strings.NewReader("val_0.JPEG n03444034 0 32 44 62\n")); err == nil {
+		t.Errorf("Expected an error for a line without tabs")
+	}
+}
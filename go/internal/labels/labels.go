@@ -0,0 +1,234 @@
+// Package labels exports dataset labels in formats that the Java implementation parses
+// identically, so processed outputs from both sides can be cross-checked image by image.
+//
+// Three formats are supported, all in the same order as the images they describe:
+//   - a binary array of little-endian int32 class indices with no header
+//   - a CSV with the columns index, label and class_index
+//   - a class map CSV with the columns class_index and label
+package labels
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// Format selects one of the label export formats
+type Format string
+
+// Supported label export formats
+const (
+	FormatBinary   Format = "binary"
+	FormatCSV      Format = "csv"
+	FormatClassMap Format = "classmap"
+)
+
+// Labels holds per-image label strings together with their class indices
+type Labels struct {
+	Names      []string // Label of each image, in image order
+	Indices    []int32  // Class index of each image, in image order
+	ClassNames []string // Label of each class, indexed by class index
+}
+
+// FromNames assigns class indices to the distinct labels in sorted order, so the same
+// set of labels always maps to the same indices regardless of image order
+func FromNames(names []string) Labels {
+	distinct := make(map[string]bool)
+	for _, name := range names {
+		distinct[name] = true
+	}
+	classNames := make([]string, 0, len(distinct))
+	for name := range distinct {
+		classNames = append(classNames, name)
+	}
+	sort.Strings(classNames)
+
+	classIndex := make(map[string]int32, len(classNames))
+	for i, name := range classNames {
+		classIndex[name] = int32(i)
+	}
+
+	indices := make([]int32, len(names))
+	for i, name := range names {
+		indices[i] = classIndex[name]
+	}
+
+	ordered := make([]string, len(names))
+	copy(ordered, names)
+	return Labels{Names: ordered, Indices: indices, ClassNames: classNames}
+}
+
+// FromIndices wraps per-image class indices whose class names are already fixed by the dataset,
+// such as the CIFAR label order, naming each image after its class. Indices without a name are
+// named by their number.
+func FromIndices(indices []int, classNames []string) Labels {
+	names := make([]string, len(indices))
+	indices32 := make([]int32, len(indices))
+	for i, index := range indices {
+		indices32[i] = int32(index)
+		if index >= 0 && index < len(classNames) {
+			names[i] = classNames[index]
+		} else {
+			names[i] = strconv.Itoa(index)
+		}
+	}
+	ordered := make([]string, len(classNames))
+	copy(ordered, classNames)
+	return Labels{Names: names, Indices: indices32, ClassNames: ordered}
+}
+
+// ParseFormat checks that name is one of the supported label formats
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatBinary, FormatCSV, FormatClassMap:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown label format %q: expected %q, %q or %q", name, FormatBinary, FormatCSV, FormatClassMap)
+}
+
+// Permute reorders the per-image labels so that position i holds the label previously at order[i].
+// Applying the same order to the images keeps both aligned.
+func (l Labels) Permute(order []int) Labels {
+	names := make([]string, len(order))
+	indices := make([]int32, len(order))
+	for i, src := range order {
+		names[i] = l.Names[src]
+		indices[i] = l.Indices[src]
+	}
+	return Labels{Names: names, Indices: indices, ClassNames: l.ClassNames}
+}
+
+// ShuffleOrder returns a seeded random permutation of n image positions
+func ShuffleOrder(n int, seed int64) []int {
+	return rand.New(rand.NewSource(seed)).Perm(n)
+}
+
+// Write encodes the labels in the given format
+func (l Labels) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatBinary:
+		if err := binary.Write(w, binary.LittleEndian, l.Indices); err != nil {
+			return fmt.Errorf("failed to write binary labels: %v", err)
+		}
+		return nil
+	case FormatCSV:
+		records := [][]string{{"index", "label", "class_index"}}
+		for i, name := range l.Names {
+			records = append(records, []string{strconv.Itoa(i), name, strconv.Itoa(int(l.Indices[i]))})
+		}
+		return writeCSV(w, records)
+	case FormatClassMap:
+		records := [][]string{{"class_index", "label"}}
+		for i, name := range l.ClassNames {
+			records = append(records, []string{strconv.Itoa(i), name})
+		}
+		return writeCSV(w, records)
+	default:
+		return fmt.Errorf("unknown label format %q", format)
+	}
+}
+
+// writeCSV writes all records and reports any buffered write error
+func writeCSV(w io.Writer, records [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write label CSV: %v", err)
+	}
+	return nil
+}
+
+// ReadBinary decodes a binary label array
+func ReadBinary(r io.Reader) ([]int32, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read binary labels: %v", err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("binary labels have %d bytes, not a multiple of 4", len(data))
+	}
+	indices := make([]int32, len(data)/4)
+	for i := range indices {
+		indices[i] = int32(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return indices, nil
+}
+
+// ReadCSV decodes a label CSV, checking that the index column matches each row's position
+func ReadCSV(r io.Reader) ([]string, []int32, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read label CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("label CSV is missing its header")
+	}
+
+	names := make([]string, 0, len(records)-1)
+	indices := make([]int32, 0, len(records)-1)
+	for row, record := range records[1:] {
+		if len(record) != 3 {
+			return nil, nil, fmt.Errorf("label CSV row %d has %d columns, expected 3", row, len(record))
+		}
+		index, err := strconv.Atoi(record[0])
+		if err != nil || index != row {
+			return nil, nil, fmt.Errorf("label CSV row %d has index %q, expected %d", row, record[0], row)
+		}
+		classIndex, err := strconv.ParseInt(record[2], 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("label CSV row %d has invalid class index %q: %v", row, record[2], err)
+		}
+		names = append(names, record[1])
+		indices = append(indices, int32(classIndex))
+	}
+	return names, indices, nil
+}
+
+// ReadClassMap decodes a class map CSV into class names indexed by class index
+func ReadClassMap(r io.Reader) ([]string, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read class map: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("class map is missing its header")
+	}
+
+	classNames := make([]string, 0, len(records)-1)
+	for row, record := range records[1:] {
+		if len(record) != 2 {
+			return nil, fmt.Errorf("class map row %d has %d columns, expected 2", row, len(record))
+		}
+		if index, err := strconv.Atoi(record[0]); err != nil || index != row {
+			return nil, fmt.Errorf("class map row %d has index %q, expected %d", row, record[0], row)
+		}
+		classNames = append(classNames, record[1])
+	}
+	return classNames, nil
+}
+
+// Mismatch describes an image whose class index differs between two label sets
+type Mismatch struct {
+	Index    int
+	Expected int32
+	Actual   int32
+}
+
+// Compare checks that two label arrays agree image by image. It returns an error when the
+// lengths differ and the list of mismatching positions otherwise, so callers can report label
+// disagreements separately from pixel differences.
+func Compare(expected, actual []int32) ([]Mismatch, error) {
+	if len(expected) != len(actual) {
+		return nil, fmt.Errorf("label count mismatch: expected %d, got %d", len(expected), len(actual))
+	}
+	var mismatches []Mismatch
+	for i := range expected {
+		if expected[i] != actual[i] {
+			mismatches = append(mismatches, Mismatch{Index: i, Expected: expected[i], Actual: actual[i]})
+		}
+	}
+	return mismatches, nil
+}
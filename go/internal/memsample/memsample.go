@@ -0,0 +1,151 @@
+// Package memsample samples heap and resident memory in the background while a run executes, so
+// the real peak is reported rather than a before/after difference that mostly reflects garbage
+// the collector has not reached yet.
+package memsample
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// DefaultInterval is how often a Sampler reads memory while a run executes
+const DefaultInterval = 50 * time.Millisecond
+
+// Sample is one reading of the Go heap and the process resident set size
+type Sample struct {
+	HeapAlloc uint64 // Bytes of allocated heap objects, live or not yet collected
+	HeapSys   uint64 // Bytes of heap memory obtained from the OS
+	RSS       uint64 // Resident set size of the process
+}
+
+// Stats summarizes the samples taken during one or more runs
+type Stats struct {
+	Samples       int
+	PeakHeapAlloc uint64
+	PeakHeapSys   uint64
+	PeakRSS       uint64
+
+	sumHeapAlloc float64
+	sumRSS       float64
+}
+
+// add includes one sample
+func (s *Stats) add(sample Sample) {
+	s.Samples++
+	s.PeakHeapAlloc = max(s.PeakHeapAlloc, sample.HeapAlloc)
+	s.PeakHeapSys = max(s.PeakHeapSys, sample.HeapSys)
+	s.PeakRSS = max(s.PeakRSS, sample.RSS)
+	s.sumHeapAlloc += float64(sample.HeapAlloc)
+	s.sumRSS += float64(sample.RSS)
+}
+
+// Merge combines the samples of two sets of runs: peaks are the higher of both, averages cover
+// every sample
+func (s Stats) Merge(other Stats) Stats {
+	return Stats{
+		Samples:       s.Samples + other.Samples,
+		PeakHeapAlloc: max(s.PeakHeapAlloc, other.PeakHeapAlloc),
+		PeakHeapSys:   max(s.PeakHeapSys, other.PeakHeapSys),
+		PeakRSS:       max(s.PeakRSS, other.PeakRSS),
+		sumHeapAlloc:  s.sumHeapAlloc + other.sumHeapAlloc,
+		sumRSS:        s.sumRSS + other.sumRSS,
+	}
+}
+
+// AvgHeapAlloc returns the mean HeapAlloc over the samples, 0 without samples
+func (s Stats) AvgHeapAlloc() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return s.sumHeapAlloc / float64(s.Samples)
+}
+
+// AvgRSS returns the mean resident set size over the samples, 0 without samples
+func (s Stats) AvgRSS() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return s.sumRSS / float64(s.Samples)
+}
+
+// Sampler reads memory on a background goroutine until Stop is called
+type Sampler struct {
+	read func() (Sample, error)
+	stop chan struct{}
+	done chan struct{}
+
+	mu    sync.Mutex
+	stats Stats
+	err   error
+}
+
+// Start samples the current process every interval, beginning immediately
+func Start(interval time.Duration) (*Sampler, error) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open current process: %v", err)
+	}
+	return start(interval, func() (Sample, error) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		info, err := proc.MemoryInfo()
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to read process memory: %v", err)
+		}
+		return Sample{HeapAlloc: m.HeapAlloc, HeapSys: m.HeapSys, RSS: info.RSS}, nil
+	}), nil
+}
+
+// start samples with read every interval until Stop
+func start(interval time.Duration, read func() (Sample, error)) *Sampler {
+	s := &Sampler{read: read, stop: make(chan struct{}), done: make(chan struct{})}
+	s.sample()
+	go s.loop(interval)
+	return s
+}
+
+// loop samples on a ticker until stop is closed
+func (s *Sampler) loop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+// sample takes one reading; the first error stops further readings from counting
+func (s *Sampler) sample() {
+	sample, err := s.read()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.stats.add(sample)
+}
+
+// Stop takes a final sample, waits for the sampling goroutine to exit and returns the statistics.
+// The error is the first failed reading, if any.
+func (s *Sampler) Stop() (Stats, error) {
+	close(s.stop)
+	<-s.done
+	s.sample()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats, s.err
+}
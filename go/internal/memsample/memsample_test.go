@@ -0,0 +1,88 @@
+package memsample
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatsPeaksAndAverages(t *testing.T) {
+	var s Stats
+	s.add(Sample{HeapAlloc: 10, HeapSys: 40, RSS: 100})
+	s.add(Sample{HeapAlloc: 30, HeapSys: 40, RSS: 300})
+	if s.Samples != 2 || s.PeakHeapAlloc != 30 || s.PeakRSS != 300 || s.PeakHeapSys != 40 {
+		t.Errorf("Unexpected peaks: %+v", s)
+	}
+	if s.AvgHeapAlloc() != 20 || s.AvgRSS() != 200 {
+		t.Errorf("Expected averages 20 and 200, got %v and %v", s.AvgHeapAlloc(), s.AvgRSS())
+	}
+
+	var other Stats
+	other.add(Sample{HeapAlloc: 50, RSS: 50})
+	merged := s.Merge(other)
+	if merged.Samples != 3 || merged.PeakHeapAlloc != 50 || merged.PeakRSS != 300 || merged.AvgRSS() != 150 {
+		t.Errorf("Unexpected merge: %+v, avg RSS %v", merged, merged.AvgRSS())
+	}
+	if (Stats{}).AvgRSS() != 0 {
+		t.Error("Expected a zero average without samples")
+	}
+}
+
+func TestSamplerStopsCleanly(t *testing.T) {
+	var reads atomic.Int64
+	s := start(time.Millisecond, func() (Sample, error) {
+		n := uint64(reads.Add(1))
+		return Sample{HeapAlloc: n, RSS: n}, nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	stats, err := s.Stop()
+	if err != nil {
+		t.Fatalf("Sampler failed: %v", err)
+	}
+	if stats.Samples < 2 || stats.PeakRSS != uint64(stats.Samples) {
+		t.Errorf("Expected every read to be sampled, got %+v after %d reads", stats, reads.Load())
+	}
+	// No goroutine may keep sampling after Stop
+	after := reads.Load()
+	time.Sleep(10 * time.Millisecond)
+	if reads.Load() != after {
+		t.Errorf("Sampler kept reading after Stop: %d reads, then %d", after, reads.Load())
+	}
+}
+
+func TestSamplerReportsReadErrors(t *testing.T) {
+	s := start(time.Hour, func() (Sample, error) { return Sample{}, errors.New("no procfs") })
+	if _, err := s.Stop(); err == nil {
+		t.Error("Expected the read error from Stop")
+	}
+}
+
+// allocSink keeps the allocation-heavy workload's memory reachable until it is released
+var allocSink [][]byte
+
+func TestSamplerCapturesAllocationPeak(t *testing.T) {
+	runtime.GC()
+	s, err := Start(time.Millisecond)
+	if err != nil {
+		t.Skipf("Process memory unavailable: %v", err)
+	}
+	const chunk, chunks = 1 << 20, 64
+	for i := 0; i < chunks; i++ {
+		allocSink = append(allocSink, make([]byte, chunk))
+		time.Sleep(100 * time.Microsecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	allocSink = nil
+	stats, err := s.Stop()
+	if err != nil {
+		t.Skipf("Process memory unavailable: %v", err)
+	}
+	if stats.PeakHeapAlloc < chunk*chunks {
+		t.Errorf("Expected a HeapAlloc peak of at least %d bytes, got %d", chunk*chunks, stats.PeakHeapAlloc)
+	}
+	if stats.PeakRSS == 0 || stats.AvgRSS() == 0 {
+		t.Errorf("Expected RSS samples, got %+v", stats)
+	}
+}
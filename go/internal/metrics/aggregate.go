@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"time"
+
+	"golang/internal/sysinfo"
+)
+
+// Add returns the totals of m and other, for averaging with Average. GC pauses of both runs are
+// kept, the longest pauses are summed so Average gives the mean of the per-run maxima, and the
+// memory samples are merged.
+func (m RunMetrics) Add(other RunMetrics) RunMetrics {
+	m.Elapsed += other.Elapsed
+	m.MemoryUsage += other.MemoryUsage
+	m.AllocCount += other.AllocCount
+	m.FreeCount += other.FreeCount
+	m.TotalAlloc += other.TotalAlloc
+	m.GCPause += other.GCPause
+	m.NumGC += other.NumGC
+	m.GCMaxPause += other.GCMaxPause
+	m.GCPauses = append(m.GCPauses, other.GCPauses...)
+	m.HeapFragmentation += other.HeapFragmentation
+	m.CPUUsage += other.CPUUsage
+	if len(m.PerCoreCPU) < len(other.PerCoreCPU) {
+		m.PerCoreCPU = append(m.PerCoreCPU, make([]float64, len(other.PerCoreCPU)-len(m.PerCoreCPU))...)
+	}
+	for i, p := range other.PerCoreCPU {
+		m.PerCoreCPU[i] += p
+	}
+	m.CPUTime = m.CPUTime.Add(other.CPUTime)
+	m.Memory = m.Memory.Merge(other.Memory)
+	return m
+}
+
+// Average returns the mean of totals summed with Add over runs runs. GC pauses and memory samples
+// stay as they are: every pause of every run, for percentiles, and the peaks over every run with
+// means over every sample.
+func (m RunMetrics) Average(runs int) RunMetrics {
+	if runs == 0 {
+		return RunMetrics{}
+	}
+	n := time.Duration(runs)
+	perCore := make([]float64, len(m.PerCoreCPU))
+	for i, p := range m.PerCoreCPU {
+		perCore[i] = p / float64(runs)
+	}
+	return RunMetrics{
+		Elapsed:           m.Elapsed / n,
+		MemoryUsage:       m.MemoryUsage / uint64(runs),
+		AllocCount:        m.AllocCount / uint64(runs),
+		FreeCount:         m.FreeCount / uint64(runs),
+		TotalAlloc:        m.TotalAlloc / uint64(runs),
+		GCPause:           m.GCPause / n,
+		NumGC:             (m.NumGC + uint32(runs)/2) / uint32(runs), // Rounded
+		GCMaxPause:        m.GCMaxPause / n,
+		GCPauses:          m.GCPauses,
+		HeapFragmentation: m.HeapFragmentation / float64(runs),
+		CPUUsage:          m.CPUUsage / float64(runs),
+		PerCoreCPU:        perCore,
+		CPUTime:           sysinfo.CPUTime{User: m.CPUTime.User / n, System: m.CPUTime.System / n},
+		Memory:            m.Memory,
+	}
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// The metric lines of every benchmark use the same precision, so one parser reads all their logs:
+// durations in seconds to the nanosecond, memory in MB to three decimals and CPU utilization in
+// percent to two
+
+// FormatDuration formats d in seconds with nanosecond resolution, such as "0.001234567"
+func FormatDuration(d time.Duration) string {
+	return FormatSeconds(d.Seconds())
+}
+
+// FormatSeconds formats a figure in seconds, such as a mean of durations, like FormatDuration
+func FormatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 9, 64)
+}
+
+// FormatMB formats bytes as megabytes with three decimals
+func FormatMB(bytes float64) string {
+	return strconv.FormatFloat(bytes/(1024*1024), 'f', 3, 64)
+}
+
+// FormatPercent formats a CPU utilization percentage with two decimals
+func FormatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', 2, 64)
+}
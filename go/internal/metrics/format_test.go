@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		got, expected string
+	}{
+		{FormatDuration(1234567891 * time.Nanosecond), "1.234567891"},
+		{FormatDuration(time.Nanosecond), "0.000000001"},
+		{FormatSeconds(0.5), "0.500000000"},
+		{FormatMB(1.5 * 1024 * 1024), "1.500"},
+		{FormatMB(1), "0.000"},
+		{FormatPercent(42.125), "42.12"},
+		{FormatPercent(100), "100.00"},
+	} {
+		if tc.got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, tc.got)
+		}
+	}
+}
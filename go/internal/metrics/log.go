@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"golang/internal/sysinfo"
+)
+
+// Log writes the memory, GC and CPU lines of measured run run to w, one Fprintf per line
+func Log(w io.Writer, run int, rm RunMetrics) {
+	fmt.Fprintf(w, "Memory Usage for Run %d: %s MB\n", run, FormatMB(float64(rm.MemoryUsage)))
+	fmt.Fprintf(w, "Allocations for Run %d: AllocCount %d, FreeCount %d\n", run, rm.AllocCount, rm.FreeCount)
+	LogSampledMemory(w, fmt.Sprintf("Sampled Memory for Run %d", run), rm)
+	fmt.Fprintf(w, "GC for Run %d: %d cycles, total pause %.3f ms, max pause %.3f ms\n", run,
+		rm.NumGC, rm.GCPause.Seconds()*1000, rm.GCMaxPause.Seconds()*1000)
+	fmt.Fprintf(w, "Heap Fragmentation for Run %d: %.3f (HeapInuse/HeapAlloc)\n", run, rm.HeapFragmentation)
+	fmt.Fprintf(w, "CPU Utilization for Run %d: %s%%\n", run, FormatPercent(rm.CPUUsage))
+	fmt.Fprintf(w, "CPU Utilization per Core for Run %d: %s\n", run, FormatPerCore(rm.PerCoreCPU))
+	LogCPUTime(w, fmt.Sprintf("CPU Time for Run %d", run), rm)
+}
+
+// LogSampledMemory writes the sampled peaks of rm with the heap bytes it allocated
+func LogSampledMemory(w io.Writer, prefix string, rm RunMetrics) {
+	fmt.Fprintf(w, "%s: Peak RSS %s MB, Peak HeapAlloc %s MB, TotalAlloc %s MB (mean RSS %s MB, mean HeapAlloc %s MB over %d samples)\n",
+		prefix, FormatMB(float64(rm.Memory.PeakRSS)), FormatMB(float64(rm.Memory.PeakHeapAlloc)), FormatMB(float64(rm.TotalAlloc)),
+		FormatMB(rm.Memory.AvgRSS()), FormatMB(rm.Memory.AvgHeapAlloc()), rm.Memory.Samples)
+}
+
+// LogCPUTime writes the user and system CPU time of rm over its wall time, and the parallel
+// efficiency: CPU time over wall time times runtime.NumCPU(), 100% when every core was busy
+// throughout. Nothing is written where CPU time is unsupported.
+func LogCPUTime(w io.Writer, prefix string, rm RunMetrics) {
+	if !sysinfo.CPUTimeSupported {
+		return
+	}
+	numCPU := runtime.NumCPU()
+	cpu := rm.CPUTime
+	fmt.Fprintf(w, "%s: %s seconds (user %s, system %s) over %s seconds wall-clock, parallel efficiency %s%% of %d CPUs\n",
+		prefix, FormatDuration(cpu.Total()), FormatDuration(cpu.User), FormatDuration(cpu.System), FormatDuration(rm.Elapsed),
+		FormatPercent(sysinfo.ParallelEfficiency(cpu.Total(), rm.Elapsed, numCPU)*100), numCPU)
+}
+
+// FormatPerCore formats per-core utilization percentages as "cpu0 12.50%, cpu1 3.25%, ..."
+func FormatPerCore(perCore []float64) string {
+	parts := make([]string, len(perCore))
+	for i, p := range perCore {
+		parts[i] = fmt.Sprintf("cpu%d %s%%", i, FormatPercent(p))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/sysinfo"
+)
+
+func TestLog(t *testing.T) {
+	rm := RunMetrics{
+		MemoryUsage: 3 << 20, AllocCount: 100, FreeCount: 40, TotalAlloc: 1 << 20,
+		NumGC: 2, GCPause: 1500 * time.Microsecond, GCMaxPause: time.Millisecond,
+		HeapFragmentation: 1.25, CPUUsage: 42.5, PerCoreCPU: []float64{50, 35},
+		Elapsed: 1234567891 * time.Nanosecond, CPUTime: sysinfo.CPUTime{User: time.Second},
+	}
+	var buf bytes.Buffer
+	Log(&buf, 3, rm)
+
+	// The CPU time line depends on the CPU count and platform and is covered by TestLogCPUTime
+	var lines []string
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if !strings.HasPrefix(line, "CPU Time for Run") {
+			lines = append(lines, line)
+		}
+	}
+	expected, err := os.ReadFile("testdata/log.golden")
+	if err != nil {
+		t.Fatalf("Failed to read golden log: %v", err)
+	}
+	if got := strings.Join(lines, ""); got != string(expected) {
+		t.Errorf("Log mismatch, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestLogCPUTime(t *testing.T) {
+	if !sysinfo.CPUTimeSupported {
+		t.Skip("Process CPU time is not supported on this platform")
+	}
+	var buf bytes.Buffer
+	rm := RunMetrics{
+		Elapsed: time.Duration(runtime.NumCPU()) * time.Second,
+		CPUTime: sysinfo.CPUTime{User: 1500 * time.Millisecond, System: 500 * time.Millisecond},
+	}
+	LogCPUTime(&buf, "CPU Time for Run 1", rm)
+	// 2 CPU seconds over NumCPU wall seconds on NumCPU cores
+	expected := fmt.Sprintf("CPU Time for Run 1: 2.000000000 seconds (user 1.500000000, system 0.500000000) over %d.000000000 seconds wall-clock, parallel efficiency %.2f%% of %d CPUs\n",
+		runtime.NumCPU(), 200/float64(runtime.NumCPU()*runtime.NumCPU()), runtime.NumCPU())
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestFormatPerCore(t *testing.T) {
+	if got := FormatPerCore([]float64{70, 10.125}); got != "cpu0 70.00%, cpu1 10.12%" {
+		t.Errorf("Expected \"cpu0 70.00%%, cpu1 10.12%%\", got %q", got)
+	}
+	if got := FormatPerCore(nil); got != "" {
+		t.Errorf("Expected an empty string without cores, got %q", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Package metrics collects the memory, GC and CPU figures of a measured run and writes them to the
+// metrics log, the same way for every benchmark program.
+//
+// A Collector brackets the run: BeforeRun takes a snapshot and starts sampling, AfterRun stops
+// and returns what changed in between as a RunMetrics.
+package metrics
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"golang/internal/gcaccount"
+	"golang/internal/memsample"
+	"golang/internal/sysinfo"
+)
+
+// RunMetrics holds the figures a Collector measured over one run
+type RunMetrics struct {
+	Elapsed           time.Duration   // Wall time from BeforeRun to AfterRun
+	MemoryUsage       uint64          // Change in HeapAlloc over the run
+	AllocCount        uint64          // Heap objects allocated during the run, whether or not the GC reclaimed them since
+	FreeCount         uint64          // Heap objects freed during the run
+	TotalAlloc        uint64          // Heap bytes allocated during the run
+	GCPause           time.Duration   // Stop-the-world GC pause time during the run
+	NumGC             uint32          // GC cycles completed during the run
+	GCMaxPause        time.Duration   // Longest single GC pause of the run
+	GCPauses          []time.Duration // Pause of each GC cycle of the run; the runtime keeps only the last 256
+	HeapFragmentation float64         // HeapInuse over HeapAlloc at the end of the run, 0 for an empty heap
+	CPUUsage          float64
+	PerCoreCPU        []float64
+	CPUTime           sysinfo.CPUTime // User and system time of the whole process during the run; empty when unsupported
+	Memory            memsample.Stats // Heap and RSS sampled while the run executes
+}
+
+// Collector measures one run at a time. The zero value is ready to use.
+type Collector struct {
+	start      time.Time
+	memStats   runtime.MemStats
+	cpuTime    sysinfo.CPUTime
+	cpuTimeErr error
+	coreTimes  sysinfo.CoreTimes
+	sampler    *memsample.Sampler
+}
+
+// BeforeRun snapshots the heap, the process CPU time and the time of every core and starts
+// sampling memory. Call it right before the run.
+func (c *Collector) BeforeRun() error {
+	runtime.ReadMemStats(&c.memStats)
+	coreTimes, err := sysinfo.ReadCoreTimes()
+	if err != nil {
+		return fmt.Errorf("failed to read CPU times: %v", err)
+	}
+	c.coreTimes = coreTimes
+	sampler, err := memsample.Start(memsample.DefaultInterval)
+	if err != nil {
+		return fmt.Errorf("failed to start memory sampler: %v", err)
+	}
+	c.sampler = sampler
+	// Left empty where the process CPU time cannot be read
+	c.cpuTime, c.cpuTimeErr = sysinfo.ProcessCPUTime()
+	c.start = time.Now()
+	return nil
+}
+
+// AfterRun stops sampling and returns the metrics of the run since BeforeRun
+func (c *Collector) AfterRun() (RunMetrics, error) {
+	if c.sampler == nil {
+		return RunMetrics{}, fmt.Errorf("AfterRun called without BeforeRun")
+	}
+	elapsed := time.Since(c.start)
+	// Snapshotted before anything else, so utilization covers the run and not the collection
+	coreTimes, coreTimesErr := sysinfo.ReadCoreTimes()
+	var cpuTime sysinfo.CPUTime
+	if after, err := sysinfo.ProcessCPUTime(); err == nil && c.cpuTimeErr == nil {
+		cpuTime = after.Sub(c.cpuTime)
+	}
+	memory, err := c.sampler.Stop()
+	c.sampler = nil
+	if err != nil {
+		return RunMetrics{}, fmt.Errorf("failed to sample memory: %v", err)
+	}
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	gc := gcaccount.Between(&c.memStats, &after)
+	var heapFragmentation float64
+	if after.HeapAlloc > 0 {
+		heapFragmentation = float64(after.HeapInuse) / float64(after.HeapAlloc)
+	}
+
+	if coreTimesErr != nil {
+		return RunMetrics{}, fmt.Errorf("failed to read CPU times: %v", coreTimesErr)
+	}
+	cpuUsage, err := coreTimes.UsageSince(c.coreTimes)
+	if err != nil {
+		return RunMetrics{}, fmt.Errorf("failed to calculate CPU usage: %v", err)
+	}
+
+	return RunMetrics{
+		Elapsed:           elapsed,
+		MemoryUsage:       after.Alloc - c.memStats.Alloc,
+		AllocCount:        after.Mallocs - c.memStats.Mallocs,
+		FreeCount:         after.Frees - c.memStats.Frees,
+		TotalAlloc:        after.TotalAlloc - c.memStats.TotalAlloc,
+		GCPause:           gc.PauseTotal,
+		NumGC:             gc.NumGC,
+		GCMaxPause:        gc.MaxPause(),
+		GCPauses:          gc.Pauses,
+		HeapFragmentation: heapFragmentation,
+		CPUUsage:          cpuUsage.Aggregate,
+		PerCoreCPU:        cpuUsage.PerCore,
+		CPUTime:           cpuTime,
+		Memory:            memory,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"golang/internal/sysinfo"
+)
+
+// sink keeps the allocations of TestCollector reachable
+var sink [][]byte
+
+func TestCollector(t *testing.T) {
+	var c Collector
+	if err := c.BeforeRun(); err != nil {
+		t.Fatalf("Failed to start collecting: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		sink = append(sink, make([]byte, 1024))
+	}
+	time.Sleep(5 * time.Millisecond)
+	rm, err := c.AfterRun()
+	if err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	sink = nil
+
+	if rm.AllocCount < 1000 || rm.TotalAlloc < 1000*1024 {
+		t.Errorf("Expected at least 1000 allocations of 1 KB, got %d totalling %d bytes", rm.AllocCount, rm.TotalAlloc)
+	}
+	if rm.Elapsed < 5*time.Millisecond {
+		t.Errorf("Expected an elapsed time of at least 5ms, got %v", rm.Elapsed)
+	}
+	if rm.HeapFragmentation <= 0 {
+		t.Errorf("Expected a positive heap fragmentation ratio, got %v", rm.HeapFragmentation)
+	}
+	if rm.CPUUsage < 0 || rm.CPUUsage > 100 || len(rm.PerCoreCPU) == 0 {
+		t.Errorf("Expected CPU usage within [0, 100] for every core, got %v over %v", rm.CPUUsage, rm.PerCoreCPU)
+	}
+	if sysinfo.CPUTimeSupported && rm.CPUTime.Total() <= 0 {
+		t.Errorf("Expected CPU time for the run, got %+v", rm.CPUTime)
+	}
+
+	if _, err := c.AfterRun(); err == nil {
+		t.Errorf("Expected an error for AfterRun without BeforeRun")
+	}
+}
+
+func TestAddAndAverage(t *testing.T) {
+	first := RunMetrics{
+		MemoryUsage: 2 << 20, AllocCount: 100, NumGC: 1, GCPause: time.Millisecond, GCMaxPause: time.Millisecond,
+		GCPauses: []time.Duration{time.Millisecond}, CPUUsage: 30, PerCoreCPU: []float64{50, 10},
+		CPUTime: sysinfo.CPUTime{User: time.Second}, HeapFragmentation: 1.2,
+	}
+	second := RunMetrics{
+		AllocCount: 200, NumGC: 2, GCPause: 4 * time.Millisecond, GCMaxPause: 3 * time.Millisecond,
+		GCPauses: []time.Duration{time.Millisecond, 3 * time.Millisecond}, CPUUsage: 50, PerCoreCPU: []float64{90, 10, 20},
+		CPUTime: sysinfo.CPUTime{User: 3 * time.Second, System: 2 * time.Second}, HeapFragmentation: 1.4,
+	}
+
+	avg := RunMetrics{}.Add(first).Add(second).Average(2)
+	if avg.MemoryUsage != 1<<20 || avg.AllocCount != 150 || avg.CPUUsage != 40 {
+		t.Errorf("Expected 1 MB, 150 allocations and 40%% CPU, got %d, %d and %v", avg.MemoryUsage, avg.AllocCount, avg.CPUUsage)
+	}
+	// 3 cycles over 2 runs round up; the max pause is the mean of the per-run maxima
+	if avg.NumGC != 2 || avg.GCMaxPause != 2*time.Millisecond || len(avg.GCPauses) != 3 {
+		t.Errorf("Expected 2 cycles, 2ms max pause and all 3 pauses, got %d, %v and %v", avg.NumGC, avg.GCMaxPause, avg.GCPauses)
+	}
+	if len(avg.PerCoreCPU) != 3 || avg.PerCoreCPU[0] != 70 || avg.PerCoreCPU[2] != 10 {
+		t.Errorf("Expected per-core means [70 10 10], got %v", avg.PerCoreCPU)
+	}
+	if avg.CPUTime.User != 2*time.Second || avg.CPUTime.System != time.Second {
+		t.Errorf("Expected 2s user and 1s system, got %+v", avg.CPUTime)
+	}
+	if avg.HeapFragmentation < 1.299 || avg.HeapFragmentation > 1.301 {
+		t.Errorf("Expected a mean fragmentation of 1.3, got %v", avg.HeapFragmentation)
+	}
+
+	if zero := first.Average(0); zero.AllocCount != 0 || zero.PerCoreCPU != nil {
+		t.Errorf("Expected empty metrics for no runs, got %+v", zero)
+	}
+}
+
+func TestCollectorCPUUsageCoversTheRun(t *testing.T) {
+	var c Collector
+	if err := c.BeforeRun(); err != nil {
+		t.Fatalf("Failed to start collecting: %v", err)
+	}
+	// Keep one core busy for the whole run
+	for start := time.Now(); time.Since(start) < 200*time.Millisecond; {
+	}
+	rm, err := c.AfterRun()
+	if err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	if busyCores := rm.CPUUsage / 100 * float64(len(rm.PerCoreCPU)); busyCores < 0.5 {
+		t.Errorf("Expected at least half a core busy over a spinning run, got %.2f cores (%.2f%% over %v)", busyCores, rm.CPUUsage, rm.PerCoreCPU)
+	}
+}
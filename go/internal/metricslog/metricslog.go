@@ -0,0 +1,116 @@
+// Package metricslog writes the timestamped metrics log the benchmark programs append their
+// measurements to.
+package metricslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Logger writes timestamped metric lines to a log file that stays open for the whole run.
+// Writes are buffered so file I/O does not interleave with the measurement loop; the first
+// write error is kept and returned from every later call and from Close.
+type Logger struct {
+	file    *os.File
+	writer  *bufio.Writer
+	logger  *log.Logger
+	written bool
+	err     error
+}
+
+// New opens filePath for appending, creating it if needed
+func New(filePath string) (*Logger, error) {
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %v", filePath, err)
+	}
+
+	writer := bufio.NewWriter(file)
+	return &Logger{
+		file:   file,
+		writer: writer,
+		logger: log.New(writer, "", log.LstdFlags),
+	}, nil
+}
+
+// NewStream writes metric lines to w instead of a file, for modes where the log
+// goes to the terminal; Close flushes but leaves w open
+func NewStream(w io.Writer) *Logger {
+	writer := bufio.NewWriter(w)
+	return &Logger{
+		writer: writer,
+		logger: log.New(writer, "", log.LstdFlags),
+	}
+}
+
+// Printf formats a message and appends it as one log line. The first call is flushed
+// immediately so an unwritable log fails fast instead of at the end of the run.
+func (m *Logger) Printf(format string, args ...interface{}) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	if err := m.logger.Output(2, fmt.Sprintln(fmt.Sprintf(format, args...))); err != nil {
+		m.err = fmt.Errorf("failed to write to log file: %v", err)
+		return m.err
+	}
+
+	if !m.written {
+		m.written = true
+		return m.Flush()
+	}
+	return nil
+}
+
+// Write logs p as one line per newline-terminated line, so metric lines can be written to the
+// logger as an io.Writer
+func (m *Logger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		if err := m.Printf("%s", line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered lines to the file
+func (m *Logger) Flush() error {
+	if m.err != nil {
+		return m.err
+	}
+	if err := m.writer.Flush(); err != nil {
+		m.err = fmt.Errorf("failed to flush log file: %v", err)
+	}
+	return m.err
+}
+
+// Err returns the first error encountered while writing, if any
+func (m *Logger) Err() error {
+	return m.err
+}
+
+// Close flushes buffered lines and closes the file, returning the first error seen during the run
+func (m *Logger) Close() error {
+	m.Flush()
+	if m.file == nil {
+		return m.err
+	}
+	if err := m.file.Close(); err != nil && m.err == nil {
+		m.err = fmt.Errorf("failed to close log file: %v", err)
+	}
+	return m.err
+}
+
+// Fatalf closes the logger, so the lines buffered so far reach the file, and then exits like
+// log.Fatalf. Fatal errors after the log is open go through it: log.Fatalf alone exits without
+// running deferred calls and loses the buffered lines.
+func (m *Logger) Fatalf(format string, args ...interface{}) {
+	if err := m.Close(); err != nil {
+		log.Printf("Error writing metrics log: %v", err)
+	}
+	log.Fatalf(format, args...)
+}
@@ -0,0 +1,167 @@
+package metricslog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+
+	if err := logger.Printf("Run %d/%d...", 1, 2); err != nil {
+		t.Fatalf("Failed to write first line: %v", err)
+	}
+	if err := logger.Printf("Execution Time for Run %d: %.2f seconds", 1, 0.5); err != nil {
+		t.Fatalf("Failed to write second line: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	data, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %q", len(lines), string(data))
+	}
+	if !strings.HasSuffix(lines[0], "Run 1/2...") || !strings.HasSuffix(lines[1], "Execution Time for Run 1: 0.50 seconds") {
+		t.Errorf("Log content mismatch: %q", string(data))
+	}
+}
+
+func TestLoggerWrite(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStream(&buf)
+	n, err := fmt.Fprintf(logger, "GC for Run %d: 2 cycles\nCPU Utilization for Run %d: 42.50%%\n", 1, 1)
+	if err != nil || n != len("GC for Run 1: 2 cycles\nCPU Utilization for Run 1: 42.50%\n") {
+		t.Fatalf("Failed to write: %d bytes, %v", n, err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "GC for Run 1: 2 cycles") || !strings.HasSuffix(lines[1], "CPU Utilization for Run 1: 42.50%") {
+		t.Errorf("Expected one timestamped line per written line, got %q", buf.String())
+	}
+}
+
+func TestLoggerFirstWriteFlushes(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+
+	logger.Printf("first line")
+	data, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "first line") {
+		t.Errorf("Expected the first line to be flushed before Close")
+	}
+}
+
+func TestLoggerUnwritableDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatalf("Failed to make directory read-only: %v", err)
+	}
+	defer os.Chmod(dir, 0755)
+
+	logFilePath := filepath.Join(dir, "metrics.log")
+	if os.Geteuid() == 0 {
+		// Root ignores directory permissions, so place the log under a regular file instead
+		blocker := filepath.Join(t.TempDir(), "not-a-directory")
+		if err := os.WriteFile(blocker, nil, 0644); err != nil {
+			t.Fatalf("Failed to create blocking file: %v", err)
+		}
+		logFilePath = filepath.Join(blocker, "metrics.log")
+	}
+
+	logger, err := New(logFilePath)
+	if err == nil {
+		logger.Close()
+		t.Fatalf("Expected an error opening a log file in an unwritable directory")
+	}
+	if !strings.Contains(err.Error(), "metrics.log") {
+		t.Errorf("Error should name the log file: %v", err)
+	}
+}
+
+func TestLoggerKeepsFirstError(t *testing.T) {
+	logger, err := New(filepath.Join(t.TempDir(), "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+
+	// Closing the file underneath the logger makes every later write fail
+	logger.file.Close()
+	first := logger.Printf("lost line")
+	if first == nil {
+		t.Fatalf("Expected the first write after closing the file to fail")
+	}
+	if err := logger.Printf("another lost line"); err != first {
+		t.Errorf("Expected later writes to return the first error, got %v", err)
+	}
+	if err := logger.Close(); err != first {
+		t.Errorf("Expected Close to return the first error, got %v", err)
+	}
+}
+
+func TestStreamLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStream(&buf)
+	logger.Printf("first line")
+	logger.Printf("second line")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close stream logger: %v", err)
+	}
+	if !strings.Contains(buf.String(), "first line") || !strings.Contains(buf.String(), "second line") {
+		t.Errorf("Expected both lines in the stream, got %q", buf.String())
+	}
+}
+
+func TestFatalfFlushesBufferedLines(t *testing.T) {
+	if logFilePath := os.Getenv("METRICSLOG_FATAL_PATH"); logFilePath != "" {
+		logger, err := New(logFilePath)
+		if err != nil {
+			t.Fatalf("Failed to open metrics logger: %v", err)
+		}
+		logger.Printf("first line")
+		logger.Printf("buffered line")
+		logger.Fatalf("fatal error")
+		return
+	}
+
+	// Fatalf exits the process, so it runs in a child test process
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalfFlushesBufferedLines$")
+	cmd.Env = append(os.Environ(), "METRICSLOG_FATAL_PATH="+logFilePath)
+	output, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("Expected Fatalf to exit with an error, got %v:\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "fatal error") {
+		t.Errorf("Expected the fatal message on stderr, got %q", output)
+	}
+	data, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "buffered line") {
+		t.Errorf("Expected Fatalf to flush the buffered line, got %q", data)
+	}
+}
@@ -0,0 +1,125 @@
+// Package monitor exposes the progress of a running benchmark over HTTP as Prometheus gauges, so
+// long sessions can be watched from a dashboard instead of the log
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Snapshot is the benchmark progress at one point in time
+type Snapshot struct {
+	Run                     int     // Number of measured runs completed, across all seeds and phases
+	LastExecutionSeconds    float64 // Execution time of the last measured run
+	AverageExecutionSeconds float64 // Mean execution time of the measured runs so far
+	MemoryBytes             uint64  // Heap bytes allocated at the last sample
+	CPUPercent              float64 // System-wide CPU utilization at the last sample
+}
+
+// Monitor holds the latest Snapshot. The run loop and the background sampler update it from
+// different goroutines, so every access goes through the mutex.
+type Monitor struct {
+	mu           sync.Mutex
+	snapshot     Snapshot
+	totalSeconds float64
+}
+
+// New returns a Monitor with no runs recorded
+func New() *Monitor {
+	return &Monitor{}
+}
+
+// RecordRun records the completion of a measured run and the heap in use after it
+func (m *Monitor) RecordRun(executionTime time.Duration) {
+	memory := heapAlloc()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.totalSeconds += executionTime.Seconds()
+	m.snapshot.Run++
+	m.snapshot.LastExecutionSeconds = executionTime.Seconds()
+	m.snapshot.AverageExecutionSeconds = m.totalSeconds / float64(m.snapshot.Run)
+	m.snapshot.MemoryBytes = memory
+}
+
+// setSample stores the latest CPU and memory sample
+func (m *Monitor) setSample(cpuPercent float64, memory uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshot.CPUPercent = cpuPercent
+	m.snapshot.MemoryBytes = memory
+}
+
+// Snapshot returns a copy of the current progress
+func (m *Monitor) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot
+}
+
+// Sample records CPU utilization and heap usage immediately and then every interval until ctx
+// is done. cpuPercent returns the utilization since its previous call; a failed sample keeps the
+// previous value.
+func (m *Monitor) Sample(ctx context.Context, interval time.Duration, cpuPercent func() (float64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cpu, err := cpuPercent()
+		if err != nil {
+			cpu = m.Snapshot().CPUPercent
+		}
+		m.setSample(cpu, heapAlloc())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// heapAlloc returns the bytes of allocated heap objects
+func heapAlloc() uint64 {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.Alloc
+}
+
+// ServeHTTP writes the current snapshot in the Prometheus text exposition format
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := m.Snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	gauges := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"benchmark_run_index", "Number of measured runs completed.", float64(s.Run)},
+		{"benchmark_last_run_execution_seconds", "Execution time of the last measured run.", s.LastExecutionSeconds},
+		{"benchmark_average_execution_seconds", "Mean execution time of the measured runs so far.", s.AverageExecutionSeconds},
+		{"benchmark_memory_bytes", "Heap bytes allocated at the last sample.", float64(s.MemoryBytes)},
+		{"benchmark_cpu_utilization_percent", "System-wide CPU utilization at the last sample.", s.CPUPercent},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.value)
+	}
+}
+
+// Listen serves m on addr at /metrics in the background. It returns once the listener is bound,
+// with the server to shut down and the bound address, which differs from addr for port 0.
+func Listen(addr string, m *Monitor) (*http.Server, net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return server, listener.Addr(), nil
+}
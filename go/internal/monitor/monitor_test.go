@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordRun(t *testing.T) {
+	m := New()
+	m.RecordRun(time.Second)
+	m.RecordRun(3 * time.Second)
+
+	s := m.Snapshot()
+	if s.Run != 2 {
+		t.Errorf("Expected run index 2, got %d", s.Run)
+	}
+	if s.LastExecutionSeconds != 3 {
+		t.Errorf("Expected last execution 3s, got %v", s.LastExecutionSeconds)
+	}
+	if s.AverageExecutionSeconds != 2 {
+		t.Errorf("Expected average execution 2s, got %v", s.AverageExecutionSeconds)
+	}
+	if s.MemoryBytes == 0 {
+		t.Errorf("Expected the heap in use to be recorded")
+	}
+}
+
+func TestSample(t *testing.T) {
+	m := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.Sample(ctx, time.Millisecond, func() (float64, error) { return 42, nil })
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Snapshot().CPUPercent != 42 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Sampler never recorded the CPU utilization")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+}
+
+// parseMetrics returns the sample values of a Prometheus text exposition
+func parseMetrics(t *testing.T, body string) map[string]string {
+	t.Helper()
+	values := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			t.Fatalf("Malformed metric line %q", line)
+		}
+		values[fields[0]] = fields[1]
+	}
+	return values
+}
+
+func TestListen(t *testing.T) {
+	m := New()
+	m.RecordRun(500 * time.Millisecond)
+	m.setSample(12.5, 2048)
+
+	server, addr, err := Listen("127.0.0.1:0", m)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer server.Close()
+
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to fetch metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+
+	values := parseMetrics(t, string(body))
+	expected := map[string]string{
+		"benchmark_run_index":                  "1",
+		"benchmark_last_run_execution_seconds": "0.5",
+		"benchmark_average_execution_seconds":  "0.5",
+		"benchmark_memory_bytes":               "2048",
+		"benchmark_cpu_utilization_percent":    "12.5",
+	}
+	for name, want := range expected {
+		if got := values[name]; got != want {
+			t.Errorf("Expected %s %s, got %q", name, want, got)
+		}
+	}
+}
+
+func TestListenInvalidAddress(t *testing.T) {
+	if _, _, err := Listen("not-an-address", New()); err == nil {
+		t.Errorf("Expected an error for an invalid address")
+	}
+}
@@ -0,0 +1,101 @@
+package result
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// MetricRecord is one measured run as written to a CSV row
+type MetricRecord struct {
+	Dataset             string
+	Run                 int // 1-based index of the measured run
+	NumWorkers          int // Goroutines the run was split across
+	BatchSize           int // Images per goroutine, 0 when the run was not split into batches
+	ExecutionTime       time.Duration
+	ConcurrencyOverhead time.Duration
+	MemoryMB            float64
+	CPUPercent          float64
+	GCPause             time.Duration // Stop-the-world pause time summed over the run's GC cycles
+	NumGC               uint32        // GC cycles completed during the run
+	GCMaxPause          time.Duration // Longest single GC pause of the run
+}
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{
+	"dataset", "run", "num_workers", "batch_size", "execution_time_seconds", "concurrency_overhead_seconds",
+	"memory_mb", "cpu_percent", "gc_pause_ns", "num_gc", "gc_max_pause_ns", "execution_time_ns", "concurrency_overhead_ns",
+}
+
+// WriteCSV writes a header row and one row per record to path, replacing any existing file
+func WriteCSV(path string, records []MetricRecord) error {
+	rows := make([][]string, len(records))
+	for i, r := range records {
+		rows[i] = []string{
+			r.Dataset,
+			strconv.Itoa(r.Run),
+			strconv.Itoa(r.NumWorkers),
+			strconv.Itoa(r.BatchSize),
+			strconv.FormatFloat(r.ExecutionTime.Seconds(), 'f', -1, 64),
+			strconv.FormatFloat(r.ConcurrencyOverhead.Seconds(), 'f', -1, 64),
+			strconv.FormatFloat(r.MemoryMB, 'f', -1, 64),
+			strconv.FormatFloat(r.CPUPercent, 'f', -1, 64),
+			strconv.FormatInt(r.GCPause.Nanoseconds(), 10),
+			strconv.FormatUint(uint64(r.NumGC), 10),
+			strconv.FormatInt(r.GCMaxPause.Nanoseconds(), 10),
+			strconv.FormatInt(r.ExecutionTime.Nanoseconds(), 10),
+			strconv.FormatInt(r.ConcurrencyOverhead.Nanoseconds(), 10),
+		}
+	}
+	return writeCSVFile(path, csvHeader, rows)
+}
+
+// BatchTimingRecord is the processing time of one batch of a measured run
+type BatchTimingRecord struct {
+	Dataset  string
+	Run      int // 1-based index of the measured run
+	Batch    int // 0-based index of the batch within the run
+	Images   int
+	Duration time.Duration
+}
+
+// batchTimingsHeader names the columns written by WriteBatchTimingsCSV
+var batchTimingsHeader = []string{"dataset", "run", "batch", "images", "duration_ns"}
+
+// WriteBatchTimingsCSV writes a header row and one row per batch to path, replacing any existing
+// file
+func WriteBatchTimingsCSV(path string, records []BatchTimingRecord) error {
+	rows := make([][]string, len(records))
+	for i, r := range records {
+		rows[i] = []string{
+			r.Dataset,
+			strconv.Itoa(r.Run),
+			strconv.Itoa(r.Batch),
+			strconv.Itoa(r.Images),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+		}
+	}
+	return writeCSVFile(path, batchTimingsHeader, rows)
+}
+
+// writeCSVFile writes header and rows to path, replacing any existing file
+func writeCSVFile(path string, header []string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file %s: %v", path, err)
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write CSV file %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close CSV file %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package result
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	records := []MetricRecord{
+		{Dataset: "cifar10-train", Run: 1, NumWorkers: 100, BatchSize: 500, ExecutionTime: 1500 * time.Millisecond,
+			ConcurrencyOverhead: 1600 * time.Millisecond, MemoryMB: 0.25, CPUPercent: 87.5, GCPause: 1200 * time.Microsecond,
+			NumGC: 3, GCMaxPause: 700 * time.Microsecond},
+		{Dataset: "cifar10-train", Run: 2, NumWorkers: 100, ExecutionTime: time.Second},
+	}
+	if err := WriteCSV(path, records); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open CSV: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d rows", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Errorf("Header mismatch: %v", rows[0])
+	}
+	expected := []string{"cifar10-train", "1", "100", "500", "1.5", "1.6", "0.25", "87.5", "1200000", "3", "700000", "1500000000", "1600000000"}
+	if !reflect.DeepEqual(rows[1], expected) {
+		t.Errorf("Row mismatch: expected %v, got %v", expected, rows[1])
+	}
+}
+
+func TestWriteBatchTimingsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batches.csv")
+	records := []BatchTimingRecord{
+		{Dataset: "cifar10-train", Run: 1, Batch: 0, Images: 32, Duration: 1500 * time.Microsecond},
+		{Dataset: "cifar10-train", Run: 1, Batch: 1, Images: 32, Duration: 15 * time.Millisecond},
+	}
+	if err := WriteBatchTimingsCSV(path, records); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open CSV: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	expected := [][]string{
+		batchTimingsHeader,
+		{"cifar10-train", "1", "0", "32", "1500000"},
+		{"cifar10-train", "1", "1", "32", "15000000"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %v, got %v", expected, rows)
+	}
+}
+
+func TestWriteCSVUnwritablePath(t *testing.T) {
+	// A path below a regular file can never be created, even as root
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(parent, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := WriteCSV(filepath.Join(parent, "results.csv"), nil); err == nil {
+		t.Errorf("Expected an error for an unwritable path")
+	}
+}
@@ -0,0 +1,50 @@
+package result
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// InfluxMeasurement is the measurement every run is written under by WriteInflux
+const InfluxMeasurement = "go_benchmark"
+
+// influxEscaper escapes the characters that delimit tags and fields in line protocol
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// WriteInflux writes one InfluxDB line protocol point per run of record. Each point is tagged
+// with the benchmark as dataset, the split and the 1-based run index, which keeps runs that share
+// the record's timestamp distinct. Durations are in seconds.
+func WriteInflux(w io.Writer, record Record, runs []Run) error {
+	bw := bufio.NewWriter(w)
+	timestamp := record.Timestamp.UnixNano()
+	for i, r := range runs {
+		fmt.Fprintf(bw, "%s,dataset=%s,run=%d,split=%s ", InfluxMeasurement,
+			influxEscaper.Replace(record.Benchmark), i+1, influxEscaper.Replace(record.Dataset.Split))
+		fields := []struct {
+			key   string
+			value float64
+		}{
+			{"execution_time", r.ExecutionSeconds},
+			{"concurrency_overhead", r.ConcurrencyOverheadSeconds},
+			{"goroutine_spawn", r.GoroutineSpawnSeconds},
+			{"memory_mb", float64(r.MemoryBytes) / (1024 * 1024)},
+			{"cpu_percent", r.CPUPercent},
+			{"images_per_second", r.ImagesPerSecond},
+			{"megapixels_per_second", r.MegapixelsPerSecond},
+		}
+		for j, f := range fields {
+			if j > 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteString(f.key + "=" + strconv.FormatFloat(f.value, 'f', -1, 64))
+		}
+		fmt.Fprintf(bw, " %d\n", timestamp)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write line protocol: %v", err)
+	}
+	return nil
+}
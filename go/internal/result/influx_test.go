@@ -0,0 +1,111 @@
+package result
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// influxPoint is a point parsed back from line protocol
+type influxPoint struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]float64
+	timestamp   int64
+}
+
+// splitUnescaped splits s at every sep that is not escaped with a backslash
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseInfluxLine parses one line of float-only line protocol, the subset WriteInflux emits
+func parseInfluxLine(line string) (influxPoint, error) {
+	sections := splitUnescaped(line, ' ')
+	if len(sections) != 3 {
+		return influxPoint{}, fmt.Errorf("expected measurement, fields and timestamp, got %d sections", len(sections))
+	}
+	unescape := strings.NewReplacer(`\,`, ",", `\=`, "=", `\ `, " ")
+	series := splitUnescaped(sections[0], ',')
+	p := influxPoint{measurement: unescape.Replace(series[0]), tags: map[string]string{}, fields: map[string]float64{}}
+	for _, tag := range series[1:] {
+		kv := splitUnescaped(tag, '=')
+		if len(kv) != 2 {
+			return influxPoint{}, fmt.Errorf("malformed tag %q", tag)
+		}
+		p.tags[unescape.Replace(kv[0])] = unescape.Replace(kv[1])
+	}
+	for _, field := range splitUnescaped(sections[1], ',') {
+		kv := splitUnescaped(field, '=')
+		if len(kv) != 2 {
+			return influxPoint{}, fmt.Errorf("malformed field %q", field)
+		}
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return influxPoint{}, fmt.Errorf("field %q: %v", field, err)
+		}
+		p.fields[unescape.Replace(kv[0])] = value
+	}
+	timestamp, err := strconv.ParseInt(sections[2], 10, 64)
+	if err != nil {
+		return influxPoint{}, fmt.Errorf("timestamp: %v", err)
+	}
+	p.timestamp = timestamp
+	return p, nil
+}
+
+func TestWriteInflux(t *testing.T) {
+	record := Record{
+		Metadata: Metadata{Benchmark: "cifar10", Timestamp: time.Unix(1234567890, 0)},
+		Dataset:  Dataset{Split: "test set, v2"},
+	}
+	runs := []Run{
+		{ExecutionSeconds: 0.123, MemoryBytes: 45 * 1024 * 1024, CPUPercent: 87.5},
+		{ExecutionSeconds: 0.25, ImagesPerSecond: 4000},
+	}
+	var buf bytes.Buffer
+	if err := WriteInflux(&buf, record, runs); err != nil {
+		t.Fatalf("WriteInflux failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(runs) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(runs), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		p, err := parseInfluxLine(line)
+		if err != nil {
+			t.Fatalf("Failed to parse line %q: %v", line, err)
+		}
+		if p.measurement != InfluxMeasurement {
+			t.Errorf("Expected measurement %s, got %s", InfluxMeasurement, p.measurement)
+		}
+		if p.tags["dataset"] != "cifar10" || p.tags["split"] != "test set, v2" || p.tags["run"] != strconv.Itoa(i+1) {
+			t.Errorf("Unexpected tags on line %d: %v", i+1, p.tags)
+		}
+		if p.fields["execution_time"] != runs[i].ExecutionSeconds {
+			t.Errorf("Expected execution_time %v, got %v", runs[i].ExecutionSeconds, p.fields["execution_time"])
+		}
+		if p.timestamp != 1234567890000000000 {
+			t.Errorf("Expected timestamp 1234567890000000000, got %d", p.timestamp)
+		}
+	}
+	if first, _ := parseInfluxLine(lines[0]); first.fields["memory_mb"] != 45 || first.fields["cpu_percent"] != 87.5 {
+		t.Errorf("Unexpected fields on the first line: %v", first.fields)
+	}
+}
@@ -0,0 +1,149 @@
+// Package result defines the machine-readable record a benchmark run is reported as
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"golang/internal/sysinfo"
+)
+
+// Metadata identifies the benchmark and the host and toolchain that produced a record
+type Metadata struct {
+	Benchmark        string
+	Timestamp        time.Time
+	Hostname         string
+	GoVersion        string
+	GOOS             string
+	GOARCH           string
+	NumCPU           int
+	GOMAXPROCS       int
+	CPUModel         string
+	TotalMemoryBytes uint64 // Physical memory of the host, 0 when unknown
+}
+
+// Dataset describes the images a record was measured on
+type Dataset struct {
+	Split  string
+	Images int
+	SHA256 string `json:",omitempty"` // Checksum of the loaded pixels from datahash.SHA256, set with -hash-dataset
+}
+
+// Run holds the metrics of a measured run, with durations in seconds and CPU in percent. The
+// Nanos fields repeat the durations in whole nanoseconds, free of floating-point rounding
+type Run struct {
+	ExecutionSeconds           float64
+	ConcurrencyOverheadSeconds float64
+	GoroutineSpawnSeconds      float64
+	ExecutionNanos             int64
+	ConcurrencyOverheadNanos   int64
+	GoroutineSpawnNanos        int64
+	ImagesProcessed            int
+	PixelsProcessed            int
+	MemoryBytes                uint64
+	CPUPercent                 float64
+	PerCoreCPUPercent          []float64
+	ImagesPerSecond            float64
+	MegapixelsPerSecond        float64
+	NumGC                      float64 // GC cycles per run
+	GCPauseSeconds             float64 // Stop-the-world GC pause time per run
+	GCMaxPauseSeconds          float64 // Longest single GC pause, averaged over the runs
+	GCPauseNanos               int64
+	GCMaxPauseNanos            int64
+	Collectors                 map[string]float64 `json:",omitempty"` // Custom collector metrics keyed "collector.metric"
+}
+
+// LoadReport counts the filesystem incidents met while loading the dataset
+type LoadReport struct {
+	RetryBudget     int // Retries allowed per filesystem operation
+	Retries         int
+	Incidents       int
+	TransientErrors int
+	PermanentErrors int
+	Recovered       int // Incidents resolved by a retry
+	Skipped         int // Entries left out of the dataset
+}
+
+// MemoryPlan records the decoded dataset size estimated before an eager load and the decision
+// taken from it, so the record of a downgraded load is not mistaken for one of the whole dataset
+type MemoryPlan struct {
+	Files          int
+	EstimatedBytes uint64
+	AvailableBytes uint64
+	Outcome        string  // "fits", "marginal" or "does not fit"
+	Downgraded     bool    // Whether SampleFraction was lowered to make the dataset fit
+	SampleFraction float64 `json:",omitempty"` // Fraction of every class loaded, 0 when all of it was
+	Suggestion     string  `json:",omitempty"`
+}
+
+// Record is a self-contained result: who ran what, with which configuration, and the outcome
+type Record struct {
+	Metadata
+	Config  interface{}
+	Dataset Dataset
+	Load    *LoadReport `json:",omitempty"` // Set by benchmarks that read the dataset through a retrying walker
+	Memory  *MemoryPlan `json:",omitempty"` // Set by benchmarks that estimate the dataset's memory before loading it
+	Warmup  int
+	Runs    int
+	Run     Run
+}
+
+// SweepPoint holds the averages of the measured runs at one GOMAXPROCS setting of a sweep
+type SweepPoint struct {
+	GOMAXPROCS int
+	Runs       int
+	Run        Run
+	Speedup    float64 // Average execution time at the baseline setting divided by the one at this setting
+	Efficiency float64 // Speedup divided by the ratio of this setting to the baseline setting
+}
+
+// Sweep is the record of a -maxprocs-sweep: the same benchmark repeated at several GOMAXPROCS
+// settings, with scaling relative to Baseline, ready to plot
+type Sweep struct {
+	Metadata
+	Config   interface{}
+	Dataset  Dataset
+	Warmup   int
+	Baseline int // GOMAXPROCS setting the speedups are relative to
+	Points   []SweepPoint
+}
+
+// NewMetadata describes the current process for the named benchmark
+func NewMetadata(benchmark string) Metadata {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return Metadata{
+		Benchmark:        benchmark,
+		Timestamp:        time.Now().UTC(),
+		Hostname:         hostname,
+		GoVersion:        runtime.Version(),
+		GOOS:             runtime.GOOS,
+		GOARCH:           runtime.GOARCH,
+		NumCPU:           runtime.NumCPU(),
+		GOMAXPROCS:       runtime.GOMAXPROCS(0),
+		CPUModel:         sysinfo.CPUModel(),
+		TotalMemoryBytes: sysinfo.TotalMemory(),
+	}
+}
+
+// Write encodes record as a single JSON document followed by a newline
+func Write(w io.Writer, record Record) error {
+	if err := json.NewEncoder(w).Encode(record); err != nil {
+		return fmt.Errorf("failed to write result record: %v", err)
+	}
+	return nil
+}
+
+// WriteSweep encodes sweep as a single JSON document followed by a newline
+func WriteSweep(w io.Writer, sweep Sweep) error {
+	if err := json.NewEncoder(w).Encode(sweep); err != nil {
+		return fmt.Errorf("failed to write sweep record: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package result
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	record := Record{
+		Metadata: NewMetadata("cifar10"),
+		Config:   map[string]int{"BatchSize": 500},
+		Dataset:  Dataset{Split: "train", Images: 1000},
+		Warmup:   1,
+		Runs:     1,
+		Run:      Run{ExecutionSeconds: 0.5, ImagesProcessed: 1000, PerCoreCPUPercent: []float64{10, 20}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, record); err != nil {
+		t.Fatalf("Failed to write record: %v", err)
+	}
+	if bytes.Count(buf.Bytes(), []byte("\n")) != 1 {
+		t.Errorf("Expected a single line, got %q", buf.String())
+	}
+
+	var decoded struct {
+		Benchmark string
+		GoVersion string
+		NumCPU    int
+		Config    map[string]int
+		Dataset   Dataset
+		Run       Run
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	if decoded.Benchmark != "cifar10" || decoded.GoVersion != runtime.Version() || decoded.NumCPU != runtime.NumCPU() {
+		t.Errorf("Metadata mismatch: %+v", decoded)
+	}
+	if decoded.Config["BatchSize"] != 500 || decoded.Dataset.Images != 1000 {
+		t.Errorf("Config or dataset mismatch: %+v", decoded)
+	}
+	if decoded.Run.ExecutionSeconds != 0.5 || len(decoded.Run.PerCoreCPUPercent) != 2 {
+		t.Errorf("Run mismatch: %+v", decoded.Run)
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	metadata := NewMetadata("tinyimagenet")
+	if metadata.CPUModel == "" || metadata.Hostname == "" || metadata.Timestamp.IsZero() {
+		t.Errorf("Expected the host to be described, got %+v", metadata)
+	}
+	record := Record{Metadata: metadata, Dataset: Dataset{Split: "train", Images: 2, SHA256: "ab12"}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, record); err != nil {
+		t.Fatalf("Failed to write record: %v", err)
+	}
+	var decoded Record
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	if !decoded.Timestamp.Equal(metadata.Timestamp) {
+		t.Errorf("Expected timestamp %v, got %v", metadata.Timestamp, decoded.Timestamp)
+	}
+	decoded.Timestamp = metadata.Timestamp
+	if decoded.Metadata != metadata {
+		t.Errorf("Metadata mismatch: expected %+v, got %+v", metadata, decoded.Metadata)
+	}
+	if decoded.Dataset.SHA256 != "ab12" {
+		t.Errorf("Expected the dataset checksum ab12, got %q", decoded.Dataset.SHA256)
+	}
+
+	// Without -hash-dataset the checksum is left out of the JSON altogether
+	buf.Reset()
+	record.Dataset.SHA256 = ""
+	if err := Write(&buf, record); err != nil {
+		t.Fatalf("Failed to write record: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("SHA256")) {
+		t.Errorf("Expected no SHA256 field without a checksum, got %s", buf.String())
+	}
+}
@@ -0,0 +1,94 @@
+// Package stats summarizes per-run benchmark samples into distribution statistics
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes the distribution of a set of samples
+type Summary struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+	P95    float64
+	P99    float64
+	StdDev float64 // Sample standard deviation, 0 for fewer than two samples
+	CV     float64 // Coefficient of variation, StdDev / Mean, 0 when the mean is 0
+}
+
+// Summarize computes the distribution statistics of samples without modifying it
+func Summarize(samples []float64) Summary {
+	if len(samples) == 0 {
+		return Summary{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, s := range sorted {
+		sum += s
+	}
+	mean := sum / float64(len(sorted))
+
+	var stddev float64
+	if len(sorted) > 1 {
+		var squares float64
+		for _, s := range sorted {
+			squares += (s - mean) * (s - mean)
+		}
+		stddev = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+
+	summary := Summary{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		Median: Percentile(sorted, 50),
+		P95:    Percentile(sorted, 95),
+		P99:    Percentile(sorted, 99),
+		StdDev: stddev,
+	}
+	if mean != 0 {
+		summary.CV = stddev / math.Abs(mean)
+	}
+	return summary
+}
+
+// CoefficientOfVariation returns the sample standard deviation of values relative to their mean,
+// a dimensionless measure of spread: 0.1 means the values typically stray 10% from the mean. It
+// is 0 for fewer than two values or a zero mean.
+func CoefficientOfVariation(values []float64) float64 {
+	return Summarize(values).CV
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted, interpolating linearly between
+// the two closest ranks. sorted must be in ascending order.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*fraction
+}
+
+// Imbalance returns Max / Mean, how much longer the slowest sample took than the average one. A
+// perfectly balanced set gives 1, an empty set or a zero mean gives 0.
+func (s Summary) Imbalance() float64 {
+	if s.Mean == 0 {
+		return 0
+	}
+	return s.Max / s.Mean
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// almostEqual compares floats with a tolerance for accumulated rounding
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPercentile(t *testing.T) {
+	cases := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"odd median", []float64{1, 2, 3, 4, 5}, 50, 3},
+		{"even median", []float64{1, 2, 3, 4}, 50, 2.5},
+		{"single sample", []float64{7}, 99, 7},
+		{"p95 interpolated", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, 95, 10.5},
+		{"p99 of 1..101", sequence(101), 99, 100},
+		{"minimum", []float64{3, 4}, 0, 3},
+		{"maximum", []float64{3, 4}, 100, 4},
+		{"empty", nil, 50, 0},
+	}
+	for _, c := range cases {
+		if got := Percentile(c.sorted, c.p); !almostEqual(got, c.want) {
+			t.Errorf("%s: expected p%v = %v, got %v", c.name, c.p, c.want, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	samples := []float64{4, 2, 8, 6}
+	s := Summarize(samples)
+
+	if s.Count != 4 || s.Min != 2 || s.Max != 8 || s.Mean != 5 || s.Median != 5 {
+		t.Errorf("Unexpected summary: %+v", s)
+	}
+	// Sample variance of 2, 4, 6, 8 is 20/3
+	if !almostEqual(s.StdDev, math.Sqrt(20.0/3)) {
+		t.Errorf("Expected standard deviation %v, got %v", math.Sqrt(20.0/3), s.StdDev)
+	}
+	if !almostEqual(s.CV, s.StdDev/5) {
+		t.Errorf("Expected CV %v, got %v", s.StdDev/5, s.CV)
+	}
+	if samples[0] != 4 {
+		t.Errorf("Summarize reordered its input: %v", samples)
+	}
+}
+
+func TestSummarizeSingleSample(t *testing.T) {
+	s := Summarize([]float64{3})
+	if s.Min != 3 || s.Max != 3 || s.Median != 3 || s.P95 != 3 || s.P99 != 3 {
+		t.Errorf("Expected every statistic to be the sample, got %+v", s)
+	}
+	if s.StdDev != 0 || s.CV != 0 {
+		t.Errorf("Expected no spread for a single sample, got %+v", s)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	if s := Summarize(nil); s != (Summary{}) {
+		t.Errorf("Expected an empty summary, got %+v", s)
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	if cv := CoefficientOfVariation([]float64{2.5, 2.5, 2.5, 2.5}); cv != 0 {
+		t.Errorf("Expected CV 0 for identical values, got %v", cv)
+	}
+	if cv := CoefficientOfVariation([]float64{4, 2, 8, 6}); !almostEqual(cv, math.Sqrt(20.0/3)/5) {
+		t.Errorf("Expected CV %v, got %v", math.Sqrt(20.0/3)/5, cv)
+	}
+	if cv := CoefficientOfVariation(nil); cv != 0 {
+		t.Errorf("Expected CV 0 without values, got %v", cv)
+	}
+}
+
+func TestImbalance(t *testing.T) {
+	if got := Summarize([]float64{4, 2, 8, 6}).Imbalance(); got != 1.6 {
+		t.Errorf("Expected imbalance 1.6, got %v", got)
+	}
+	if got := Summarize([]float64{3, 3, 3}).Imbalance(); got != 1 {
+		t.Errorf("Expected imbalance 1 for equal samples, got %v", got)
+	}
+	if got := Summarize(nil).Imbalance(); got != 0 {
+		t.Errorf("Expected imbalance 0 without samples, got %v", got)
+	}
+}
+
+// sequence returns 1..n as floats
+func sequence(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+	return values
+}
@@ -0,0 +1,84 @@
+// Package subset cuts a loaded dataset down for quick iteration, by truncating it or by sampling
+// a fraction of every class
+package subset
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Apply keeps the first limit images and labels, then a random fraction of those with Sample,
+// in load order. A limit or fraction of 0 skips that step, as does a limit at or above the
+// number of images. Labels that do not line up with images, such as none at all, are sampled
+// as a single class and returned unchanged.
+func Apply[L comparable](images [][]float32, labels []L, limit int, fraction float64, seed int64) ([][]float32, []L) {
+	labelled := len(labels) == len(images)
+	if limit > 0 && limit < len(images) {
+		images = images[:limit]
+		if labelled {
+			labels = labels[:limit]
+		}
+	}
+	if fraction <= 0 || fraction >= 1 {
+		return images, labels
+	}
+
+	classes := labels
+	if !labelled {
+		classes = make([]L, len(images))
+	}
+	kept := Sample(classes, fraction, seed)
+	sampledImages := make([][]float32, len(kept))
+	for i, index := range kept {
+		sampledImages[i] = images[index]
+	}
+	if !labelled {
+		return sampledImages, labels
+	}
+	sampledLabels := make([]L, len(kept))
+	for i, index := range kept {
+		sampledLabels[i] = labels[index]
+	}
+	return sampledImages, sampledLabels
+}
+
+// Sample returns the indices of a random fraction of every class in labels, in ascending order,
+// so the class balance of the sample matches the whole. Each class keeps its size times fraction
+// rounded to the nearest image, but at least one. The same seed draws the same sample. A
+// fraction outside (0, 1) keeps every index.
+func Sample[L comparable](labels []L, fraction float64, seed int64) []int {
+	if fraction <= 0 || fraction >= 1 {
+		kept := make([]int, len(labels))
+		for i := range kept {
+			kept[i] = i
+		}
+		return kept
+	}
+
+	// Classes are drawn from in order of first appearance, so the sample does not depend on map
+	// iteration order
+	var order []L
+	members := make(map[L][]int)
+	for i, label := range labels {
+		if _, seen := members[label]; !seen {
+			order = append(order, label)
+		}
+		members[label] = append(members[label], i)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	var kept []int
+	for _, label := range order {
+		indices := members[label]
+		n := int(math.Round(float64(len(indices)) * fraction))
+		if n < 1 {
+			n = 1
+		}
+		for _, j := range rng.Perm(len(indices))[:n] {
+			kept = append(kept, indices[j])
+		}
+	}
+	sort.Ints(kept)
+	return kept
+}
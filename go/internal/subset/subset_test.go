@@ -0,0 +1,86 @@
+package subset
+
+import (
+	"reflect"
+	"testing"
+)
+
+// dataset returns n single-pixel images holding their index, labelled with classes in turn
+func dataset(n int, classes []string) ([][]float32, []string) {
+	images := make([][]float32, n)
+	labels := make([]string, n)
+	for i := range images {
+		images[i] = []float32{float32(i)}
+		labels[i] = classes[i%len(classes)]
+	}
+	return images, labels
+}
+
+func TestApplyLimit(t *testing.T) {
+	images, labels := dataset(10, []string{"cat", "dog"})
+
+	limited, limitedLabels := Apply(images, labels, 4, 0, 1)
+	if len(limited) != 4 || len(limitedLabels) != 4 || limited[3][0] != 3 {
+		t.Errorf("Expected the first 4 images, got %v", limited)
+	}
+
+	// A limit beyond the dataset keeps everything
+	all, allLabels := Apply(images, labels, 50, 0, 1)
+	if len(all) != 10 || len(allLabels) != 10 {
+		t.Errorf("Expected all 10 images with a limit of 50, got %d", len(all))
+	}
+}
+
+func TestApplyFractionBounds(t *testing.T) {
+	images, labels := dataset(10, []string{"cat", "dog"})
+	for _, fraction := range []float64{0, 1} {
+		kept, keptLabels := Apply(images, labels, 0, fraction, 1)
+		if !reflect.DeepEqual(kept, images) || !reflect.DeepEqual(keptLabels, labels) {
+			t.Errorf("Expected every image in order for a fraction of %v, got %v", fraction, kept)
+		}
+	}
+}
+
+func TestApplyFractionIsStratified(t *testing.T) {
+	// 30 cats and 10 dogs
+	images, labels := dataset(40, []string{"cat", "cat", "cat", "dog"})
+	kept, keptLabels := Apply(images, labels, 0, 0.5, 7)
+
+	counts := map[string]int{}
+	for i, label := range keptLabels {
+		counts[label]++
+		if labels[int(kept[i][0])] != label {
+			t.Errorf("Image %v lost its label %q", kept[i][0], label)
+		}
+		if i > 0 && kept[i][0] <= kept[i-1][0] {
+			t.Errorf("Expected images in load order, got %v after %v", kept[i][0], kept[i-1][0])
+		}
+	}
+	if counts["cat"] != 15 || counts["dog"] != 5 {
+		t.Errorf("Expected 15 cats and 5 dogs, got %v", counts)
+	}
+
+	// Every class keeps at least one image
+	if _, keptLabels := Apply(images, labels, 0, 0.01, 7); len(keptLabels) != 2 {
+		t.Errorf("Expected one image of each class, got %v", keptLabels)
+	}
+}
+
+func TestSampleIsDeterministic(t *testing.T) {
+	_, labels := dataset(1000, []string{"a", "b", "c"})
+	first := Sample(labels, 0.1, 42)
+	if second := Sample(labels, 0.1, 42); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same sample for the same seed, got %v and %v", first, second)
+	}
+	if other := Sample(labels, 0.1, 43); reflect.DeepEqual(first, other) {
+		t.Errorf("Expected a different sample for another seed, got %v for both", first)
+	}
+}
+
+func TestApplyWithoutLabels(t *testing.T) {
+	images, _ := dataset(20, []string{"x"})
+	kept, labels := Apply[int](images, nil, 0, 0.25, 1)
+	if len(kept) != 5 || labels != nil {
+		t.Errorf("Expected 5 unlabelled images, got %d images and labels %v", len(kept), labels)
+	}
+}
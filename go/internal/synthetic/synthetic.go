@@ -0,0 +1,42 @@
+// Package synthetic generates deterministic image data so the benchmarks can run
+// without downloading the real datasets.
+package synthetic
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// NumClasses is the number of distinct labels assigned to generated images
+const NumClasses = 10
+
+// GenerateSyntheticDataset returns n images of height x width x channels pixels in [0, 1),
+// drawn from a generator seeded with seed so the same arguments always yield the same data.
+// Labels cycle through the class indices 0 to 9, like the integer labels of CIFAR-10.
+func GenerateSyntheticDataset(n, height, width, channels int, seed int64) ([][]float32, []int) {
+	rng := rand.New(rand.NewSource(seed))
+	imageSize := height * width * channels
+
+	images := make([][]float32, n)
+	labels := make([]int, n)
+	for i := 0; i < n; i++ {
+		image := make([]float32, imageSize)
+		for j := range image {
+			image[j] = rng.Float32()
+		}
+		images[i] = image
+		labels[i] = i % NumClasses
+	}
+	return images, labels
+}
+
+// GenerateSyntheticImages returns the images of GenerateSyntheticDataset with the labels as
+// decimal strings "0" to "9", like the wnid directory labels of Tiny ImageNet
+func GenerateSyntheticImages(n, height, width, channels int, seed int64) ([][]float32, []string) {
+	images, classes := GenerateSyntheticDataset(n, height, width, channels, seed)
+	labels := make([]string, n)
+	for i, class := range classes {
+		labels[i] = strconv.Itoa(class)
+	}
+	return images, labels
+}
@@ -0,0 +1,63 @@
+package synthetic
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestGenerateSyntheticImagesShape(t *testing.T) {
+	images, labels := GenerateSyntheticImages(25, 4, 3, 2, 1)
+	if len(images) != 25 || len(labels) != 25 {
+		t.Fatalf("Expected 25 images and labels, got %d and %d", len(images), len(labels))
+	}
+
+	for i, img := range images {
+		if len(img) != 4*3*2 {
+			t.Errorf("Image %d size mismatch: expected %d, got %d", i, 4*3*2, len(img))
+		}
+		for j, val := range img {
+			if val < 0 || val >= 1 {
+				t.Errorf("Image %d pixel %d out of range: %.4f", i, j, val)
+			}
+		}
+		if labels[i] != strconv.Itoa(i%NumClasses) {
+			t.Errorf("Image %d label mismatch: expected %d, got %s", i, i%NumClasses, labels[i])
+		}
+	}
+}
+
+func TestGenerateSyntheticImagesDeterministic(t *testing.T) {
+	first, _ := GenerateSyntheticImages(5, 8, 8, 3, 42)
+	second, _ := GenerateSyntheticImages(5, 8, 8, 3, 42)
+	other, _ := GenerateSyntheticImages(5, 8, 8, 3, 43)
+
+	differs := false
+	for i := range first {
+		for j := range first[i] {
+			if first[i][j] != second[i][j] {
+				t.Fatalf("Image %d pixel %d differs between runs with the same seed", i, j)
+			}
+			if first[i][j] != other[i][j] {
+				differs = true
+			}
+		}
+	}
+	if !differs {
+		t.Errorf("Expected different seeds to produce different pixels")
+	}
+}
+
+func TestGenerateSyntheticDatasetMatchesImages(t *testing.T) {
+	images, labels := GenerateSyntheticDataset(12, 4, 4, 3, 7)
+	named, names := GenerateSyntheticImages(12, 4, 4, 3, 7)
+	for i := range images {
+		if labels[i] != i%NumClasses || names[i] != strconv.Itoa(labels[i]) {
+			t.Errorf("Image %d: expected label %d, got %d and %q", i, i%NumClasses, labels[i], names[i])
+		}
+		for j := range images[i] {
+			if images[i][j] != named[i][j] {
+				t.Fatalf("Image %d pixel %d differs between the int and string label generators", i, j)
+			}
+		}
+	}
+}
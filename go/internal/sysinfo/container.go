@@ -0,0 +1,160 @@
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Paths, relative to the filesystem root, that reveal a container and its CPU quota. cgroup v1
+// splits the quota over two files, cgroup v2 keeps "<quota> <period>" in cpu.max.
+const (
+	cgroupPath      = "proc/self/cgroup"
+	dockerEnvPath   = ".dockerenv"
+	cfsQuotaPath    = "sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cfsPeriodPath   = "sys/fs/cgroup/cpu/cpu.cfs_period_us"
+	cgroupV2MaxPath = "sys/fs/cgroup/cpu.max"
+)
+
+// containerMarkers are substrings of /proc/self/cgroup paths set up by container runtimes
+var containerMarkers = []string{"docker", "kubepods", "containerd", "libpod", "lxc"}
+
+// DetectContainerCPULimit reports whether the process runs in a container (Docker, Kubernetes
+// and other cgroup-based runtimes) and the number of CPUs its CFS quota allows, which
+// runtime.NumCPU does not see. cpuLimit is 0 when no quota is set.
+func DetectContainerCPULimit() (cpuLimit float64, isContainer bool, err error) {
+	return detectContainerCPULimit("/")
+}
+
+// detectContainerCPULimit is DetectContainerCPULimit reading the files below root
+func detectContainerCPULimit(root string) (float64, bool, error) {
+	isContainer, err := inContainer(root)
+	if err != nil {
+		return 0, false, err
+	}
+	cpuLimit, err := cfsCPULimit(root)
+	if err != nil {
+		return 0, isContainer, err
+	}
+	return cpuLimit, isContainer, nil
+}
+
+// inContainer looks for the /.dockerenv marker and for container runtime names in the cgroup
+// paths of the process. Hosts without /proc/self/cgroup are not containers.
+func inContainer(root string) (bool, error) {
+	if _, err := os.Stat(filepath.Join(root, dockerEnvPath)); err == nil {
+		return true, nil
+	}
+	file, err := os.Open(filepath.Join(root, cgroupPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open /%s: %v", cgroupPath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		for _, marker := range containerMarkers {
+			if strings.Contains(scanner.Text(), marker) {
+				return true, nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read /%s: %v", cgroupPath, err)
+	}
+	return false, nil
+}
+
+// cfsCPULimit returns quota / period from the cgroup v1 CFS files, falling back to the cgroup
+// v2 cpu.max. A quota of -1 or "max", or no cgroup CPU files at all, means no limit and gives 0.
+func cfsCPULimit(root string) (float64, error) {
+	quota, err := os.ReadFile(filepath.Join(root, cfsQuotaPath))
+	if err == nil {
+		period, err := os.ReadFile(filepath.Join(root, cfsPeriodPath))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read /%s: %v", cfsPeriodPath, err)
+		}
+		return parseCPUQuota(string(quota), string(period))
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to read /%s: %v", cfsQuotaPath, err)
+	}
+
+	cpuMax, err := os.ReadFile(filepath.Join(root, cgroupV2MaxPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read /%s: %v", cgroupV2MaxPath, err)
+	}
+	quotaField, periodField, found := strings.Cut(strings.TrimSpace(string(cpuMax)), " ")
+	if !found {
+		return 0, fmt.Errorf("unexpected /%s contents %q", cgroupV2MaxPath, cpuMax)
+	}
+	return parseCPUQuota(quotaField, periodField)
+}
+
+// parseCPUQuota divides a CFS quota by its period, both in microseconds
+func parseCPUQuota(quota, period string) (float64, error) {
+	quota, period = strings.TrimSpace(quota), strings.TrimSpace(period)
+	if quota == "max" || quota == "-1" {
+		return 0, nil
+	}
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU quota %q: %v", quota, err)
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("invalid CPU period %q", period)
+	}
+	return q / p, nil
+}
+
+// LimitedGOMAXPROCS returns the GOMAXPROCS setting matching a CPU limit: the whole number of
+// CPUs it allows, at least 1, and no more than current. A cpuLimit of 0 keeps current.
+func LimitedGOMAXPROCS(cpuLimit float64, current int) int {
+	if cpuLimit <= 0 {
+		return current
+	}
+	procs := max(1, int(cpuLimit))
+	return min(procs, current)
+}
+
+// ApplyContainerCPULimit lowers GOMAXPROCS to the CPU limit found by DetectContainerCPULimit,
+// unless GOMAXPROCS is set in the environment, and returns a line describing the outcome for
+// the log
+func ApplyContainerCPULimit() string {
+	cpuLimit, isContainer, err := DetectContainerCPULimit()
+	return applyCPULimit(cpuLimit, isContainer, err, os.Getenv("GOMAXPROCS") != "")
+}
+
+// applyCPULimit is ApplyContainerCPULimit for an already detected limit
+func applyCPULimit(cpuLimit float64, isContainer bool, err error, fromEnv bool) string {
+	current := runtime.GOMAXPROCS(0)
+	if err != nil {
+		return fmt.Sprintf("Container CPU Limit: unknown (%v), GOMAXPROCS %d", err, current)
+	}
+	environment := "no container detected"
+	if isContainer {
+		environment = "container detected"
+	}
+	switch {
+	case cpuLimit <= 0:
+		return fmt.Sprintf("Container CPU Limit: none (%s), GOMAXPROCS %d", environment, current)
+	case fromEnv:
+		return fmt.Sprintf("Container CPU Limit: %.2f CPUs (%s), GOMAXPROCS left at %d as set in the environment", cpuLimit, environment, current)
+	}
+	procs := LimitedGOMAXPROCS(cpuLimit, current)
+	runtime.GOMAXPROCS(procs)
+	return fmt.Sprintf("Container CPU Limit: %.2f CPUs (%s), GOMAXPROCS adjusted from %d to %d", cpuLimit, environment, current, procs)
+}
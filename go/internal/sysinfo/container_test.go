@@ -0,0 +1,135 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeRootFiles creates a fake filesystem root holding the given files, keyed by path below root
+func writeRootFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", full, err)
+		}
+	}
+	return root
+}
+
+func TestDetectContainerCPULimitCgroupV1(t *testing.T) {
+	root := writeRootFiles(t, map[string]string{
+		cgroupPath:    "12:cpu,cpuacct:/kubepods/burstable/pod1234/abcd\n0::/\n",
+		cfsQuotaPath:  "250000\n",
+		cfsPeriodPath: "100000\n",
+	})
+	cpuLimit, isContainer, err := detectContainerCPULimit(root)
+	if err != nil {
+		t.Fatalf("Failed to detect CPU limit: %v", err)
+	}
+	if !isContainer {
+		t.Errorf("Expected a kubepods cgroup to be detected as a container")
+	}
+	if cpuLimit != 2.5 {
+		t.Errorf("Expected a limit of 2.5 CPUs, got %v", cpuLimit)
+	}
+}
+
+func TestDetectContainerCPULimitCgroupV2(t *testing.T) {
+	root := writeRootFiles(t, map[string]string{
+		dockerEnvPath:   "",
+		cgroupPath:      "0::/\n",
+		cgroupV2MaxPath: "150000 100000\n",
+	})
+	cpuLimit, isContainer, err := detectContainerCPULimit(root)
+	if err != nil {
+		t.Fatalf("Failed to detect CPU limit: %v", err)
+	}
+	if !isContainer || cpuLimit != 1.5 {
+		t.Errorf("Expected a container limited to 1.5 CPUs, got %v CPUs, container %v", cpuLimit, isContainer)
+	}
+}
+
+func TestDetectContainerCPULimitUnlimited(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"v1 quota -1":  {cgroupPath: "4:cpu:/docker/abcd\n", cfsQuotaPath: "-1\n", cfsPeriodPath: "100000\n"},
+		"v2 quota max": {cgroupPath: "0::/user.slice\n", cgroupV2MaxPath: "max 100000\n"},
+		"no cgroups":   {},
+	} {
+		cpuLimit, _, err := detectContainerCPULimit(writeRootFiles(t, files))
+		if err != nil {
+			t.Errorf("%s: failed to detect CPU limit: %v", name, err)
+		}
+		if cpuLimit != 0 {
+			t.Errorf("%s: expected no limit, got %v CPUs", name, cpuLimit)
+		}
+	}
+}
+
+func TestDetectContainerCPULimitHost(t *testing.T) {
+	root := writeRootFiles(t, map[string]string{cgroupPath: "0::/user.slice/user-1000.slice/session-2.scope\n"})
+	_, isContainer, err := detectContainerCPULimit(root)
+	if err != nil {
+		t.Fatalf("Failed to detect CPU limit: %v", err)
+	}
+	if isContainer {
+		t.Errorf("Expected a user session cgroup not to be detected as a container")
+	}
+}
+
+func TestDetectContainerCPULimitInvalid(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"bad quota":      {cfsQuotaPath: "lots\n", cfsPeriodPath: "100000\n"},
+		"missing period": {cfsQuotaPath: "100000\n"},
+		"zero period":    {cgroupV2MaxPath: "100000 0\n"},
+		"one field":      {cgroupV2MaxPath: "100000\n"},
+	} {
+		if _, _, err := detectContainerCPULimit(writeRootFiles(t, files)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLimitedGOMAXPROCS(t *testing.T) {
+	for _, c := range []struct {
+		cpuLimit float64
+		current  int
+		expected int
+	}{
+		{0, 8, 8},
+		{2.5, 8, 2},
+		{0.5, 8, 1},
+		{16, 8, 8},
+	} {
+		if got := LimitedGOMAXPROCS(c.cpuLimit, c.current); got != c.expected {
+			t.Errorf("LimitedGOMAXPROCS(%v, %d): expected %d, got %d", c.cpuLimit, c.current, c.expected, got)
+		}
+	}
+}
+
+func TestApplyCPULimit(t *testing.T) {
+	previous := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(previous)
+	runtime.GOMAXPROCS(4)
+
+	if got := applyCPULimit(2, true, nil, true); !strings.Contains(got, "GOMAXPROCS left at 4") || runtime.GOMAXPROCS(0) != 4 {
+		t.Errorf("Expected GOMAXPROCS set in the environment to be kept, got %q", got)
+	}
+	if got := applyCPULimit(0, false, nil, false); !strings.Contains(got, "none (no container detected), GOMAXPROCS 4") {
+		t.Errorf("Expected no limit to be reported, got %q", got)
+	}
+	got := applyCPULimit(2, true, nil, false)
+	if !strings.Contains(got, "2.00 CPUs (container detected), GOMAXPROCS adjusted from 4 to 2") {
+		t.Errorf("Expected the adjustment to be reported, got %q", got)
+	}
+	if runtime.GOMAXPROCS(0) != 2 {
+		t.Errorf("Expected GOMAXPROCS 2, got %d", runtime.GOMAXPROCS(0))
+	}
+}
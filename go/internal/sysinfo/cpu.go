@@ -0,0 +1,88 @@
+package sysinfo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+)
+
+// CPUProfile holds CPU utilization over a processing window, both overall and for each core.
+// Every value is a percentage from 0 to 100, ready to log without rescaling.
+type CPUProfile struct {
+	Aggregate float64
+	PerCore   []float64
+}
+
+// CoreTimes is a snapshot of the cumulative busy and idle time of every core
+type CoreTimes []cpu.TimesStat
+
+// ReadCoreTimes snapshots the time every core has spent busy and idle since boot
+func ReadCoreTimes() (CoreTimes, error) {
+	times, err := cpu.Times(true)
+	if err != nil {
+		return nil, err
+	}
+	return CoreTimes(times), nil
+}
+
+// UsageSince returns the CPU utilization of each core between the earlier snapshot and t. The
+// aggregate is the mean over the cores, so a workload pinned to one core shows up as a single hot
+// entry. A core whose counters did not advance, as over a window shorter than the kernel's clock
+// tick, reads as idle.
+func (t CoreTimes) UsageSince(earlier CoreTimes) (CPUProfile, error) {
+	if len(t) != len(earlier) {
+		return CPUProfile{}, fmt.Errorf("core count changed from %d to %d", len(earlier), len(t))
+	}
+	percentages := make([]float64, len(t))
+	for i := range t {
+		total := t[i].Total() - earlier[i].Total()
+		busy := total - (t[i].Idle - earlier[i].Idle)
+		if total > 0 {
+			percentages[i] = busy / total * 100
+		}
+	}
+	return newCPUProfile(percentages)
+}
+
+// CPUUsage measures CPU utilization per core over the next duration, as UsageSince does between
+// snapshots taken at either end of it
+func CPUUsage(duration time.Duration) (CPUProfile, error) {
+	before, err := ReadCoreTimes()
+	if err != nil {
+		return CPUProfile{}, err
+	}
+	time.Sleep(duration)
+	after, err := ReadCoreTimes()
+	if err != nil {
+		return CPUProfile{}, err
+	}
+	return after.UsageSince(before)
+}
+
+// newCPUProfile builds a profile from per-core percentages, clamping
+// each to [0, 100] since counter rounding can push an idle or saturated core just past the bounds
+func newCPUProfile(percentages []float64) (CPUProfile, error) {
+	if len(percentages) == 0 {
+		return CPUProfile{}, fmt.Errorf("no per-core CPU usage reported")
+	}
+
+	perCore := make([]float64, len(percentages))
+	var total float64
+	for i, p := range percentages {
+		perCore[i] = clampPercent(p)
+		total += perCore[i]
+	}
+	return CPUProfile{Aggregate: total / float64(len(perCore)), PerCore: perCore}, nil
+}
+
+// clampPercent limits p to the range [0, 100]
+func clampPercent(p float64) float64 {
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
@@ -0,0 +1,62 @@
+package sysinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewCPUProfile(t *testing.T) {
+	profile, err := newCPUProfile([]float64{100.4, 50, -0.1, 25})
+	if err != nil {
+		t.Fatalf("Failed to build CPU profile: %v", err)
+	}
+	expected := []float64{100, 50, 0, 25}
+	for i, p := range profile.PerCore {
+		if p != expected[i] {
+			t.Errorf("Core %d: expected %.2f%%, got %.2f%%", i, expected[i], p)
+		}
+	}
+	if profile.Aggregate != 43.75 {
+		t.Errorf("Expected aggregate 43.75%%, got %.2f%%", profile.Aggregate)
+	}
+
+	if _, err := newCPUProfile(nil); err == nil {
+		t.Error("Expected an error when no cores are reported")
+	}
+}
+
+func TestCPUUsage(t *testing.T) {
+	profile, err := CPUUsage(100 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to measure CPU usage: %v", err)
+	}
+	if profile.Aggregate < 0 || profile.Aggregate > 100 {
+		t.Errorf("Expected aggregate within [0, 100], got %.2f%%", profile.Aggregate)
+	}
+	if len(profile.PerCore) == 0 {
+		t.Fatal("Expected per-core CPU usage")
+	}
+}
+
+func TestCoreTimesUsageSince(t *testing.T) {
+	before := CoreTimes{{User: 10, Idle: 90}, {User: 5, System: 5, Idle: 90}, {User: 1, Idle: 9}}
+	after := CoreTimes{{User: 20, Idle: 90}, {User: 8, System: 7, Idle: 95}, {User: 1, Idle: 9}}
+	profile, err := after.UsageSince(before)
+	if err != nil {
+		t.Fatalf("Failed to compute CPU usage: %v", err)
+	}
+	// Busy for all 10s of the first core's window, half of the second's, and the third did not advance
+	expected := []float64{100, 50, 0}
+	for i, p := range profile.PerCore {
+		if p != expected[i] {
+			t.Errorf("Core %d: expected %.2f%%, got %.2f%%", i, expected[i], p)
+		}
+	}
+	if profile.Aggregate != 50 {
+		t.Errorf("Expected aggregate 50%%, got %.2f%%", profile.Aggregate)
+	}
+
+	if _, err := after.UsageSince(before[:2]); err == nil {
+		t.Error("Expected an error when the core count changes")
+	}
+}
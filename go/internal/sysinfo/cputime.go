@@ -0,0 +1,34 @@
+package sysinfo
+
+import "time"
+
+// CPUTime is the CPU time a process has used, split into user and kernel time
+type CPUTime struct {
+	User   time.Duration
+	System time.Duration
+}
+
+// Total returns the user and system time together
+func (t CPUTime) Total() time.Duration {
+	return t.User + t.System
+}
+
+// Sub returns the CPU time used between earlier and t
+func (t CPUTime) Sub(earlier CPUTime) CPUTime {
+	return CPUTime{User: t.User - earlier.User, System: t.System - earlier.System}
+}
+
+// Add returns the CPU time of t and other together
+func (t CPUTime) Add(other CPUTime) CPUTime {
+	return CPUTime{User: t.User + other.User, System: t.System + other.System}
+}
+
+// ParallelEfficiency returns the share of the capacity of numCPU cores over wall that cpu used:
+// 1 when every core was busy for the whole window, 1/numCPU for a single busy core. It returns 0
+// for an empty window.
+func ParallelEfficiency(cpu, wall time.Duration, numCPU int) float64 {
+	if wall <= 0 || numCPU <= 0 {
+		return 0
+	}
+	return cpu.Seconds() / (wall.Seconds() * float64(numCPU))
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package sysinfo
+
+import "fmt"
+
+// CPUTimeSupported reports whether ProcessCPUTime can read the CPU time of the process here
+const CPUTimeSupported = false
+
+// ProcessCPUTime is unavailable without getrusage
+func ProcessCPUTime() (CPUTime, error) {
+	return CPUTime{}, fmt.Errorf("process CPU time is not supported on this platform")
+}
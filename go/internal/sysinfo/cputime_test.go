@@ -0,0 +1,52 @@
+package sysinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProcessCPUTime(t *testing.T) {
+	if !CPUTimeSupported {
+		t.Skip("Process CPU time is not supported on this platform")
+	}
+	before, err := ProcessCPUTime()
+	if err != nil {
+		t.Fatalf("Failed to read CPU time: %v", err)
+	}
+	// Spin long enough for the kernel's accounting to register it
+	x := 1.0
+	for start := time.Now(); time.Since(start) < 50*time.Millisecond; {
+		x = x*1.0000001 + 1e-9
+	}
+	after, err := ProcessCPUTime()
+	if err != nil {
+		t.Fatalf("Failed to read CPU time: %v", err)
+	}
+	used := after.Sub(before)
+	if used.User <= 0 || used.Total() < used.User {
+		t.Errorf("Expected user time from the spin loop, got %+v (x=%v)", used, x)
+	}
+}
+
+func TestParallelEfficiency(t *testing.T) {
+	for _, tc := range []struct {
+		cpu, wall time.Duration
+		numCPU    int
+		expected  float64
+	}{
+		{4 * time.Second, time.Second, 4, 1},
+		{time.Second, time.Second, 4, 0.25},
+		{3 * time.Second, 2 * time.Second, 3, 0.5},
+		{time.Second, 0, 4, 0},
+		{time.Second, time.Second, 0, 0},
+	} {
+		if got := ParallelEfficiency(tc.cpu, tc.wall, tc.numCPU); got != tc.expected {
+			t.Errorf("ParallelEfficiency(%v, %v, %d): expected %v, got %v", tc.cpu, tc.wall, tc.numCPU, tc.expected, got)
+		}
+	}
+
+	sum := CPUTime{User: time.Second, System: 2 * time.Second}.Add(CPUTime{User: 3 * time.Second})
+	if sum.Total() != 6*time.Second || sum.Sub(CPUTime{System: time.Second}).System != time.Second {
+		t.Errorf("Expected 4s user and 2s system, got %+v", sum)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package sysinfo
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// CPUTimeSupported reports whether ProcessCPUTime can read the CPU time of the process here
+const CPUTimeSupported = true
+
+// ProcessCPUTime returns the user and system CPU time this process has used so far, summed over
+// all of its threads, as reported by getrusage
+func ProcessCPUTime() (CPUTime, error) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return CPUTime{}, fmt.Errorf("failed to read resource usage: %v", err)
+	}
+	return CPUTime{
+		User:   time.Duration(usage.Utime.Nano()),
+		System: time.Duration(usage.Stime.Nano()),
+	}, nil
+}
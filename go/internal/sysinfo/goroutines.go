@@ -0,0 +1,40 @@
+package sysinfo
+
+import (
+	"sync"
+	"time"
+)
+
+// GoroutineRateSamples is the number of goroutines GoroutineCreationRate is given at startup,
+// enough to average out scheduler noise in a few tens of milliseconds
+const GoroutineRateSamples = 100000
+
+// GoroutineCreationRate starts n goroutines that each do one channel receive and exit, and returns
+// the goroutines created, run and retired per second. A benchmark that spawns one goroutine per
+// batch cannot start batches faster than this.
+func GoroutineCreationRate(n int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	// Buffered with a token per goroutine, so no receive blocks and none of them outlive its work
+	work := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		work <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		go func() {
+			<-work
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
@@ -0,0 +1,32 @@
+package sysinfo
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGoroutineCreationRate(t *testing.T) {
+	before := runtime.NumGoroutine()
+	rate := GoroutineCreationRate(1000)
+	if rate <= 0 {
+		t.Errorf("Expected a positive rate, got %v", rate)
+	}
+	// Every goroutine has called Done; give the last ones a moment to return
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected the goroutines to exit, got %d running after, %d before", after, before)
+	}
+	if rate := GoroutineCreationRate(0); rate != 0 {
+		t.Errorf("Expected 0 for no goroutines, got %v", rate)
+	}
+}
+
+// BenchmarkGoroutineCreationRate reports the goroutines a single creator can start, run and retire
+// per nanosecond; ns/op is the cost of one of them
+func BenchmarkGoroutineCreationRate(b *testing.B) {
+	rate := GoroutineCreationRate(b.N)
+	b.ReportMetric(rate/1e9, "goroutines/ns")
+}
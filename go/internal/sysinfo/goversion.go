@@ -0,0 +1,63 @@
+package sysinfo
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// MinGoVersion is the oldest Go release the benchmarks support, matching the go directive in
+// go.mod. The batch goroutines rely on the per-iteration loop variables of Go 1.22.
+const MinGoVersion = "go1.23"
+
+// A toolchain before Go 1.19 stops here with "undefined: atomic.Int64". The go directive in go.mod
+// makes Go 1.21 and later refuse older releases outright; this keeps the failure readable on the
+// releases before that, which ignore the directive and compile until the first missing API.
+// Install Go 1.23 or later.
+var _ = atomic.Int64{}
+
+// AtLeastGoVersion reports whether the Go release version, as returned by runtime.Version, is
+// minimum or newer. Versions compare by their numeric fields, so go1.9 is older than go1.21, and
+// prereleases such as go1.23rc1 count as their release. Development builds ("devel ...") are
+// assumed to be new enough.
+func AtLeastGoVersion(version, minimum string) bool {
+	if strings.HasPrefix(version, "devel") {
+		return true
+	}
+	v, m := goVersionFields(version), goVersionFields(minimum)
+	for i := 0; i < len(v) || i < len(m); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(m) {
+			b = m[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+// goVersionFields returns the numeric fields of a version such as go1.23.3, dropping any
+// prerelease or build suffix
+func goVersionFields(version string) []int {
+	version = strings.TrimPrefix(version, "go")
+	var fields []int
+	for _, part := range strings.Split(version, ".") {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(part[:end])
+		if err != nil {
+			break
+		}
+		fields = append(fields, n)
+		if end < len(part) {
+			break
+		}
+	}
+	return fields
+}
@@ -0,0 +1,35 @@
+package sysinfo
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGoVersion(t *testing.T) {
+	if !AtLeastGoVersion(runtime.Version(), "go1.21") {
+		t.Errorf("Expected Go 1.21 or later, got %s", runtime.Version())
+	}
+	if !AtLeastGoVersion(runtime.Version(), MinGoVersion) {
+		t.Errorf("Expected at least %s from go.mod, got %s", MinGoVersion, runtime.Version())
+	}
+}
+
+func TestAtLeastGoVersion(t *testing.T) {
+	for _, c := range []struct {
+		version, minimum string
+		expected         bool
+	}{
+		{"go1.21.0", "go1.21", true},
+		{"go1.23.3", "go1.21", true},
+		{"go1.9", "go1.21", false},
+		{"go1.20.14", "go1.21", false},
+		{"go1.21rc2", "go1.21", true},
+		{"go1.22.1 X:nocoverageredesign", "go1.22.2", false},
+		{"go2", "go1.21", true},
+		{"devel go1.24-abcdef", "go1.21", true},
+	} {
+		if got := AtLeastGoVersion(c.version, c.minimum); got != c.expected {
+			t.Errorf("AtLeastGoVersion(%q, %q): expected %v, got %v", c.version, c.minimum, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package sysinfo
+
+import (
+	"strings"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+)
+
+// CPUModel returns the model name of the host's first CPU, or "unknown" when the platform does
+// not report one
+func CPUModel() string {
+	infos, err := cpu.Info()
+	if err != nil || len(infos) == 0 {
+		return "unknown"
+	}
+	if model := strings.TrimSpace(infos[0].ModelName); model != "" {
+		return model
+	}
+	return "unknown"
+}
+
+// TotalMemory returns the host's physical memory in bytes, or 0 when it cannot be read
+func TotalMemory() uint64 {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return 0
+	}
+	return vm.Total
+}
@@ -0,0 +1,15 @@
+package sysinfo
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestHostDescription(t *testing.T) {
+	if model := CPUModel(); model == "" {
+		t.Errorf("Expected a CPU model or \"unknown\", got an empty string")
+	}
+	if total := TotalMemory(); runtime.GOOS == "linux" && total == 0 {
+		t.Errorf("Expected the total memory from /proc/meminfo, got 0")
+	}
+}
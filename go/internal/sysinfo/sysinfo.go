@@ -0,0 +1,66 @@
+// Package sysinfo inspects the host so benchmark logs can flag environments that make
+// results hard to compare.
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// cpuInfoPath is the Linux file listing one block of fields per logical processor
+const cpuInfoPath = "/proc/cpuinfo"
+
+// DetectCPUSockets returns the number of physical CPU sockets on a Linux host
+func DetectCPUSockets() (sockets int, err error) {
+	file, err := os.Open(cpuInfoPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %v", cpuInfoPath, err)
+	}
+	defer file.Close()
+
+	return countCPUSockets(file)
+}
+
+// countCPUSockets counts the unique "physical id" values in cpuinfo-formatted input. Hosts
+// that list processors without a physical id (common on VMs and ARM) are treated as one socket.
+func countCPUSockets(r io.Reader) (int, error) {
+	physicalIDs := make(map[string]bool)
+	processors := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "processor":
+			processors++
+		case "physical id":
+			physicalIDs[strings.TrimSpace(value)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read cpuinfo: %v", err)
+	}
+
+	if processors == 0 {
+		return 0, fmt.Errorf("no processors listed in cpuinfo")
+	}
+	if len(physicalIDs) == 0 {
+		return 1, nil
+	}
+	return len(physicalIDs), nil
+}
+
+// SocketWarning returns a warning to log when sockets > 1, or an empty string otherwise
+func SocketWarning(sockets int) string {
+	if sockets <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("WARNING: %d CPU sockets detected; benchmark results may vary due to NUMA effects. "+
+		"Pin the benchmark to one socket's cores (e.g. with taskset) for consistent results.", sockets)
+}
@@ -0,0 +1,52 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountCPUSocketsTwoSockets(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "cpuinfo_2_sockets"))
+	if err != nil {
+		t.Fatalf("Failed to open mock cpuinfo: %v", err)
+	}
+	defer file.Close()
+
+	sockets, err := countCPUSockets(file)
+	if err != nil {
+		t.Fatalf("Failed to count sockets: %v", err)
+	}
+	if sockets != 2 {
+		t.Errorf("Expected 2 sockets, got %d", sockets)
+	}
+	if !strings.HasPrefix(SocketWarning(sockets), "WARNING") {
+		t.Errorf("Expected a NUMA warning for 2 sockets, got %q", SocketWarning(sockets))
+	}
+}
+
+func TestCountCPUSocketsWithoutPhysicalID(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "cpuinfo_no_physical_id"))
+	if err != nil {
+		t.Fatalf("Failed to open mock cpuinfo: %v", err)
+	}
+	defer file.Close()
+
+	sockets, err := countCPUSockets(file)
+	if err != nil {
+		t.Fatalf("Failed to count sockets: %v", err)
+	}
+	if sockets != 1 {
+		t.Errorf("Expected 1 socket, got %d", sockets)
+	}
+	if SocketWarning(sockets) != "" {
+		t.Errorf("Expected no warning for a single socket")
+	}
+}
+
+func TestCountCPUSocketsEmpty(t *testing.T) {
+	if _, err := countCPUSockets(strings.NewReader("")); err == nil {
+		t.Errorf("Expected an error for empty cpuinfo")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang/internal/benchmark"
+	"golang/internal/metrics"
+)
+
+// resource is a large float32 slice initialized lazily through a sync.Once
+type resource struct {
+	once   sync.Once
+	values []float32
+	ready  atomic.Bool // Set by the initializer once values is filled, while Do still holds the Once
+}
+
+// initialize allocates and fills the slice of size values
+func (r *resource) initialize(size int) {
+	values := make([]float32, size)
+	for i := range values {
+		values[i] = float32(i)
+	}
+	r.values = values
+	r.ready.Store(true)
+}
+
+// preinitialized returns a resource whose Once has already run, so Do only checks a flag
+func preinitialized(size int) *resource {
+	r := &resource{}
+	r.once.Do(func() { r.initialize(size) })
+	return r
+}
+
+// peak tracks the highest value reported to it from any goroutine
+type peak struct{ value atomic.Int64 }
+
+// observe raises the peak to v if v is higher
+func (p *peak) observe(v int64) {
+	for {
+		current := p.value.Load()
+		if v <= current || p.value.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+// onceRun is the outcome of one race to a resource
+type onceRun struct {
+	Elapsed time.Duration // From releasing the goroutines until all of them had read the resource
+	Init    time.Duration // Time the initializer spent in Do, 0 when the Once had already run
+	Blocked int           // Goroutines that called Do while the initialization was in progress
+	MaxWait time.Duration // Longest any goroutine other than the initializer spent in Do
+}
+
+// raceOnce releases goroutines goroutines at the same moment to call Do on r, initializing it
+// with size values, and read one element each. A goroutine counts as blocked when it called Do
+// before the initializer had filled the slice and did not run the initialization itself: Do
+// held it until the initialization was done.
+func raceOnce(goroutines, size int, r *resource) onceRun {
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	var blocked atomic.Int64
+	var initTime atomic.Int64
+	var maxWait peak
+	// Each goroutine writes only its own slot, so the reads cannot be optimized away
+	read := make([]float32, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-release
+			inProgress := !r.ready.Load()
+			ranInit := false
+			called := time.Now()
+			r.once.Do(func() {
+				ranInit = true
+				r.initialize(size)
+				initTime.Store(int64(time.Since(called)))
+			})
+			if !ranInit {
+				maxWait.observe(int64(time.Since(called)))
+				if inProgress {
+					blocked.Add(1)
+				}
+			}
+			read[g] = r.values[g%len(r.values)]
+		}()
+	}
+	start := time.Now()
+	close(release)
+	wg.Wait()
+	return onceRun{
+		Elapsed: time.Since(start),
+		Init:    time.Duration(initTime.Load()),
+		Blocked: int(blocked.Load()),
+		MaxWait: time.Duration(maxWait.value.Load()),
+	}
+}
+
+// onceResult is the averaged outcome of the runs of one scenario
+type onceResult struct {
+	Name    string
+	Mean    time.Duration // Mean wall time of a run
+	Init    time.Duration // Mean time spent initializing, 0 when the Once had already run
+	Blocked float64       // Mean goroutines blocked in Do per run
+	MaxWait time.Duration // Longest wait in Do over the runs
+}
+
+// measureOnce runs cfg.Warmup unmeasured and cfg.NumRuns measured races on the resource
+// newResource returns for each run, and logs the mean time, the blocked goroutines and the
+// longest wait
+func measureOnce(cfg Config, logger *log.Logger, name string, newResource func() *resource) onceResult {
+	run := func() onceRun { return raceOnce(cfg.Goroutines, cfg.Size, newResource()) }
+	benchmark.RunN(cfg.Warmup, run, func(onceRun) {})
+
+	res := onceResult{Name: name}
+	var init time.Duration
+	blocked := 0
+	avg := benchmark.RunN(cfg.NumRuns, run, func(r onceRun) {
+		init += r.Init
+		blocked += r.Blocked
+		res.MaxWait = max(res.MaxWait, r.MaxWait)
+	})
+	res.Mean = avg.Mean
+	res.Init = init / time.Duration(avg.Runs)
+	res.Blocked = float64(blocked) / float64(avg.Runs)
+	logger.Printf("%s sync.Once: mean %s seconds over %d runs (initialization %s seconds), %.1f of %d goroutines blocked in Do, longest wait %s seconds",
+		res.Name, metrics.FormatDuration(res.Mean), avg.Runs, metrics.FormatDuration(res.Init), res.Blocked, cfg.Goroutines, metrics.FormatDuration(res.MaxWait))
+	return res
+}
+
+// compareOnce measures goroutines racing to a fresh Once against the same goroutines calling
+// Do on one that has already run, and logs how much longer the contended race takes
+func compareOnce(cfg Config, logger *log.Logger) []onceResult {
+	contended := measureOnce(cfg, logger, "Contended", func() *resource { return &resource{} })
+	ready := preinitialized(cfg.Size)
+	uncontended := measureOnce(cfg, logger, "Pre-initialized", func() *resource { return ready })
+	if uncontended.Mean > 0 {
+		logger.Printf("Contention Cost: the contended race takes %.2fx as long as pre-initialized access", contended.Mean.Seconds()/uncontended.Mean.Seconds())
+	}
+	return []onceResult{contended, uncontended}
+}
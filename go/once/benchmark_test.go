@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRaceOnceInitializesOnce(t *testing.T) {
+	const goroutines, size = 16, 1 << 16
+	r := &resource{}
+	run := raceOnce(goroutines, size, r)
+	if len(r.values) != size || r.values[size-1] != size-1 {
+		t.Fatalf("Expected %d initialized values, got %d", size, len(r.values))
+	}
+	if run.Init <= 0 || run.Elapsed < run.Init {
+		t.Errorf("Expected a positive initialization time within the run, got %+v", run)
+	}
+	if run.Blocked > goroutines-1 {
+		t.Errorf("Expected at most %d goroutines blocked behind the initializer, got %d", goroutines-1, run.Blocked)
+	}
+
+	// Once initialized, Do never runs the function again and nobody blocks
+	again := raceOnce(goroutines, size, r)
+	if again.Init != 0 || again.Blocked != 0 {
+		t.Errorf("Expected no initialization and no blocked goroutines on a second race, got %+v", again)
+	}
+}
+
+func TestRaceOnceCountsBlockedGoroutines(t *testing.T) {
+	const goroutines, size = 16, 1 << 10
+	r := &resource{}
+	// Hold the Once open, so every goroutine of the race reaches Do before the initialization ends
+	entered, finish := make(chan struct{}), make(chan struct{})
+	go r.once.Do(func() {
+		close(entered)
+		<-finish
+		r.initialize(size)
+	})
+	<-entered
+	time.AfterFunc(50*time.Millisecond, func() { close(finish) })
+
+	run := raceOnce(goroutines, size, r)
+	if run.Blocked != goroutines {
+		t.Errorf("Expected all %d goroutines blocked in Do, got %d", goroutines, run.Blocked)
+	}
+	if run.MaxWait < 10*time.Millisecond {
+		t.Errorf("Expected the longest wait to cover most of the held initialization, got %v", run.MaxWait)
+	}
+}
+
+func TestCompareOnce(t *testing.T) {
+	cfg := Config{Goroutines: 8, Size: 1 << 16, NumRuns: 3}
+	var buf bytes.Buffer
+	results := compareOnce(cfg, log.New(&buf, "", 0))
+
+	if len(results) != 2 || results[0].Name != "Contended" || results[1].Name != "Pre-initialized" {
+		t.Fatalf("Expected the contended and pre-initialized scenarios, got %+v", results)
+	}
+	if results[0].Init <= 0 || results[1].Init != 0 || results[1].Blocked != 0 {
+		t.Errorf("Expected initialization only in the contended scenario, got %+v", results)
+	}
+	for _, expected := range []string{"Contended sync.Once: mean ", "Pre-initialized sync.Once: mean ", "goroutines blocked in Do", "Contention Cost: "} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Command once measures what lazy initialization through sync.Once costs when many goroutines
+// race to it at the same moment. The goroutines all call Do on a fresh Once that allocates and
+// fills a large float32 slice, and the runs are compared with the same goroutines calling Do on
+// a Once that has already run. The log reports how many goroutines blocked in Do while the
+// initialization was in progress.
+//
+//	go run ./once -goroutines 64 -num-runs 20
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"golang/internal/metrics"
+)
+
+// Config holds the size of the shared resource and the run parameters of the comparison
+type Config struct {
+	Goroutines int // Goroutines racing to initialize the resource in every run
+	Size       int // float32 elements of the shared resource
+	NumRuns    int // Measured runs per scenario
+	Warmup     int // Runs per scenario before the measured ones, excluded from the averages
+}
+
+// DefaultConfig returns a comparison of 8 goroutines per CPU racing to initialize a 16 MB slice
+func DefaultConfig() Config {
+	return Config{
+		Goroutines: 8 * runtime.NumCPU(),
+		Size:       1 << 22,
+		NumRuns:    20,
+		Warmup:     2,
+	}
+}
+
+// RegisterFlags binds the configuration fields to command-line flags, using the current values as defaults
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Goroutines, "goroutines", c.Goroutines, "number of goroutines racing to initialize the shared resource")
+	fs.IntVar(&c.Size, "size", c.Size, "number of float32 elements in the shared resource")
+	fs.IntVar(&c.NumRuns, "num-runs", c.NumRuns, "number of measured runs per scenario")
+	fs.IntVar(&c.Warmup, "warmup", c.Warmup, "number of warmup runs per scenario excluded from the averages")
+}
+
+// Validate reports the first setting that cannot produce a comparison
+func (c Config) Validate() error {
+	switch {
+	case c.Goroutines <= 0:
+		return fmt.Errorf("-goroutines must be positive, got %d", c.Goroutines)
+	case c.Size <= 0:
+		return fmt.Errorf("-size must be positive, got %d", c.Size)
+	case c.NumRuns <= 0:
+		return fmt.Errorf("-num-runs must be positive, got %d", c.NumRuns)
+	}
+	return nil
+}
+
+func main() {
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	logFilePath := flag.String("log", "go_once_metrics_result.log", "file to append the metrics log to")
+	flag.Parse()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	file, err := os.OpenFile(*logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("Error opening log file: %v", err)
+	}
+	defer file.Close()
+	logger := log.New(io.MultiWriter(os.Stdout, file), "", log.LstdFlags)
+
+	logger.Printf("sync.Once Contention: %d goroutines initializing %d float32 values (%s MB), %d runs per scenario",
+		cfg.Goroutines, cfg.Size, metrics.FormatMB(float64(4*cfg.Size)), cfg.NumRuns)
+	compareOnce(cfg, logger)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang/internal/benchmark"
+	"golang/internal/metrics"
+)
+
+// ImageBatch is a group of images sent over the channel as one value
+type ImageBatch struct {
+	Images [][]float32
+	Labels []int
+}
+
+// makeBatches splits images into full batches of batchSize; a trailing partial batch is dropped
+func makeBatches(images [][]float32, labels []int, batchSize int) []ImageBatch {
+	batches := make([]ImageBatch, len(images)/batchSize)
+	for i := range batches {
+		start, end := i*batchSize, (i+1)*batchSize
+		batches[i] = ImageBatch{Images: images[start:end], Labels: labels[start:end]}
+	}
+	return batches
+}
+
+// bufferSizes returns the channel capacities of the sweep: unbuffered, then powers of two up to
+// numBatches, which is always included, so the producer never blocks at the largest size
+func bufferSizes(numBatches int) []int {
+	sizes := []int{0}
+	for size := 1; size < numBatches; size *= 2 {
+		sizes = append(sizes, size)
+	}
+	if numBatches > 0 {
+		sizes = append(sizes, numBatches)
+	}
+	return sizes
+}
+
+// runProducerConsumer sends every batch on a channel of capacity buffer from one producer
+// goroutine while consumers goroutines drain it, doubling every pixel into a new image. It
+// returns the number of images processed.
+func runProducerConsumer(batches []ImageBatch, buffer, consumers int) int {
+	queue := make(chan ImageBatch, buffer)
+	go func() {
+		defer close(queue)
+		for _, batch := range batches {
+			queue <- batch
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	processed := 0
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := 0
+			for batch := range queue {
+				for _, image := range batch.Images {
+					doubled := make([]float32, len(image))
+					for j, pixel := range image {
+						doubled[j] = pixel * 2
+					}
+				}
+				n += len(batch.Images)
+			}
+			mu.Lock()
+			processed += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return processed
+}
+
+// sweepPoint is the averaged outcome of the runs at one channel capacity
+type sweepPoint struct {
+	Buffer          int
+	Mean            time.Duration // Mean wall time of a run
+	ImagesPerSecond float64
+}
+
+// sweepBufferSizes measures cfg.NumRuns runs at every buffer size of bufferSizes, after
+// cfg.Warmup unmeasured runs at each, and logs the mean time and throughput of each size
+func sweepBufferSizes(cfg Config, logger *log.Logger, batches []ImageBatch) ([]sweepPoint, error) {
+	var points []sweepPoint
+	for _, buffer := range bufferSizes(len(batches)) {
+		run := func() int { return runProducerConsumer(batches, buffer, cfg.Consumers) }
+		benchmark.RunN(cfg.Warmup, run, func(int) {})
+
+		images := 0
+		var err error
+		avg := benchmark.RunN(cfg.NumRuns, run, func(n int) {
+			if n != len(batches)*cfg.BatchSize && err == nil {
+				err = fmt.Errorf("buffer %d processed %d of %d images", buffer, n, len(batches)*cfg.BatchSize)
+			}
+			images += n
+		})
+		if err != nil {
+			return nil, err
+		}
+		point := sweepPoint{Buffer: buffer, Mean: avg.Mean}
+		if avg.Elapsed > 0 {
+			point.ImagesPerSecond = float64(images) / avg.Elapsed.Seconds()
+		}
+		logger.Printf("Buffer %d: mean %s seconds over %d runs, %.2f images/second",
+			buffer, metrics.FormatDuration(point.Mean), avg.Runs, point.ImagesPerSecond)
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// plotWidth is the number of characters of the longest bar of plotThroughput
+const plotWidth = 50
+
+// plotThroughput writes a horizontal bar chart of throughput against buffer size, one line per
+// point, scaled so the fastest point fills plotWidth
+func plotThroughput(w io.Writer, points []sweepPoint) {
+	var best float64
+	for _, p := range points {
+		if p.ImagesPerSecond > best {
+			best = p.ImagesPerSecond
+		}
+	}
+	fmt.Fprintf(w, "Throughput vs Channel Buffer Size (images/second):\n")
+	for _, p := range points {
+		bar := 0
+		if best > 0 {
+			bar = int(p.ImagesPerSecond / best * plotWidth)
+		}
+		fmt.Fprintf(w, "%8d | %-*s %.2f\n", p.Buffer, plotWidth, strings.Repeat("#", bar), p.ImagesPerSecond)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/synthetic"
+)
+
+func TestBufferSizes(t *testing.T) {
+	for _, tc := range []struct {
+		numBatches int
+		expected   []int
+	}{
+		{8, []int{0, 1, 2, 4, 8}},
+		{6, []int{0, 1, 2, 4, 6}},
+		{1, []int{0, 1}},
+		{0, []int{0}},
+	} {
+		if got := bufferSizes(tc.numBatches); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Expected %v for %d batches, got %v", tc.expected, tc.numBatches, got)
+		}
+	}
+}
+
+func TestRunProducerConsumerDrainsEveryBatch(t *testing.T) {
+	images, labels := synthetic.GenerateSyntheticDataset(100, 4, 4, 3, 1)
+	batches := makeBatches(images, labels, 8)
+	if len(batches) != 12 {
+		t.Fatalf("Expected 12 full batches, got %d", len(batches))
+	}
+	for _, buffer := range []int{0, 3, len(batches)} {
+		if n := runProducerConsumer(batches, buffer, 4); n != 96 {
+			t.Errorf("Expected 96 images processed with buffer %d, got %d", buffer, n)
+		}
+	}
+}
+
+func TestSweepBufferSizes(t *testing.T) {
+	cfg := Config{Images: 64, BatchSize: 16, Height: 4, Width: 4, Channels: 3, Consumers: 2, NumRuns: 2, Seed: 1}
+	images, labels := synthetic.GenerateSyntheticDataset(cfg.Images, cfg.Height, cfg.Width, cfg.Channels, cfg.Seed)
+	var buf bytes.Buffer
+	points, err := sweepBufferSizes(cfg, log.New(&buf, "", 0), makeBatches(images, labels, cfg.BatchSize))
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if len(points) != 4 || points[3].Buffer != 4 {
+		t.Fatalf("Expected buffer sizes 0, 1, 2 and 4, got %+v", points)
+	}
+	for _, p := range points {
+		if p.Mean <= 0 || p.ImagesPerSecond <= 0 {
+			t.Errorf("Expected a positive time and throughput, got %+v", p)
+		}
+	}
+	if !strings.Contains(buf.String(), "Buffer 0: mean ") || !strings.Contains(buf.String(), "over 2 runs") {
+		t.Errorf("Expected a line per buffer size, got:\n%s", buf.String())
+	}
+}
+
+func TestPlotThroughput(t *testing.T) {
+	var buf bytes.Buffer
+	plotThroughput(&buf, []sweepPoint{
+		{Buffer: 0, Mean: time.Second, ImagesPerSecond: 500},
+		{Buffer: 8, Mean: time.Second, ImagesPerSecond: 1000},
+	})
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a title and 2 bars, got:\n%s", buf.String())
+	}
+	// The fastest point fills the plot and the others scale with it
+	if strings.Count(lines[2], "#") != plotWidth || strings.Count(lines[1], "#") != plotWidth/2 {
+		t.Errorf("Expected bars of %d and %d, got:\n%s", plotWidth, plotWidth/2, buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "       0 | ") || !strings.HasSuffix(lines[2], " 1000.00") {
+		t.Errorf("Unexpected bar format:\n%s", buf.String())
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Images = cfg.BatchSize - 1
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an error with fewer images than one batch")
+	}
+}
@@ -0,0 +1,102 @@
+// Command producer-consumer measures how the buffer size of a channel affects throughput. One
+// producer sends synthetic image batches on the channel while N consumers drain it, at every
+// capacity from unbuffered up to the number of batches, mirroring the Java BlockingQueue
+// capacity benchmarks.
+//
+//	go run ./producer-consumer -consumers 8 -num-runs 20
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"golang/internal/synthetic"
+)
+
+// Config holds the dataset shape and run parameters of the sweep
+type Config struct {
+	Images    int // Number of synthetic images to produce per run
+	BatchSize int // Images per channel value
+	Height    int
+	Width     int
+	Channels  int
+	Consumers int // Goroutines draining the channel
+	NumRuns   int // Measured runs per buffer size
+	Warmup    int // Runs per buffer size before the measured ones, excluded from the averages
+	Seed      int64
+}
+
+// DefaultConfig returns a sweep over 10000 CIFAR-sized images in batches of 100, one consumer per CPU
+func DefaultConfig() Config {
+	return Config{
+		Images:    10000,
+		BatchSize: 100,
+		Height:    32,
+		Width:     32,
+		Channels:  3,
+		Consumers: runtime.NumCPU(),
+		NumRuns:   20,
+		Warmup:    2,
+		Seed:      1,
+	}
+}
+
+// RegisterFlags binds the configuration fields to command-line flags, using the current values as defaults
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Images, "images", c.Images, "number of synthetic images to produce per run")
+	fs.IntVar(&c.BatchSize, "batch-size", c.BatchSize, "number of images per channel value")
+	fs.IntVar(&c.Height, "height", c.Height, "image height in pixels")
+	fs.IntVar(&c.Width, "width", c.Width, "image width in pixels")
+	fs.IntVar(&c.Channels, "channels", c.Channels, "number of color channels")
+	fs.IntVar(&c.Consumers, "consumers", c.Consumers, "number of goroutines draining the channel")
+	fs.IntVar(&c.NumRuns, "num-runs", c.NumRuns, "number of measured runs per buffer size")
+	fs.IntVar(&c.Warmup, "warmup", c.Warmup, "number of warmup runs per buffer size excluded from the averages")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed for the synthetic image generator")
+}
+
+// Validate reports the first setting that cannot produce a sweep
+func (c Config) Validate() error {
+	switch {
+	case c.BatchSize <= 0:
+		return fmt.Errorf("-batch-size must be positive, got %d", c.BatchSize)
+	case c.Images < c.BatchSize:
+		return fmt.Errorf("-images must hold at least one batch of %d, got %d", c.BatchSize, c.Images)
+	case c.Consumers <= 0:
+		return fmt.Errorf("-consumers must be positive, got %d", c.Consumers)
+	case c.NumRuns <= 0:
+		return fmt.Errorf("-num-runs must be positive, got %d", c.NumRuns)
+	}
+	return nil
+}
+
+func main() {
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	logFilePath := flag.String("log", "go_producer_consumer_metrics_result.log", "file to append the metrics log to")
+	flag.Parse()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	file, err := os.OpenFile(*logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("Error opening log file: %v", err)
+	}
+	defer file.Close()
+	logger := log.New(io.MultiWriter(os.Stdout, file), "", log.LstdFlags)
+
+	images, labels := synthetic.GenerateSyntheticDataset(cfg.Images, cfg.Height, cfg.Width, cfg.Channels, cfg.Seed)
+	batches := makeBatches(images, labels, cfg.BatchSize)
+	logger.Printf("Producer-Consumer Channel Sweep: %d batches of %d images, %d consumers, %d runs per buffer size",
+		len(batches), cfg.BatchSize, cfg.Consumers, cfg.NumRuns)
+
+	points, err := sweepBufferSizes(cfg, logger, batches)
+	if err != nil {
+		log.Fatalf("Error running sweep: %v", err)
+	}
+	plotThroughput(logger.Writer(), points)
+}
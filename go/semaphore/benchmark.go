@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+
+	"golang/internal/benchmark"
+	"golang/internal/metrics"
+)
+
+// ImageBatch is a group of images processed by one goroutine
+type ImageBatch struct {
+	Images [][]float32
+	Labels []int
+}
+
+// makeBatches splits images into full batches of batchSize; a trailing partial batch is dropped
+func makeBatches(images [][]float32, labels []int, batchSize int) []ImageBatch {
+	batches := make([]ImageBatch, len(images)/batchSize)
+	for i := range batches {
+		start, end := i*batchSize, (i+1)*batchSize
+		batches[i] = ImageBatch{Images: images[start:end], Labels: labels[start:end]}
+	}
+	return batches
+}
+
+// gate bounds how many batches are processed at once: acquire blocks until a slot is free and
+// release returns it
+type gate struct {
+	name    string
+	acquire func()
+	release func()
+}
+
+// channelGate is a semaphore of limit slots built on a buffered chan struct{}
+func channelGate(limit int) gate {
+	slots := make(chan struct{}, limit)
+	return gate{
+		name:    "chan struct{}",
+		acquire: func() { slots <- struct{}{} },
+		release: func() { <-slots },
+	}
+}
+
+// weightedGate is a semaphore of limit slots from golang.org/x/sync/semaphore, acquiring one
+// unit per batch
+func weightedGate(limit int) gate {
+	sem := semaphore.NewWeighted(int64(limit))
+	return gate{
+		name: "x/sync/semaphore",
+		// Acquire only fails once its context is done, which context.Background never is
+		acquire: func() { sem.Acquire(context.Background(), 1) },
+		release: func() { sem.Release(1) },
+	}
+}
+
+// peak tracks the highest value reported to it from any goroutine
+type peak struct{ value atomic.Int64 }
+
+// observe raises the peak to v if v is higher
+func (p *peak) observe(v int64) {
+	for {
+		current := p.value.Load()
+		if v <= current || p.value.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+// gatedRun is the outcome of one pass over the batches
+type gatedRun struct {
+	Images         int
+	PeakWorkers    int // Most batch goroutines running at once
+	PeakGoroutines int // Most goroutines in the process, seen as each batch goroutine started
+}
+
+// runGated acquires a slot of g before starting a goroutine for each batch, so at most the
+// gate's limit of batch goroutines exist at once, and doubles every pixel of the batch into a
+// new image
+func runGated(batches []ImageBatch, g gate) gatedRun {
+	var wg sync.WaitGroup
+	var live atomic.Int64
+	var processed atomic.Int64
+	var workers, goroutines peak
+	for _, batch := range batches {
+		g.acquire()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer g.release()
+			workers.observe(live.Add(1))
+			defer live.Add(-1)
+			goroutines.observe(int64(runtime.NumGoroutine()))
+			for _, image := range batch.Images {
+				doubled := make([]float32, len(image))
+				for j, pixel := range image {
+					doubled[j] = pixel * 2
+				}
+			}
+			processed.Add(int64(len(batch.Images)))
+		}()
+	}
+	wg.Wait()
+	return gatedRun{
+		Images:         int(processed.Load()),
+		PeakWorkers:    int(workers.value.Load()),
+		PeakGoroutines: int(goroutines.value.Load()),
+	}
+}
+
+// gateResult is the averaged outcome of the runs of one semaphore
+type gateResult struct {
+	Name            string
+	Mean            time.Duration // Mean wall time of a run
+	ImagesPerSecond float64
+	PeakWorkers     int // Highest over the runs
+	PeakGoroutines  int // Highest over the runs
+}
+
+// measureGate runs cfg.Warmup unmeasured and cfg.NumRuns measured passes over batches through
+// the gate newGate builds for each pass, and logs the mean time, throughput and peaks
+func measureGate(cfg Config, logger *log.Logger, batches []ImageBatch, newGate func(limit int) gate) gateResult {
+	run := func() gatedRun { return runGated(batches, newGate(cfg.Limit)) }
+	benchmark.RunN(cfg.Warmup, run, func(gatedRun) {})
+
+	res := gateResult{Name: newGate(cfg.Limit).name}
+	images := 0
+	avg := benchmark.RunN(cfg.NumRuns, run, func(r gatedRun) {
+		images += r.Images
+		res.PeakWorkers = max(res.PeakWorkers, r.PeakWorkers)
+		res.PeakGoroutines = max(res.PeakGoroutines, r.PeakGoroutines)
+	})
+	res.Mean = avg.Mean
+	if avg.Elapsed > 0 {
+		res.ImagesPerSecond = float64(images) / avg.Elapsed.Seconds()
+	}
+	logger.Printf("%s Semaphore: mean %s seconds over %d runs, %.2f images/second, peak %d batch goroutines (%d in the process)",
+		res.Name, metrics.FormatDuration(res.Mean), avg.Runs, res.ImagesPerSecond, res.PeakWorkers, res.PeakGoroutines)
+	return res
+}
+
+// compareGates measures the channel semaphore and the weighted semaphore on the same batches
+// and logs the throughput of the weighted one relative to the channel
+func compareGates(cfg Config, logger *log.Logger, batches []ImageBatch) []gateResult {
+	channel := measureGate(cfg, logger, batches, channelGate)
+	weighted := measureGate(cfg, logger, batches, weightedGate)
+	if channel.ImagesPerSecond > 0 {
+		logger.Printf("x/sync/semaphore Throughput: %.2fx the chan struct{} semaphore", weighted.ImagesPerSecond/channel.ImagesPerSecond)
+	}
+	return []gateResult{channel, weighted}
+}
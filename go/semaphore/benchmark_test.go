@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"golang/internal/synthetic"
+)
+
+func TestRunGatedBoundsConcurrentBatches(t *testing.T) {
+	images, labels := synthetic.GenerateSyntheticDataset(200, 4, 4, 3, 1)
+	batches := makeBatches(images, labels, 10)
+	for _, newGate := range []func(int) gate{channelGate, weightedGate} {
+		g := newGate(3)
+		r := runGated(batches, g)
+		if r.Images != 200 {
+			t.Errorf("%s: expected 200 images processed, got %d", g.name, r.Images)
+		}
+		if r.PeakWorkers < 1 || r.PeakWorkers > 3 {
+			t.Errorf("%s: expected between 1 and 3 batch goroutines at once, got %d", g.name, r.PeakWorkers)
+		}
+		if r.PeakGoroutines < r.PeakWorkers {
+			t.Errorf("%s: expected at least the %d batch goroutines in the process, got %d", g.name, r.PeakWorkers, r.PeakGoroutines)
+		}
+	}
+}
+
+func TestCompareGates(t *testing.T) {
+	cfg := Config{Images: 64, BatchSize: 8, Height: 4, Width: 4, Channels: 3, Limit: 2, NumRuns: 2, Seed: 1}
+	images, labels := synthetic.GenerateSyntheticDataset(cfg.Images, cfg.Height, cfg.Width, cfg.Channels, cfg.Seed)
+	var buf bytes.Buffer
+	results := compareGates(cfg, log.New(&buf, "", 0), makeBatches(images, labels, cfg.BatchSize))
+
+	if len(results) != 2 || results[0].Name != "chan struct{}" || results[1].Name != "x/sync/semaphore" {
+		t.Fatalf("Expected the channel and weighted semaphores, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Mean <= 0 || r.ImagesPerSecond <= 0 || r.PeakWorkers > cfg.Limit {
+			t.Errorf("Expected a positive time and throughput within the limit, got %+v", r)
+		}
+	}
+	for _, expected := range []string{"chan struct{} Semaphore: mean ", "x/sync/semaphore Semaphore: mean ", "x/sync/semaphore Throughput: "} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestPeakObserve(t *testing.T) {
+	var p peak
+	for _, v := range []int64{3, 7, 5} {
+		p.observe(v)
+	}
+	if got := p.value.Load(); got != 7 {
+		t.Errorf("Expected a peak of 7, got %d", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Limit = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected an error without any slots")
+	}
+}
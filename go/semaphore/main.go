@@ -0,0 +1,98 @@
+// Command semaphore compares two ways of bounding concurrent image batch processing: a
+// buffered chan struct{} used as a semaphore and the weighted semaphore of
+// golang.org/x/sync/semaphore. Both gate the same synthetic batches, and the log reports the
+// throughput and peak goroutine count of each.
+//
+//	go run ./semaphore -limit 8 -num-runs 20
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"golang/internal/synthetic"
+)
+
+// Config holds the dataset shape and run parameters of the comparison
+type Config struct {
+	Images    int // Number of synthetic images to process per run
+	BatchSize int // Images per batch goroutine
+	Height    int
+	Width     int
+	Channels  int
+	Limit     int // Batch goroutines allowed at once by either semaphore
+	NumRuns   int // Measured runs per semaphore
+	Warmup    int // Runs per semaphore before the measured ones, excluded from the averages
+	Seed      int64
+}
+
+// DefaultConfig returns a comparison over 10000 CIFAR-sized images in batches of 100, with as
+// many batches at once as there are CPUs
+func DefaultConfig() Config {
+	return Config{
+		Images:    10000,
+		BatchSize: 100,
+		Height:    32,
+		Width:     32,
+		Channels:  3,
+		Limit:     runtime.NumCPU(),
+		NumRuns:   20,
+		Warmup:    2,
+		Seed:      1,
+	}
+}
+
+// RegisterFlags binds the configuration fields to command-line flags, using the current values as defaults
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Images, "images", c.Images, "number of synthetic images to process per run")
+	fs.IntVar(&c.BatchSize, "batch-size", c.BatchSize, "number of images per batch goroutine")
+	fs.IntVar(&c.Height, "height", c.Height, "image height in pixels")
+	fs.IntVar(&c.Width, "width", c.Width, "image width in pixels")
+	fs.IntVar(&c.Channels, "channels", c.Channels, "number of color channels")
+	fs.IntVar(&c.Limit, "limit", c.Limit, "number of batch goroutines either semaphore allows at once")
+	fs.IntVar(&c.NumRuns, "num-runs", c.NumRuns, "number of measured runs per semaphore")
+	fs.IntVar(&c.Warmup, "warmup", c.Warmup, "number of warmup runs per semaphore excluded from the averages")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed for the synthetic image generator")
+}
+
+// Validate reports the first setting that cannot produce a comparison
+func (c Config) Validate() error {
+	switch {
+	case c.BatchSize <= 0:
+		return fmt.Errorf("-batch-size must be positive, got %d", c.BatchSize)
+	case c.Images < c.BatchSize:
+		return fmt.Errorf("-images must hold at least one batch of %d, got %d", c.BatchSize, c.Images)
+	case c.Limit <= 0:
+		return fmt.Errorf("-limit must be positive, got %d", c.Limit)
+	case c.NumRuns <= 0:
+		return fmt.Errorf("-num-runs must be positive, got %d", c.NumRuns)
+	}
+	return nil
+}
+
+func main() {
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	logFilePath := flag.String("log", "go_semaphore_metrics_result.log", "file to append the metrics log to")
+	flag.Parse()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	file, err := os.OpenFile(*logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("Error opening log file: %v", err)
+	}
+	defer file.Close()
+	logger := log.New(io.MultiWriter(os.Stdout, file), "", log.LstdFlags)
+
+	images, labels := synthetic.GenerateSyntheticDataset(cfg.Images, cfg.Height, cfg.Width, cfg.Channels, cfg.Seed)
+	batches := makeBatches(images, labels, cfg.BatchSize)
+	logger.Printf("Semaphore Comparison: %d batches of %d images, at most %d at once, %d runs per semaphore",
+		len(batches), cfg.BatchSize, cfg.Limit, cfg.NumRuns)
+	compareGates(cfg, logger, batches)
+}
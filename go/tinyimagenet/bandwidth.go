@@ -0,0 +1,31 @@
+package main
+
+import "golang/internal/metricslog"
+
+// bytesPerPixelValue is the in-memory size of one pixel value, a float32
+const bytesPerPixelValue = 4
+
+// TheoreticalMaxThroughput returns the images per second a purely memory-bound pass reaches with
+// dramBandwidthGBps of DRAM bandwidth, in 1e9 bytes per second. Each image is read once and
+// written once, so it costs twice its size in traffic. Non-positive inputs give 0.
+func TheoreticalMaxThroughput(dramBandwidthGBps float64, imageSizeBytes int) float64 {
+	if dramBandwidthGBps <= 0 || imageSizeBytes <= 0 {
+		return 0
+	}
+	return dramBandwidthGBps * 1e9 / float64(imageSizeBytes*2)
+}
+
+// maxThroughput returns the theoretical maximum images per second for the images of cfg
+func maxThroughput(cfg BenchmarkConfig) float64 {
+	return TheoreticalMaxThroughput(cfg.DRAMBandwidth, cfg.ImageSize()*bytesPerPixelValue)
+}
+
+// logBandwidthEfficiency writes imagesPerSecond as a percentage of the theoretical maximum, when
+// cfg.DRAMBandwidth is set
+func logBandwidthEfficiency(cfg BenchmarkConfig, logger *metricslog.Logger, prefix string, imagesPerSecond float64) {
+	limit := maxThroughput(cfg)
+	if limit == 0 {
+		return
+	}
+	logger.Printf("%s: %.2f%% (%.2f of %.2f images/second)", prefix, imagesPerSecond/limit*100, imagesPerSecond, limit)
+}
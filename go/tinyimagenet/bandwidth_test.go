@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+)
+
+func TestTheoreticalMaxThroughput(t *testing.T) {
+	// 25.6 GB/s over 32x32x3 float32 images, read and written once: 25.6e9 / (12288 * 2)
+	if got, expected := TheoreticalMaxThroughput(25.6, 32*32*3*4), 25.6e9/24576; math.Abs(got-expected) > 1e-6 {
+		t.Errorf("Expected %v images/second, got %v", expected, got)
+	}
+	if got := TheoreticalMaxThroughput(0, 1000); got != 0 {
+		t.Errorf("Expected 0 without a bandwidth, got %v", got)
+	}
+	if got := TheoreticalMaxThroughput(10, 0); got != 0 {
+		t.Errorf("Expected 0 for empty images, got %v", got)
+	}
+}
+
+func TestBandwidthEfficiencyLogged(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	// 1000-byte images at 1 GB/s allow 500000 images/second
+	cfg := BenchmarkConfig{ImageHeight: 25, ImageWidth: 10, Channels: 1, NumRuns: 1, DRAMBandwidth: 1}
+	summary, err := runBenchmark(cfg, logger, func() (runResult, error) {
+		return runResult{ImagesProcessed: 250000, ExecutionTime: time.Second}, nil
+	})
+	if err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	logBandwidthEfficiency(cfg, logger, "Average Memory Bandwidth Efficiency", throughput(summary.Total.ImagesProcessed, summary.Total.ExecutionTime))
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{
+		"Memory Bandwidth Efficiency for Run 1: 50.00% (250000.00 of 500000.00 images/second)",
+		"Average Memory Bandwidth Efficiency: 50.00%",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
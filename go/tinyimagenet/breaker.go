@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker that refuses a call after too many consecutive
+// failures
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+// A closed breaker lets calls through, an open one refuses them, and a half-open one lets a
+// single trial call through to decide whether to close again
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops calls to a failing dependency, such as a dataset on a flaky network mount,
+// instead of letting every caller wait out its own failure. It opens after a number of
+// consecutive failures, refuses calls until a reset timeout has passed, then half-opens: the next
+// call is a trial that closes the breaker on success and opens it again on failure. It is safe
+// for concurrent use.
+type CircuitBreaker struct {
+	threshold    int
+	resetTimeout time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // When the breaker last opened
+	trial    bool      // A half-open trial call is in progress
+}
+
+// NewCircuitBreaker returns a closed breaker that opens after threshold consecutive failures and
+// half-opens resetTimeout after opening. A threshold below 1 is treated as 1.
+func NewCircuitBreaker(threshold int, resetTimeout time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, resetTimeout: resetTimeout, now: time.Now}
+}
+
+// State returns the current state, half-open once the reset timeout of an open breaker has passed
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpenIfDue()
+	return b.state
+}
+
+// halfOpenIfDue moves an open breaker whose reset timeout has passed to half-open; b.mu is held
+func (b *CircuitBreaker) halfOpenIfDue() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.resetTimeout {
+		b.state = BreakerHalfOpen
+	}
+}
+
+// Do runs fn unless the breaker refuses it with ErrCircuitOpen, and counts its error towards
+// opening the breaker. While half-open, calls other than the trial are refused until it finishes.
+func (b *CircuitBreaker) Do(fn func() error) error {
+	b.mu.Lock()
+	b.halfOpenIfDue()
+	if b.state == BreakerOpen || (b.state == BreakerHalfOpen && b.trial) {
+		b.mu.Unlock()
+		return ErrCircuitOpen
+	}
+	isTrial := b.state == BreakerHalfOpen
+	b.trial = b.trial || isTrial
+	b.mu.Unlock()
+
+	err := fn()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if isTrial {
+		b.trial = false
+	}
+	switch {
+	case err == nil:
+		b.state, b.failures = BreakerClosed, 0
+	case isTrial:
+		b.state, b.openedAt = BreakerOpen, b.now()
+	case b.state == BreakerClosed:
+		if b.failures++; b.failures >= b.threshold {
+			b.state, b.openedAt, b.failures = BreakerOpen, b.now(), 0
+		}
+	}
+	return err
+}
+
+// LoadTinyImageNetWithCircuitBreaker loads the dataset like LoadTinyImageNet, with every image
+// load going through breaker. Once breaker opens, the remaining images fail fast and the load
+// stops with ErrCircuitOpen, even under cfg.SkipUnreadable, rather than trying every file on a
+// mount that has stopped responding.
+func LoadTinyImageNetWithCircuitBreaker(cfg BenchmarkConfig, dataDir string, breaker *CircuitBreaker) ([][]float32, []string, error) {
+	w := newWalker(cfg, osFS{})
+	w.breaker = breaker
+	return loadWithWalker(cfg, w, dataDir, cfg.LoadWorkers)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeClockBreaker returns a breaker whose clock only moves when the returned function advances it
+func fakeClockBreaker(threshold int, resetTimeout time.Duration) (*CircuitBreaker, func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(threshold, resetTimeout)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreakerOpensAndHalfOpens(t *testing.T) {
+	b, advance := fakeClockBreaker(3, time.Second)
+	errRead := errors.New("read failed")
+	fail := func() error { return errRead }
+	succeed := func() error { return nil }
+
+	// A success between failures resets the count
+	b.Do(fail)
+	b.Do(fail)
+	b.Do(succeed)
+	b.Do(fail)
+	b.Do(fail)
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("Expected the breaker closed after 2 consecutive failures, got %v", state)
+	}
+
+	if err := b.Do(fail); !errors.Is(err, errRead) {
+		t.Errorf("Expected the third failure to be returned, got %v", err)
+	}
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("Expected the breaker open after 3 consecutive failures, got %v", state)
+	}
+	called := false
+	if err := b.Do(func() error { called = true; return nil }); !errors.Is(err, ErrCircuitOpen) || called {
+		t.Errorf("Expected an open breaker to refuse the call with ErrCircuitOpen, got %v (called %v)", err, called)
+	}
+
+	advance(999 * time.Millisecond)
+	if state := b.State(); state != BreakerOpen {
+		t.Errorf("Expected the breaker still open before the reset timeout, got %v", state)
+	}
+	advance(time.Millisecond)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("Expected the breaker half-open after the reset timeout, got %v", state)
+	}
+
+	// A failed trial opens it again for another reset timeout
+	if err := b.Do(fail); !errors.Is(err, errRead) {
+		t.Errorf("Expected the trial call to run, got %v", err)
+	}
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("Expected a failed trial to reopen the breaker, got %v", state)
+	}
+	advance(time.Second)
+	if err := b.Do(succeed); err != nil {
+		t.Errorf("Expected the trial call to succeed, got %v", err)
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("Expected a successful trial to close the breaker, got %v", state)
+	}
+}
+
+func TestCircuitBreakerAllowsOneTrial(t *testing.T) {
+	b, advance := fakeClockBreaker(1, time.Second)
+	b.Do(func() error { return errors.New("down") })
+	advance(time.Second)
+
+	var inner error
+	b.Do(func() error {
+		inner = b.Do(func() error { return nil })
+		return nil
+	})
+	if !errors.Is(inner, ErrCircuitOpen) {
+		t.Errorf("Expected a second call during the half-open trial to be refused, got %v", inner)
+	}
+}
+
+func TestLoadStopsWhenBreakerOpens(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 2, 10)
+	fsys := newScriptedFS()
+	for i := 2; i < 8; i++ {
+		fsys.always[filepath.Join(dataDir, "n00", fmt.Sprintf("img_%03d.png", i))] = syscall.EIO
+	}
+
+	cfg := testImageConfig()
+	cfg.IORetries, cfg.SkipUnreadable = 1, true
+	w, _ := scriptedWalker(cfg, fsys)
+	w.breaker = NewCircuitBreaker(3, time.Hour)
+	_, _, err := loadWithWalker(cfg, w, dataDir, 1)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the load to stop with ErrCircuitOpen despite -skip-unreadable, got %v", err)
+	}
+	if state := w.breaker.State(); state != BreakerOpen {
+		t.Errorf("Expected the breaker open, got %v", state)
+	}
+	// Images 2 to 4 exhaust their retries and open the breaker; nothing after them is opened
+	for i := 5; i < 10; i++ {
+		path := filepath.Join(dataDir, "n00", fmt.Sprintf("img_%03d.png", i))
+		if calls := fsys.calls[path]; calls != 0 {
+			t.Errorf("Expected %s not to be opened once the breaker was open, got %d calls", path, calls)
+		}
+	}
+
+	// Without failures the breaker stays closed and the whole dataset loads
+	images, _, err := LoadTinyImageNetWithCircuitBreaker(testImageConfig(), dataDir, NewCircuitBreaker(3, time.Hour))
+	if err != nil || len(images) != 20 {
+		t.Errorf("Expected all 20 images through a closed breaker, got %d (%v)", len(images), err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang/internal/labels"
+	"golang/internal/metricslog"
+)
+
+// Tiny ImageNet metadata files: wnids.txt lists the class wnids, one per line, in class index
+// order, and words.txt maps every WordNet ID to its English synonyms
+const (
+	wnidsFile = "wnids.txt"
+	wordsFile = "words.txt"
+)
+
+// findMetadataFile returns the path of the named file in dataDir or, as in the Tiny ImageNet
+// archive, next to the train directory. It returns "" when neither exists.
+func findMetadataFile(dataDir, name string) (string, error) {
+	for _, dir := range []string{dataDir, filepath.Dir(filepath.Clean(dataDir))} {
+		path := filepath.Join(dir, name)
+		_, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return "", nil
+}
+
+// loadWnidIndex reads the wnid-to-class-index map from the wnids.txt found by findMetadataFile.
+// It returns nil when there is none.
+func loadWnidIndex(dataDir string) (map[string]int, error) {
+	path, err := findMetadataFile(dataDir, wnidsFile)
+	if path == "" || err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return labels.ReadWnids(file)
+}
+
+// LoadLabelMap reads a Tiny ImageNet words.txt into a map from wnid to English class name
+func LoadLabelMap(wordsFilePath string) (map[string]string, error) {
+	file, err := os.Open(wordsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", wordsFilePath, err)
+	}
+	defer file.Close()
+	return labels.ReadWords(file)
+}
+
+// loadLabelMap reads the class names from the words.txt found by findMetadataFile. It returns
+// nil when there is none.
+func loadLabelMap(dataDir string) (map[string]string, error) {
+	path, err := findMetadataFile(dataDir, wordsFile)
+	if path == "" || err != nil {
+		return nil, err
+	}
+	return LoadLabelMap(path)
+}
+
+// classLabel returns the wnid directory an image belongs to. The archive keeps training images
+// under train/<wnid>/images/, so an images directory is skipped over.
+func classLabel(imagePath string) string {
+	dir := filepath.Dir(imagePath)
+	if filepath.Base(dir) == "images" {
+		dir = filepath.Dir(dir)
+	}
+	return filepath.Base(dir)
+}
+
+// logClasses writes the number of distinct classes and the images per class. With a wnid index,
+// each class is shown with its index and classes missing from wnids.txt are counted. Classes
+// with an entry in names are annotated with their English name.
+func logClasses(logger *metricslog.Logger, imageLabels []string, wnidIndex map[string]int, names map[string]string) {
+	histogram := labels.Histogram(imageLabels)
+	logger.Printf("Number of Classes: %d\n", len(histogram))
+	if len(histogram) == 0 {
+		return
+	}
+	min, max := labels.CountRange(histogram)
+	logger.Printf("Images per Class: min %d, max %d", min, max)
+	unknown := 0
+	for _, c := range histogram {
+		label := c.Label
+		if name, ok := names[c.Label]; ok {
+			label = fmt.Sprintf("%s %s", c.Label, name)
+		}
+		index, ok := wnidIndex[c.Label]
+		switch {
+		case wnidIndex == nil:
+			logger.Printf("  %s: %d", label, c.Count)
+		case ok:
+			logger.Printf("  %s (class %d): %d", label, index, c.Count)
+		default:
+			unknown++
+			logger.Printf("  %s (not in %s): %d", label, wnidsFile, c.Count)
+		}
+	}
+	if wnidIndex != nil {
+		logger.Printf("Class Index: %d wnids in %s, %d loaded classes not listed", len(wnidIndex), wnidsFile, unknown)
+	}
+}
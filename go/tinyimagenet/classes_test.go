@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestLoadWnidIndex(t *testing.T) {
+	root := t.TempDir()
+	trainDir := filepath.Join(root, "train")
+	if err := os.Mkdir(trainDir, 0755); err != nil {
+		t.Fatalf("Failed to create train directory: %v", err)
+	}
+
+	index, err := loadWnidIndex(trainDir)
+	if err != nil || index != nil {
+		t.Fatalf("Expected no index without %s, got %v, %v", wnidsFile, index, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, wnidsFile), []byte("n02\nn01\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", wnidsFile, err)
+	}
+	index, err = loadWnidIndex(trainDir)
+	if err != nil {
+		t.Fatalf("Failed to load wnid index: %v", err)
+	}
+	if len(index) != 2 || index["n02"] != 0 || index["n01"] != 1 {
+		t.Errorf("Expected the wnids.txt order n02=0 n01=1, got %v", index)
+	}
+}
+
+func TestLoadLabelMap(t *testing.T) {
+	root := t.TempDir()
+	trainDir := filepath.Join(root, "train")
+	if err := os.Mkdir(trainDir, 0755); err != nil {
+		t.Fatalf("Failed to create train directory: %v", err)
+	}
+
+	names, err := loadLabelMap(trainDir)
+	if err != nil || names != nil {
+		t.Fatalf("Expected no label map without %s, got %v, %v", wordsFile, names, err)
+	}
+	if _, err := LoadLabelMap(filepath.Join(root, wordsFile)); err == nil {
+		t.Errorf("Expected an error for a missing %s", wordsFile)
+	}
+
+	words := "n01443537\tgoldfish, Carassius auratus\nn02123045\ttabby, tabby cat\n"
+	if err := os.WriteFile(filepath.Join(root, wordsFile), []byte(words), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", wordsFile, err)
+	}
+	names, err = loadLabelMap(trainDir)
+	if err != nil {
+		t.Fatalf("Failed to load label map: %v", err)
+	}
+	if len(names) != 2 || names["n01443537"] != "goldfish" || names["n02123045"] != "tabby" {
+		t.Errorf("Expected goldfish and tabby, got %v", names)
+	}
+}
+
+func TestClassLabel(t *testing.T) {
+	for path, expected := range map[string]string{
+		"train/n01443537/images/n01443537_0.JPEG": "n01443537",
+		"train/n01443537/n01443537_0.png":         "n01443537",
+	} {
+		if got := classLabel(path); got != expected {
+			t.Errorf("%s: expected label %s, got %s", path, expected, got)
+		}
+	}
+}
+
+func TestLogClassesMapsWnids(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logClasses(logger, []string{"n01", "n02", "n01", "n09", "n01"}, map[string]int{"n02": 0, "n01": 1}, map[string]string{"n01": "goldfish", "n09": "bullfrog"})
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{
+		"Number of Classes: 3",
+		"Images per Class: min 1, max 3",
+		"n01 goldfish (class 1): 3",
+		"n02 (class 0): 1",
+		"n09 bullfrog (not in wnids.txt): 1",
+		"1 loaded classes not listed",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang/internal/collector"
+	"golang/internal/metricslog"
+)
+
+// newCollectorSet creates the collectors named in cfg.Collectors, or returns nil when none are
+// selected. Collectors that fail later are reported to logger and disabled.
+func newCollectorSet(cfg BenchmarkConfig, logger *metricslog.Logger) (*collector.Set, error) {
+	names := collector.ParseNames(cfg.Collectors)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	set, err := collector.New(names, func(format string, args ...interface{}) {
+		logger.Printf(format, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Collectors: %s", strings.Join(set.Active(), ", "))
+	return set, nil
+}
+
+// withCollectors wraps run so every measured run is bracketed by the collectors in set and
+// carries their metrics. Warmup runs are told apart by their position in the benchmark loop,
+// which starts with cfg.Warmup of them.
+func withCollectors(cfg BenchmarkConfig, benchmark string, set *collector.Set, run func() (runResult, error)) func() (runResult, error) {
+	calls := 0
+	return func() (runResult, error) {
+		position := calls % (cfg.Warmup + cfg.NumRuns)
+		calls++
+		if position < cfg.Warmup {
+			return run()
+		}
+		set.Start(context.Background(), collector.RunInfo{Benchmark: benchmark, Run: position - cfg.Warmup + 1})
+		result, err := run()
+		result.Collected = set.Stop()
+		return result, err
+	}
+}
+
+// formatCollected formats collector metrics as "name.metric=value, ..." in key order
+func formatCollected(metrics map[string]float64) string {
+	keys := make([]string, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%g", key, metrics[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// logCollectorOverhead writes the total time each collector spent in Start and Stop
+func logCollectorOverhead(logger *metricslog.Logger, set *collector.Set) {
+	overhead := set.Overhead()
+	names := make([]string, 0, len(overhead))
+	for name := range overhead {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %.3f ms", name, overhead[name].Seconds()*1000)
+	}
+	logger.Printf("Collector Overhead: %s", strings.Join(parts, ", "))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"golang/internal/collector"
+	"golang/internal/metricslog"
+)
+
+// countingCollector reports the index of the run it was started for
+type countingCollector struct {
+	run int
+}
+
+func (c *countingCollector) Start(ctx context.Context, info collector.RunInfo) error {
+	c.run = info.Run
+	return nil
+}
+
+func (c *countingCollector) Stop() (map[string]float64, error) {
+	return map[string]float64{"run": float64(c.run)}, nil
+}
+
+func init() {
+	collector.Register("counting", func() collector.Collector { return &countingCollector{} })
+}
+
+func TestWithCollectorsSkipsWarmup(t *testing.T) {
+	cfg := BenchmarkConfig{Warmup: 2, NumRuns: 3, Collectors: "counting"}
+	logger, err := metricslog.New(filepath.Join(t.TempDir(), "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+	set, err := newCollectorSet(cfg, logger)
+	if err != nil {
+		t.Fatalf("Failed to create collectors: %v", err)
+	}
+
+	var collected []map[string]float64
+	run := withCollectors(cfg, "test", set, func() (runResult, error) {
+		return runResult{}, nil
+	})
+	for i := 0; i < cfg.Warmup+cfg.NumRuns; i++ {
+		result, err := run()
+		if err != nil {
+			t.Fatalf("Run %d failed: %v", i, err)
+		}
+		collected = append(collected, result.Collected)
+	}
+
+	for i := 0; i < cfg.Warmup; i++ {
+		if collected[i] != nil {
+			t.Errorf("Warmup run %d: expected no collector metrics, got %v", i+1, collected[i])
+		}
+	}
+	for i, metrics := range collected[cfg.Warmup:] {
+		if metrics["counting.run"] != float64(i+1) {
+			t.Errorf("Measured run %d: expected counting.run %d, got %v", i+1, i+1, metrics)
+		}
+	}
+}
+
+func TestNewCollectorSetRejectsUnknown(t *testing.T) {
+	logger, err := metricslog.New(filepath.Join(t.TempDir(), "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+	if _, err := newCollectorSet(BenchmarkConfig{Collectors: "counting,bogus"}, logger); err == nil {
+		t.Error("Expected an error for an unknown collector")
+	}
+	if set, err := newCollectorSet(BenchmarkConfig{}, logger); set != nil || err != nil {
+		t.Errorf("Expected no collectors without -collectors, got %v, %v", set, err)
+	}
+}
+
+func TestRunOnceRecordsCollectorMetrics(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.BatchSize = 16
+	cfg.SyntheticImages = 64
+	cfg.Collectors = "counting,goroutines"
+
+	var stdout, stderr bytes.Buffer
+	if err := runOnce(cfg, "", &stdout, &stderr); err != nil {
+		t.Fatalf("runOnce failed: %v", err)
+	}
+	var record struct {
+		Run struct {
+			Collectors map[string]float64
+		}
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &record); err != nil {
+		t.Fatalf("stdout is not a JSON document: %v", err)
+	}
+	for _, key := range []string{"counting.run", "counting.overhead_seconds", "goroutines.peak"} {
+		if _, ok := record.Run.Collectors[key]; !ok {
+			t.Errorf("Expected %q under Run.Collectors, got %v", key, record.Run.Collectors)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"runtime"
+	"time"
+)
+
+// BenchmarkConfig holds the image shape and run parameters of the benchmark
+type BenchmarkConfig struct {
+	ImageHeight        int
+	ImageWidth         int
+	Channels           int
+	BatchSize          int           // Processing batch size
+	NumRuns            int           // Number of times to repeat the task for averaging
+	Warmup             int           // Number of runs before the measured runs, excluded from averages
+	GCBetweenRuns      bool          // Run a full garbage collection after every run and log how long it took
+	FreeOSMemory       bool          // With GCBetweenRuns, collect with debug.FreeOSMemory, returning freed memory to the OS
+	Cooldown           time.Duration // Pause before each measured run, outside its timings, 0 for none
+	MaxInFlight        int           // Maximum number of batches processed at once, 0 for one per batch goroutine
+	TileRows           int           // Goroutines the rows of each image are split among by the double, blur and Sobel kernels, 1 for none
+	NumSeeds           int           // Number of times to repeat the whole benchmark loop with a differently shuffled dataset
+	MaxProcsSweep      string        // Comma-separated GOMAXPROCS settings to repeat the benchmark at, empty to disable
+	Kernel             string        // Transform applied to each image, KernelDouble, KernelBlur, KernelSobel or KernelNormalize
+	ErrorRate          float64       // Fraction of images the double kernel fails on with ErrInjected, picked at random, 0 disables
+	ChannelMean        [3]float32    // Per-channel mean the normalize kernel subtracts, computed from the dataset by withChannelStats
+	ChannelStd         [3]float32    // Per-channel standard deviation the normalize kernel divides by, computed with ChannelMean
+	GPUTransferLatency float64       // Simulated host-to-GPU copy time in milliseconds per MB of each batch, 0 disables
+	DRAMBandwidth      float64       // Machine DRAM bandwidth in GB/s for the memory bandwidth efficiency metric, 0 disables
+	SyntheticImages    int           // Number of generated images to use instead of the real dataset, 0 to disable
+	Seed               int64         // Seed for the synthetic image generator and the first shuffle seed
+	Limit              int           // Maximum number of dataset images to load, 0 loads all
+	SampleFraction     float64       // Fraction of the loaded images to keep, sampled per class with Seed, 0 keeps all
+	AutoDowngrade      bool          // Lower SampleFraction automatically when the dataset does not fit in memory
+	LoadWorkers        int           // Number of goroutines decoding dataset images, at least 1
+	IORetries          int           // Retries of a filesystem operation failing with EIO, ESTALE or EAGAIN
+	SkipUnreadable     bool          // Leave out dataset entries that cannot be read instead of aborting the load
+	DedupShards        int           // Number of shards of the map used to drop duplicate images after loading, 0 disables
+
+	Once             bool   // Run one warmup and one measured run and report them as a JSON record
+	DryRun           bool   // Load the dataset, log the loading time and exit without processing
+	HashDataset      bool   // Log a SHA-256 of the loaded pixels and add it to the JSON output
+	JSONPath         string // Destination of the -once record, "-" or empty for stdout
+	CSVPath          string // File to write one CSV row per measured run to, empty to disable
+	InfluxPath       string // File to write one InfluxDB line protocol point per measured run to, empty to disable
+	BatchTimingsPath string // File to write one CSV row per batch of every measured run to, empty to disable
+	GridPath         string // PNG file to render sample images before and after the kernel to, empty to disable
+	CPUProfilePath   string // File to write a pprof CPU profile of the benchmark runs to, empty to disable
+	MemProfilePath   string // File name for the pprof heap profile of each measured run, numbered by run, empty to disable
+	MutexProfilePath string // File to write a pprof mutex profile of one extra run to, empty to disable
+	ListenAddr       string // Address to serve progress gauges on at /metrics, empty to disable
+	Collectors       string // Comma-separated names of extra per-run metric collectors, empty for none
+
+	GCAccounting     bool   // Separate the heap retained by the dataset from the run's allocations
+	GCAccountingFile string // CSV file collecting GC samples across invocations
+	GCTop            int    // Number of allocation sites to report in the heap breakdown
+
+	Pipeline        bool // Stream batches from the loader to the processors instead of loading everything first
+	PipelineWorkers int  // Number of processor goroutines in pipeline mode
+	PipelineBuffer  int  // Number of loaded batches the pipeline queue holds before the loader blocks, at least 1
+}
+
+// DefaultConfig returns the configuration matching the Tiny ImageNet image shape
+func DefaultConfig() BenchmarkConfig {
+	return BenchmarkConfig{
+		ImageHeight:     64,
+		ImageWidth:      64,
+		Channels:        3,
+		BatchSize:       500,
+		NumRuns:         100,
+		NumSeeds:        1,
+		MaxInFlight:     2 * runtime.NumCPU(),
+		TileRows:        1,
+		Kernel:          KernelDouble,
+		Warmup:          5,
+		Seed:            1,
+		LoadWorkers:     runtime.NumCPU(),
+		IORetries:       3,
+		PipelineWorkers: runtime.NumCPU(),
+		PipelineBuffer:  4,
+
+		GCAccountingFile: "go_tinyimagenet_gc_accounting.csv",
+		GCTop:            10,
+	}
+}
+
+// ImageSize returns the number of pixel values in a single image
+func (c BenchmarkConfig) ImageSize() int {
+	return c.ImageHeight * c.ImageWidth * c.Channels
+}
+
+// RegisterFlags binds the configuration fields to command-line flags, using the current values as defaults
+func (c *BenchmarkConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.ImageHeight, "height", c.ImageHeight, "image height in pixels")
+	fs.IntVar(&c.ImageWidth, "width", c.ImageWidth, "image width in pixels")
+	fs.IntVar(&c.Channels, "channels", c.Channels, "number of color channels")
+	fs.IntVar(&c.BatchSize, "batch-size", c.BatchSize, "number of images per processing batch")
+	fs.IntVar(&c.NumRuns, "num-runs", c.NumRuns, "number of times to repeat the task for averaging")
+	fs.IntVar(&c.Warmup, "warmup", c.Warmup, "number of warmup runs excluded from the averages")
+	fs.BoolVar(&c.GCBetweenRuns, "gc-between-runs", c.GCBetweenRuns, "run a full garbage collection after every run, outside its timings, so no run pays for the previous run's garbage, and log how long it took")
+	fs.BoolVar(&c.GCBetweenRuns, "force-gc", c.GCBetweenRuns, "deprecated alias of -gc-between-runs")
+	fs.BoolVar(&c.FreeOSMemory, "free-os-memory", c.FreeOSMemory, "with -gc-between-runs, collect with debug.FreeOSMemory to return freed memory to the OS")
+	fs.DurationVar(&c.Cooldown, "cooldown", c.Cooldown, "pause before each measured run, outside its timings, e.g. 500ms")
+	fs.IntVar(&c.MaxInFlight, "max-inflight", c.MaxInFlight, "maximum number of batch goroutines processing at once (0 for no limit)")
+	fs.IntVar(&c.TileRows, "tile-rows", c.TileRows, "goroutines to split the rows of each image among, with a barrier per image, for the double, blur and sobel kernels (1 for none)")
+	fs.StringVar(&c.Kernel, "kernel", c.Kernel, "image transform to apply: double, blur, sobel or normalize (by the dataset's per-channel mean and std)")
+	fs.Float64Var(&c.ErrorRate, "error-rate", c.ErrorRate, "fraction of images the double kernel fails on at random, to test that the first error cancels the run (0 disables)")
+	fs.Float64Var(&c.GPUTransferLatency, "gpu-transfer-latency", c.GPUTransferLatency, "simulated CPU-to-GPU transfer time in milliseconds per MB of batch data (0 disables)")
+	fs.Float64Var(&c.DRAMBandwidth, "dram-bandwidth", c.DRAMBandwidth, "DRAM bandwidth of the machine in GB/s, to log measured throughput as a share of the memory-bound maximum (0 disables)")
+	fs.IntVar(&c.NumSeeds, "num-seeds", c.NumSeeds, "number of shuffle seeds, starting at -seed, to repeat the benchmark with; reports variance across seeds")
+	fs.StringVar(&c.MaxProcsSweep, "maxprocs-sweep", c.MaxProcsSweep, "comma-separated GOMAXPROCS settings such as 1,2,4,8 to repeat the benchmark at, with a scaling table")
+	fs.IntVar(&c.SyntheticImages, "synthetic", c.SyntheticImages, "number of synthetic images to generate instead of loading the dataset (0 loads the dataset)")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed for the synthetic image generator, -sample-fraction and the first -num-seeds shuffle")
+	fs.IntVar(&c.Limit, "limit", c.Limit, "maximum number of dataset images to load (0 loads all)")
+	fs.Float64Var(&c.SampleFraction, "sample-fraction", c.SampleFraction, "fraction of the loaded images to keep, sampled per class with -seed (0 keeps all)")
+	fs.BoolVar(&c.AutoDowngrade, "auto-downgrade", c.AutoDowngrade, "sample every class down with -sample-fraction when the dataset does not fit in available memory")
+	fs.IntVar(&c.LoadWorkers, "load-workers", c.LoadWorkers, "number of goroutines decoding dataset images")
+	fs.IntVar(&c.IORetries, "io-retries", c.IORetries, "retries of a dataset read failing with a transient error (EIO, ESTALE, EAGAIN)")
+	fs.BoolVar(&c.SkipUnreadable, "skip-unreadable", c.SkipUnreadable, "skip dataset files and directories that cannot be read instead of aborting the load")
+	fs.IntVar(&c.DedupShards, "dedup-shards", c.DedupShards, "drop images whose pixels repeat an earlier image after loading, tracking hashes in a map with this many shards (0 disables)")
+	fs.BoolVar(&c.Once, "once", c.Once, "run one warmup and one measured run on a limited dataset and print a JSON record")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "load the dataset, log the loading time and exit without processing it")
+	fs.BoolVar(&c.HashDataset, "hash-dataset", c.HashDataset, "log a SHA-256 of the loaded pixels, also written to the JSON output, to check that runs read identical data")
+	fs.StringVar(&c.JSONPath, "json", c.JSONPath, "with -once, file to write the JSON record to (- for stdout)")
+	fs.StringVar(&c.CSVPath, "csv", c.CSVPath, "file to write one CSV row per measured run to")
+	fs.StringVar(&c.InfluxPath, "influx", c.InfluxPath, "file to write one InfluxDB line protocol point per measured run to")
+	fs.StringVar(&c.BatchTimingsPath, "batch-timings", c.BatchTimingsPath, "file to write the duration of every batch of every measured run to, as CSV")
+	fs.StringVar(&c.GridPath, "save-grid", c.GridPath, "PNG file to render a grid of sample images before and after the kernel to")
+	fs.StringVar(&c.CPUProfilePath, "cpuprofile", c.CPUProfilePath, "file to write a pprof CPU profile of the benchmark runs to")
+	fs.StringVar(&c.MemProfilePath, "memprofile", c.MemProfilePath, "file name for a pprof heap profile written after each measured run; mem.pprof becomes mem.run1.pprof, mem.run2.pprof, ...")
+	fs.StringVar(&c.MutexProfilePath, "mutexprofile", c.MutexProfilePath, "file to write a pprof mutex profile of one extra run to, logging the most contended call sites")
+	fs.StringVar(&c.ListenAddr, "listen", c.ListenAddr, "address such as :9090 to serve Prometheus progress gauges on at /metrics")
+	fs.StringVar(&c.Collectors, "collectors", c.Collectors, "comma-separated extra per-run metric collectors, e.g. loadavg,goroutines")
+	fs.BoolVar(&c.GCAccounting, "gc-accounting", c.GCAccounting, "report heap retained by the dataset separately from run allocations and GC work")
+	fs.StringVar(&c.GCAccountingFile, "gc-accounting-file", c.GCAccountingFile, "CSV file that collects GC samples across -limit settings")
+	fs.IntVar(&c.GCTop, "gc-top", c.GCTop, "number of allocation sites to list in the heap breakdown")
+	fs.BoolVar(&c.Pipeline, "pipeline", c.Pipeline, "stream batches from the loader to the processors instead of loading the dataset first")
+	fs.IntVar(&c.PipelineWorkers, "pipeline-workers", c.PipelineWorkers, "number of processor goroutines in -pipeline mode")
+	fs.IntVar(&c.PipelineBuffer, "pipeline-buffer", c.PipelineBuffer, "number of loaded batches buffered between the loader and the processors in -pipeline mode, at least 1")
+}
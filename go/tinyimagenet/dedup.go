@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// FNV-1a offset basis and prime of the 64-bit image hash
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// dedupShard is one lock-protected part of a ConcurrentDeduplicator. It maps each image hash
+// to the lowest index it was seen at.
+type dedupShard struct {
+	mu    sync.Mutex
+	first map[uint64]int
+}
+
+// ConcurrentDeduplicator tracks the image hashes seen by several workers in a map split into
+// shards, each behind its own mutex, so workers only contend when their hashes share a shard
+type ConcurrentDeduplicator struct {
+	shards []dedupShard
+}
+
+// NewConcurrentDeduplicator returns a deduplicator with numShards shards, at least one
+func NewConcurrentDeduplicator(numShards int) *ConcurrentDeduplicator {
+	if numShards < 1 {
+		numShards = 1
+	}
+	d := &ConcurrentDeduplicator{shards: make([]dedupShard, numShards)}
+	for i := range d.shards {
+		d.shards[i].first = make(map[uint64]int)
+	}
+	return d
+}
+
+// NumShards returns the number of shards of the map
+func (d *ConcurrentDeduplicator) NumShards() int {
+	return len(d.shards)
+}
+
+// observe records that the image at index has the given hash, keeping the lowest index per hash
+func (d *ConcurrentDeduplicator) observe(hash uint64, index int) {
+	shard := &d.shards[hash%uint64(len(d.shards))]
+	shard.mu.Lock()
+	if first, ok := shard.first[hash]; !ok || index < first {
+		shard.first[hash] = index
+	}
+	shard.mu.Unlock()
+}
+
+// firstIndex returns the lowest index recorded for hash
+func (d *ConcurrentDeduplicator) firstIndex(hash uint64) int {
+	shard := &d.shards[hash%uint64(len(d.shards))]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return shard.first[hash]
+}
+
+// Deduplicate hashes the images on workers goroutines and returns the images and labels
+// without repeats. The first occurrence of each image is kept, so the result does not
+// depend on the order the workers finish in.
+func (d *ConcurrentDeduplicator) Deduplicate(images [][]float32, labels []string, workers int) ([][]float32, []string) {
+	if workers < 1 {
+		workers = 1
+	}
+	hashes := make([]uint64, len(images))
+	chunkSize := (len(images) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(images); start += chunkSize {
+		end := start + chunkSize
+		if end > len(images) {
+			end = len(images)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				hashes[i] = hashImage(images[i])
+				d.observe(hashes[i], i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	keptImages := make([][]float32, 0, len(images))
+	keptLabels := make([]string, 0, len(labels))
+	for i, hash := range hashes {
+		if d.firstIndex(hash) != i {
+			continue
+		}
+		keptImages = append(keptImages, images[i])
+		if i < len(labels) {
+			keptLabels = append(keptLabels, labels[i])
+		}
+	}
+	return keptImages, keptLabels
+}
+
+// DeduplicateImages drops images whose pixels repeat an earlier image, such as the same file
+// copied into two class directories, using one shard per worker
+func DeduplicateImages(images [][]float32, labels []string, workers int) ([][]float32, []string) {
+	return NewConcurrentDeduplicator(workers).Deduplicate(images, labels, workers)
+}
+
+// deduplicateDataset removes repeated images with a cfg.DedupShards-shard deduplicator and
+// logs how many were dropped and the time it added to the load
+func deduplicateDataset(cfg BenchmarkConfig, logger *metricslog.Logger, images [][]float32, labels []string, loadingTime time.Duration) ([][]float32, []string) {
+	workers := runtime.NumCPU()
+	start := time.Now()
+	keptImages, keptLabels := NewConcurrentDeduplicator(cfg.DedupShards).Deduplicate(images, labels, workers)
+	elapsed := time.Since(start)
+	logger.Printf("Deduplication: %d duplicate images removed, %d remaining (%d shards, %d workers)",
+		len(images)-len(keptImages), len(keptImages), cfg.DedupShards, workers)
+	logger.Printf("Deduplication Time: %s seconds (%.2f%% overhead on the %s second load without deduplication)",
+		metrics.FormatDuration(elapsed), dedupOverhead(elapsed, loadingTime), metrics.FormatDuration(loadingTime))
+	return keptImages, keptLabels
+}
+
+// dedupOverhead returns the deduplication time as a percentage of the load time
+func dedupOverhead(dedup, load time.Duration) float64 {
+	if load <= 0 {
+		return 0
+	}
+	return 100 * dedup.Seconds() / load.Seconds()
+}
+
+// hashImage returns a 64-bit FNV-1a style hash of the pixel values, taking each value's bit
+// pattern as one 32-bit word rather than four bytes to keep up with the loader
+func hashImage(image []float32) uint64 {
+	hash := uint64(fnvOffset64)
+	for _, v := range image {
+		hash ^= uint64(math.Float32bits(v))
+		hash *= fnvPrime64
+	}
+	return hash
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+	"golang/internal/synthetic"
+)
+
+func TestDeduplicateImagesKeepsFirstOccurrence(t *testing.T) {
+	a, b, c := []float32{1, 2, 3}, []float32{4, 5, 6}, []float32{7, 8, 9}
+	images := [][]float32{a, b, a, c, b, a}
+	labels := []string{"n1", "n2", "n3", "n4", "n5", "n6"}
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		keptImages, keptLabels := DeduplicateImages(images, labels, workers)
+		if !reflect.DeepEqual(keptImages, [][]float32{a, b, c}) {
+			t.Errorf("Expected images a, b, c with %d workers, got %v", workers, keptImages)
+		}
+		if !reflect.DeepEqual(keptLabels, []string{"n1", "n2", "n4"}) {
+			t.Errorf("Expected labels n1, n2, n4 with %d workers, got %v", workers, keptLabels)
+		}
+	}
+}
+
+func TestDeduplicateImagesKeepsDistinctImages(t *testing.T) {
+	cfg := syntheticConfig()
+	images, labels := synthetic.GenerateSyntheticImages(cfg.SyntheticImages, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+
+	keptImages, keptLabels := NewConcurrentDeduplicator(4).Deduplicate(images, labels, 3)
+	if len(keptImages) != len(images) || len(keptLabels) != len(labels) {
+		t.Errorf("Expected all %d distinct images to be kept, got %d images and %d labels", len(images), len(keptImages), len(keptLabels))
+	}
+}
+
+func TestNewConcurrentDeduplicatorHasOneShardAtLeast(t *testing.T) {
+	if got := NewConcurrentDeduplicator(0).NumShards(); got != 1 {
+		t.Errorf("Expected 1 shard, got %d", got)
+	}
+	if got := NewConcurrentDeduplicator(8).NumShards(); got != 8 {
+		t.Errorf("Expected 8 shards, got %d", got)
+	}
+}
+
+func TestDeduplicateDatasetLogsOverhead(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.DedupShards = 4
+	image := []float32{1, 2, 3}
+	images, labels := deduplicateDataset(cfg, logger, [][]float32{image, image, {4, 5, 6}}, []string{"n1", "n1", "n2"}, time.Second)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	if len(images) != 2 || len(labels) != 2 {
+		t.Errorf("Expected 2 images and labels, got %d and %d", len(images), len(labels))
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{"Deduplication: 1 duplicate images removed, 2 remaining (4 shards", "Deduplication Time:", "overhead on the 1.000000000 second load"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestDedupOverhead(t *testing.T) {
+	if got := dedupOverhead(250*time.Millisecond, time.Second); got != 25 {
+		t.Errorf("Expected 25%% overhead, got %v", got)
+	}
+	if got := dedupOverhead(time.Second, 0); got != 0 {
+		t.Errorf("Expected 0%% overhead without a load time, got %v", got)
+	}
+}
+
+// BenchmarkDeduplicateImages compares the deduplication throughput at 1, 4 and 8 shards with
+// the no-dedup baseline of passing the images through unchanged
+func BenchmarkDeduplicateImages(b *testing.B) {
+	cfg := DefaultConfig()
+	images, labels := synthetic.GenerateSyntheticImages(2000, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+	// Every fourth image repeats an earlier one
+	for i := 3; i < len(images); i += 4 {
+		images[i] = images[i-3]
+	}
+	const workers = 8
+
+	b.Run("no-dedup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			kept := make([][]float32, 0, len(images))
+			keptLabels := make([]string, 0, len(labels))
+			kept = append(kept, images...)
+			keptLabels = append(keptLabels, labels...)
+			allocSinkImages, allocSinkLabels = kept, keptLabels
+		}
+		b.ReportMetric(float64(len(images)*b.N)/b.Elapsed().Seconds(), "images/s")
+	})
+	for _, shards := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("shards-%d", shards), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				allocSinkImages, allocSinkLabels = NewConcurrentDeduplicator(shards).Deduplicate(images, labels, workers)
+			}
+			b.ReportMetric(float64(len(images)*b.N)/b.Elapsed().Seconds(), "images/s")
+		})
+	}
+}
+
+// allocSinkImages and allocSinkLabels keep the benchmark results alive so the work is not optimized away
+var (
+	allocSinkImages [][]float32
+	allocSinkLabels []string
+)
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"time"
+
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// logDryRun writes the outcome of a -dry-run, which loads the dataset and stops before any
+// processing, so the loading time reflects storage and decoding alone
+func logDryRun(logger *metricslog.Logger, numImages int, loadingTime time.Duration) {
+	logger.Printf("Dry Run: loaded %d images in %s seconds (%.2f images/second), processing skipped",
+		numImages, metrics.FormatDuration(loadingTime), throughput(numImages, loadingTime))
+}
+
+// logLoadIO writes the bytes the process read and wrote while loading the dataset, or why they
+// are unavailable
+func logLoadIO(logger *metricslog.Logger, ioErr error, readBytes, writeBytes uint64) {
+	if ioErr != nil {
+		logger.Printf("Dataset load I/O: unavailable (%v)", ioErr)
+		return
+	}
+	logger.Printf("Dataset load I/O: %s MB read, %s MB written",
+		metrics.FormatMB(float64(readBytes)), metrics.FormatMB(float64(writeBytes)))
+}
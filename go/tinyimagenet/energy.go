@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"golang/internal/energy"
+	"golang/internal/metricslog"
+)
+
+// newEnergyMeter opens the RAPL counters and logs whether energy will be reported. It returns
+// nil when the host has no RAPL support or the counters are not readable.
+func newEnergyMeter(logger *metricslog.Logger, root string) *energy.Meter {
+	meter, err := energy.NewMeter(root)
+	switch {
+	case errors.Is(err, energy.ErrUnavailable):
+		logger.Printf("Energy: unavailable (no RAPL counters on this host)")
+		return nil
+	case errors.Is(err, fs.ErrPermission):
+		logger.Printf("Energy: unavailable (permission denied reading RAPL counters; run as root to measure)")
+		return nil
+	case err != nil:
+		logger.Printf("Energy: unavailable (%v)", err)
+		return nil
+	}
+	logger.Printf("Energy: measuring RAPL domains %s", strings.Join(meter.Domains(), ", "))
+	return meter
+}
+
+// withEnergy wraps run so every call is bracketed by RAPL counter readings
+func withEnergy(meter *energy.Meter, run func() (runResult, error)) func() (runResult, error) {
+	return func() (runResult, error) {
+		if err := meter.Start(); err != nil {
+			return runResult{}, fmt.Errorf("failed to read energy counters: %v", err)
+		}
+		result, err := run()
+		if err != nil {
+			return result, err
+		}
+		measurement, err := meter.Stop()
+		if err != nil {
+			return result, fmt.Errorf("failed to read energy counters: %v", err)
+		}
+		result.Energy = measurement
+		return result, nil
+	}
+}
+
+// logEnergy writes the energy of a run or of the run totals; images is the number of images
+// processed while the energy was measured
+func logEnergy(logger *metricslog.Logger, label string, m energy.Measurement, runs, images int) {
+	if !m.Available() || runs == 0 {
+		return
+	}
+	var perImage float64
+	if images > 0 {
+		perImage = m.Joules() / float64(images)
+	}
+	logger.Printf("%s: %.9f J per run (package %.9f J, DRAM %.9f J), %.6f J/image, %.2f W", label,
+		m.Joules()/float64(runs), m.PackageJoules()/float64(runs), m.DRAMJoules()/float64(runs), perImage, m.Watts())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+// writeZone creates a RAPL package zone with the given energy counter under root
+func writeZone(t *testing.T, root, energyUJ string) {
+	t.Helper()
+	dir := filepath.Join(root, "intel-rapl:0")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	files := map[string]string{"name": "package-0\n", "energy_uj": energyUJ + "\n", "max_energy_range_uj": "262143328850\n"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestWithEnergy(t *testing.T) {
+	root := t.TempDir()
+	writeZone(t, root, "1000000")
+	logPath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logPath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+
+	meter := newEnergyMeter(logger, root)
+	if meter == nil {
+		t.Fatalf("Expected a meter for a readable powercap tree")
+	}
+	run := withEnergy(meter, func() (runResult, error) {
+		writeZone(t, root, "3500000")
+		return runResult{ImagesProcessed: 10}, nil
+	})
+	result, err := run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Energy.Joules() != 2.5 {
+		t.Errorf("Expected 2.5 J, got %v", result.Energy.Joules())
+	}
+
+	var summary runSummary
+	summary.add(result)
+	logAverages(logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(content), "0.250000 J/image") {
+		t.Errorf("Expected joules per image in the log, got:\n%s", content)
+	}
+}
+
+func TestNewEnergyMeterUnavailable(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logPath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	if meter := newEnergyMeter(logger, t.TempDir()); meter != nil {
+		t.Errorf("Expected no meter without RAPL zones")
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(content), "Energy: unavailable") {
+		t.Errorf("Expected energy to be marked unavailable, got:\n%s", content)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "sync"
+
+// ImageBatchFlat holds a batch of images in one contiguous buffer, image after image, instead of
+// one heap allocation per image behind a slice of pointers. A kernel then streams through memory
+// in order, which suits the hardware prefetcher.
+type ImageBatchFlat struct {
+	Data      []float32 // numImages*ImageSize pixel values
+	Labels    []string
+	ImageSize int // Pixel values per image
+}
+
+// NewImageBatchFlat copies a jagged batch into a flat one
+func NewImageBatchFlat(cfg BenchmarkConfig, batch ImageBatch) ImageBatchFlat {
+	size := cfg.ImageSize()
+	data := make([]float32, len(batch.Images)*size)
+	for i, image := range batch.Images {
+		copy(data[i*size:(i+1)*size], image)
+	}
+	return ImageBatchFlat{Data: data, Labels: batch.Labels, ImageSize: size}
+}
+
+// Len returns the number of images in the batch
+func (b ImageBatchFlat) Len() int {
+	if b.ImageSize == 0 {
+		return 0
+	}
+	return len(b.Data) / b.ImageSize
+}
+
+// Image returns image i as a view of the buffer. Its capacity ends with the image, so appending
+// to it cannot overwrite the next one.
+func (b ImageBatchFlat) Image(i int) []float32 {
+	start, end := i*b.ImageSize, (i+1)*b.ImageSize
+	return b.Data[start:end:end]
+}
+
+// ProcessBatchFlat processes a flat batch like ProcessBatch. Kernels that return a new image have
+// it copied back into the buffer.
+func ProcessBatchFlat(cfg BenchmarkConfig, batch ImageBatchFlat, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for i := 0; i < batch.Len(); i++ {
+		image := batch.Image(i)
+		if processed := ProcessImage(cfg, image); len(processed) > 0 && &processed[0] != &image[0] {
+			copy(image, processed)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"golang/internal/gcaccount"
+	"golang/internal/metricslog"
+)
+
+// gcAccountingProfileRate samples on average one allocation per 4 KB, so every decoded
+// image is recorded while the per-run slice headers stay cheap to profile
+const gcAccountingProfileRate = 4096
+
+// gcAccountant separates the heap retained by the loaded dataset from what the measured runs
+// allocate, and tracks the GC work done during the measured runs
+type gcAccountant struct {
+	cfg        BenchmarkConfig
+	afterLoad  gcaccount.Profile
+	afterRun   gcaccount.Profile
+	captured   bool
+	calls      int
+	measured   int
+	gcCycles   uint32
+	gcPauseNs  uint64
+	captureErr error
+}
+
+// newGCAccountant enables allocation profiling; call it before the dataset is loaded
+func newGCAccountant(cfg BenchmarkConfig) *gcAccountant {
+	runtime.MemProfileRate = gcAccountingProfileRate
+	return &gcAccountant{cfg: cfg}
+}
+
+// captureAfterLoad records the heap retained once the dataset is in memory
+func (a *gcAccountant) captureAfterLoad() error {
+	profile, err := gcaccount.CaptureHeapProfile()
+	if err != nil {
+		return err
+	}
+	a.afterLoad = profile
+	return nil
+}
+
+// wrap returns a run function that records GC cycles and pause time for measured runs and
+// captures a heap profile after the first measured run
+func (a *gcAccountant) wrap(run func() (runResult, error)) func() (runResult, error) {
+	return func() (runResult, error) {
+		// Every benchmark loop, one per seed, starts with its own warmup runs
+		position := a.calls % (a.cfg.Warmup + a.cfg.NumRuns)
+		a.calls++
+		if position < a.cfg.Warmup {
+			return run()
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		result, err := run()
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			return result, err
+		}
+		a.measured++
+		a.gcCycles += after.NumGC - before.NumGC
+		a.gcPauseNs += after.PauseTotalNs - before.PauseTotalNs
+
+		if !a.captured {
+			a.captured = true
+			a.afterRun, a.captureErr = gcaccount.CaptureHeapProfile()
+		}
+		return result, nil
+	}
+}
+
+// sample summarizes the measured runs for the correlation file
+func (a *gcAccountant) sample() gcaccount.Sample {
+	s := gcaccount.Sample{Limit: a.cfg.Limit, RetainedBytes: a.afterLoad.InUseBytes()}
+	if a.measured > 0 {
+		s.GCCyclesPerRun = float64(a.gcCycles) / float64(a.measured)
+		s.GCPausePerRun = time.Duration(a.gcPauseNs / uint64(a.measured))
+	}
+	return s
+}
+
+// report logs the dataset vs run breakdown, appends this invocation to the samples file and
+// logs the correlation across every invocation recorded there
+func (a *gcAccountant) report(logger *metricslog.Logger) error {
+	logger.Printf("\nGC Accounting:")
+	if a.captureErr != nil {
+		return fmt.Errorf("failed to capture heap profile after the first measured run: %v", a.captureErr)
+	}
+	if a.captured {
+		for _, line := range gcaccount.Compare(a.afterLoad, a.afterRun, a.cfg.GCTop).Report() {
+			logger.Printf("%s", line)
+		}
+	}
+	sample := a.sample()
+	logger.Printf("GC Cycles per Run: %.2f", sample.GCCyclesPerRun)
+	logger.Printf("GC Pause per Run: %.3f ms", sample.GCPausePerRun.Seconds()*1000)
+
+	if err := gcaccount.AppendSample(a.cfg.GCAccountingFile, sample); err != nil {
+		return err
+	}
+	file, err := os.Open(a.cfg.GCAccountingFile)
+	if err != nil {
+		return fmt.Errorf("failed to open GC samples file: %v", err)
+	}
+	defer file.Close()
+	samples, err := gcaccount.ReadSamples(file)
+	if err != nil {
+		return err
+	}
+	for _, line := range gcaccount.CorrelationReport(samples) {
+		logger.Printf("%s", line)
+	}
+	return nil
+}
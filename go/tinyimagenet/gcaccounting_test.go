@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestGCAccountantSeparatesDatasetFromRuns(t *testing.T) {
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.BatchSize = 16
+	cfg.NumRuns = 3
+	cfg.Warmup = 2
+	cfg.SyntheticImages = 128
+	cfg.GCTop = 3
+	cfg.GCAccountingFile = filepath.Join(dir, "gc.csv")
+
+	accountant := newGCAccountant(cfg)
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to load dataset: %v", err)
+	}
+	if err = accountant.captureAfterLoad(); err != nil {
+		t.Fatalf("Failed to capture heap profile: %v", err)
+	}
+
+	calls := 0
+	run := accountant.wrap(func() (runResult, error) {
+		calls++
+		return measureRun(cfg, images, labels)
+	})
+	logger, err := metricslog.New(filepath.Join(dir, "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open logger: %v", err)
+	}
+	if _, err := runBenchmark(cfg, logger, run); err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+
+	if calls != cfg.Warmup+cfg.NumRuns {
+		t.Errorf("Expected %d calls, got %d", cfg.Warmup+cfg.NumRuns, calls)
+	}
+	if accountant.measured != cfg.NumRuns {
+		t.Errorf("Expected %d measured runs, got %d", cfg.NumRuns, accountant.measured)
+	}
+	if !accountant.captured {
+		t.Errorf("Expected a heap profile after the first measured run")
+	}
+	// Images smaller than the sampling rate are estimated, so only require half the dataset
+	if accountant.afterLoad.InUseBytes() < int64(128*cfg.ImageSize()*4/2) {
+		t.Errorf("Expected the dataset to be retained after loading, got %d bytes", accountant.afterLoad.InUseBytes())
+	}
+
+	if err := accountant.report(logger); err != nil {
+		t.Fatalf("Failed to report GC accounting: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, want := range []string{"Dataset Retained Heap", "GC Pause per Run", "GC Correlation Samples: 1"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected log to contain %q", want)
+		}
+	}
+}
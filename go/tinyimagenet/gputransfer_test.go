@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGPUTransferSerializesBatches(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 64, 64, 1
+	cfg.BatchSize, cfg.SyntheticImages = 64, 256
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	// Each batch is 64 x 4096 float32 values, 1 MB, so the four transfers take 4 x 20ms on one bus
+	cfg.GPUTransferLatency = 20
+
+	executionTime, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if imagesProcessed != 256 {
+		t.Errorf("Expected 256 images processed, got %d", imagesProcessed)
+	}
+	if executionTime < 80*time.Millisecond {
+		t.Errorf("Expected the transfers to take at least 80ms in total, got %v", executionTime)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveGrid(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 8, 8, 3
+	cfg.SyntheticImages = 10
+	images, _, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	first := append([]float32(nil), images[0]...)
+
+	path := filepath.Join(t.TempDir(), "grid.png")
+	if err := saveGrid(cfg, images, path); err != nil {
+		t.Fatalf("Failed to save grid: %v", err)
+	}
+	for i, v := range first {
+		if images[0][i] != v {
+			t.Fatalf("Expected the dataset image to be left unchanged, pixel %d changed from %v to %v", i, v, images[0][i])
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open grid: %v", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode grid: %v", err)
+	}
+	// Two columns (original and kernel) are wider than one, and gridSamples rows taller than gridSamples images
+	if b := img.Bounds(); b.Dx() <= 2*cfg.ImageWidth || b.Dy() <= gridSamples*cfg.ImageHeight {
+		t.Errorf("Expected a grid larger than %d x %d, got %d x %d", 2*cfg.ImageWidth, gridSamples*cfg.ImageHeight, b.Dx(), b.Dy())
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"golang/internal/kernels"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+)
+
+// Names of the processing kernels that can be selected with the -kernel flag
+const (
+	KernelDouble    = "double"
+	KernelBlur      = "blur"
+	KernelSobel     = "sobel"
+	KernelNormalize = "normalize"
+)
+
+// ErrBadImage is returned for an image a kernel cannot process
+var ErrBadImage = errors.New("bad image")
+
+// ErrInjected is returned by SimulateImageProcessing for the images cfg.ErrorRate picks to fail
+var ErrInjected = errors.New("injected processing error")
+
+// kernelFunc transforms one image like ProcessImage, or returns an error for an image it cannot
+// process
+type kernelFunc func(cfg BenchmarkConfig, image []float32) ([]float32, error)
+
+// kernelFuncs holds the fallible kernels that can be selected by name besides the built-in ones.
+// Tests register kernels here that fail on chosen images.
+var kernelFuncs = map[string]kernelFunc{}
+
+// validateKernel checks that name is a known processing kernel
+func validateKernel(name string) error {
+	switch name {
+	case KernelDouble, KernelBlur, KernelSobel, KernelNormalize:
+		return nil
+	}
+	if _, ok := kernelFuncs[name]; ok {
+		return nil
+	}
+	return fmt.Errorf("unknown kernel %q: expected %q, %q, %q or %q", name, KernelDouble, KernelBlur, KernelSobel, KernelNormalize)
+}
+
+// ProcessImage applies the kernel selected by cfg.Kernel to image. The double kernel works in
+// place; the blur and Sobel kernels need their neighbours unchanged, so they return a new image,
+// as does the normalize kernel, which applies the channel statistics in cfg.
+// ProcessImage never fails: the errors cfg.ErrorRate injects only surface through processImage.
+// With cfg.TileRows above 1 the double, blur and Sobel kernels split the rows of the image among
+// that many goroutines and return once all of them are done.
+func ProcessImage(cfg BenchmarkConfig, image []float32) []float32 {
+	switch cfg.Kernel {
+	case KernelBlur:
+		if cfg.TileRows > 1 {
+			return kernels.GaussianBlurTiled(image, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.TileRows)
+		}
+		return kernels.GaussianBlur(image, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels)
+	case KernelSobel:
+		return kernels.SobelTiled(image, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, max(cfg.TileRows, 1))
+	case KernelNormalize:
+		return kernels.Normalize(image, cfg.Channels, cfg.ChannelMean, cfg.ChannelStd)
+	}
+	return doubleImage(cfg, image)
+}
+
+// withChannelStats returns cfg carrying the per-channel mean and standard deviation of images
+// when cfg.Kernel is the normalize kernel, and cfg unchanged otherwise. The statistics and the
+// time their reduction took are logged here, apart from the per-image transform the runs time.
+func withChannelStats(cfg BenchmarkConfig, logger *metricslog.Logger, images [][]float32) BenchmarkConfig {
+	if cfg.Kernel != KernelNormalize {
+		return cfg
+	}
+	start := time.Now()
+	cfg.ChannelMean, cfg.ChannelStd = kernels.ComputeChannelStats(images, cfg.Channels)
+	logger.Printf("Channel Statistics: mean %v, std %v over %d images, reduced in %s seconds (%d goroutines)",
+		cfg.ChannelMean, cfg.ChannelStd, len(images), metrics.FormatDuration(time.Since(start)), runtime.NumCPU())
+	return cfg
+}
+
+// logTiling records the intra-image tiling next to the batch concurrency it nests inside. Every
+// batch in flight runs its own cfg.TileRows tile goroutines, so with numBatches batches their
+// product over GOMAXPROCS is how far the kernels can over-subscribe the CPUs.
+func logTiling(cfg BenchmarkConfig, logger *metricslog.Logger, numBatches int) {
+	if cfg.TileRows <= 1 {
+		logger.Printf("Tile Rows per Image: 1 (untiled)")
+		return
+	}
+	inFlight := numBatches
+	if cfg.MaxInFlight > 0 && cfg.MaxInFlight < numBatches {
+		inFlight = cfg.MaxInFlight
+	}
+	tiles := min(cfg.TileRows, cfg.ImageHeight)
+	procs := runtime.GOMAXPROCS(0)
+	logger.Printf("Tile Rows per Image: %d (%d tiles of %d rows)", cfg.TileRows, tiles, cfg.ImageHeight)
+	logger.Printf("Kernel Goroutines: up to %d batches x %d tiles = %d on GOMAXPROCS %d (%.2fx over-subscription)",
+		inFlight, tiles, inFlight*tiles, procs, float64(inFlight*tiles)/float64(procs))
+}
+
+// processImage applies the kernel selected by cfg.Kernel to image like ProcessImage, but returns
+// an error instead of running the kernel out of bounds on an image of the wrong size, and passes
+// on the errors of the kernels in kernelFuncs and the ones SimulateImageProcessing injects
+func processImage(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+	if len(image) != cfg.ImageSize() {
+		return nil, fmt.Errorf("%w: %d pixel values, expected %d", ErrBadImage, len(image), cfg.ImageSize())
+	}
+	if kernel, ok := kernelFuncs[cfg.Kernel]; ok {
+		return kernel(cfg, image)
+	}
+	switch cfg.Kernel {
+	case KernelBlur, KernelSobel, KernelNormalize:
+		return ProcessImage(cfg, image), nil
+	}
+	return SimulateImageProcessing(cfg, image)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestProcessImageKernels(t *testing.T) {
+	cfg := DefaultConfig()
+	image := make([]float32, cfg.ImageSize())
+	for i := range image {
+		image[i] = 1
+	}
+
+	cfg.Kernel = KernelBlur
+	blurred := ProcessImage(cfg, image)
+	if blurred[0] != 1 || image[0] != 1 {
+		t.Errorf("Expected blur to keep a constant image and leave the input alone, got %.2f and %.2f", blurred[0], image[0])
+	}
+
+	cfg.Kernel = KernelSobel
+	edges := ProcessImage(cfg, image)
+	if edges[0] != 0 || edges[1] != 1 || image[0] != 1 {
+		t.Errorf("Expected Sobel to find no edges in a constant image and leave the input alone, got %.2f, %.2f and %.2f", edges[0], edges[1], image[0])
+	}
+
+	cfg.Kernel = KernelDouble
+	doubled := ProcessImage(cfg, image)
+	if doubled[0] != 2 {
+		t.Errorf("Expected double to give 2, got %.2f", doubled[0])
+	}
+}
+
+func TestNormalizeKernelUsesDatasetStats(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth = 8, 8
+	cfg.SyntheticImages = 50
+	cfg.Kernel = KernelNormalize
+	images, _, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	var logOutput bytes.Buffer
+	logger := metricslog.NewStream(&logOutput)
+	cfg = withChannelStats(cfg, logger, images)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+	if !strings.Contains(logOutput.String(), "Channel Statistics: mean [") || !strings.Contains(logOutput.String(), "over 50 images, reduced in") {
+		t.Errorf("Expected the channel statistics and reduction time in the log, got:\n%s", logOutput.String())
+	}
+
+	// Normalized with its own statistics, the dataset has a mean of 0 in every channel
+	var sums [3]float64
+	for _, image := range images {
+		normalized := ProcessImage(cfg, image)
+		for i, v := range normalized {
+			sums[i%3] += float64(v)
+		}
+	}
+	pixels := float64(len(images) * cfg.ImageHeight * cfg.ImageWidth)
+	for c, sum := range sums {
+		if mean := sum / pixels; mean > 1e-4 || mean < -1e-4 {
+			t.Errorf("Expected channel %d to have mean 0 after normalizing, got %v", c, mean)
+		}
+	}
+
+	// Other kernels need no statistics
+	cfg.Kernel, cfg.ChannelStd = KernelDouble, [3]float32{}
+	if withChannelStats(cfg, metricslog.NewStream(io.Discard), images).ChannelStd != ([3]float32{}) {
+		t.Errorf("Expected the config unchanged for the double kernel")
+	}
+}
+
+func TestTiledProcessImageMatchesUntiled(t *testing.T) {
+	cfg := DefaultConfig()
+	rng := rand.New(rand.NewSource(1))
+	image := make([]float32, cfg.ImageSize())
+	for i := range image {
+		image[i] = rng.Float32()
+	}
+	tiled := cfg
+	tiled.TileRows = 5 // Does not divide the image height, so the tiles differ in size
+
+	// Doubling is done in place, so each configuration gets its own copy
+	for _, kernel := range []string{KernelDouble, KernelBlur, KernelSobel} {
+		cfg.Kernel, tiled.Kernel = kernel, kernel
+		expected := ProcessImage(cfg, append([]float32(nil), image...))
+		got := ProcessImage(tiled, append([]float32(nil), image...))
+		for i := range expected {
+			switch kernel {
+			case KernelDouble:
+				if math.Float32bits(got[i]) != math.Float32bits(expected[i]) {
+					t.Fatalf("%s: pixel %d not bit-identical: expected %v, got %v", kernel, i, expected[i], got[i])
+				}
+			default:
+				if math.Abs(float64(got[i]-expected[i])) > 1e-6 {
+					t.Fatalf("%s: pixel %d mismatch: expected %v, got %v", kernel, i, expected[i], got[i])
+				}
+			}
+		}
+	}
+
+	var logOutput bytes.Buffer
+	logger := metricslog.NewStream(&logOutput)
+	tiled.MaxInFlight = 4
+	logTiling(tiled, logger, 10)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+	expected := fmt.Sprintf("Kernel Goroutines: up to 4 batches x 5 tiles = 20 on GOMAXPROCS %d", runtime.GOMAXPROCS(0))
+	if !strings.Contains(logOutput.String(), "Tile Rows per Image: 5") || !strings.Contains(logOutput.String(), expected) {
+		t.Errorf("Expected the tiling and batch settings in the log, got:\n%s", logOutput.String())
+	}
+}
@@ -1,26 +1,33 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
+	"io"
 	"log"
+	"math/rand"
 	"os"
-	"path/filepath"
-	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "image/png"
 
-	"github.com/shirou/gopsutil/cpu"
-)
-
-const (
-	imageHeight = 64
-	imageWidth  = 64
-	channels    = 3
-	batchSize   = 500 // Processing batch size
-	numRuns     = 100 // Number of times to repeat the task for averaging
+	"github.com/shirou/gopsutil/process"
+	"golang.org/x/sync/errgroup"
+
+	"golang/internal/energy"
+	"golang/internal/kernels"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+	"golang/internal/monitor"
+	"golang/internal/result"
+	"golang/internal/subset"
+	"golang/internal/synthetic"
+	"golang/internal/sysinfo"
 )
 
 // ImageBatch represents a batch of images
@@ -30,106 +37,436 @@ type ImageBatch struct {
 }
 
 // LoadTinyImageNet loads all images and their labels from a specified directory
-func LoadTinyImageNet(dataDir string) ([][]float32, []string, error) {
-	var allImages [][]float32
-	var allLabels []string
+// using cfg.LoadWorkers decoding goroutines
+func LoadTinyImageNet(cfg BenchmarkConfig, dataDir string) ([][]float32, []string, error) {
+	return LoadTinyImageNetWithWorkers(cfg, dataDir, cfg.LoadWorkers)
+}
 
-	fmt.Println("Loading Tiny ImageNet dataset...")
+// loadDataset generates synthetic images when cfg.SyntheticImages is set and loads the Tiny ImageNet
+// training set from dataDir otherwise, cut down by -limit and -sample-fraction
+func loadDataset(cfg BenchmarkConfig, dataDir string) ([][]float32, []string, error) {
+	images, labels, _, err := loadDatasetWithReport(cfg, dataDir)
+	return images, labels, err
+}
 
-	err := filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// loadDatasetWithReport is loadDataset that also returns the filesystem incidents of the load,
+// which are empty for synthetic images
+func loadDatasetWithReport(cfg BenchmarkConfig, dataDir string) ([][]float32, []string, LoadReport, error) {
+	if cfg.SyntheticImages > 0 {
+		numImages := cfg.SyntheticImages
+		if cfg.Limit > 0 && cfg.Limit < numImages {
+			numImages = cfg.Limit
 		}
-		if !info.IsDir() && (filepath.Ext(path) == ".jpg" || filepath.Ext(path) == ".png") {
-			img, label, err := loadImage(path)
-			if err != nil {
-				return fmt.Errorf("failed to load image %s: %v", path, err)
+		images, labels := synthetic.GenerateSyntheticImages(numImages, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+		images, labels = subset.Apply(images, labels, cfg.Limit, cfg.SampleFraction, cfg.Seed)
+		return images, labels, LoadReport{RetryBudget: cfg.IORetries}, nil
+	}
+	// The walker applies -limit and -sample-fraction to the paths, before anything is decoded
+	w := newWalker(cfg, osFS{})
+	images, labels, err := loadWithWalker(cfg, w, dataDir, cfg.LoadWorkers)
+	return images, labels, w.Report(), err
+}
+
+// loadListedWithReport is loadDatasetWithReport for the real dataset, decoding the paths w
+// already collected, so a caller that listed the dataset for its own checks does not walk it again
+func loadListedWithReport(cfg BenchmarkConfig, w *walker, paths []string) ([][]float32, []string, LoadReport, error) {
+	images, labels, err := loadPaths(cfg, w, paths, cfg.LoadWorkers)
+	return images, labels, w.Report(), err
+}
+
+// loadResult carries a decoded image back to the collector along with its position in the walk order
+type loadResult struct {
+	index int
+	image []float32
+	label string
+	err   error
+}
+
+// LoadTinyImageNetWithWorkers collects the image paths under dataDir and decodes them with a
+// bounded pool of goroutines. Results are stored by walk index, so the returned order is
+// deterministic regardless of the number of workers.
+func LoadTinyImageNetWithWorkers(cfg BenchmarkConfig, dataDir string, numWorkers int) ([][]float32, []string, error) {
+	return loadWithWalker(cfg, newWalker(cfg, osFS{}), dataDir, numWorkers)
+}
+
+// LoadTinyImageNetParallel loads the images under dataDir in the default image shape, collecting
+// the file paths first and then decoding them across numWorkers goroutines. The returned order
+// matches a serial walk.
+func LoadTinyImageNetParallel(dataDir string, numWorkers int) ([][]float32, []string, error) {
+	return LoadTinyImageNetWithWorkers(DefaultConfig(), dataDir, numWorkers)
+}
+
+// loadWithWalker loads the dataset through w, which retries transient filesystem errors. Under the
+// skip policy images that still fail are left out; otherwise the first failure aborts the load.
+// The paths are cut down by cfg.Limit and sampled per class by cfg.SampleFraction before they
+// are decoded, so a sampled load only holds the sample in memory.
+func loadWithWalker(cfg BenchmarkConfig, w *walker, dataDir string, numWorkers int) ([][]float32, []string, error) {
+	paths, err := w.collectImagePaths(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loadPaths(cfg, w, paths, numWorkers)
+}
+
+// loadPaths is loadWithWalker over the paths w collected under the dataset directory
+func loadPaths(cfg BenchmarkConfig, w *walker, paths []string, numWorkers int) ([][]float32, []string, error) {
+	fmt.Fprintln(os.Stderr, "Loading Tiny ImageNet dataset...")
+
+	if cfg.Limit > 0 && cfg.Limit < len(paths) {
+		paths = paths[:cfg.Limit]
+	}
+	if cfg.SampleFraction > 0 && cfg.SampleFraction < 1 {
+		classes := make([]string, len(paths))
+		for i, path := range paths {
+			classes[i] = classLabel(path)
+		}
+		kept := subset.Sample(classes, cfg.SampleFraction, cfg.Seed)
+		sampled := make([]string, len(kept))
+		for i, index := range kept {
+			sampled[i] = paths[index]
+		}
+		paths = sampled
+	}
+	return loadImagePaths(cfg, w, paths, nil, numWorkers)
+}
+
+// loadImagePaths decodes paths through w on numWorkers goroutines, keeping their order. Each
+// image is labelled with pathLabels at its index or, when pathLabels is nil, with the class
+// directory it is stored in.
+func loadImagePaths(cfg BenchmarkConfig, w *walker, paths, pathLabels []string, numWorkers int) ([][]float32, []string, error) {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	jobs := make(chan int)
+	results := make(chan loadResult, numWorkers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				img, label, err := w.loadImage(cfg, paths[idx])
+				if err != nil {
+					err = fmt.Errorf("failed to load image %s: %w", paths[idx], err)
+				}
+				results <- loadResult{index: idx, image: img, label: label, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for idx := range paths {
+			jobs <- idx
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	allImages := make([][]float32, len(paths))
+	allLabels := make([]string, len(paths))
+	var firstErr, breakerErr error
+	for res := range results {
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if breakerErr == nil && errors.Is(res.err, ErrCircuitOpen) {
+				breakerErr = res.err
 			}
-			allImages = append(allImages, img)
-			allLabels = append(allLabels, label)
+			continue
 		}
-		return nil
-	})
+		allImages[res.index] = res.image
+		allLabels[res.index] = res.label
+		if pathLabels != nil {
+			allLabels[res.index] = pathLabels[res.index]
+		}
+	}
 
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to walk through dataset directory: %v", err)
+	// An open circuit breaker stops the load even under the skip policy, which would otherwise
+	// quietly drop every remaining image
+	if breakerErr != nil {
+		return nil, nil, fmt.Errorf("stopped loading the dataset: %w", breakerErr)
+	}
+	if firstErr != nil && !w.skip {
+		return nil, nil, fmt.Errorf("failed to load dataset: %w", firstErr)
 	}
 
-	return allImages, allLabels, nil
+	// Skipped images leave gaps that are closed up, keeping the walk order
+	images, labels := allImages[:0], allLabels[:0]
+	for i, img := range allImages {
+		if img != nil {
+			images = append(images, img)
+			labels = append(labels, allLabels[i])
+		}
+	}
+	return images, labels, nil
 }
 
-// loadImage loads and preprocesses a single image
-func loadImage(imagePath string) ([]float32, string, error) {
-	file, err := os.Open(imagePath)
+// collectImagePaths returns the .jpg and .png files under dataDir in lexical walk order, retrying
+// transient filesystem errors and skipping unreadable directories as configured by cfg
+func collectImagePaths(cfg BenchmarkConfig, dataDir string) ([]string, error) {
+	return newWalker(cfg, osFS{}).collectImagePaths(dataDir)
+}
+
+// ErrIncompleteImage is returned for an image whose dimensions differ from the configured
+// ImageWidth x ImageHeight, such as a truncated or foreign file in the dataset
+var ErrIncompleteImage = errors.New("image dimensions do not match the configured shape")
+
+// decodeImage decodes and preprocesses the image at imagePath read from r. Read errors are
+// wrapped so the caller can tell transient filesystem failures apart.
+func decodeImage(cfg BenchmarkConfig, r io.Reader, imagePath string) ([]float32, string, error) {
+	img, _, err := image.Decode(r)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to open image: %v", err)
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode image: %v", err)
+	// The pixels are laid out with the configured stride, so any other shape would scramble them
+	bounds := img.Bounds()
+	if bounds.Dx() != cfg.ImageWidth || bounds.Dy() != cfg.ImageHeight {
+		return nil, "", fmt.Errorf("%w: %s is %dx%d, expected %dx%d", ErrIncompleteImage,
+			imagePath, bounds.Dx(), bounds.Dy(), cfg.ImageWidth, cfg.ImageHeight)
 	}
 
-	pixels := make([]float32, imageHeight*imageWidth*channels)
-	idx := 0
-	for y := 0; y < img.Bounds().Dy(); y++ {
-		for x := 0; x < img.Bounds().Dx(); x++ {
-			r, g, b, _ := img.At(x, y).RGBA()
-			pixels[idx] = float32(r) / 65535.0
-			pixels[idx+1] = float32(g) / 65535.0
-			pixels[idx+2] = float32(b) / 65535.0
-			idx += 3
+	// Only the first cfg.Channels of RGB are kept
+	pixels := make([]float32, cfg.ImageSize())
+	for y := 0; y < cfg.ImageHeight; y++ {
+		for x := 0; x < cfg.ImageWidth; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			rgb := [3]float32{float32(r) / 65535.0, float32(g) / 65535.0, float32(b) / 65535.0}
+			idx := (y*cfg.ImageWidth + x) * cfg.Channels
+			for c := 0; c < cfg.Channels && c < len(rgb); c++ {
+				pixels[idx+c] = rgb[c]
+			}
 		}
 	}
 
-	label := filepath.Base(filepath.Dir(imagePath))
-	return pixels, label, nil
+	return pixels, classLabel(imagePath), nil
+}
+
+// SimulateImageProcessing performs dummy image transformations on an image of the configured shape.
+// It fails with ErrInjected on a random cfg.ErrorRate of the images, leaving them untouched, so
+// the error handling of the benchmark can be exercised without a kernel that really fails.
+func SimulateImageProcessing(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+	if cfg.ErrorRate > 0 && rand.Float64() < cfg.ErrorRate {
+		return nil, ErrInjected
+	}
+	return doubleImage(cfg, image), nil
 }
 
-// SimulateImageProcessing performs dummy image transformations
-func SimulateImageProcessing(image []float32) []float32 {
-	for i := range image {
-		image[i] = image[i] * 2
+// doubleImage doubles every pixel of image in place, with the rows split among cfg.TileRows
+// goroutines when it is above 1
+func doubleImage(cfg BenchmarkConfig, image []float32) []float32 {
+	if cfg.TileRows <= 1 {
+		doubleRows(cfg, image, 0, cfg.ImageHeight)
+		return image
 	}
+	kernels.Tile(cfg.ImageHeight, cfg.TileRows, func(y0, y1 int) {
+		doubleRows(cfg, image, y0, y1)
+	})
 	return image
 }
 
+// doubleRows doubles the pixels of rows [y0, y1) of image in place, in one linear pass over
+// their span of the flat slice
+func doubleRows(cfg BenchmarkConfig, image []float32, y0, y1 int) {
+	rowSize := cfg.ImageWidth * cfg.Channels
+	rows := image[y0*rowSize : y1*rowSize]
+	for i := range rows {
+		rows[i] = rows[i] * 2
+	}
+}
+
+// SimulateImageProcessingReadOnly visits the same pixels as SimulateImageProcessing, in the same
+// order, but only reads them, returning their sum. Comparing the two separates the cost of
+// writing pixels back to memory from the cost of scheduling the work.
+func SimulateImageProcessingReadOnly(cfg BenchmarkConfig, image []float32) float32 {
+	var sum float32
+	for _, pixel := range image[:cfg.ImageSize()] {
+		sum += pixel
+	}
+	return sum
+}
+
 // ProcessBatch processes a batch of images concurrently
-func ProcessBatch(batch ImageBatch, wg *sync.WaitGroup) {
+func ProcessBatch(cfg BenchmarkConfig, batch ImageBatch, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for i, image := range batch.Images {
+		batch.Images[i] = ProcessImage(cfg, image)
+	}
+}
+
+// ProcessBatchReadOnly checksums a batch of images with SimulateImageProcessingReadOnly, leaving
+// the pixels untouched, and stores the sum of the image checksums in checksum
+func ProcessBatchReadOnly(cfg BenchmarkConfig, batch ImageBatch, wg *sync.WaitGroup, checksum *float32) {
+	defer wg.Done()
+	var sum float32
+	for _, image := range batch.Images {
+		sum += SimulateImageProcessingReadOnly(cfg, image)
+	}
+	*checksum = sum
+}
+
+// ProcessBatchAtomic processes a batch of images like ProcessBatch, adding one to processed after
+// each image. Every worker increments the same counter, so comparing it with ProcessBatch measures
+// the cost of contended atomic increments on a hot path.
+func ProcessBatchAtomic(cfg BenchmarkConfig, batch ImageBatch, wg *sync.WaitGroup, processed *atomic.Int64) {
 	defer wg.Done()
 	for i, image := range batch.Images {
-		batch.Images[i] = SimulateImageProcessing(image)
+		batch.Images[i] = ProcessImage(cfg, image)
+		processed.Add(1)
+	}
+}
+
+// ProcessBatchWithContext processes a batch of images like ProcessBatch, but stops before
+// the next image once ctx is done. It returns the number of images processed, with ctx.Err() if
+// processing stopped early or the kernel's error, naming the image, if an image failed.
+func ProcessBatchWithContext(ctx context.Context, cfg BenchmarkConfig, batch ImageBatch) (int, error) {
+	for i, image := range batch.Images {
+		select {
+		case <-ctx.Done():
+			return i, ctx.Err()
+		default:
+		}
+		processed, err := processImage(cfg, image)
+		if err != nil {
+			return i, fmt.Errorf("image %d of batch: %w", i, err)
+		}
+		batch.Images[i] = processed
 	}
+	return len(batch.Images), nil
+}
+
+// RunProcessingTask runs the preprocessing task once and returns its timings and the amount of work done.
+// Concurrency overhead spans the whole call, starting before the images are partitioned into batches,
+// while execution time starts once the batches are ready and goroutines are about to be launched.
+// The difference between the two is the cost of setting up the concurrent work.
+// Goroutine spawn duration runs from the first go statement until the last goroutine has started
+// executing, isolating the scheduler's spawn overhead from the processing itself.
+// The image and pixel counts cover only full batches; a trailing partial batch is not processed.
+// An image the kernel fails on stops the run early with lower counts and an error wrapping the
+// kernel's, such as ErrInjected.
+// Batch durations holds the time each batch goroutine took from starting to finishing, indexed by
+// batch, to expose load imbalance that the execution time hides. With cfg.MaxInFlight set, a
+// goroutine waits for one of that many slots before processing, and its duration starts once it
+// has one.
+func RunProcessingTask(cfg BenchmarkConfig, images [][]float32, labels []string) (executionTime, concurrencyOverhead, goroutineSpawnDuration time.Duration, imagesProcessed, pixelsProcessed int, batchDurations []time.Duration, err error) {
+	result, err := runProcessingTask(context.Background(), cfg, images, labels)
+	return result.ExecutionTime, result.ConcurrencyOverhead, result.GoroutineSpawnDuration, result.ImagesProcessed, result.PixelsProcessed, result.BatchDurations, err
 }
 
-// RunProcessingTask runs the preprocessing task once and returns execution time and concurrency overhead
-func RunProcessingTask(images [][]float32, labels []string) (time.Duration, time.Duration) {
+// RunProcessingTaskTTFB runs the preprocessing task once like RunProcessingTask and returns its
+// time to first batch: from entry until the first batch has finished processing, covering the
+// partitioning, the goroutine launches and one batch of work that the total time hides. The total
+// spans the whole call, like the concurrency overhead of RunProcessingTask. The time to first
+// batch is 0 when no batch completed.
+func RunProcessingTaskTTFB(cfg BenchmarkConfig, images [][]float32, labels []string) (ttfb time.Duration, total time.Duration) {
+	result, _ := runProcessingTask(context.Background(), cfg, images, labels)
+	return result.TimeToFirstBatch, result.ConcurrencyOverhead
+}
+
+// RunProcessingTaskErrgroup runs the preprocessing task once like RunProcessingTask, under ctx,
+// and returns only the first error a batch fails with. The batches run in an errgroup, so
+// that error cancels the batches still waiting or running, which stop before their next image.
+// The error wraps the kernel's, such as ErrInjected, or ctx.Err() when ctx is done first.
+func RunProcessingTaskErrgroup(ctx context.Context, cfg BenchmarkConfig, images [][]float32, labels []string) error {
+	_, err := runProcessingTask(ctx, cfg, images, labels)
+	return err
+}
+
+// taskResult holds the timings and work counts of one pass of the preprocessing task, as
+// described on RunProcessingTask, and its time to first batch as described on RunProcessingTaskTTFB
+type taskResult struct {
+	ExecutionTime          time.Duration
+	ConcurrencyOverhead    time.Duration
+	GoroutineSpawnDuration time.Duration
+	ImagesProcessed        int
+	PixelsProcessed        int
+	BatchDurations         []time.Duration
+	TimeToFirstBatch       time.Duration
+}
+
+// runProcessingTask is RunProcessingTask with cancellation and error propagation. Batch workers
+// check ctx between images, so a cancelled run stops promptly, and the first image a kernel
+// fails on cancels the remaining batches. The counts then cover only the images processed, and
+// the error wraps ctx.Err() or the kernel's error. Time to first batch is that of
+// RunProcessingTaskTTFB.
+func runProcessingTask(ctx context.Context, cfg BenchmarkConfig, images [][]float32, labels []string) (result taskResult, err error) {
+	startOverhead := time.Now()
+
 	totalImages := len(images)
-	numBatches := totalImages / batchSize
+	numBatches := totalImages / cfg.BatchSize
 	batches := make([]ImageBatch, numBatches)
 	for i := 0; i < numBatches; i++ {
-		start := i * batchSize
-		end := start + batchSize
+		start := i * cfg.BatchSize
+		end := start + cfg.BatchSize
 		batches[i] = ImageBatch{
 			Images: images[start:end],
 			Labels: labels[start:end],
 		}
 	}
 
-	startOverhead := time.Now()
-
 	startExecution := time.Now()
-	var wg sync.WaitGroup
-	for _, batch := range batches {
-		wg.Add(1)
-		go ProcessBatch(batch, &wg)
+	// Each goroutine reports its start time on the barrier channel before processing
+	started := make(chan time.Time, numBatches)
+	gpu := &GPUTransferSimulator{TransferLatencyPerMB: cfg.GPUTransferLatency}
+	group, groupCtx := errgroup.WithContext(ctx)
+	var processed atomic.Int64
+	// Nanoseconds from startOverhead until the first batch finished, set once by that batch
+	var firstBatch atomic.Int64
+	// Each goroutine writes only its own batch's slot, so no locking is needed
+	result.BatchDurations = make([]time.Duration, numBatches)
+	// Every batch still gets its goroutine, but it holds a slot of the semaphore while processing,
+	// so at most cfg.MaxInFlight batches run at once; a nil semaphore leaves them unbounded
+	var inFlight chan struct{}
+	if cfg.MaxInFlight > 0 {
+		inFlight = make(chan struct{}, cfg.MaxInFlight)
 	}
-	wg.Wait()
+	for i, batch := range batches {
+		group.Go(func() error {
+			started <- time.Now()
+			if inFlight != nil {
+				select {
+				case inFlight <- struct{}{}:
+					defer func() { <-inFlight }()
+				case <-groupCtx.Done():
+					return nil
+				}
+			}
+			start := time.Now()
+			gpu.Transfer(batch)
+			n, err := ProcessBatchWithContext(groupCtx, cfg, batch)
+			result.BatchDurations[i] = time.Since(start)
+			processed.Add(int64(n))
+			if err == nil {
+				firstBatch.CompareAndSwap(0, int64(time.Since(startOverhead)))
+			}
+			return err
+		})
+	}
+	groupErr := group.Wait()
+
+	result.ExecutionTime = time.Since(startExecution)
+	result.ConcurrencyOverhead = time.Since(startOverhead)
 
-	executionTime := time.Since(startExecution)
-	concurrencyOverhead := time.Since(startOverhead)
-	return executionTime, concurrencyOverhead
+	close(started)
+	for startTime := range started {
+		if spawn := startTime.Sub(startExecution); spawn > result.GoroutineSpawnDuration {
+			result.GoroutineSpawnDuration = spawn
+		}
+	}
+	result.TimeToFirstBatch = time.Duration(firstBatch.Load())
+	result.ImagesProcessed = int(processed.Load())
+	result.PixelsProcessed = result.ImagesProcessed * cfg.ImageHeight * cfg.ImageWidth
+	switch {
+	case ctx.Err() != nil && result.ImagesProcessed < numBatches*cfg.BatchSize:
+		err = fmt.Errorf("processing stopped after %d of %d images: %w", result.ImagesProcessed, numBatches*cfg.BatchSize, ctx.Err())
+	case groupErr != nil:
+		err = fmt.Errorf("processing failed after %d of %d images: %w", result.ImagesProcessed, numBatches*cfg.BatchSize, groupErr)
+	}
+	return result, err
 }
 
 // AppendToLogFile appends a string to the specified log file
@@ -146,69 +483,429 @@ func AppendToLogFile(filePath, message string) error {
 	return nil
 }
 
-// calculateCPUUsage calculates average CPU utilization during a processing window
-func calculateCPUUsage(duration time.Duration) (float64, error) {
-	percentages, err := cpu.Percent(duration, false) // Measure CPU usage over the given duration
+// throughput returns count per second over the given duration, or 0 for an empty duration
+func throughput(count int, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(count) / duration.Seconds()
+}
+
+// ReadProcessIOStats returns the cumulative bytes read from and written to storage by the current process
+func ReadProcessIOStats() (readBytes, writeBytes uint64, err error) {
+	proc, err := process.NewProcess(int32(os.Getpid()))
 	if err != nil {
-		return 0, err
+		return 0, 0, fmt.Errorf("failed to open current process: %v", err)
 	}
-	return percentages[0], nil
+	counters, err := proc.IOCounters()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read process I/O counters: %v", err)
+	}
+	return counters.ReadBytes, counters.WriteBytes, nil
 }
 
 // Main function
 func main() {
+	cfg := DefaultConfig()
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if cfg.NumSeeds < 1 {
+		log.Fatalf("-num-seeds must be at least 1, got %d", cfg.NumSeeds)
+	}
+	if err := validateKernel(cfg.Kernel); err != nil {
+		log.Fatalf("Invalid -kernel: %v", err)
+	}
+	if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		log.Fatalf("-error-rate must be between 0 and 1, got %v", cfg.ErrorRate)
+	}
+	if cfg.ErrorRate > 0 && cfg.Kernel != KernelDouble {
+		log.Fatalf("-error-rate only applies to the double kernel, got -kernel %s", cfg.Kernel)
+	}
+	if cfg.GPUTransferLatency < 0 {
+		log.Fatalf("-gpu-transfer-latency must not be negative, got %v", cfg.GPUTransferLatency)
+	}
+	if cfg.DRAMBandwidth < 0 {
+		log.Fatalf("-dram-bandwidth must not be negative, got %v", cfg.DRAMBandwidth)
+	}
+	if cfg.MaxInFlight < 0 {
+		log.Fatalf("-max-inflight must not be negative, got %d", cfg.MaxInFlight)
+	}
+	if cfg.Cooldown < 0 {
+		log.Fatalf("-cooldown must not be negative, got %v", cfg.Cooldown)
+	}
+	if cfg.FreeOSMemory && !cfg.GCBetweenRuns {
+		log.Fatalf("-free-os-memory requires -gc-between-runs")
+	}
+	if cfg.TileRows < 1 {
+		log.Fatalf("-tile-rows must be at least 1, got %d", cfg.TileRows)
+	}
+	if cfg.TileRows > 1 && cfg.Kernel == KernelNormalize {
+		log.Fatalf("-tile-rows only applies to the double, blur and sobel kernels, got -kernel %s", cfg.Kernel)
+	}
+	if cfg.PipelineWorkers < 1 || cfg.PipelineBuffer < 1 {
+		log.Fatalf("-pipeline-workers and -pipeline-buffer must be at least 1, got %d and %d", cfg.PipelineWorkers, cfg.PipelineBuffer)
+	}
+	var sweep []int
+	if cfg.MaxProcsSweep != "" {
+		settings, err := parseMaxProcsSweep(cfg.MaxProcsSweep)
+		if err != nil {
+			log.Fatalf("Invalid -maxprocs-sweep: %v", err)
+		}
+		if cfg.Once || cfg.NumSeeds > 1 {
+			log.Fatalf("-maxprocs-sweep cannot be combined with -once or -num-seeds")
+		}
+		sweep = settings
+	}
+	if cfg.InfluxPath != "" && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-influx cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.BatchTimingsPath != "" && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-batch-timings cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.DryRun && (cfg.Once || cfg.Pipeline || sweep != nil) {
+		log.Fatalf("-dry-run cannot be combined with -once, -pipeline or -maxprocs-sweep")
+	}
+	if cfg.LoadWorkers < 1 {
+		log.Fatalf("-load-workers must be at least 1, got %d", cfg.LoadWorkers)
+	}
+	if cfg.SampleFraction < 0 || cfg.SampleFraction > 1 {
+		log.Fatalf("-sample-fraction must be between 0 and 1, got %v", cfg.SampleFraction)
+	}
+	if cfg.Kernel == KernelNormalize && cfg.Pipeline {
+		log.Fatalf("-kernel normalize cannot be combined with -pipeline, which streams the dataset before its statistics are known")
+	}
+	if cfg.SampleFraction > 0 && cfg.Pipeline {
+		log.Fatalf("-sample-fraction cannot be combined with -pipeline, which streams the dataset")
+	}
+	if cfg.MemProfilePath != "" && (cfg.Once || cfg.Pipeline) {
+		log.Fatalf("-memprofile cannot be combined with -once or -pipeline")
+	}
+	if cfg.MutexProfilePath != "" && (cfg.Once || cfg.Pipeline) {
+		log.Fatalf("-mutexprofile cannot be combined with -once or -pipeline")
+	}
+	if cfg.DedupShards < 0 || (cfg.DedupShards > 0 && (cfg.Once || cfg.Pipeline)) {
+		log.Fatalf("-dedup-shards must not be negative and cannot be combined with -once or -pipeline, got %d", cfg.DedupShards)
+	}
+	if cfg.JSONPath != "" && !cfg.Once && sweep == nil {
+		log.Fatalf("-json is only supported together with -once or -maxprocs-sweep")
+	}
+
+	// runtime.NumCPU sees the host's CPUs, not a container's quota, so GOMAXPROCS is lowered
+	// to the quota before anything runs
+	cpuLimitSummary := sysinfo.ApplyContainerCPULimit()
+
+	dataDir := defaultDataDir
+	if cfg.Once {
+		if cfg.JSONPath == "" || cfg.JSONPath == "-" {
+			if err := runOnce(cfg, dataDir, os.Stdout, os.Stderr); err != nil {
+				log.Fatalf("Error running one-shot benchmark: %v", err)
+			}
+			return
+		}
+		file, err := os.Create(cfg.JSONPath)
+		if err != nil {
+			log.Fatalf("Error creating JSON output: %v", err)
+		}
+		if err := runOnce(cfg, dataDir, file, os.Stderr); err != nil {
+			log.Fatalf("Error running one-shot benchmark: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			log.Fatalf("Error writing JSON output: %v", err)
+		}
+		return
+	}
+
 	logFilePath := "go_tinyimagenet_metrics_result.log"
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		log.Fatalf("Error opening metrics log: %v", err)
+	}
+	defer func() {
+		if err := logger.Close(); err != nil {
+			log.Fatalf("Error writing metrics log: %v", err)
+		}
+	}()
 
 	// Load Tiny ImageNet dataset
-	dataDir := "../../tiny-imagenet-200/train"
-	images, labels, err := LoadTinyImageNet(dataDir)
+	logMetadata(logger, result.NewMetadata("tinyimagenet"))
+	if err := logger.Printf("Loading Tiny ImageNet dataset..."); err != nil {
+		log.Fatalf("Error writing metrics log: %v", err)
+	}
+	// Multi-socket hosts add NUMA effects that make runs hard to compare
+	if sockets, err := sysinfo.DetectCPUSockets(); err != nil {
+		logger.Printf("CPU Sockets: unknown (%v)", err)
+	} else {
+		logger.Printf("CPU Sockets: %d", sockets)
+		if warning := sysinfo.SocketWarning(sockets); warning != "" {
+			logger.Printf("%s", warning)
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+	logger.Printf("%s", cpuLimitSummary)
+	meter := newEnergyMeter(logger, energy.DefaultRoot)
+	var progress *monitor.Monitor
+	if cfg.ListenAddr != "" {
+		m, addr, stop, err := startMonitor(cfg.ListenAddr)
+		if err != nil {
+			logger.Fatalf("Error starting -listen server: %v", err)
+		}
+		defer stop()
+		progress = m
+		logger.Printf("Serving progress metrics on http://%s/metrics", addr)
+	}
+	memProfiles := newMemProfiler(cfg, logger)
+	collectors, err := newCollectorSet(cfg, logger)
 	if err != nil {
-		log.Fatalf("Error loading Tiny ImageNet: %v", err)
+		logger.Fatalf("Invalid -collectors: %v", err)
 	}
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Dataset loaded successfully. Total Images: %d\n", len(images)))
 
-	err = AppendToLogFile(logFilePath, "\nDataset Parameters:")
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Total Images: %d\n", len(images)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Image Shape: %d x %d x %d (Height x Width x Channels)\n", imageHeight, imageWidth, channels))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Number of Classes: %d\n", len(labels)))
+	// The dataset is walked once; the preflight check, the memory plan and the load share the paths
+	var listing *walker
+	var paths []string
+	if cfg.SyntheticImages == 0 {
+		listing = newWalker(cfg, osFS{})
+		if paths, err = listing.collectImagePaths(dataDir); err != nil {
+			logger.Fatalf("Error listing Tiny ImageNet: %v", err)
+		}
+		totalFiles, unreachable, err := checkImagePaths(osFS{}, paths, listing.Report().skippedDirectories())
+		logger.Printf("Preflight Check: %d image files, %d unreachable", totalFiles, unreachable)
+		if err != nil {
+			logger.Fatalf("Preflight check failed: %v", err)
+		}
+	}
 
-	var totalExecutionTime, totalConcurrencyOverhead time.Duration
-	var totalMemoryUsage uint64
-	var totalCPUUsage float64
+	// Streaming holds only the buffered batches, so the memory plan does not apply
+	if cfg.Pipeline {
+		stopProfile, err := startBenchmarkProfile(cfg, logger)
+		if err != nil {
+			logger.Fatalf("Error starting -cpuprofile: %v", err)
+		}
+		if err := runPipelineMode(cfg, logger, dataDir); err != nil {
+			logger.Fatalf("Error running pipeline benchmark: %v", err)
+		}
+		if err := stopProfile(); err != nil {
+			logger.Fatalf("Error writing -cpuprofile: %v", err)
+		}
+		return
+	}
+
+	var plan LoadPlan
+	if cfg.SyntheticImages == 0 {
+		cfg, plan, err = planDatasetLoad(cfg, len(paths))
+		if err != nil {
+			logger.Fatalf("Error planning the Tiny ImageNet load: %v", err)
+		}
+		fmt.Fprintln(os.Stderr, plan)
+	}
 
-	for i := 0; i < numRuns; i++ {
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("\nRun %d/%d...\n", i+1, numRuns))
+	// Profiling must be enabled before the dataset is allocated
+	var accountant *gcAccountant
+	if cfg.GCAccounting {
+		accountant = newGCAccountant(cfg)
+	}
 
-		var memStatsBefore runtime.MemStats
-		runtime.ReadMemStats(&memStatsBefore)
-		memoryBefore := memStatsBefore.Alloc
+	readBefore, writeBefore, ioErr := ReadProcessIOStats()
+	startLoading := time.Now()
+	var images [][]float32
+	var labels []string
+	var loadReport LoadReport
+	if listing != nil {
+		images, labels, loadReport, err = loadListedWithReport(cfg, listing, paths)
+	} else {
+		images, labels, loadReport, err = loadDatasetWithReport(cfg, dataDir)
+	}
+	if err != nil {
+		logger.Fatalf("Error loading Tiny ImageNet: %v", err)
+	}
+	loadingTime := time.Since(startLoading)
+	readAfter, writeAfter, err := ReadProcessIOStats()
+	if ioErr == nil {
+		ioErr = err
+	}
+	logger.Printf("Dataset loaded successfully. Total Images: %d\n", len(images))
+	logSubset(cfg, logger, len(images))
+	logger.Printf("Loading Time: %s seconds (%d workers)", metrics.FormatDuration(loadingTime), max(cfg.LoadWorkers, 1))
+	if cfg.SyntheticImages == 0 {
+		for _, line := range loadReport.Lines() {
+			logger.Printf("%s", line)
+		}
+	}
+	if cfg.DryRun {
+		logLoadIO(logger, ioErr, readAfter-readBefore, writeAfter-writeBefore)
+		logDryRun(logger, len(images), loadingTime)
+		return
+	}
+	if cfg.DedupShards > 0 {
+		images, labels = deduplicateDataset(cfg, logger, images, labels, loadingTime)
+	}
+	cfg = withChannelStats(cfg, logger, images)
+	if cfg.GridPath != "" {
+		if err := saveGrid(cfg, images, cfg.GridPath); err != nil {
+			logger.Fatalf("Error saving sample grid: %v", err)
+		}
+		logger.Printf("Sample grid saved to %s", cfg.GridPath)
+	}
 
-		startCPUTime := time.Now()
-		executionTime, concurrencyOverhead := RunProcessingTask(images, labels)
-		cpuUsage, err := calculateCPUUsage(time.Since(startCPUTime))
+	logger.Printf("\nDataset Parameters:")
+	logger.Printf("Total Images: %d\n", len(images))
+	var checksum string
+	if cfg.HashDataset {
+		checksum = hashDataset(logger, images)
+	}
+	logLoadIO(logger, ioErr, readAfter-readBefore, writeAfter-writeBefore)
+	if plan.NumFiles > 0 {
+		logger.Printf("%s", plan)
+	}
+	logger.Printf("Image Shape: %d x %d x %d (Height x Width x Channels)\n", cfg.ImageHeight, cfg.ImageWidth, cfg.Channels)
+	if cfg.MaxInFlight > 0 {
+		logger.Printf("Max In-Flight Batches: %d", cfg.MaxInFlight)
+	} else {
+		logger.Printf("Max In-Flight Batches: unlimited")
+	}
+	logTiling(cfg, logger, len(images)/cfg.BatchSize)
+	logBetweenRuns(cfg, logger)
+	logGoroutineCreationRate(logger, sysinfo.GoroutineCreationRate(sysinfo.GoroutineRateSamples), len(images)/cfg.BatchSize)
+	if cfg.GPUTransferLatency > 0 {
+		logger.Printf("Simulated GPU Transfer Latency: %.2f ms/MB", cfg.GPUTransferLatency)
+	}
+	if cfg.DRAMBandwidth > 0 {
+		logger.Printf("DRAM Bandwidth: %.2f GB/s (memory-bound maximum %.2f images/second)", cfg.DRAMBandwidth, maxThroughput(cfg))
+	}
+	var wnidIndex map[string]int
+	var labelMap map[string]string
+	if cfg.SyntheticImages == 0 {
+		wnidIndex, err = loadWnidIndex(dataDir)
+		if err != nil {
+			logger.Fatalf("Error reading %s: %v", wnidsFile, err)
+		}
+		labelMap, err = loadLabelMap(dataDir)
 		if err != nil {
-			log.Fatalf("Error calculating CPU usage: %v", err)
+			logger.Fatalf("Error reading %s: %v", wordsFile, err)
 		}
+	}
+	logClasses(logger, labels, wnidIndex, labelMap)
+	if cfg.MutexProfilePath != "" {
+		if err := CollectMutexProfile(cfg, logger, images, labels, cfg.MutexProfilePath); err != nil {
+			logger.Fatalf("Error collecting -mutexprofile: %v", err)
+		}
+	}
 
-		var memStatsAfter runtime.MemStats
-		runtime.ReadMemStats(&memStatsAfter)
-		memoryAfter := memStatsAfter.Alloc
-		memoryUsage := memoryAfter - memoryBefore
+	// From here on Ctrl-C stops the current run and reports the completed ones
+	ctx := interruptContext()
+	runImages, runLabels := images, labels
+	run := func() (runResult, error) {
+		return measureRunWithContext(ctx, cfg, runImages, runLabels)
+	}
+	if collectors != nil {
+		run = withCollectors(cfg, "tinyimagenet", collectors, run)
+	}
+	if meter != nil {
+		run = withEnergy(meter, run)
+	}
+	if progress != nil {
+		run = withMonitor(cfg, progress, run)
+	}
+	if accountant != nil {
+		if err := accountant.captureAfterLoad(); err != nil {
+			logger.Fatalf("Error capturing heap profile: %v", err)
+		}
+		run = accountant.wrap(run)
+	}
+	if memProfiles != nil {
+		run = memProfiles.wrap(run)
+	}
 
-		totalExecutionTime += executionTime
-		totalConcurrencyOverhead += concurrencyOverhead
-		totalMemoryUsage += memoryUsage
-		totalCPUUsage += cpuUsage
+	// With several seeds the loop is repeated over differently shuffled copies of the dataset
+	var seeds []int64
+	var seedAverages []time.Duration
+	jsonOut, closeJSON, err := createSweepOutput(cfg.JSONPath)
+	if err != nil {
+		logger.Fatalf("Error creating JSON output: %v", err)
+	}
+	stopProfile, err := startBenchmarkProfile(cfg, logger)
+	if err != nil {
+		logger.Fatalf("Error starting -cpuprofile: %v", err)
+	}
+	split := "train"
+	if cfg.SyntheticImages > 0 {
+		split = "synthetic"
+	}
+	var records []result.MetricRecord
+	var influxResults []BenchmarkResult
+	var batchTimings []result.BatchTimingRecord
+	for i := 0; i < cfg.NumSeeds; i++ {
+		datasetName := "tinyimagenet"
+		if cfg.NumSeeds > 1 {
+			seed := cfg.Seed + int64(i)
+			logger.Printf("\nSeed %d (%d/%d)", seed, i+1, cfg.NumSeeds)
+			runImages, runLabels = shuffleImages(images, labels, seed)
+			seeds = append(seeds, seed)
+			datasetName = fmt.Sprintf("%s-seed%d", datasetName, seed)
+		}
+
+		if sweep != nil {
+			sweepDataset := result.Dataset{Split: split, Images: len(images), SHA256: checksum}
+			sweepRecords, err := runMaxProcsSweep(cfg, logger, datasetName, sweepDataset, sweep, run, jsonOut)
+			if err != nil {
+				logger.Fatalf("Error running GOMAXPROCS sweep: %v", err)
+			}
+			records = append(records, sweepRecords...)
+			continue
+		}
 
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("Execution Time for Run %d: %.9f seconds", i+1, executionTime.Seconds()))
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("Concurrency Overhead for Run %d: %.9f seconds", i+1, concurrencyOverhead.Seconds()))
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("Memory Usage for Run %d: %.9f MB", i+1, float64(memoryUsage)/(1024*1024)))
-		err = AppendToLogFile(logFilePath, fmt.Sprintf("CPU Utilization for Run %d: %.9f%%", i+1, cpuUsage))
+		summary, err := runBenchmark(cfg, logger, run)
+		if err != nil {
+			if interrupted(err) {
+				exitInterrupted(cfg, logger, logFilePath, summary)
+			}
+			exitFailed(logger, summary, err)
+		}
+
+		logger.Printf("\nAverage Metrics:")
+		logAverages(logger, summary)
+		logBandwidthEfficiency(cfg, logger, "Average Memory Bandwidth Efficiency", throughput(summary.Total.ImagesProcessed, summary.Total.ExecutionTime))
+		seedAverages = append(seedAverages, summary.averages().ExecutionTime)
+		records = append(records, metricRecords(datasetName, summary)...)
+		batchTimings = append(batchTimings, batchTimingRecords(datasetName, summary)...)
+		if cfg.InfluxPath != "" {
+			influxResults = append(influxResults, newBenchmarkResult("tinyimagenet", cfg, split, len(runImages), summary))
+		}
+	}
+	if cfg.NumSeeds > 1 {
+		logSeedVariance(logger, seeds, seedAverages)
 	}
 
-	err = AppendToLogFile(logFilePath, "\nAverage Metrics:")
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average Execution Time: %.9f seconds", totalExecutionTime.Seconds()/float64(numRuns)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average Concurrency Overhead: %.9f seconds", totalConcurrencyOverhead.Seconds()/float64(numRuns)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average Memory Usage: %.9f MB", float64(totalMemoryUsage)/(float64(numRuns)*1024*1024)))
-	err = AppendToLogFile(logFilePath, fmt.Sprintf("Average CPU Utilization: %.9f%%", totalCPUUsage/float64(numRuns)))
+	if err := stopProfile(); err != nil {
+		logger.Fatalf("Error writing -cpuprofile: %v", err)
+	}
+	if err := closeJSON(); err != nil {
+		logger.Fatalf("Error writing JSON output: %v", err)
+	}
+	if collectors != nil {
+		logCollectorOverhead(logger, collectors)
+	}
+	if cfg.CSVPath != "" {
+		if err := result.WriteCSV(cfg.CSVPath, records); err != nil {
+			logger.Fatalf("Error writing CSV results: %v", err)
+		}
+		logger.Printf("\nPer-run results written to %s", cfg.CSVPath)
+	}
+	if cfg.BatchTimingsPath != "" {
+		if err := result.WriteBatchTimingsCSV(cfg.BatchTimingsPath, batchTimings); err != nil {
+			logger.Fatalf("Error writing batch timings: %v", err)
+		}
+		logger.Printf("Per-batch timings written to %s", cfg.BatchTimingsPath)
+	}
+	if cfg.InfluxPath != "" {
+		if err := writeInflux(cfg.InfluxPath, influxResults); err != nil {
+			logger.Fatalf("Error writing line protocol results: %v", err)
+		}
+		logger.Printf("Line protocol results written to %s", cfg.InfluxPath)
+	}
+
+	if accountant != nil {
+		if err := accountant.report(logger); err != nil {
+			logger.Fatalf("Error reporting GC accounting: %v", err)
+		}
+	}
 }
@@ -1,18 +1,44 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"golang/internal/synthetic"
 )
 
+// runMain makes the test binary run main() instead of the tests; TestMainIntegration
+// re-executes itself with it to exercise main() in a subprocess
+var runMain = flag.Bool("test.run-main", false, "run main() with the arguments after --")
+
 func TestSimulateImageProcessing(t *testing.T) {
-	image := make([]float32, imageHeight*imageWidth*channels)
+	cfg := DefaultConfig()
+	image := make([]float32, cfg.ImageSize())
 	for i := range image {
 		image[i] = 1.0
 	}
 
-	processedImage := SimulateImageProcessing(image)
+	processedImage, err := SimulateImageProcessing(cfg, image)
+	if err != nil {
+		t.Fatalf("Expected no error without -error-rate, got %v", err)
+	}
 	for i, val := range processedImage {
 		if val != 2.0 {
 			t.Errorf("Pixel %d value mismatch: expected 2.0, got %.2f", i, val)
@@ -20,15 +46,36 @@ func TestSimulateImageProcessing(t *testing.T) {
 	}
 }
 
+// BenchmarkSimulateImageProcessing shows how the throughput of the doubling loop scales from the
+// CIFAR-10 and Tiny ImageNet shapes up to images that no longer fit in the CPU caches
+func BenchmarkSimulateImageProcessing(b *testing.B) {
+	for _, size := range []int{32, 64, 224, 512} {
+		cfg := DefaultConfig()
+		cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = size, size, 3
+		image := make([]float32, cfg.ImageSize())
+		for i := range image {
+			image[i] = 1.0
+		}
+
+		b.Run(fmt.Sprintf("%dx%dx%d", size, size, cfg.Channels), func(b *testing.B) {
+			b.SetBytes(int64(len(image) * 4))
+			for i := 0; i < b.N; i++ {
+				SimulateImageProcessing(cfg, image)
+			}
+		})
+	}
+}
+
 func TestProcessBatch(t *testing.T) {
+	cfg := DefaultConfig()
 	batch := ImageBatch{
-		Images: make([][]float32, batchSize),
-		Labels: make([]string, batchSize),
+		Images: make([][]float32, cfg.BatchSize),
+		Labels: make([]string, cfg.BatchSize),
 	}
 
-	for i := 0; i < batchSize; i++ {
-		image := make([]float32, imageHeight*imageWidth*channels)
-		for j := 0; j < imageHeight*imageWidth*channels; j++ {
+	for i := 0; i < cfg.BatchSize; i++ {
+		image := make([]float32, cfg.ImageSize())
+		for j := 0; j < cfg.ImageSize(); j++ {
 			image[j] = 1.0
 		}
 		batch.Images[i] = image
@@ -37,7 +84,7 @@ func TestProcessBatch(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	go ProcessBatch(batch, &wg)
+	go ProcessBatch(cfg, batch, &wg)
 	wg.Wait()
 
 	for i, img := range batch.Images {
@@ -49,14 +96,50 @@ func TestProcessBatch(t *testing.T) {
 	}
 }
 
+// requireDataset skips a test that reads a downloaded dataset in -short mode or when dataDir
+// does not exist, so the suite passes without the multi-gigabyte downloads
+func requireDataset(t *testing.T, dataDir string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping real dataset test in -short mode")
+	}
+	if _, err := os.Stat(dataDir); err != nil {
+		t.Skipf("Dataset not available: %v", err)
+	}
+}
+
 func TestRunProcessingTask(t *testing.T) {
-	dataDir := "../../tiny-imagenet-200/train"
-	images, labels, err := LoadTinyImageNet(dataDir)
+	cfg := DefaultConfig()
+	images, labels := synthetic.GenerateSyntheticImages(500, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+
+	executionTime, concurrencyOverhead, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if executionTime == 0 {
+		t.Errorf("Execution time should not be zero")
+	}
+	if concurrencyOverhead < executionTime {
+		t.Errorf("Concurrency overhead should be greater than or equal to execution time")
+	}
+	if imagesProcessed != len(images) {
+		t.Errorf("Expected %d images processed, got %d", len(images), imagesProcessed)
+	}
+}
+
+func TestRunProcessingTaskRealDataset(t *testing.T) {
+	cfg := DefaultConfig()
+	dataDir := defaultDataDir
+	requireDataset(t, dataDir)
+	images, labels, err := LoadTinyImageNet(cfg, dataDir)
 	if err != nil {
 		t.Fatalf("Failed to load Tiny ImageNet dataset: %v", err)
 	}
 
-	executionTime, concurrencyOverhead := RunProcessingTask(images, labels)
+	executionTime, concurrencyOverhead, _, _, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
 	if executionTime == 0 {
 		t.Errorf("Execution time should not be zero")
 	}
@@ -65,14 +148,587 @@ func TestRunProcessingTask(t *testing.T) {
 	}
 }
 
-func TestCalculateCPUUsage(t *testing.T) {
-	duration := 2 * time.Second
-	cpuUsage, err := calculateCPUUsage(duration)
+// BenchmarkRunProcessingTask measures one processing run, as a single measured run of the benchmark
+// does, over the dataset when it is downloaded and 10000 synthetic images otherwise
+func BenchmarkRunProcessingTask(b *testing.B) {
+	cfg := DefaultConfig()
+	images, labels, err := LoadTinyImageNet(cfg, defaultDataDir)
+	if err != nil {
+		images, labels = synthetic.GenerateSyntheticImages(10000, cfg.ImageHeight, cfg.ImageWidth, cfg.Channels, cfg.Seed)
+	}
+	b.SetBytes(int64(len(images) * cfg.ImageSize() * 4))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RunProcessingTask(cfg, images, labels)
+	}
+	b.ReportMetric(float64(len(images)*b.N)/b.Elapsed().Seconds(), "images/s")
+}
+
+// testImageSize is the width and height of the PNGs written by writeTestImages
+const testImageSize = 4
+
+// testImageConfig returns the default configuration shaped for the images of writeTestImages
+func testImageConfig() BenchmarkConfig {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth = testImageSize, testImageSize
+	return cfg
+}
+
+// writeTestImages writes numClasses x perClass tiny PNGs under dir, encoding each image's
+// walk index into its red channel so ordering can be checked after loading
+func writeTestImages(t *testing.T, dir string, numClasses, perClass int) {
+	t.Helper()
+	for c := 0; c < numClasses; c++ {
+		classDir := filepath.Join(dir, fmt.Sprintf("n%02d", c))
+		if err := os.MkdirAll(classDir, 0755); err != nil {
+			t.Fatalf("Failed to create class directory: %v", err)
+		}
+		for i := 0; i < perClass; i++ {
+			img := image.NewRGBA(image.Rect(0, 0, testImageSize, testImageSize))
+			for y := 0; y < testImageSize; y++ {
+				for x := 0; x < testImageSize; x++ {
+					img.Set(x, y, color.RGBA{R: uint8(c*perClass + i), G: 0, B: 0, A: 255})
+				}
+			}
+			file, err := os.Create(filepath.Join(classDir, fmt.Sprintf("img_%03d.png", i)))
+			if err != nil {
+				t.Fatalf("Failed to create image file: %v", err)
+			}
+			if err := png.Encode(file, img); err != nil {
+				t.Fatalf("Failed to encode image: %v", err)
+			}
+			file.Close()
+		}
+	}
+}
+
+// writeNoiseImages writes n PNGs of random pixels in the shape of cfg under root, 50 per class
+// directory in the Tiny ImageNet layout, and returns their paths in walk order. Noise compresses
+// about as poorly as photographs, so the files are close to real dataset sizes.
+func writeNoiseImages(tb testing.TB, root string, cfg BenchmarkConfig, n int) []string {
+	tb.Helper()
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("n%08d", i/50), "images")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		img := image.NewRGBA(image.Rect(0, 0, cfg.ImageWidth, cfg.ImageHeight))
+		for y := 0; y < cfg.ImageHeight; y++ {
+			for x := 0; x < cfg.ImageWidth; x++ {
+				img.Set(x, y, color.RGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255})
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			tb.Fatalf("Failed to encode image: %v", err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("n%08d_%02d.png", i/50, i%50))
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			tb.Fatalf("Failed to write image: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestLoadTinyImageNetWrapsDecodeErrors(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 2, 3)
+
+	// Every image is the wrong size for this shape, so the first decode failure aborts the load
+	cfg := testImageConfig()
+	cfg.ImageWidth++
+	cfg.LoadWorkers = 2
+	_, _, err := LoadTinyImageNet(cfg, dataDir)
+	if !errors.Is(err, ErrIncompleteImage) {
+		t.Fatalf("Expected the decode error to be wrapped, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failed to load dataset: ") {
+		t.Errorf("Expected a load error, got %q", err)
+	}
+}
+
+func TestDecodeImageRejectsOffSizeImages(t *testing.T) {
+	cfg := testImageConfig()
+	for _, size := range []image.Point{{testImageSize, testImageSize - 1}, {testImageSize + 1, testImageSize}} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size.X, size.Y))); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		_, _, err := decodeImage(cfg, &buf, filepath.Join("n00", "truncated.png"))
+		if !errors.Is(err, ErrIncompleteImage) {
+			t.Errorf("%dx%d image: expected ErrIncompleteImage, got %v", size.X, size.Y, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, testImageSize, testImageSize))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	pixels, label, err := decodeImage(cfg, &buf, filepath.Join("n00", "complete.png"))
+	if err != nil {
+		t.Fatalf("Expected a correctly sized image to decode, got %v", err)
+	}
+	if len(pixels) != cfg.ImageSize() || label != "n00" {
+		t.Errorf("Expected %d pixels labelled n00, got %d labelled %q", cfg.ImageSize(), len(pixels), label)
+	}
+}
+
+// TestNormalizationDenominator checks that Tiny ImageNet pixels are divided by 65535, the range of
+// the 16-bit RGBA values image.Image returns, for 16-bit and 8-bit PNGs alike. Dividing by the
+// 255 of CIFAR-10 would push them far above 1.0.
+func TestNormalizationDenominator(t *testing.T) {
+	cfg := testImageConfig()
+	raw := []color.RGBA64{
+		{R: 0, G: 1, B: 65535, A: 65535},
+		{R: 1000, G: 32768, B: 65534, A: 65535},
+	}
+	wide := image.NewRGBA64(image.Rect(0, 0, testImageSize, testImageSize))
+	narrow := image.NewRGBA(image.Rect(0, 0, testImageSize, testImageSize))
+	for y := 0; y < testImageSize; y++ {
+		for x := 0; x < testImageSize; x++ {
+			wide.SetRGBA64(x, y, raw[(y*testImageSize+x)%len(raw)])
+			narrow.SetRGBA(x, y, color.RGBA{R: uint8(x * 80), G: uint8(y * 60), B: 255, A: 255})
+		}
+	}
+
+	for _, c := range []struct {
+		name     string
+		img      image.Image
+		expected func(x, y, channel int) float32
+	}{
+		{"16-bit", wide, func(x, y, channel int) float32 {
+			p := raw[(y*testImageSize+x)%len(raw)]
+			return float32([3]uint16{p.R, p.G, p.B}[channel]) / 65535.0
+		}},
+		// image.Image widens 8-bit values v to v*257, so v/255 comes out either way
+		{"8-bit", narrow, func(x, y, channel int) float32 {
+			return float32([3]int{x * 80, y * 60, 255}[channel]) / 255.0
+		}},
+	} {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, c.img); err != nil {
+			t.Fatalf("Failed to encode %s image: %v", c.name, err)
+		}
+		pixels, _, err := decodeImage(cfg, &buf, filepath.Join("n00", "normalize.png"))
+		if err != nil {
+			t.Fatalf("Failed to decode %s image: %v", c.name, err)
+		}
+		for y := 0; y < testImageSize; y++ {
+			for x := 0; x < testImageSize; x++ {
+				for channel := 0; channel < cfg.Channels; channel++ {
+					got, expected := pixels[(y*testImageSize+x)*cfg.Channels+channel], c.expected(x, y, channel)
+					if math.Abs(float64(got-expected)) > 1.0/(1<<23) {
+						t.Errorf("%s pixel (%d, %d) channel %d: expected %v, got %v", c.name, x, y, channel, expected, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLoadTinyImageNetWithWorkers(t *testing.T) {
+	dataDir := t.TempDir()
+	numClasses, perClass := 5, 50
+	writeTestImages(t, dataDir, numClasses, perClass)
+
+	for _, workers := range []int{1, 4, 16} {
+		images, labels, err := LoadTinyImageNetWithWorkers(testImageConfig(), dataDir, workers)
+		if err != nil {
+			t.Fatalf("Failed to load generated dataset with %d workers: %v", workers, err)
+		}
+
+		if len(images) != numClasses*perClass {
+			t.Fatalf("Expected %d images, got %d", numClasses*perClass, len(images))
+		}
+
+		for i, img := range images {
+			expectedLabel := fmt.Sprintf("n%02d", i/perClass)
+			if labels[i] != expectedLabel {
+				t.Errorf("Image %d label mismatch: expected %s, got %s", i, expectedLabel, labels[i])
+			}
+			expectedRed := float32(uint8(i)) * 257 / 65535.0
+			if img[0] != expectedRed {
+				t.Errorf("Image %d out of order: expected red %.4f, got %.4f", i, expectedRed, img[0])
+			}
+		}
+	}
+}
+
+func TestLoadTinyImageNetParallelMatchesSerialOrder(t *testing.T) {
+	dataDir := t.TempDir()
+	paths := writeNoiseImages(t, dataDir, DefaultConfig(), 120)
+
+	serialImages, serialLabels, err := LoadTinyImageNetParallel(dataDir, 1)
+	if err != nil {
+		t.Fatalf("Failed to load serially: %v", err)
+	}
+	if len(serialImages) != len(paths) {
+		t.Fatalf("Expected %d images, got %d", len(paths), len(serialImages))
+	}
+	for _, workers := range []int{3, 8} {
+		images, labels, err := LoadTinyImageNetParallel(dataDir, workers)
+		if err != nil {
+			t.Fatalf("Failed to load with %d workers: %v", workers, err)
+		}
+		if !reflect.DeepEqual(images, serialImages) || !reflect.DeepEqual(labels, serialLabels) {
+			t.Errorf("Expected %d workers to return the serial order", workers)
+		}
+	}
+}
+
+// BenchmarkLoadTinyImageNetParallel compares a serial load of 1000 generated images with
+// LoadTinyImageNetParallel at 2, 4, 8 and 16 workers
+func BenchmarkLoadTinyImageNetParallel(b *testing.B) {
+	dataDir := b.TempDir()
+	numImages := len(writeNoiseImages(b, dataDir, DefaultConfig(), 1000))
+
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		name := fmt.Sprintf("workers-%d", workers)
+		if workers == 1 {
+			name = "serial"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				images, labels, err := LoadTinyImageNetParallel(dataDir, workers)
+				if err != nil {
+					b.Fatalf("Load failed: %v", err)
+				}
+				allocSinkImages, allocSinkLabels = images, labels
+			}
+			b.ReportMetric(float64(numImages*b.N)/b.Elapsed().Seconds(), "images/s")
+		})
+	}
+}
+
+func TestReadProcessIOStats(t *testing.T) {
+	readBefore, writeBefore, err := ReadProcessIOStats()
+	if err != nil {
+		t.Skipf("Process I/O counters unavailable on this platform: %v", err)
+	}
+
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 1, 10)
+
+	readAfter, writeAfter, err := ReadProcessIOStats()
+	if err != nil {
+		t.Fatalf("Failed to read process I/O stats: %v", err)
+	}
+	if readAfter < readBefore || writeAfter < writeBefore {
+		t.Errorf("I/O counters decreased: read %d -> %d, written %d -> %d", readBefore, readAfter, writeBefore, writeAfter)
+	}
+}
+
+func TestLoadDatasetSynthetic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 2 * cfg.BatchSize
+
+	images, labels, err := loadDataset(cfg, "does-not-exist")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	if len(images) != cfg.SyntheticImages || len(labels) != cfg.SyntheticImages {
+		t.Fatalf("Expected %d images and labels, got %d and %d", cfg.SyntheticImages, len(images), len(labels))
+	}
+
+	expected := make([]float32, len(images[0]))
+	copy(expected, images[0])
+
+	RunProcessingTask(cfg, images, labels)
+	for j, val := range images[0] {
+		if val != expected[j]*2 {
+			t.Fatalf("Pixel %d mismatch after processing: expected %.4f, got %.4f", j, expected[j]*2, val)
+		}
+	}
+}
+
+func TestRunProcessingTaskTTFB(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 8 * cfg.BatchSize
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	ttfb, total := RunProcessingTaskTTFB(cfg, images, labels)
+	if ttfb <= 0 {
+		t.Errorf("Time to first batch should be positive, got %v", ttfb)
+	}
+	if ttfb > total {
+		t.Errorf("Time to first batch %v should not exceed total time %v", ttfb, total)
+	}
+
+	// Without a full batch nothing completes
+	if ttfb, _ := RunProcessingTaskTTFB(cfg, images[:cfg.BatchSize-1], labels[:cfg.BatchSize-1]); ttfb != 0 {
+		t.Errorf("Expected no time to first batch without a full batch, got %v", ttfb)
+	}
+}
+
+func TestSimulateImageProcessingInjectsErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ErrorRate = 1
+	image := make([]float32, cfg.ImageSize())
+	for i := range image {
+		image[i] = 1.0
+	}
+	if _, err := SimulateImageProcessing(cfg, image); !errors.Is(err, ErrInjected) {
+		t.Fatalf("Expected ErrInjected at an error rate of 1, got %v", err)
+	}
+	if image[0] != 1.0 {
+		t.Errorf("Expected a failed image to be left untouched, got %.2f", image[0])
+	}
+}
+
+func TestRunProcessingTaskErrgroup(t *testing.T) {
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.BatchSize = 500, 10
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	if err := RunProcessingTaskErrgroup(context.Background(), cfg, images, labels); err != nil {
+		t.Fatalf("Expected no error without -error-rate, got %v", err)
+	}
+
+	// With one batch at a time the run stops at the first failure, about 20 images in. Had the
+	// other batches carried on to their own first failure, most of the 500 would be processed.
+	cfg.ErrorRate, cfg.MaxInFlight = 0.05, 1
+	result, err := runProcessingTask(context.Background(), cfg, images, labels)
+	if !errors.Is(err, ErrInjected) {
+		t.Fatalf("Expected the injected error, got %v", err)
+	}
+	if result.ImagesProcessed >= len(images)/2 {
+		t.Errorf("Expected the first error to cancel the remaining batches, got %d of %d images processed", result.ImagesProcessed, len(images))
+	}
+	if err := RunProcessingTaskErrgroup(context.Background(), cfg, images, labels); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected the injected error, got %v", err)
+	}
+	if _, _, _, _, _, _, err := RunProcessingTask(cfg, images, labels); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected RunProcessingTask to return the injected error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg.ErrorRate = 0
+	if err := RunProcessingTaskErrgroup(ctx, cfg, images, labels); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled for a cancelled context, got %v", err)
+	}
+}
+
+func TestRunProcessingTaskGoroutineSpawn(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SyntheticImages = 8 * cfg.BatchSize
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	executionTime, concurrencyOverhead, goroutineSpawnDuration, _, _, _, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	if goroutineSpawnDuration <= 0 {
+		t.Errorf("Goroutine spawn duration should be positive, got %v", goroutineSpawnDuration)
+	}
+	if goroutineSpawnDuration > executionTime {
+		t.Errorf("Goroutine spawn duration %v should not exceed execution time %v", goroutineSpawnDuration, executionTime)
+	}
+	if concurrencyOverhead < executionTime {
+		t.Errorf("Concurrency overhead should be greater than or equal to execution time")
+	}
+}
+
+func TestRunProcessingTaskCounts(t *testing.T) {
+	cfg := DefaultConfig()
+	// Three full batches plus a partial batch that is not processed
+	cfg.SyntheticImages = 3*cfg.BatchSize + 7
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	_, _, _, imagesProcessed, pixelsProcessed, _, err := RunProcessingTask(cfg, images, labels)
 	if err != nil {
-		t.Fatalf("Failed to calculate CPU usage: %v", err)
+		t.Fatalf("RunProcessingTask failed: %v", err)
 	}
+	if imagesProcessed != 3*cfg.BatchSize {
+		t.Errorf("Expected %d images processed, got %d", 3*cfg.BatchSize, imagesProcessed)
+	}
+	if pixelsProcessed != imagesProcessed*cfg.ImageHeight*cfg.ImageWidth {
+		t.Errorf("Expected %d pixels processed, got %d", imagesProcessed*cfg.ImageHeight*cfg.ImageWidth, pixelsProcessed)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	if got := throughput(500, 250*time.Millisecond); got != 2000 {
+		t.Errorf("Expected 2000 per second, got %.2f", got)
+	}
+	if got := throughput(500, 0); got != 0 {
+		t.Errorf("Expected 0 for an empty duration, got %.2f", got)
+	}
+}
 
-	if cpuUsage < 0 || cpuUsage > 100 {
-		t.Errorf("CPU usage out of bounds: %.2f%%", cpuUsage)
+func TestMainIntegration(t *testing.T) {
+	if *runMain {
+		// Hand main() only the benchmark arguments, with a fresh flag set for it to register on
+		args := flag.Args()
+		os.Args = append([]string{os.Args[0]}, args...)
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+		main()
+		return
+	}
+
+	dir := runMainInTempDir(t, "-synthetic", "64", "-height", "8", "-width", "8", "-batch-size", "16", "-num-runs", "2", "-warmup", "1", "-csv", "runs.csv")
+
+	content := readMetricsLog(t, dir)
+	if !strings.Contains(string(content), "Average Execution Time") {
+		t.Errorf("Log file is missing the averages:\n%s", content)
+	}
+
+	csvContent, err := os.ReadFile(filepath.Join(dir, "runs.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read CSV results: %v", err)
+	}
+	if rows := strings.Count(string(csvContent), "\n"); rows != 3 {
+		t.Errorf("Expected a header and 2 run rows in the CSV, got %d lines:\n%s", rows, csvContent)
+	}
+}
+
+// runMainInTempDir runs main() with args in a subprocess, through TestMainIntegration, inside a
+// fresh temporary directory that it returns
+func runMainInTempDir(t *testing.T, args ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestMainIntegration$", "-test.run-main", "--"}, args...)...)
+	cmd.Dir = dir
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("main() failed: %v\nstdout:\n%s\nstderr:\n%s", err, stdout.String(), stderr.String())
+	}
+	return dir
+}
+
+// readMetricsLog returns the metrics log main() wrote into dir, failing the test if it is empty
+func readMetricsLog(t *testing.T, dir string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, "go_tinyimagenet_metrics_result.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if len(content) == 0 {
+		t.Fatalf("Log file is empty")
+	}
+	return content
+}
+
+func TestDryRun(t *testing.T) {
+	dir := runMainInTempDir(t, "-synthetic", "64", "-height", "8", "-width", "8", "-batch-size", "16", "-dry-run", "-csv", "runs.csv")
+	content := string(readMetricsLog(t, dir))
+
+	if !strings.Contains(content, "Dry Run: loaded 64 images in") || !strings.Contains(content, "processing skipped") {
+		t.Errorf("Expected the dry run to log the image count and loading time:\n%s", content)
+	}
+	for _, unexpected := range []string{"Run 1/", "Average Metrics"} {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("Expected no processing with -dry-run, found %q:\n%s", unexpected, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "runs.csv")); err == nil {
+		t.Errorf("Expected no CSV results without runs")
+	}
+}
+
+func TestNumRunsZero(t *testing.T) {
+	dir := runMainInTempDir(t, "-synthetic", "64", "-height", "8", "-width", "8", "-batch-size", "16", "-num-runs", "0", "-warmup", "0")
+	content := string(readMetricsLog(t, dir))
+
+	if !strings.Contains(content, "Dataset Parameters") {
+		t.Errorf("Expected the dataset parameters to be logged:\n%s", content)
+	}
+	if strings.Contains(content, "Execution Time for Run") {
+		t.Errorf("Expected no run entries with -num-runs 0:\n%s", content)
+	}
+	if !strings.Contains(content, "Average Metrics") {
+		t.Fatalf("Expected the average metrics section:\n%s", content)
+	}
+	averages := content[strings.Index(content, "Average Metrics"):]
+	for _, line := range strings.Split(averages, "\n") {
+		for _, metric := range []string{"Average Execution Time:", "Average Memory Usage:", "Average Throughput:"} {
+			if i := strings.Index(line, metric); i >= 0 {
+				value := line[i+len(metric):]
+				if strings.ContainsAny(value, "123456789") && !strings.Contains(value, "N/A") {
+					t.Errorf("Expected zero or N/A averages without runs, got %q", line)
+				}
+			}
+		}
+		if strings.Contains(line, "NaN") || strings.Contains(line, "Inf") {
+			t.Errorf("Expected no NaN or Inf without runs, got %q", line)
+		}
+	}
+}
+
+func TestProcessBatchAtomic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 2, 2, 3
+	images := make([][]float32, 6)
+	expected := make([][]float32, len(images))
+	for i := range images {
+		images[i] = make([]float32, cfg.ImageSize())
+		for j := range images[i] {
+			images[i][j] = float32(i + j)
+		}
+		expected[i] = ProcessImage(cfg, append([]float32(nil), images[i]...))
+	}
+
+	var processed atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go ProcessBatchAtomic(cfg, ImageBatch{Images: images[:3]}, &wg, &processed)
+	go ProcessBatchAtomic(cfg, ImageBatch{Images: images[3:]}, &wg, &processed)
+	wg.Wait()
+
+	if processed.Load() != int64(len(images)) {
+		t.Errorf("Expected %d processed images, got %d", len(images), processed.Load())
+	}
+	for i := range images {
+		for j := range images[i] {
+			if images[i][j] != expected[i][j] {
+				t.Fatalf("Image %d pixel %d: expected %v, got %v", i, j, expected[i][j], images[i][j])
+			}
+		}
+	}
+}
+
+func TestProcessBatchReadOnly(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 2, 2, 3
+	images := make([][]float32, 3)
+	var expected float32
+	for i := range images {
+		images[i] = make([]float32, cfg.ImageSize())
+		for j := range images[i] {
+			images[i][j] = float32(i*len(images[i]) + j)
+			expected += images[i][j]
+		}
+	}
+
+	var checksum float32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go ProcessBatchReadOnly(cfg, ImageBatch{Images: images}, &wg, &checksum)
+	wg.Wait()
+
+	if checksum != expected {
+		t.Errorf("Expected checksum %v, got %v", expected, checksum)
+	}
+	for i := range images {
+		for j, p := range images[i] {
+			if p != float32(i*len(images[i])+j) {
+				t.Fatalf("Image %d pixel %d was modified: got %v", i, j, p)
+			}
+		}
 	}
 }
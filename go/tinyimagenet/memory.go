@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/shirou/gopsutil/mem"
+
+	"golang/internal/metrics"
+	"golang/internal/result"
+)
+
+// Fractions of available memory used to classify whether the decoded dataset fits
+const (
+	safeMemoryFraction     = 0.7 // Estimates below this share of available memory fit comfortably
+	marginalMemoryFraction = 1.0 // Estimates up to all available memory fit, but risk swapping
+)
+
+// Outcomes of comparing the decoded dataset estimate against available memory
+const (
+	MemoryFits       = "fits"
+	MemoryMarginal   = "marginal"
+	MemoryDoesNotFit = "does not fit"
+)
+
+const (
+	float32SizeBytes = 4
+	sliceHeaderBytes = 24
+	bytesPerMegabyte = 1024 * 1024
+)
+
+// LoadPlan records the memory estimate for eager loading and the decision taken from it
+type LoadPlan struct {
+	NumFiles       int
+	EstimatedBytes uint64
+	AvailableBytes uint64
+	Outcome        string
+	SampleFraction float64 // Fraction of every class to load, 0 loads every image
+	Downgraded     bool
+	Suggestion     string
+}
+
+// estimateDecodedBytes approximates the memory needed to hold numImages decoded float32 images
+func estimateDecodedBytes(cfg BenchmarkConfig, numImages int) uint64 {
+	return uint64(numImages) * uint64(cfg.ImageSize()*float32SizeBytes+sliceHeaderBytes)
+}
+
+// PlanLoad decides how much of numFiles images to load given availableBytes of free memory.
+// A user-supplied cfg.Limit and cfg.SampleFraction are always honored; when the remaining
+// estimate does not fit and cfg.AutoDowngrade is set, the images are sampled down to what fits
+// in the safe share of memory. The downgrade goes through the stratified -sample-fraction rather
+// than -limit, which keeps the first files in walk order and would drop whole classes.
+func PlanLoad(cfg BenchmarkConfig, numFiles int, availableBytes uint64) LoadPlan {
+	numImages := numFiles
+	if cfg.Limit > 0 && cfg.Limit < numImages {
+		numImages = cfg.Limit
+	}
+	sampled := numImages
+	if cfg.SampleFraction > 0 && cfg.SampleFraction < 1 {
+		sampled = int(math.Round(float64(numImages) * cfg.SampleFraction))
+	}
+
+	plan := LoadPlan{
+		NumFiles:       numFiles,
+		EstimatedBytes: estimateDecodedBytes(cfg, sampled),
+		AvailableBytes: availableBytes,
+		SampleFraction: cfg.SampleFraction,
+	}
+
+	// The fraction is rounded down to a tenth of a percent, so the sample stays within the safe share
+	safeImages := float64(availableBytes) * safeMemoryFraction / float64(estimateDecodedBytes(cfg, 1))
+	safeFraction := math.Max(math.Floor(safeImages/float64(max(numImages, 1))*1000)/1000, 0.001)
+
+	switch {
+	case float64(plan.EstimatedBytes) <= float64(availableBytes)*safeMemoryFraction:
+		plan.Outcome = MemoryFits
+	case float64(plan.EstimatedBytes) <= float64(availableBytes)*marginalMemoryFraction:
+		plan.Outcome = MemoryMarginal
+		plan.Suggestion = fmt.Sprintf("loading may swap; consider -sample-fraction %g, or -pipeline to stream the dataset", safeFraction)
+	default:
+		plan.Outcome = MemoryDoesNotFit
+		if cfg.AutoDowngrade {
+			plan.SampleFraction = safeFraction
+			plan.Downgraded = true
+			plan.Suggestion = fmt.Sprintf("automatically sampled %g of every class; -pipeline streams the whole dataset instead", safeFraction)
+		} else {
+			plan.Suggestion = fmt.Sprintf("rerun with -sample-fraction %g, -pipeline to stream the dataset, or -auto-downgrade", safeFraction)
+		}
+	}
+	return plan
+}
+
+// planDatasetLoad estimates the decoded size of the numFiles images of the dataset against the
+// available memory and returns cfg with the -sample-fraction of the plan applied
+func planDatasetLoad(cfg BenchmarkConfig, numFiles int) (BenchmarkConfig, LoadPlan, error) {
+	available, err := availableMemory()
+	if err != nil {
+		return cfg, LoadPlan{}, err
+	}
+	plan := PlanLoad(cfg, numFiles, available)
+	cfg.SampleFraction = plan.SampleFraction
+	return cfg, plan, nil
+}
+
+// Metadata returns the estimate and decision for the JSON result record
+func (p LoadPlan) Metadata() *result.MemoryPlan {
+	return &result.MemoryPlan{
+		Files:          p.NumFiles,
+		EstimatedBytes: p.EstimatedBytes,
+		AvailableBytes: p.AvailableBytes,
+		Outcome:        p.Outcome,
+		Downgraded:     p.Downgraded,
+		SampleFraction: p.SampleFraction,
+		Suggestion:     p.Suggestion,
+	}
+}
+
+// availableMemory returns the memory the OS reports as available for new allocations
+func availableMemory() (uint64, error) {
+	stats, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read virtual memory stats: %v", err)
+	}
+	return stats.Available, nil
+}
+
+// String formats the plan for the dataset parameters section of the log
+func (p LoadPlan) String() string {
+	decision := fmt.Sprintf("Memory Estimate: %s MB decoded for %d files, %s MB available (%s)",
+		metrics.FormatMB(float64(p.EstimatedBytes)), p.NumFiles, metrics.FormatMB(float64(p.AvailableBytes)), p.Outcome)
+	if p.Suggestion != "" {
+		decision += "; " + p.Suggestion
+	}
+	return decision
+}
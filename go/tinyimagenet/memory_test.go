@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanLoadBoundaries(t *testing.T) {
+	cfg := DefaultConfig()
+	numFiles := 100
+	estimate := estimateDecodedBytes(cfg, numFiles)
+
+	tests := []struct {
+		name      string
+		available uint64
+		outcome   string
+	}{
+		{"plenty of memory", estimate * 2, MemoryFits},
+		{"exactly the safe share", uint64(float64(estimate)/safeMemoryFraction) + 1, MemoryFits},
+		{"just above the safe share", estimate + 1, MemoryMarginal},
+		{"exactly all available memory", estimate, MemoryMarginal},
+		{"just over available memory", estimate - 1, MemoryDoesNotFit},
+		{"tiny machine", estimate / 10, MemoryDoesNotFit},
+	}
+
+	for _, tt := range tests {
+		plan := PlanLoad(cfg, numFiles, tt.available)
+		if plan.Outcome != tt.outcome {
+			t.Errorf("%s: expected outcome %q, got %q", tt.name, tt.outcome, plan.Outcome)
+		}
+		if plan.Downgraded || plan.SampleFraction != 0 {
+			t.Errorf("%s: expected no downgrade without -auto-downgrade, got sample fraction %g", tt.name, plan.SampleFraction)
+		}
+		if tt.outcome != MemoryFits && !strings.Contains(plan.Suggestion, "-pipeline") {
+			t.Errorf("%s: expected the suggestion to offer streaming with -pipeline, got %q", tt.name, plan.Suggestion)
+		}
+		if plan.EstimatedBytes != estimate {
+			t.Errorf("%s: estimate mismatch: expected %d, got %d", tt.name, estimate, plan.EstimatedBytes)
+		}
+	}
+}
+
+func TestPlanLoadAutoDowngrade(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AutoDowngrade = true
+	numFiles := 100
+	perImage := estimateDecodedBytes(cfg, 1)
+
+	plan := PlanLoad(cfg, numFiles, perImage*20)
+	if plan.Outcome != MemoryDoesNotFit || !plan.Downgraded {
+		t.Fatalf("Expected a downgrade, got %+v", plan)
+	}
+	if plan.SampleFraction != 0.14 {
+		t.Errorf("Expected a sample fraction of 0.14 (70%% of 20 images out of 100), got %g", plan.SampleFraction)
+	}
+	if !strings.Contains(plan.String(), "automatically sampled 0.14 of every class") || !strings.Contains(plan.String(), "-pipeline") {
+		t.Errorf("Decision missing from plan summary: %s", plan.String())
+	}
+
+	plan = PlanLoad(cfg, numFiles, perImage*1000)
+	if plan.Downgraded || plan.SampleFraction != 0 {
+		t.Errorf("Expected no downgrade when the dataset fits, got sample fraction %g", plan.SampleFraction)
+	}
+}
+
+func TestPlanLoadHonorsUserLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Limit = 10
+	perImage := estimateDecodedBytes(cfg, 1)
+
+	// 100 files would not fit in 50 images' worth of memory, but the limit of 10 does
+	plan := PlanLoad(cfg, 100, perImage*50)
+	if plan.Outcome != MemoryFits || plan.Downgraded {
+		t.Errorf("Expected the user limit to fit, got %+v", plan)
+	}
+
+	// A user sample of half of 100 files fits in 50 images' worth of memory only marginally
+	cfg.Limit, cfg.SampleFraction = 0, 0.5
+	plan = PlanLoad(cfg, 100, perImage*50)
+	if plan.Outcome != MemoryMarginal || plan.SampleFraction != 0.5 {
+		t.Errorf("Expected the user sample fraction to be estimated and kept, got %+v", plan)
+	}
+}
+
+func TestLoadTinyImageNetLimit(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 2, 10)
+
+	cfg := testImageConfig()
+	cfg.Limit = 5
+	images, labels, err := LoadTinyImageNetWithWorkers(cfg, dataDir, 2)
+	if err != nil {
+		t.Fatalf("Failed to load generated dataset: %v", err)
+	}
+	if len(images) != 5 || len(labels) != 5 {
+		t.Errorf("Expected 5 images with -limit 5, got %d", len(images))
+	}
+}
+
+func TestLoadTinyImageNetSampleFractionKeepsEveryClass(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 4, 10)
+
+	// A -limit of 10 would keep only the first class in walk order
+	cfg := testImageConfig()
+	cfg.SampleFraction = 0.25
+	images, labels, err := LoadTinyImageNetWithWorkers(cfg, dataDir, 2)
+	if err != nil {
+		t.Fatalf("Failed to load generated dataset: %v", err)
+	}
+	if len(images) != 12 {
+		t.Errorf("Expected 3 of 10 images from each of 4 classes, got %d", len(images))
+	}
+	classes := make(map[string]int)
+	for _, label := range labels {
+		classes[label]++
+	}
+	if len(classes) != 4 {
+		t.Errorf("Expected every class in the sample, got %v", classes)
+	}
+}
+
+func TestLoadPlanMetadata(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AutoDowngrade = true
+	plan := PlanLoad(cfg, 100, estimateDecodedBytes(cfg, 20))
+
+	m := plan.Metadata()
+	if m.Files != 100 || m.EstimatedBytes != plan.EstimatedBytes || m.AvailableBytes != plan.AvailableBytes {
+		t.Errorf("Estimate missing from metadata: %+v", *m)
+	}
+	if m.Outcome != MemoryDoesNotFit || !m.Downgraded || m.SampleFraction != 0.14 || m.Suggestion == "" {
+		t.Errorf("Decision missing from metadata: %+v", *m)
+	}
+}
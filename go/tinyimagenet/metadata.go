@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"golang/internal/datahash"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+	"golang/internal/result"
+)
+
+// logMetadata writes the host and toolchain a log was produced on, so its results can be traced
+// back to them long after the run
+func logMetadata(logger *metricslog.Logger, m result.Metadata) {
+	logger.Printf("Run Metadata:")
+	logger.Printf("Timestamp: %s", m.Timestamp.Format(time.RFC3339))
+	logger.Printf("Host: %s (%s/%s)", m.Hostname, m.GOOS, m.GOARCH)
+	logger.Printf("CPU Model: %s", m.CPUModel)
+	logger.Printf("CPUs: %d (GOMAXPROCS %d)", m.NumCPU, m.GOMAXPROCS)
+	if m.TotalMemoryBytes > 0 {
+		logger.Printf("Total Memory: %.2f GB", float64(m.TotalMemoryBytes)/(1024*1024*1024))
+	} else {
+		logger.Printf("Total Memory: unknown")
+	}
+	logger.Printf("Go Version: %s\n", m.GoVersion)
+}
+
+// hashDataset returns the datahash.SHA256 checksum of images and logs it with the time hashing
+// took, which grows with the dataset
+func hashDataset(logger *metricslog.Logger, images [][]float32) string {
+	start := time.Now()
+	checksum := datahash.SHA256(images)
+	logger.Printf("Dataset SHA-256: %s (%d images, hashed in %s seconds)", checksum, len(images), metrics.FormatDuration(time.Since(start)))
+	return checksum
+}
@@ -0,0 +1,139 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// ioBenchmarkImages is the number of image files each concurrent I/O benchmark iteration loads
+const ioBenchmarkImages = 1000
+
+// ioBenchmarkPaths returns the first ioBenchmarkImages images of the Tiny ImageNet training split
+// when it is available, otherwise as many generated noise PNGs
+func ioBenchmarkPaths(b *testing.B, cfg BenchmarkConfig) []string {
+	b.Helper()
+	if _, err := os.Stat(defaultDataDir); err == nil {
+		paths, err := collectImagePaths(cfg, defaultDataDir)
+		if err != nil {
+			b.Fatalf("Failed to collect image paths: %v", err)
+		}
+		if len(paths) >= ioBenchmarkImages {
+			return paths[:ioBenchmarkImages]
+		}
+	}
+	return writeNoiseImages(b, b.TempDir(), cfg, ioBenchmarkImages)
+}
+
+// readFileImage loads an image by copying the whole file into a heap buffer with os.ReadFile
+func readFileImage(cfg BenchmarkConfig, path string) ([]float32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pixels, _, err := decodeImage(cfg, bytes.NewReader(data), path)
+	return pixels, err
+}
+
+// mmapImage loads an image by decoding straight from a read-only shared mapping of the file, so
+// the bytes come from the page cache without a copy
+func mmapImage(cfg BenchmarkConfig, path string) ([]float32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("cannot map empty file %s", path)
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mmap %s: %v", path, err)
+	}
+	defer syscall.Munmap(data)
+	pixels, _, err := decodeImage(cfg, bytes.NewReader(data), path)
+	return pixels, err
+}
+
+// loadConcurrently loads paths with numWorkers goroutines, each taking the next unread path, the
+// access pattern of the ioWorkers in LoadTinyImageNetWithWorkers
+func loadConcurrently(cfg BenchmarkConfig, paths []string, numWorkers int, load func(BenchmarkConfig, string) ([]float32, error)) error {
+	jobs := make(chan string)
+	errs := make(chan error, numWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				if _, err := load(cfg, path); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, path := range paths {
+			select {
+			case jobs <- path:
+			case err := <-errs:
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// BenchmarkConcurrentImageLoad compares loading 1000 images through os.ReadFile and through
+// mmap with 4, 8 and 16 concurrent workers, reporting images/s and the file bandwidth in MB/s.
+// The files are read once before timing, so both strategies are served from a warm page cache.
+func BenchmarkConcurrentImageLoad(b *testing.B) {
+	cfg := DefaultConfig()
+	paths := ioBenchmarkPaths(b, cfg)
+	var totalBytes int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			b.Fatalf("Failed to stat %s: %v", path, err)
+		}
+		totalBytes += info.Size()
+	}
+	if err := loadConcurrently(cfg, paths, 4, readFileImage); err != nil {
+		b.Fatalf("Warmup load failed: %v", err)
+	}
+
+	strategies := []struct {
+		name string
+		load func(BenchmarkConfig, string) ([]float32, error)
+	}{
+		{"readfile", readFileImage},
+		{"mmap", mmapImage},
+	}
+	for _, strategy := range strategies {
+		for _, workers := range []int{4, 8, 16} {
+			b.Run(fmt.Sprintf("%s/workers-%d", strategy.name, workers), func(b *testing.B) {
+				b.SetBytes(totalBytes)
+				for i := 0; i < b.N; i++ {
+					if err := loadConcurrently(cfg, paths, workers, strategy.load); err != nil {
+						b.Fatalf("Load failed: %v", err)
+					}
+				}
+				b.ReportMetric(float64(len(paths)*b.N)/b.Elapsed().Seconds(), "images/s")
+			})
+		}
+	}
+}
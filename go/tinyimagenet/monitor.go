@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+
+	"golang/internal/monitor"
+)
+
+// monitorSampleInterval is how often -listen samples CPU utilization and memory
+const monitorSampleInterval = time.Second
+
+// startMonitor serves progress gauges on addr and samples CPU utilization and memory in the
+// background. It returns the bound address and a function that stops the server and sampler.
+func startMonitor(addr string) (*monitor.Monitor, net.Addr, func(), error) {
+	m := monitor.New()
+	server, bound, err := monitor.Listen(addr, m)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go m.Sample(ctx, monitorSampleInterval, sampleCPU)
+	stop := func() {
+		cancel()
+		server.Close()
+	}
+	return m, bound, stop, nil
+}
+
+// sampleCPU returns the system-wide CPU utilization since its previous call
+func sampleCPU() (float64, error) {
+	percentages, err := cpu.Percent(0, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(percentages) == 0 {
+		return 0, fmt.Errorf("no CPU usage reported")
+	}
+	return percentages[0], nil
+}
+
+// withMonitor wraps run so every measured run is recorded in m. Warmup runs are told apart by
+// their position in each benchmark loop, as in gcAccountant.
+func withMonitor(cfg BenchmarkConfig, m *monitor.Monitor, run func() (runResult, error)) func() (runResult, error) {
+	calls := 0
+	return func() (runResult, error) {
+		position := calls % (cfg.Warmup + cfg.NumRuns)
+		calls++
+		result, err := run()
+		if err == nil && position >= cfg.Warmup {
+			m.RecordRun(result.ExecutionTime)
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"golang/internal/metricslog"
+	"golang/internal/result"
+)
+
+// onceLimit caps the dataset in -once mode when no -limit is given, so a probe returns promptly
+const onceLimit = 1000
+
+// runOnce performs one warmup and one measured run on a limited dataset and writes the measured
+// run to stdout as a single JSON record. Every human-readable line goes to stderr so stdout
+// can be piped straight into a JSON parser.
+func runOnce(cfg BenchmarkConfig, dataDir string, stdout, stderr io.Writer) error {
+	cfg.Warmup, cfg.NumRuns, cfg.NumSeeds = 1, 1, 1
+	if cfg.Limit == 0 {
+		cfg.Limit = onceLimit
+	}
+
+	logger := metricslog.NewStream(stderr)
+	var memory *result.MemoryPlan
+	var images [][]float32
+	var labels []string
+	var loadReport LoadReport
+	var err error
+	if cfg.SyntheticImages == 0 {
+		// The plan and the load share one walk of the dataset
+		w := newWalker(cfg, osFS{})
+		var paths []string
+		if paths, err = w.collectImagePaths(dataDir); err != nil {
+			return fmt.Errorf("failed to list Tiny ImageNet: %v", err)
+		}
+		var plan LoadPlan
+		cfg, plan, err = planDatasetLoad(cfg, len(paths))
+		if err != nil {
+			return fmt.Errorf("failed to plan the Tiny ImageNet load: %v", err)
+		}
+		logger.Printf("%s", plan)
+		memory = plan.Metadata()
+		images, labels, loadReport, err = loadListedWithReport(cfg, w, paths)
+	} else {
+		images, labels, loadReport, err = loadDatasetWithReport(cfg, dataDir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load Tiny ImageNet: %v", err)
+	}
+	split := "train"
+	if cfg.SyntheticImages > 0 {
+		split = "synthetic"
+	}
+	logger.Printf("Loaded %d images (%s)", len(images), split)
+	cfg = withChannelStats(cfg, logger, images)
+	var checksum string
+	if cfg.HashDataset {
+		checksum = hashDataset(logger, images)
+	}
+	if cfg.SyntheticImages == 0 {
+		for _, line := range loadReport.Lines() {
+			logger.Printf("%s", line)
+		}
+	}
+
+	run := func() (runResult, error) {
+		return measureRun(cfg, images, labels)
+	}
+	collectors, err := newCollectorSet(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("invalid -collectors: %v", err)
+	}
+	if collectors != nil {
+		run = withCollectors(cfg, "tinyimagenet", collectors, run)
+	}
+	summary, err := runBenchmark(cfg, logger, run)
+	if err != nil {
+		return err
+	}
+	if err := logger.Close(); err != nil {
+		return err
+	}
+
+	record := newRecord("tinyimagenet", cfg, split, len(images), summary)
+	record.Dataset.SHA256 = checksum
+	load := loadReport.Metadata()
+	record.Load = &load
+	record.Memory = memory
+	return result.Write(stdout, record)
+}
+
+// newRecord builds the JSON record for the averages of the measured runs in summary
+func newRecord(benchmark string, cfg BenchmarkConfig, split string, numImages int, summary runSummary) result.Record {
+	return result.Record{
+		Metadata: result.NewMetadata(benchmark),
+		Config:   cfg,
+		Dataset:  result.Dataset{Split: split, Images: numImages},
+		Warmup:   cfg.Warmup,
+		Runs:     summary.Runs,
+		Run:      summaryRun(summary),
+	}
+}
+
+// summaryRun returns the record run for the averages of the measured runs in summary, with
+// throughput over all of them and the exact mean number of GC cycles
+func summaryRun(summary runSummary) result.Run {
+	run := newRun(summary.averages(), summary.Total.ImagesProcessed, summary.Total.PixelsProcessed, summary.Total.ExecutionTime)
+	run.NumGC = summary.gcCyclesPerRun()
+	return run
+}
+
+// newRun converts the metrics of r to a record run, with throughput computed from the images and
+// pixels processed in elapsed time
+func newRun(r runResult, images, pixels int, elapsed time.Duration) result.Run {
+	return result.Run{
+		ExecutionSeconds:           r.ExecutionTime.Seconds(),
+		ConcurrencyOverheadSeconds: r.ConcurrencyOverhead.Seconds(),
+		GoroutineSpawnSeconds:      r.GoroutineSpawn.Seconds(),
+		ExecutionNanos:             r.ExecutionTime.Nanoseconds(),
+		ConcurrencyOverheadNanos:   r.ConcurrencyOverhead.Nanoseconds(),
+		GoroutineSpawnNanos:        r.GoroutineSpawn.Nanoseconds(),
+		ImagesProcessed:            r.ImagesProcessed,
+		PixelsProcessed:            r.PixelsProcessed,
+		MemoryBytes:                r.MemoryUsage,
+		CPUPercent:                 r.CPUUsage,
+		PerCoreCPUPercent:          r.PerCoreCPU,
+		ImagesPerSecond:            throughput(images, elapsed),
+		MegapixelsPerSecond:        throughput(pixels, elapsed) / 1e6,
+		NumGC:                      float64(r.NumGC),
+		GCPauseSeconds:             r.GCPause.Seconds(),
+		GCMaxPauseSeconds:          r.GCMaxPause.Seconds(),
+		GCPauseNanos:               r.GCPause.Nanoseconds(),
+		GCMaxPauseNanos:            r.GCMaxPause.Nanoseconds(),
+		Collectors:                 r.Collected,
+	}
+}
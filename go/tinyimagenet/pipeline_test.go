@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPipelineSyntheticProducer(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 8, 40
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 2, 1
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	// A slow loader: processors spend most of the run waiting for it
+	const loadDelay = 10 * time.Millisecond
+	inMemory := sliceProducer(cfg, images, labels)
+	slow := func(emit func(ImageBatch)) error {
+		return inMemory(func(batch ImageBatch) {
+			time.Sleep(loadDelay)
+			emit(batch)
+		})
+	}
+
+	result, err := runPipeline(cfg, slow)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.Batches != 5 || result.ImagesProcessed != 40 {
+		t.Errorf("Expected 5 batches and 40 images, got %d and %d", result.Batches, result.ImagesProcessed)
+	}
+	if result.Load < 5*loadDelay || result.LoadWait < loadDelay {
+		t.Errorf("Expected the slow loader to show in load (%v) and load wait (%v)", result.Load, result.LoadWait)
+	}
+}
+
+func TestRunPipelineBackpressureVerdict(t *testing.T) {
+	const name = "slow-consumer"
+	kernelFuncs[name] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		time.Sleep(time.Millisecond)
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, name) })
+
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 4, 40
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 1, 2
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	inMemory := sliceProducer(cfg, images, labels)
+
+	// A loader slower than the kernel leaves the queue empty
+	slowProducer := func(emit func(ImageBatch)) error {
+		return inMemory(func(batch ImageBatch) {
+			time.Sleep(10 * time.Millisecond)
+			emit(batch)
+		})
+	}
+	result, err := runPipeline(cfg, slowProducer)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if verdict := result.Backpressure.Verdict(); !strings.HasPrefix(verdict, "producer-bound") {
+		t.Errorf("Expected a producer-bound verdict with a slow loader, got %q", verdict)
+	}
+
+	// A kernel slower than the in-memory loader keeps the queue full
+	cfg.Kernel = name
+	result, err = runPipeline(cfg, inMemory)
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if verdict := result.Backpressure.Verdict(); !strings.HasPrefix(verdict, "consumer-bound") {
+		t.Errorf("Expected a consumer-bound verdict with a slow kernel, got %q", verdict)
+	}
+	if result.Backpressure.ProducerBlocked <= 0 {
+		t.Errorf("Expected the loader to block on the full queue, got %v", result.Backpressure.ProducerBlocked)
+	}
+}
+
+func TestRunPipelineKernelError(t *testing.T) {
+	const name = "fail-on-marker"
+	kernelFuncs[name] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		if image[0] < 0 {
+			return nil, fmt.Errorf("%w: marked image", ErrBadImage)
+		}
+		time.Sleep(time.Millisecond)
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, name) })
+
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 4, 4, 3
+	cfg.BatchSize, cfg.SyntheticImages = 8, 200
+	cfg.PipelineWorkers, cfg.PipelineBuffer = 2, 1
+	cfg.Kernel = name
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+	images[20][0] = -1
+
+	// The failure must reach the caller, and the batches after it must not wait for the kernel
+	result, err := runPipeline(cfg, sliceProducer(cfg, images, labels))
+	if !errors.Is(err, ErrBadImage) {
+		t.Fatalf("Expected the kernel error, got %v", err)
+	}
+	if result.ImagesProcessed >= len(images)/2 {
+		t.Errorf("Expected the failure to stop processing, but %d of %d images were processed", result.ImagesProcessed, len(images))
+	}
+}
+
+func TestFileProducer(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 3, 5)
+	cfg := testImageConfig()
+	cfg.BatchSize, cfg.PipelineWorkers = 4, 2
+
+	// 15 images stream as three full batches and a partial batch of 3
+	result, err := runPipeline(cfg, fileProducer(cfg, dataDir))
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.Batches != 4 || result.ImagesProcessed != 15 {
+		t.Errorf("Expected 4 batches and 15 images, got %d and %d", result.Batches, result.ImagesProcessed)
+	}
+
+	cfg.Limit = 6
+	result, err = runPipeline(cfg, fileProducer(cfg, dataDir))
+	if err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+	if result.ImagesProcessed != 6 {
+		t.Errorf("Expected -limit to stop after 6 images, got %d", result.ImagesProcessed)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+)
+
+// maxUnreachableFraction is the share of unreachable image files PreflightCheck tolerates
+const maxUnreachableFraction = 0.01
+
+// PreflightCheck walks dataDir and opens every image file without decoding it, so corrupted
+// permissions or a flaky mount are found before any run rather than halfway through. An
+// unreadable subdirectory counts as one unreachable file, since its images cannot be listed.
+// It returns an error when more than 1% of the files are unreachable.
+func PreflightCheck(dataDir string) (totalFiles, unreachable int, err error) {
+	return preflightCheck(osFS{}, dataDir)
+}
+
+// preflightCheck implements PreflightCheck over fsys, retrying transient errors with the default
+// budget so a briefly unavailable mount is not reported as unreachable
+func preflightCheck(fsys datasetFS, dataDir string) (totalFiles, unreachable int, err error) {
+	cfg := DefaultConfig()
+	cfg.SkipUnreadable = true
+	w := newWalker(cfg, fsys)
+
+	paths, err := w.collectImagePaths(dataDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	return checkImagePaths(fsys, paths, w.Report().skippedDirectories())
+}
+
+// checkImagePaths is PreflightCheck over the paths of a walk that skipped unreadableDirs
+// subdirectories, so a caller that already listed the dataset does not walk it again
+func checkImagePaths(fsys datasetFS, paths []string, unreadableDirs int) (totalFiles, unreachable int, err error) {
+	w := newWalker(DefaultConfig(), fsys)
+	unreachable = unreadableDirs
+	for _, path := range paths {
+		err := w.do("open image", path, func() error {
+			file, err := fsys.Open(path)
+			if err != nil {
+				return err
+			}
+			return file.Close()
+		})
+		if err != nil {
+			unreachable++
+		}
+	}
+
+	totalFiles = len(paths) + unreadableDirs
+	if float64(unreachable) > maxUnreachableFraction*float64(totalFiles) {
+		return totalFiles, unreachable, fmt.Errorf("%d of %d image files are unreachable, more than %.0f%%",
+			unreachable, totalFiles, maxUnreachableFraction*100)
+	}
+	return totalFiles, unreachable, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestPreflightCheckAllReachable(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 3, 5)
+
+	totalFiles, unreachable, err := PreflightCheck(dataDir)
+	if err != nil {
+		t.Fatalf("Preflight check failed: %v", err)
+	}
+	if totalFiles != 15 || unreachable != 0 {
+		t.Errorf("Expected 15 files and 0 unreachable, got %d and %d", totalFiles, unreachable)
+	}
+}
+
+func TestPreflightCheckThreshold(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 4, 50)
+
+	// One unreachable file in 200 is within the 1% tolerance
+	fsys := newScriptedFS()
+	fsys.always[filepath.Join(dataDir, "n01", "img_003.png")] = syscall.EACCES
+	totalFiles, unreachable, err := preflightCheck(fsys, dataDir)
+	if err != nil {
+		t.Fatalf("Expected 1 of 200 unreachable to pass, got: %v", err)
+	}
+	if totalFiles != 200 || unreachable != 1 {
+		t.Errorf("Expected 200 files and 1 unreachable, got %d and %d", totalFiles, unreachable)
+	}
+
+	// Three are not
+	fsys.always[filepath.Join(dataDir, "n02", "img_000.png")] = syscall.EACCES
+	fsys.always[filepath.Join(dataDir, "n03", "img_049.png")] = syscall.ENOENT
+	if _, unreachable, err := preflightCheck(fsys, dataDir); err == nil || unreachable != 3 {
+		t.Errorf("Expected an error with 3 unreachable files, got %d unreachable and %v", unreachable, err)
+	}
+}
+
+func TestPreflightCheckUnreadableDirectory(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 3, 5)
+	fsys := newScriptedFS()
+	fsys.always[filepath.Join(dataDir, "n02")] = syscall.EACCES
+
+	totalFiles, unreachable, err := preflightCheck(fsys, dataDir)
+	if err == nil {
+		t.Error("Expected an error for an unreadable class directory")
+	}
+	if totalFiles != 11 || unreachable != 1 {
+		t.Errorf("Expected 10 listed files plus 1 unreadable directory, got %d files and %d unreachable", totalFiles, unreachable)
+	}
+}
+
+func TestCheckImagePathsSharesTheLoadListing(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 3, 5)
+	fsys := newScriptedFS()
+	fsys.always[filepath.Join(dataDir, "n02")] = syscall.EACCES
+
+	// The walk the load will use counts the directory it skipped as unreachable
+	cfg := testImageConfig()
+	cfg.SkipUnreadable = true
+	w := newWalker(cfg, fsys)
+	paths, err := w.collectImagePaths(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to list the dataset: %v", err)
+	}
+	totalFiles, unreachable, err := checkImagePaths(fsys, paths, w.Report().skippedDirectories())
+	if err == nil || totalFiles != 11 || unreachable != 1 {
+		t.Errorf("Expected 11 files, 1 unreachable and an error, got %d, %d and %v", totalFiles, unreachable, err)
+	}
+
+	images, _, report, err := loadListedWithReport(cfg, w, paths)
+	if err != nil {
+		t.Fatalf("Failed to load the listed paths: %v", err)
+	}
+	if len(images) != 10 || report.Metadata().Skipped != 1 {
+		t.Errorf("Expected the 10 listed images and the skipped directory in the report, got %d images and %+v", len(images), report.Metadata())
+	}
+}
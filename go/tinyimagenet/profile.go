@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"golang/internal/contention"
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+	"golang/internal/sysinfo"
+)
+
+// startCPUProfile starts writing a pprof CPU profile to path. The returned function stops the
+// profile and closes the file; it must be called before the profile can be read.
+func startCPUProfile(path string) (func() error, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %v", err)
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %v", err)
+	}
+	return func() error {
+		pprof.StopCPUProfile()
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write CPU profile: %v", err)
+		}
+		return nil
+	}, nil
+}
+
+// startBenchmarkProfile starts the -cpuprofile profile when one is configured. The returned
+// function finishes it and notes the file in the log.
+func startBenchmarkProfile(cfg BenchmarkConfig, logger *metricslog.Logger) (func() error, error) {
+	if cfg.CPUProfilePath == "" {
+		return func() error { return nil }, nil
+	}
+	stop, err := startCPUProfile(cfg.CPUProfilePath)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		if err := stop(); err != nil {
+			return err
+		}
+		return logger.Printf("CPU profile written to %s", cfg.CPUProfilePath)
+	}, nil
+}
+
+// memProfiler writes a heap profile after every measured run. Runs are numbered across the whole
+// benchmark, so the profiles of later phases, seeds or sweep settings never overwrite earlier ones.
+type memProfiler struct {
+	cfg    BenchmarkConfig
+	logger *metricslog.Logger
+	calls  int
+	runs   int // Measured runs profiled so far
+}
+
+// newMemProfiler returns a profiler for cfg.MemProfilePath, or nil when none is configured
+func newMemProfiler(cfg BenchmarkConfig, logger *metricslog.Logger) *memProfiler {
+	if cfg.MemProfilePath == "" {
+		return nil
+	}
+	return &memProfiler{cfg: cfg, logger: logger}
+}
+
+// wrap writes a heap profile after every measured run of run. Warmup runs are told apart by their
+// position in the benchmark loop, which starts with cfg.Warmup of them.
+func (m *memProfiler) wrap(run func() (runResult, error)) func() (runResult, error) {
+	return func() (runResult, error) {
+		position := m.calls % (m.cfg.Warmup + m.cfg.NumRuns)
+		m.calls++
+		result, err := run()
+		if err != nil || position < m.cfg.Warmup {
+			return result, err
+		}
+		m.runs++
+		path := heapProfilePath(m.cfg.MemProfilePath, m.runs)
+		if err := writeHeapProfile(path); err != nil {
+			return result, err
+		}
+		m.logger.Printf("Heap profile for Run %d written to %s", position-m.cfg.Warmup+1, path)
+		return result, nil
+	}
+}
+
+// heapProfilePath inserts the run number before the extension of path, so mem.pprof becomes
+// mem.run3.pprof
+func heapProfilePath(path string, run int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.run%d%s", strings.TrimSuffix(path, ext), run, ext)
+}
+
+// writeHeapProfile writes a pprof heap profile to path. A GC runs first so the profile reflects
+// the heap as the run left it rather than as of the last collection.
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create heap profile: %v", err)
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write heap profile: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write heap profile: %v", err)
+	}
+	return nil
+}
+
+// mutexProfileRate samples 1 in 100 contention events into the -mutexprofile profile
+const mutexProfileRate = 100
+
+// mutexProfileSites is how many of the most contended call sites -mutexprofile logs
+const mutexProfileSites = 3
+
+// CollectMutexProfile runs the benchmark loop once over images with mutex profiling enabled,
+// writes the pprof mutex profile to profilePath and logs the call sites that kept other
+// goroutines waiting the longest. The ratio of that waiting to the CPU time of the run tells
+// whether lock contention is worth chasing at all. WaitGroup and channel waits that involve no
+// contended lock do not appear; the block profile covers those.
+func CollectMutexProfile(cfg BenchmarkConfig, logger *metricslog.Logger, images [][]float32, labels []string, profilePath string) error {
+	file, err := os.Create(profilePath)
+	if err != nil {
+		return fmt.Errorf("failed to create mutex profile: %v", err)
+	}
+	restore := contention.Enable(mutexProfileRate)
+	cpuBefore, cpuErr := sysinfo.ProcessCPUTime()
+	start := time.Now()
+	_, runErr := runProcessingTask(context.Background(), cfg, images, labels)
+	elapsed := time.Since(start)
+	cpuAfter, err := sysinfo.ProcessCPUTime()
+	if cpuErr == nil {
+		cpuErr = err
+	}
+	restore()
+	if runErr != nil {
+		file.Close()
+		return runErr
+	}
+	if err := pprof.Lookup("mutex").WriteTo(file, 0); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write mutex profile: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write mutex profile: %v", err)
+	}
+
+	sites := contention.Sites(mutexProfileRate)
+	blocked := contention.TotalDelay(sites)
+	logger.Printf("\nMutex Profile (1 in %d contention events sampled) written to %s", mutexProfileRate, profilePath)
+	// Without process CPU time the run's wall time on every P stands in for the running time
+	running, basis := cpuAfter.Sub(cpuBefore).Total(), "CPU time"
+	if cpuErr != nil {
+		running, basis = elapsed*time.Duration(runtime.GOMAXPROCS(0)), "wall time x GOMAXPROCS"
+	}
+	ratio := 0.0
+	if running > 0 {
+		ratio = blocked.Seconds() / running.Seconds()
+	}
+	logger.Printf("Mutex Wait Time: %s seconds blocked against %s seconds of %s (ratio %.4f)",
+		metrics.FormatDuration(blocked), metrics.FormatDuration(running), basis, ratio)
+	if len(sites) == 0 {
+		logger.Printf("No contended locks were sampled")
+		return nil
+	}
+	for i, site := range sites[:min(mutexProfileSites, len(sites))] {
+		share := 0.0
+		if blocked > 0 {
+			share = 100 * site.Delay.Seconds() / blocked.Seconds()
+		}
+		logger.Printf("Contention Site %d: %s (%s:%d) - %s seconds waited (%s%% of the total), ~%d events",
+			i+1, site.Function, filepath.Base(site.File), site.Line, metrics.FormatDuration(site.Delay), metrics.FormatPercent(share), site.Count)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+
+	"golang/internal/metricslog"
+)
+
+func TestStartCPUProfile(t *testing.T) {
+	cfg := syntheticConfig()
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cpu.pprof")
+	stop, err := startCPUProfile(path)
+	if err != nil {
+		t.Fatalf("Failed to start CPU profile: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		RunProcessingTask(cfg, images, labels)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Failed to stop CPU profile: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open CPU profile: %v", err)
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.Size() == 0 {
+		t.Fatalf("Expected a non-empty CPU profile, got %v (%v)", info, err)
+	}
+	p, err := profile.Parse(file)
+	if err != nil {
+		t.Fatalf("Failed to parse CPU profile: %v", err)
+	}
+	if len(p.SampleType) == 0 {
+		t.Error("Expected sample types in the CPU profile")
+	}
+}
+
+func TestStartCPUProfileRejectsBadPath(t *testing.T) {
+	if _, err := startCPUProfile(filepath.Join(t.TempDir(), "missing", "cpu.pprof")); err == nil {
+		t.Error("Expected an error for a profile in a missing directory")
+	}
+}
+
+func TestHeapProfilePath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"mem.pprof":            "mem.run3.pprof",
+		"out/heap":             "out/heap.run3",
+		"profiles.d/mem.pb.gz": "profiles.d/mem.pb.run3.gz",
+	} {
+		if got := heapProfilePath(path, 3); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, path, got)
+		}
+	}
+}
+
+func TestMemProfilerWritesEachMeasuredRun(t *testing.T) {
+	dir := t.TempDir()
+	cfg := BenchmarkConfig{Warmup: 1, NumRuns: 2, MemProfilePath: filepath.Join(dir, "mem.pprof")}
+	logger, err := metricslog.New(filepath.Join(dir, "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+
+	profiler := newMemProfiler(cfg, logger)
+	run := profiler.wrap(func() (runResult, error) {
+		memorySink = make([][]byte, 100)
+		return runResult{}, nil
+	})
+	// Two benchmark loops, as with two phases or seeds, must not overwrite each other's profiles
+	for i := 0; i < 2; i++ {
+		if _, err := runBenchmark(cfg, logger, run); err != nil {
+			t.Fatalf("runBenchmark failed: %v", err)
+		}
+	}
+
+	for run := 1; run <= 4; run++ {
+		file, err := os.Open(filepath.Join(dir, fmt.Sprintf("mem.run%d.pprof", run)))
+		if err != nil {
+			t.Fatalf("Expected a heap profile for measured run %d: %v", run, err)
+		}
+		_, err = profile.Parse(file)
+		file.Close()
+		if err != nil {
+			t.Errorf("Failed to parse heap profile %d: %v", run, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mem.run5.pprof")); !os.IsNotExist(err) {
+		t.Errorf("Expected no profile for warmup runs, got %v", err)
+	}
+}
+
+func TestCollectMutexProfile(t *testing.T) {
+	cfg := syntheticConfig()
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "metrics.log")
+	logger, err := metricslog.New(logPath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	path := filepath.Join(dir, "mutex.pprof")
+	if err := CollectMutexProfile(cfg, logger, images, labels, path); err != nil {
+		t.Fatalf("CollectMutexProfile failed: %v", err)
+	}
+	logger.Close()
+
+	if rate := runtime.SetMutexProfileFraction(-1); rate != 0 {
+		t.Errorf("Expected mutex profiling to be switched off again, got a rate of %d", rate)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open mutex profile: %v", err)
+	}
+	defer file.Close()
+	p, err := profile.Parse(file)
+	if err != nil {
+		t.Fatalf("Failed to parse mutex profile: %v", err)
+	}
+	if len(p.SampleType) != 2 || p.SampleType[1].Type != "delay" {
+		t.Errorf("Expected contentions and delay samples, got %v", p.SampleType)
+	}
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if !strings.Contains(string(content), "Mutex Wait Time: ") || !strings.Contains(string(content), "(ratio ") {
+		t.Errorf("Expected the blocking to running ratio in the log, got:\n%s", content)
+	}
+}
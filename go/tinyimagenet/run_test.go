@@ -0,0 +1,666 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang/internal/metrics"
+	"golang/internal/metricslog"
+	"golang/internal/stats"
+	"golang/internal/sysinfo"
+)
+
+func TestRunBenchmarkExcludesWarmup(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Warmup = 2
+	cfg.NumRuns = 3
+
+	// Warmup runs are slow and allocation-heavy; measured runs take exactly one second
+	calls := 0
+	summary, err := runBenchmark(cfg, logger, func() (runResult, error) {
+		calls++
+		if calls <= cfg.Warmup {
+			return runResult{ExecutionTime: time.Minute, ImagesProcessed: 1, RunMetrics: metrics.RunMetrics{MemoryUsage: 1 << 30}}, nil
+		}
+		return runResult{ExecutionTime: time.Second, ImagesProcessed: 100, RunMetrics: metrics.RunMetrics{MemoryUsage: 1024, CPUUsage: 0.5}}, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to run benchmark: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	if calls != cfg.Warmup+cfg.NumRuns {
+		t.Errorf("Expected %d calls, got %d", cfg.Warmup+cfg.NumRuns, calls)
+	}
+	if summary.Runs != cfg.NumRuns {
+		t.Errorf("Expected %d measured runs, got %d", cfg.NumRuns, summary.Runs)
+	}
+
+	avg := summary.averages()
+	if avg.ExecutionTime != time.Second {
+		t.Errorf("Expected average execution time of 1s, got %v", avg.ExecutionTime)
+	}
+	if avg.MemoryUsage != 1024 || avg.ImagesProcessed != 100 || avg.CPUUsage != 0.5 {
+		t.Errorf("Warmup runs leaked into the averages: %+v", avg)
+	}
+
+	data, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	log := string(data)
+	if strings.Count(log, "Warmup Run") != cfg.Warmup {
+		t.Errorf("Expected %d warmup lines in the log:\n%s", cfg.Warmup, log)
+	}
+	if strings.Count(log, "Execution Time for Run") != cfg.NumRuns {
+		t.Errorf("Expected %d measured runs in the log:\n%s", cfg.NumRuns, log)
+	}
+}
+
+func TestRunSummaryAveragesPerCore(t *testing.T) {
+	var summary runSummary
+	summary.add(runResult{RunMetrics: metrics.RunMetrics{CPUUsage: 30, PerCoreCPU: []float64{50, 10}}})
+	summary.add(runResult{RunMetrics: metrics.RunMetrics{CPUUsage: 50, PerCoreCPU: []float64{90, 10}}})
+
+	avg := summary.averages()
+	if len(avg.PerCoreCPU) != 2 || avg.PerCoreCPU[0] != 70 || avg.PerCoreCPU[1] != 10 {
+		t.Errorf("Expected per-core averages [70 10], got %v", avg.PerCoreCPU)
+	}
+	if got := metrics.FormatPerCore(avg.PerCoreCPU); got != "cpu0 70.00%, cpu1 10.00%" {
+		t.Errorf("Unexpected per-core format: %q", got)
+	}
+}
+
+func TestLogAveragesIncludesDistribution(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+
+	var summary runSummary
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		summary.add(runResult{ExecutionTime: d})
+	}
+	if len(summary.Results) != 3 {
+		t.Fatalf("Expected 3 collected results, got %d", len(summary.Results))
+	}
+	logAverages(logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, metric := range []string{"Execution Time", "Concurrency Overhead", "Memory Usage", "CPU Utilization"} {
+		if !strings.Contains(string(content), metric+" Distribution") {
+			t.Errorf("Expected a distribution line for %s", metric)
+		}
+	}
+	if !strings.Contains(string(content), "CV 50.00%") {
+		t.Errorf("Expected a 50%% CV for execution times 1s, 2s, 3s, got:\n%s", content)
+	}
+}
+
+func TestMetricRecords(t *testing.T) {
+	var summary runSummary
+	summary.add(runResult{ExecutionTime: time.Second, NumWorkers: 4, RunMetrics: metrics.RunMetrics{MemoryUsage: 2 << 20, CPUUsage: 50, GCPause: time.Millisecond}})
+	summary.add(runResult{ExecutionTime: 2 * time.Second, NumWorkers: 4})
+
+	records := metricRecords("synthetic", summary)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	first := records[0]
+	if first.Dataset != "synthetic" || first.Run != 1 || first.NumWorkers != 4 || first.MemoryMB != 2 || first.CPUPercent != 50 || first.GCPause != time.Millisecond {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	if records[1].Run != 2 || records[1].ExecutionTime != 2*time.Second {
+		t.Errorf("Unexpected second record: %+v", records[1])
+	}
+}
+
+func TestCPUUtilizationLoggedAsPercent(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	cfg := BenchmarkConfig{NumRuns: 2}
+	summary, err := runBenchmark(cfg, logger, func() (runResult, error) {
+		return runResult{ExecutionTime: time.Second, RunMetrics: metrics.RunMetrics{CPUUsage: 42.5}}, nil
+	})
+	if err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	logAverages(logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	// calculateCPUUsage already returns 0-100, so the logged figures must match it exactly
+	for _, expected := range []string{"CPU Utilization for Run 1: 42.50%", "Average CPU Utilization: 42.50%", "CPU Utilization Distribution (%): min 42.50"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestAllocationCountsLogged(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	counts := []runResult{{RunMetrics: metrics.RunMetrics{AllocCount: 100, FreeCount: 40}}, {RunMetrics: metrics.RunMetrics{AllocCount: 200, FreeCount: 60}}}
+	calls := 0
+	summary, err := runBenchmark(BenchmarkConfig{NumRuns: len(counts)}, logger, func() (runResult, error) {
+		calls++
+		return counts[calls-1], nil
+	})
+	if err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	logAverages(logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+
+	if avg := summary.averages(); avg.AllocCount != 150 || avg.FreeCount != 50 {
+		t.Errorf("Expected average AllocCount 150 and FreeCount 50, got %d and %d", avg.AllocCount, avg.FreeCount)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{"Allocations for Run 1: AllocCount 100, FreeCount 40", "Average Allocations: AllocCount 150, FreeCount 50"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
+
+// memorySink keeps the allocation-heavy workload reachable while the sampler runs
+var memorySink [][]byte
+
+func TestMeasureTaskSamplesMemory(t *testing.T) {
+	const chunk, chunks = 1 << 20, 32
+	result, err := measureTask(1, func() (time.Duration, time.Duration, time.Duration, int, int) {
+		for i := 0; i < chunks; i++ {
+			memorySink = append(memorySink, make([]byte, chunk))
+			time.Sleep(5 * time.Millisecond)
+		}
+		return 0, 0, 0, 0, 0
+	})
+	memorySink = nil
+	if err != nil {
+		t.Fatalf("measureTask failed: %v", err)
+	}
+	if result.Memory.Samples < 2 {
+		t.Errorf("Expected background samples during the run, got %d", result.Memory.Samples)
+	}
+	if result.Memory.PeakHeapAlloc < chunk*chunks {
+		t.Errorf("Expected a peak HeapAlloc of at least %d bytes, got %d", chunk*chunks, result.Memory.PeakHeapAlloc)
+	}
+	if result.TotalAlloc < chunk*chunks {
+		t.Errorf("Expected TotalAlloc of at least %d bytes, got %d", chunk*chunks, result.TotalAlloc)
+	}
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	summary, err := runBenchmark(BenchmarkConfig{NumRuns: 1}, logger, func() (runResult, error) { return result, nil })
+	if err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	logAverages(logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{"Sampled Memory for Run 1: Peak RSS", "Sampled Memory across Runs: Peak RSS"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestMeasureTaskRecordsGC(t *testing.T) {
+	const cycles = 3
+	result, err := measureTask(1, func() (time.Duration, time.Duration, time.Duration, int, int) {
+		for i := 0; i < cycles; i++ {
+			for j := 0; j < 16; j++ {
+				memorySink = append(memorySink, make([]byte, 1<<20))
+			}
+			memorySink = nil
+			runtime.GC()
+		}
+		return 0, 0, 0, 0, 0
+	})
+	if err != nil {
+		t.Fatalf("measureTask failed: %v", err)
+	}
+	if result.NumGC < cycles {
+		t.Errorf("Expected at least %d GC cycles, got %d", cycles, result.NumGC)
+	}
+	if len(result.GCPauses) != int(result.NumGC) {
+		t.Errorf("Expected one pause per cycle, got %d pauses for %d cycles", len(result.GCPauses), result.NumGC)
+	}
+	if result.GCPause <= 0 || result.GCMaxPause <= 0 || result.GCMaxPause > result.GCPause {
+		t.Errorf("Expected a max pause within a non-zero total, got max %v of %v", result.GCMaxPause, result.GCPause)
+	}
+}
+
+func TestLogGCAveragesRuns(t *testing.T) {
+	var summary runSummary
+	summary.add(runResult{RunMetrics: metrics.RunMetrics{NumGC: 1, GCPause: time.Millisecond, GCMaxPause: time.Millisecond, GCPauses: []time.Duration{time.Millisecond}}})
+	summary.add(runResult{RunMetrics: metrics.RunMetrics{NumGC: 2, GCPause: 4 * time.Millisecond, GCMaxPause: 3 * time.Millisecond, GCPauses: []time.Duration{time.Millisecond, 3 * time.Millisecond}}})
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logGC(logger, summary)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{
+		"Average GC per Run: 1.50 cycles, total pause 2.500 ms, max pause 2.000 ms",
+		"GC Pause Percentiles (ms): p50 1.000",
+		"max 3.000 over 3 pauses",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+
+	if run := summaryRun(summary); run.NumGC != 1.5 || run.GCPauseSeconds != 0.0025 || run.GCMaxPauseSeconds != 0.002 {
+		t.Errorf("Expected 1.5 cycles, 2.5 ms and 2 ms in the record, got %v, %v and %v", run.NumGC, run.GCPauseSeconds, run.GCMaxPauseSeconds)
+	}
+	records := metricRecords("synthetic", summary)
+	if records[1].NumGC != 2 || records[1].GCMaxPause != 3*time.Millisecond {
+		t.Errorf("Expected 2 cycles and a 3 ms max pause in the second CSV record, got %d and %v", records[1].NumGC, records[1].GCMaxPause)
+	}
+}
+
+// skewedKernel is the name of a kernel that spends a millisecond on every image, or ten on an image
+// whose first pixel is skewMarker, so a batch of marked images takes 10x as long as the others
+const (
+	skewedKernel = "skewed"
+	skewMarker   = -2
+)
+
+func registerSkewedKernel(t *testing.T) {
+	t.Helper()
+	kernelFuncs[skewedKernel] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		if image[0] == skewMarker {
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			time.Sleep(time.Millisecond)
+		}
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, skewedKernel) })
+}
+
+func TestRunProcessingTaskDetectsBatchImbalance(t *testing.T) {
+	registerSkewedKernel(t)
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.BatchSize, cfg.Kernel = 40, 5, skewedKernel
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+
+	_, _, _, _, _, balanced, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+	const slowBatch = 3
+	for _, image := range images[slowBatch*cfg.BatchSize : (slowBatch+1)*cfg.BatchSize] {
+		image[0] = skewMarker
+	}
+	_, _, _, _, _, skewed, err := RunProcessingTask(cfg, images, labels)
+	if err != nil {
+		t.Fatalf("RunProcessingTask failed: %v", err)
+	}
+
+	if len(skewed) != 8 {
+		t.Fatalf("Expected a duration for each of the 8 batches, got %d", len(skewed))
+	}
+	slowest := 0
+	for i, d := range skewed {
+		if d > skewed[slowest] {
+			slowest = i
+		}
+	}
+	if slowest != slowBatch {
+		t.Errorf("Expected batch %d to be the slowest, got batch %d in %v", slowBatch, slowest, skewed)
+	}
+	imbalance := func(durations []time.Duration) float64 {
+		ms := make([]float64, len(durations))
+		for i, d := range durations {
+			ms[i] = d.Seconds() * 1000
+		}
+		return stats.Summarize(ms).Imbalance()
+	}
+	if got := imbalance(skewed); got < 3 {
+		t.Errorf("Expected the 10x batch to give an imbalance of at least 3, got %.2f from %v", got, skewed)
+	}
+	if imbalance(balanced) >= imbalance(skewed) {
+		t.Errorf("Expected the balanced run to be less imbalanced, got %.2f against %.2f", imbalance(balanced), imbalance(skewed))
+	}
+}
+
+func TestLogBatchDurations(t *testing.T) {
+	var summary runSummary
+	summary.add(runResult{BatchSize: 4, BatchDurations: []time.Duration{2 * time.Millisecond, 2 * time.Millisecond, 2 * time.Millisecond, 10 * time.Millisecond}})
+	summary.add(runResult{BatchSize: 4, BatchDurations: []time.Duration{4 * time.Millisecond}})
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logBatchDurations(logger, "Batch Durations for Run 1", summary.Results[0].BatchDurations)
+	logBatchDurations(logger, "Batch Durations across Runs", summary.averages().BatchDurations)
+	logBatchDurations(logger, "Batch Durations for Pipeline", nil)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{
+		"Batch Durations for Run 1: min 2.000 ms, median 2.000 ms, max 10.000 ms, imbalance 2.50x (max/mean) over 4 batches",
+		"Batch Durations across Runs: min 2.000 ms, median 2.000 ms, max 10.000 ms, imbalance 2.50x (max/mean) over 5 batches",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+	if strings.Contains(string(content), "Pipeline") {
+		t.Errorf("Expected nothing logged without batches, got:\n%s", content)
+	}
+
+	records := batchTimingRecords("synthetic", summary)
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 batch records, got %d", len(records))
+	}
+	if last := records[4]; last.Run != 2 || last.Batch != 0 || last.Images != 4 || last.Duration != 4*time.Millisecond {
+		t.Errorf("Expected run 2, batch 0 of 4 images taking 4ms, got %+v", last)
+	}
+}
+
+func TestLogVariabilityWarnsAboveThreshold(t *testing.T) {
+	var steady, noisy runSummary
+	for _, seconds := range []float64{1, 1, 1} {
+		steady.add(runResult{ExecutionTime: time.Duration(seconds * float64(time.Second)), RunMetrics: metrics.RunMetrics{MemoryUsage: 1 << 20}})
+	}
+	for _, seconds := range []float64{1, 1.5, 2} {
+		noisy.add(runResult{ExecutionTime: time.Duration(seconds * float64(time.Second)), RunMetrics: metrics.RunMetrics{MemoryUsage: 1 << 20}})
+	}
+
+	for name, c := range map[string]struct {
+		summary  runSummary
+		expected []string
+		warns    bool
+	}{
+		"steady": {steady, []string{"Execution Time Coefficient of Variation: 0.00%", "Memory Usage Coefficient of Variation: 0.00%"}, false},
+		"noisy":  {noisy, []string{"Execution Time Coefficient of Variation: 33.33%", "WARNING: High variability detected in Execution Time (CV 33.33% over 3 runs); consider increasing -num-runs or enabling -gc-between-runs."}, true},
+	} {
+		logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+		logger, err := metricslog.New(logFilePath)
+		if err != nil {
+			t.Fatalf("Failed to open metrics logger: %v", err)
+		}
+		logVariability(logger, c.summary)
+		if err := logger.Close(); err != nil {
+			t.Fatalf("Failed to close metrics logger: %v", err)
+		}
+		content, err := os.ReadFile(logFilePath)
+		if err != nil {
+			t.Fatalf("Failed to read log: %v", err)
+		}
+		for _, expected := range c.expected {
+			if !strings.Contains(string(content), expected) {
+				t.Errorf("%s: expected %q in log, got:\n%s", name, expected, content)
+			}
+		}
+		if warns := strings.Count(string(content), "WARNING"); (warns > 0) != c.warns || warns > 1 {
+			t.Errorf("%s: expected a warning only for the execution time, got:\n%s", name, content)
+		}
+	}
+}
+
+func TestRunBenchmarkGCBetweenRuns(t *testing.T) {
+	logger, err := metricslog.New(filepath.Join(t.TempDir(), "metrics.log"))
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	defer logger.Close()
+
+	cfg := DefaultConfig()
+	cfg.Warmup, cfg.NumRuns, cfg.GCBetweenRuns = 1, 2, true
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := runBenchmark(cfg, logger, func() (runResult, error) { return runResult{}, nil }); err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	if cycles := after.NumGC - before.NumGC; cycles < 3 {
+		t.Errorf("Expected a forced GC after each of the 3 runs, got %d cycles", cycles)
+	}
+}
+
+func TestRunBenchmarkCooldownAndGCBetweenRuns(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logPath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Warmup, cfg.NumRuns = 1, 3
+	cfg.Cooldown, cfg.GCBetweenRuns, cfg.FreeOSMemory = 20*time.Millisecond, true, true
+	run := func() (runResult, error) {
+		memorySink = make([][]byte, 100)
+		return runResult{}, nil
+	}
+
+	start := time.Now()
+	summary, err := runBenchmark(cfg, logger, run)
+	if err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(cfg.NumRuns)*cfg.Cooldown {
+		t.Errorf("Expected at least %v for %d runs with a cooldown of %v, got %v", time.Duration(cfg.NumRuns)*cfg.Cooldown, cfg.NumRuns, cfg.Cooldown, elapsed)
+	}
+	for i, r := range summary.Results {
+		if !r.ForcedGC || r.GCDuration <= 0 {
+			t.Errorf("Expected run %d to report a forced GC and its duration, got %v, %v", i+1, r.ForcedGC, r.GCDuration)
+		}
+	}
+
+	// Without -gc-between-runs nothing is collected after the runs
+	cfg.Cooldown, cfg.GCBetweenRuns, cfg.FreeOSMemory = 0, false, false
+	summary, err = runBenchmark(cfg, logger, run)
+	if err != nil {
+		t.Fatalf("runBenchmark failed: %v", err)
+	}
+	if r := summary.Results[0]; r.ForcedGC || r.GCDuration != 0 {
+		t.Errorf("Expected no forced GC without -gc-between-runs, got %v, %v", r.ForcedGC, r.GCDuration)
+	}
+	logger.Close()
+
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{"Forced GC After Run 3: yes, ", "Forced GC After Run 1: no"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+}
+
+// registerCountingKernel registers a kernel that sleeps a millisecond per image and records the
+// most kernel calls it saw running at once in peak. A batch goroutine processes its images one at
+// a time, so peak is the number of batches that were in flight together.
+func registerCountingKernel(t *testing.T, peak *atomic.Int64) string {
+	t.Helper()
+	const name = "counting"
+	var active atomic.Int64
+	kernelFuncs[name] = func(cfg BenchmarkConfig, image []float32) ([]float32, error) {
+		n := active.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		active.Add(-1)
+		return image, nil
+	}
+	t.Cleanup(func() { delete(kernelFuncs, name) })
+	return name
+}
+
+func TestRunProcessingTaskBoundsInFlightBatches(t *testing.T) {
+	var peak atomic.Int64
+	cfg := syntheticConfig()
+	cfg.SyntheticImages, cfg.BatchSize, cfg.Kernel = 64, 2, registerCountingKernel(t, &peak)
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to build synthetic dataset: %v", err)
+	}
+
+	for _, limit := range []int{1, 3} {
+		peak.Store(0)
+		cfg.MaxInFlight = limit
+		_, _, _, imagesProcessed, _, _, err := RunProcessingTask(cfg, images, labels)
+		if err != nil {
+			t.Fatalf("RunProcessingTask failed: %v", err)
+		}
+		if imagesProcessed != len(images) {
+			t.Errorf("Limit %d: expected %d images processed, got %d", limit, len(images), imagesProcessed)
+		}
+		if got := peak.Load(); got < 1 || got > int64(limit) {
+			t.Errorf("Expected between 1 and %d batches in flight, got %d", limit, got)
+		}
+	}
+
+	// Without a limit the 32 sleeping batches overlap, which shows the kernel counts them
+	peak.Store(0)
+	cfg.MaxInFlight = 0
+	RunProcessingTask(cfg, images, labels)
+	if got := peak.Load(); got <= 3 {
+		t.Errorf("Expected more than 3 batches in flight without a limit, got %d", got)
+	}
+}
+
+func TestLogGoroutineCreationRate(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logGoroutineCreationRate(logger, 2e6, 100)
+	logGoroutineCreationRate(logger, 0, 7)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	expected := "Goroutine Creation Rate: 2000000 goroutines/second (0.050 ms to start the 100 batch goroutines of a run)"
+	if !strings.Contains(string(content), expected) {
+		t.Errorf("Expected %q in log, got:\n%s", expected, content)
+	}
+	if strings.Count(string(content), "Goroutine Creation Rate") != 1 {
+		t.Errorf("Expected nothing logged without a rate, got:\n%s", content)
+	}
+}
+
+func TestMeasureRunRecordsCPUTime(t *testing.T) {
+	if !sysinfo.CPUTimeSupported {
+		t.Skip("Process CPU time is not supported on this platform")
+	}
+	result, err := measureTask(1, func() (time.Duration, time.Duration, time.Duration, int, int) {
+		start := time.Now()
+		x := 1.0
+		for time.Since(start) < 50*time.Millisecond {
+			x = x*1.0000001 + 1e-9
+		}
+		return time.Since(start), 0, 0, int(x) & 1, 0
+	})
+	if err != nil {
+		t.Fatalf("Failed to measure task: %v", err)
+	}
+	if result.CPUTime.Total() <= 0 {
+		t.Errorf("Expected CPU time for a busy task, got %+v", result.CPUTime)
+	}
+}
+
+func TestLogHeapFragmentation(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	var steady, growing runSummary
+	for _, f := range []float64{1.2, 1.3, 1.5} {
+		steady.add(runResult{RunMetrics: metrics.RunMetrics{HeapFragmentation: f}})
+	}
+	for _, f := range []float64{1.2, 1.5, 1.9} {
+		growing.add(runResult{RunMetrics: metrics.RunMetrics{HeapFragmentation: f}})
+	}
+	logHeapFragmentation(logger, steady)
+	logHeapFragmentation(logger, growing)
+	logHeapFragmentation(logger, runSummary{})
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, expected := range []string{
+		"Average Heap Fragmentation: 1.333 (HeapInuse/HeapAlloc; first run 1.200, last run 1.500)",
+		"WARNING: Heap fragmentation grew from 1.200 in the first run to 1.900 in the last (1.58x over 3 runs)",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in log, got:\n%s", expected, content)
+		}
+	}
+	if strings.Count(string(content), "WARNING") != 1 || strings.Count(string(content), "Average Heap Fragmentation") != 2 {
+		t.Errorf("Expected one warning, for the growing runs only, and nothing without runs, got:\n%s", content)
+	}
+}
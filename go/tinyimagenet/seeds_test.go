@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/internal/metricslog"
+)
+
+func TestShuffleImagesKeepsPairs(t *testing.T) {
+	images := make([][]float32, 8)
+	labels := []string{"0", "1", "2", "3", "4", "5", "6", "7"}
+	for i := range images {
+		images[i] = []float32{float32(i)}
+	}
+
+	shuffled, shuffledLabels := shuffleImages(images, labels, 3)
+	again, _ := shuffleImages(images, labels, 3)
+	other, _ := shuffleImages(images, labels, 4)
+
+	for i, img := range shuffled {
+		if shuffledLabels[i] != strconv.Itoa(int(img[0])) {
+			t.Errorf("Label %v separated from image %v at position %d", shuffledLabels[i], img[0], i)
+		}
+	}
+	if !reflect.DeepEqual(shuffled, again) {
+		t.Errorf("Expected the same seed to give the same order")
+	}
+	if reflect.DeepEqual(shuffled, other) {
+		t.Errorf("Expected different seeds to give different orders")
+	}
+	for i := range images {
+		if images[i][0] != float32(i) {
+			t.Errorf("Expected the original slice to be left untouched, got %v at position %d", images[i][0], i)
+		}
+	}
+}
+
+func TestSeedVariance(t *testing.T) {
+	mean, variance := seedVariance([]time.Duration{time.Second, 2 * time.Second, 3 * time.Second})
+	if mean != 2 || variance != 1 {
+		t.Errorf("Expected mean 2 and variance 1, got %v and %v", mean, variance)
+	}
+	mean, variance = seedVariance([]time.Duration{time.Second})
+	if mean != 1 || variance != 0 {
+		t.Errorf("Expected mean 1 and variance 0 for a single seed, got %v and %v", mean, variance)
+	}
+}
+
+func TestLogSeedVarianceWarning(t *testing.T) {
+	cases := []struct {
+		averages []time.Duration
+		warn     bool
+	}{
+		{[]time.Duration{100 * time.Millisecond, 101 * time.Millisecond, 99 * time.Millisecond}, false},
+		{[]time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 60 * time.Millisecond}, true},
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "metrics.log")
+		logger, err := metricslog.New(path)
+		if err != nil {
+			t.Fatalf("Failed to open metrics logger: %v", err)
+		}
+		logSeedVariance(logger, []int64{1, 2, 3}, c.averages)
+		if err := logger.Close(); err != nil {
+			t.Fatalf("Failed to close metrics logger: %v", err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read log: %v", err)
+		}
+		if got := strings.Contains(string(content), "WARNING"); got != c.warn {
+			t.Errorf("Expected warning %v for %v, got log:\n%s", c.warn, c.averages, content)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang/internal/metricslog"
+)
+
+func TestLoadDatasetSampleFraction(t *testing.T) {
+	cfg := testImageConfig()
+	cfg.SyntheticImages = 1000
+	cfg.SampleFraction = 0.1
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to load synthetic dataset: %v", err)
+	}
+	// 10 classes of 100 images keep 10 each
+	counts := map[string]int{}
+	for _, label := range labels {
+		counts[label]++
+	}
+	if len(images) != 100 || len(counts) != 10 || counts["3"] != 10 {
+		t.Errorf("Expected 10 images in each of 10 classes, got %d images over %v", len(images), counts)
+	}
+
+	logFilePath := filepath.Join(t.TempDir(), "metrics.log")
+	logger, err := metricslog.New(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to open metrics logger: %v", err)
+	}
+	logSubset(cfg, logger, len(images))
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close metrics logger: %v", err)
+	}
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	expected := "Dataset Subset: 100 images (limit 0, sample fraction 0.1, seed 1)"
+	if !strings.Contains(string(content), expected) {
+		t.Errorf("Expected %q in log, got:\n%s", expected, content)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang/internal/metricslog"
+	"golang/internal/result"
+)
+
+// parseMaxProcsSweep parses a comma-separated list of GOMAXPROCS settings such as "1,2,4,8"
+func parseMaxProcsSweep(list string) ([]int, error) {
+	var settings []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("GOMAXPROCS setting %q is not a positive integer", field)
+		}
+		settings = append(settings, n)
+	}
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no GOMAXPROCS settings in %q", list)
+	}
+	return settings, nil
+}
+
+// sweepBaseline returns the setting speedups are measured against: 1 when swept, the smallest
+// setting otherwise
+func sweepBaseline(settings []int) int {
+	baseline := settings[0]
+	for _, n := range settings {
+		if n < baseline {
+			baseline = n
+		}
+	}
+	return baseline
+}
+
+// scalingPoints computes the speedup and parallel efficiency of each setting relative to the
+// baseline setting
+func scalingPoints(settings []int, summaries []runSummary) []result.SweepPoint {
+	baseline := sweepBaseline(settings)
+	var baselineSeconds float64
+	for i, n := range settings {
+		if n == baseline {
+			baselineSeconds = summaries[i].averages().ExecutionTime.Seconds()
+			break
+		}
+	}
+
+	points := make([]result.SweepPoint, len(settings))
+	for i, n := range settings {
+		summary := summaries[i]
+		avg := summary.averages()
+		points[i] = result.SweepPoint{
+			GOMAXPROCS: n,
+			Runs:       summary.Runs,
+			Run:        summaryRun(summary),
+		}
+		if seconds := avg.ExecutionTime.Seconds(); seconds > 0 {
+			points[i].Speedup = baselineSeconds / seconds
+			points[i].Efficiency = points[i].Speedup / (float64(n) / float64(baseline))
+		}
+	}
+	return points
+}
+
+// runMaxProcsSweep repeats the benchmark, warmup included, at each GOMAXPROCS setting and logs
+// each setting's averages under its own heading followed by a scaling table. GOMAXPROCS is
+// restored afterwards. It returns the CSV records of every measured run, named after the
+// setting, and writes the sweep as one JSON document to jsonOut unless it is nil.
+func runMaxProcsSweep(cfg BenchmarkConfig, logger *metricslog.Logger, name string, dataset result.Dataset, settings []int, run func() (runResult, error), jsonOut io.Writer) ([]result.MetricRecord, error) {
+	previous := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(previous)
+
+	var records []result.MetricRecord
+	summaries := make([]runSummary, len(settings))
+	for i, n := range settings {
+		runtime.GOMAXPROCS(n)
+		logger.Printf("\nGOMAXPROCS Sweep: %d (%d/%d)", n, i+1, len(settings))
+		summary, err := runBenchmark(cfg, logger, run)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("\nAverage Metrics (GOMAXPROCS=%d):", n)
+		logAverages(logger, summary)
+		summaries[i] = summary
+		records = append(records, metricRecords(fmt.Sprintf("%s-gomaxprocs%d", name, n), summary)...)
+	}
+
+	points := scalingPoints(settings, summaries)
+	baseline := sweepBaseline(settings)
+	logger.Printf("\nScaling Table (relative to GOMAXPROCS=%d):", baseline)
+	logger.Printf("  %10s  %8s  %16s  %12s  %8s  %10s", "GOMAXPROCS", "Runs", "Avg Time (s)", "Images/s", "Speedup", "Efficiency")
+	for _, p := range points {
+		logger.Printf("  %10d  %8d  %16.6f  %12.2f  %7.2fx  %9.1f%%", p.GOMAXPROCS, p.Runs, p.Run.ExecutionSeconds, p.Run.ImagesPerSecond, p.Speedup, p.Efficiency*100)
+	}
+
+	if jsonOut != nil {
+		sweep := result.Sweep{
+			Metadata: result.NewMetadata("tinyimagenet"),
+			Config:   cfg,
+			Dataset:  dataset,
+			Warmup:   cfg.Warmup,
+			Baseline: baseline,
+			Points:   points,
+		}
+		sweep.GOMAXPROCS = previous
+		if err := result.WriteSweep(jsonOut, sweep); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// createSweepOutput opens the -json destination of a sweep: nil for an empty path, stdout for
+// "-" and a created file otherwise. The returned function closes it.
+func createSweepOutput(path string) (io.Writer, func() error, error) {
+	switch path {
+	case "":
+		return nil, func() error { return nil }, nil
+	case "-":
+		return os.Stdout, func() error { return nil }, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	return file, file.Close, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "image/jpeg"
+
+	"golang/internal/labels"
+)
+
+// The Tiny ImageNet validation split keeps every image in one flat images directory, with the
+// class of each image listed in val_annotations.txt next to it
+const (
+	valImagesDir       = "images"
+	valAnnotationsFile = "val_annotations.txt"
+)
+
+// LoadTinyImageNetVal loads the validation images under dataDir, the val directory of the
+// archive, in the default image shape. Images are labelled with the wnid val_annotations.txt gives
+// them and returned in the order of that file.
+func LoadTinyImageNetVal(dataDir string) ([][]float32, []string, error) {
+	return loadTinyImageNetVal(DefaultConfig(), dataDir)
+}
+
+// loadTinyImageNetVal is LoadTinyImageNetVal for the image shape, limit and filesystem policy of cfg
+func loadTinyImageNetVal(cfg BenchmarkConfig, dataDir string) ([][]float32, []string, error) {
+	fmt.Fprintln(os.Stderr, "Loading Tiny ImageNet validation set...")
+
+	annotationsPath := filepath.Join(dataDir, valAnnotationsFile)
+	file, err := os.Open(annotationsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %v", annotationsPath, err)
+	}
+	annotations, err := labels.ReadValAnnotations(file)
+	file.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %v", annotationsPath, err)
+	}
+	if cfg.Limit > 0 && cfg.Limit < len(annotations) {
+		annotations = annotations[:cfg.Limit]
+	}
+
+	paths := make([]string, len(annotations))
+	wnids := make([]string, len(annotations))
+	for i, a := range annotations {
+		paths[i] = filepath.Join(dataDir, valImagesDir, a.Filename)
+		wnids[i] = a.Wnid
+	}
+	return loadImagePaths(cfg, newWalker(cfg, osFS{}), paths, wnids, cfg.LoadWorkers)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeValSplit lays out a synthetic validation split under a new directory: the images of
+// writeNoiseImages moved into one flat images directory, and a val_annotations.txt assigning
+// them the given wnids in order
+func writeValSplit(t *testing.T, wnids []string) string {
+	t.Helper()
+	valDir := t.TempDir()
+	imagesDir := filepath.Join(valDir, valImagesDir)
+	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", imagesDir, err)
+	}
+
+	var annotations strings.Builder
+	for i, path := range writeNoiseImages(t, t.TempDir(), DefaultConfig(), len(wnids)) {
+		name := fmt.Sprintf("val_%d.png", i)
+		if err := os.Rename(path, filepath.Join(imagesDir, name)); err != nil {
+			t.Fatalf("Failed to move image: %v", err)
+		}
+		fmt.Fprintf(&annotations, "%s\t%s\t0\t0\t63\t63\n", name, wnids[i])
+	}
+	if err := os.WriteFile(filepath.Join(valDir, valAnnotationsFile), []byte(annotations.String()), 0644); err != nil {
+		t.Fatalf("Failed to write annotations: %v", err)
+	}
+	return valDir
+}
+
+func TestLoadTinyImageNetVal(t *testing.T) {
+	// val_10.png sorts before val_2.png, so the images only come back in this order if the
+	// annotations, not a directory listing, drive the load
+	wnids := make([]string, 60)
+	for i := range wnids {
+		wnids[i] = fmt.Sprintf("n%02d", (i*7)%5)
+	}
+	valDir := writeValSplit(t, wnids)
+
+	images, labels, err := LoadTinyImageNetVal(valDir)
+	if err != nil {
+		t.Fatalf("Failed to load validation split: %v", err)
+	}
+	if !reflect.DeepEqual(labels, wnids) {
+		t.Errorf("Expected labels from the annotations in file order, got %v", labels)
+	}
+	if len(images) != len(wnids) {
+		t.Fatalf("Expected %d images, got %d", len(wnids), len(images))
+	}
+
+	// Each image must be the one its annotation names
+	cfg := DefaultConfig()
+	for _, i := range []int{0, 9, 59} {
+		file, err := os.Open(filepath.Join(valDir, valImagesDir, fmt.Sprintf("val_%d.png", i)))
+		if err != nil {
+			t.Fatalf("Failed to open image: %v", err)
+		}
+		expected, _, err := decodeImage(cfg, file, file.Name())
+		file.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode image: %v", err)
+		}
+		if !reflect.DeepEqual(images[i], expected) {
+			t.Errorf("Image %d does not match val_%d.png", i, i)
+		}
+	}
+}
+
+func TestLoadTinyImageNetValLimit(t *testing.T) {
+	valDir := writeValSplit(t, []string{"n01", "n02", "n03"})
+	cfg := DefaultConfig()
+	cfg.Limit = 2
+	_, labels, err := loadTinyImageNetVal(cfg, valDir)
+	if err != nil {
+		t.Fatalf("Failed to load validation split: %v", err)
+	}
+	if !reflect.DeepEqual(labels, []string{"n01", "n02"}) {
+		t.Errorf("Expected the first 2 annotated images, got %v", labels)
+	}
+}
+
+func TestLoadTinyImageNetValErrors(t *testing.T) {
+	if _, _, err := LoadTinyImageNetVal(t.TempDir()); err == nil || !strings.Contains(err.Error(), valAnnotationsFile) {
+		t.Errorf("Expected a missing %s to be reported, got %v", valAnnotationsFile, err)
+	}
+
+	valDir := writeValSplit(t, []string{"n01"})
+	annotations := "val_0.png\tn01\t0\t0\t63\t63\nval_missing.png\tn02\t0\t0\t63\t63\n"
+	if err := os.WriteFile(filepath.Join(valDir, valAnnotationsFile), []byte(annotations), 0644); err != nil {
+		t.Fatalf("Failed to write annotations: %v", err)
+	}
+	if _, _, err := LoadTinyImageNetVal(valDir); err == nil || !strings.Contains(err.Error(), "val_missing.png") {
+		t.Errorf("Expected the annotated image missing from images/ to be reported, got %v", err)
+	}
+}
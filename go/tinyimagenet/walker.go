@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang/internal/result"
+)
+
+// Backoff between attempts at a transiently failing filesystem operation
+const (
+	initialRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 2 * time.Second
+)
+
+// datasetFS is the filesystem the dataset is read through, so tests can script failures
+type datasetFS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Open(name string) (io.ReadCloser, error)
+}
+
+// osFS reads the dataset from the operating system's filesystem
+type osFS struct{}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+
+// isTransient reports whether err is a filesystem error worth retrying, as network and FUSE mounts
+// return EIO, ESTALE or EAGAIN for failures that clear up. Anything else, such as ENOENT or
+// EACCES, is permanent.
+func isTransient(err error) bool {
+	return errors.Is(err, syscall.EIO) || errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EAGAIN)
+}
+
+// fsIncident records a filesystem operation that failed at least once
+type fsIncident struct {
+	Op        string
+	Path      string
+	Err       error // The last error seen
+	Attempts  int
+	Recovered bool // A retry succeeded
+	Skipped   bool // The entry was left out of the dataset
+}
+
+// LoadReport summarizes the filesystem incidents of a dataset load
+type LoadReport struct {
+	RetryBudget int // Retries allowed per operation
+	Retries     int
+	Incidents   []fsIncident
+}
+
+// Lines formats the report as log lines, one per incident after a summary line
+func (r LoadReport) Lines() []string {
+	m := r.Metadata()
+	lines := []string{fmt.Sprintf("Filesystem Incidents: %d (%d transient, %d permanent, %d recovered, %d skipped); %d retries with a budget of %d per operation",
+		len(r.Incidents), m.TransientErrors, m.PermanentErrors, m.Recovered, m.Skipped, r.Retries, r.RetryBudget)}
+	for _, incident := range r.Incidents {
+		outcome := "failed"
+		switch {
+		case incident.Recovered:
+			outcome = "recovered"
+		case incident.Skipped:
+			outcome = "skipped"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s: %v (%s after %d attempts)", incident.Op, incident.Path, incident.Err, outcome, incident.Attempts))
+	}
+	return lines
+}
+
+// Metadata returns the incident counts for the JSON result record
+func (r LoadReport) Metadata() result.LoadReport {
+	m := result.LoadReport{RetryBudget: r.RetryBudget, Retries: r.Retries, Incidents: len(r.Incidents)}
+	for _, incident := range r.Incidents {
+		if isTransient(incident.Err) {
+			m.TransientErrors++
+		} else {
+			m.PermanentErrors++
+		}
+		if incident.Recovered {
+			m.Recovered++
+		}
+		if incident.Skipped {
+			m.Skipped++
+		}
+	}
+	return m
+}
+
+// skippedDirectories returns the number of subdirectories the walk left out as unreadable
+func (r LoadReport) skippedDirectories() int {
+	n := 0
+	for _, incident := range r.Incidents {
+		if incident.Op == "read directory" && incident.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// walker reads the dataset through fsys, retrying transient errors with bounded exponential
+// backoff and recording every incident. It is safe for concurrent use by the decoding workers.
+type walker struct {
+	fsys    datasetFS
+	retries int
+	skip    bool
+	sleep   func(time.Duration)
+	breaker *CircuitBreaker // Guards every image load when set
+
+	mu     sync.Mutex
+	report LoadReport
+}
+
+// newWalker returns a walker over fsys with the retry budget and skip policy of cfg
+func newWalker(cfg BenchmarkConfig, fsys datasetFS) *walker {
+	return &walker{
+		fsys:    fsys,
+		retries: cfg.IORetries,
+		skip:    cfg.SkipUnreadable,
+		sleep:   time.Sleep,
+		report:  LoadReport{RetryBudget: cfg.IORetries},
+	}
+}
+
+// Report returns the incidents recorded so far
+func (w *walker) Report() LoadReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	report := w.report
+	report.Incidents = append([]fsIncident(nil), w.report.Incidents...)
+	return report
+}
+
+// do runs fn, retrying transient failures up to the retry budget. A failure that remains is
+// recorded as skipped when the skip policy allows it; the caller then leaves the entry out.
+func (w *walker) do(op, path string, fn func() error) error {
+	backoff := initialRetryBackoff
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if lastErr != nil {
+				w.record(fsIncident{Op: op, Path: path, Err: lastErr, Attempts: attempt, Recovered: true})
+			}
+			return nil
+		}
+		lastErr = err
+		if !isTransient(err) || attempt > w.retries {
+			w.record(fsIncident{Op: op, Path: path, Err: err, Attempts: attempt, Skipped: w.skip})
+			return err
+		}
+
+		w.mu.Lock()
+		w.report.Retries++
+		w.mu.Unlock()
+		w.sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// record appends an incident to the report
+func (w *walker) record(incident fsIncident) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.report.Incidents = append(w.report.Incidents, incident)
+}
+
+// collectImagePaths returns the .jpg and .png files under root in lexical walk order. Under the
+// skip policy an unreadable subdirectory is left out; the root itself must always be readable.
+func (w *walker) collectImagePaths(root string) ([]string, error) {
+	var paths []string
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		var entries []fs.DirEntry
+		err := w.do("read directory", dir, func() (err error) {
+			entries, err = w.fsys.ReadDir(dir)
+			return err
+		})
+		if err != nil {
+			if w.skip && dir != root {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if err := walk(path); err != nil {
+					return err
+				}
+			} else if filepath.Ext(path) == ".jpg" || filepath.Ext(path) == ".png" {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root); err != nil {
+		return nil, fmt.Errorf("failed to walk through dataset directory: %v", err)
+	}
+	return paths, nil
+}
+
+// loadImage opens and decodes imagePath, retrying transient failures of either step. Errors are
+// wrapped with %w only when a step fails, so a successful load allocates none; see
+// BenchmarkFmtErrorfWrap for why a sentinel error is not worth losing the cause over. With a
+// breaker, an image whose retries are exhausted counts as one failure, and an open breaker
+// returns ErrCircuitOpen without touching the file.
+func (w *walker) loadImage(cfg BenchmarkConfig, imagePath string) ([]float32, string, error) {
+	if w.breaker == nil {
+		return w.loadImageOnce(cfg, imagePath)
+	}
+	var pixels []float32
+	var label string
+	err := w.breaker.Do(func() (err error) {
+		pixels, label, err = w.loadImageOnce(cfg, imagePath)
+		return err
+	})
+	return pixels, label, err
+}
+
+// loadImageOnce is loadImage without the breaker
+func (w *walker) loadImageOnce(cfg BenchmarkConfig, imagePath string) ([]float32, string, error) {
+	var pixels []float32
+	var label string
+	err := w.do("load image", imagePath, func() error {
+		file, err := w.fsys.Open(imagePath)
+		if err != nil {
+			return fmt.Errorf("failed to open image: %w", err)
+		}
+		defer file.Close()
+		pixels, label, err = decodeImage(cfg, file, imagePath)
+		return err
+	})
+	return pixels, label, err
+}
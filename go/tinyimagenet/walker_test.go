@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// scriptedFS reads from the real filesystem but first fails each scripted path with its queued
+// errors, one per call. A path scripted with always fails every call with that error.
+type scriptedFS struct {
+	mu      sync.Mutex
+	errs    map[string][]error
+	always  map[string]error
+	calls   map[string]int
+	backing osFS
+}
+
+func newScriptedFS() *scriptedFS {
+	return &scriptedFS{errs: map[string][]error{}, always: map[string]error{}, calls: map[string]int{}}
+}
+
+// next returns the scripted error for the next call on name, or nil to let it through
+func (s *scriptedFS) next(op, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[name]++
+	if err, ok := s.always[name]; ok {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if queued := s.errs[name]; len(queued) > 0 {
+		s.errs[name] = queued[1:]
+		return &fs.PathError{Op: op, Path: name, Err: queued[0]}
+	}
+	return nil
+}
+
+func (s *scriptedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := s.next("readdirent", name); err != nil {
+		return nil, err
+	}
+	return s.backing.ReadDir(name)
+}
+
+func (s *scriptedFS) Open(name string) (io.ReadCloser, error) {
+	if err := s.next("open", name); err != nil {
+		return nil, err
+	}
+	return s.backing.Open(name)
+}
+
+// scriptedWalker returns a walker over fsys that records its backoff sleeps instead of sleeping
+func scriptedWalker(cfg BenchmarkConfig, fsys datasetFS) (*walker, *[]time.Duration) {
+	w := newWalker(cfg, fsys)
+	var mu sync.Mutex
+	var sleeps []time.Duration
+	w.sleep = func(d time.Duration) {
+		mu.Lock()
+		sleeps = append(sleeps, d)
+		mu.Unlock()
+	}
+	return w, &sleeps
+}
+
+func TestWalkerRecoversFromTransientErrors(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 3, 5)
+	fsys := newScriptedFS()
+	image := filepath.Join(dataDir, "n01", "img_002.png")
+	fsys.errs[image] = []error{syscall.EIO, syscall.EAGAIN}
+	fsys.errs[filepath.Join(dataDir, "n02")] = []error{syscall.ESTALE}
+
+	cfg := testImageConfig()
+	w, sleeps := scriptedWalker(cfg, fsys)
+	images, labels, err := loadWithWalker(cfg, w, dataDir, 4)
+	if err != nil {
+		t.Fatalf("Expected transient errors to be retried, got %v", err)
+	}
+	if len(images) != 15 || len(labels) != 15 {
+		t.Errorf("Expected the full dataset of 15 images, got %d", len(images))
+	}
+	if fsys.calls[image] != 3 {
+		t.Errorf("Expected 3 attempts at %s, got %d", image, fsys.calls[image])
+	}
+
+	report := w.Report()
+	if report.Retries != 3 {
+		t.Errorf("Expected 3 retries, got %d", report.Retries)
+	}
+	m := report.Metadata()
+	if m.Incidents != 2 || m.Recovered != 2 || m.TransientErrors != 2 || m.Skipped != 0 {
+		t.Errorf("Expected 2 recovered transient incidents, got %+v", m)
+	}
+	for _, d := range *sleeps {
+		if d != initialRetryBackoff && d != 2*initialRetryBackoff {
+			t.Errorf("Unexpected backoff %v", d)
+		}
+	}
+}
+
+func TestWalkerBackoffIsBounded(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 1, 1)
+	fsys := newScriptedFS()
+	image := filepath.Join(dataDir, "n00", "img_000.png")
+	fsys.always[image] = syscall.EIO
+
+	cfg := DefaultConfig()
+	cfg.IORetries = 6
+	w, sleeps := scriptedWalker(cfg, fsys)
+	if _, _, err := w.loadImage(cfg, image); !errors.Is(err, syscall.EIO) {
+		t.Fatalf("Expected EIO once the retries run out, got %v", err)
+	}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond, 2 * time.Second}
+	if !reflect.DeepEqual(*sleeps, expected) {
+		t.Errorf("Expected backoffs %v, got %v", expected, *sleeps)
+	}
+	if fsys.calls[image] != 7 {
+		t.Errorf("Expected 7 attempts, got %d", fsys.calls[image])
+	}
+}
+
+func TestWalkerSkipsFailedEntries(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 3, 5)
+	fsys := newScriptedFS()
+	missing := filepath.Join(dataDir, "n00", "img_001.png")
+	flaky := filepath.Join(dataDir, "n00", "img_003.png")
+	fsys.always[missing] = syscall.ENOENT
+	fsys.always[flaky] = syscall.EAGAIN
+	fsys.always[filepath.Join(dataDir, "n01")] = syscall.EACCES
+
+	cfg := testImageConfig()
+	cfg.IORetries = 2
+	cfg.SkipUnreadable = true
+	w, _ := scriptedWalker(cfg, fsys)
+	images, labels, err := loadWithWalker(cfg, w, dataDir, 4)
+	if err != nil {
+		t.Fatalf("Expected failed entries to be skipped, got %v", err)
+	}
+
+	// n01 is unreadable and two images of n00 fail: 15 - 5 - 2 remain, in walk order
+	if len(images) != 8 {
+		t.Fatalf("Expected 8 images, got %d", len(images))
+	}
+	expectedLabels := []string{"n00", "n00", "n00", "n02", "n02", "n02", "n02", "n02"}
+	if !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("Expected labels %v, got %v", expectedLabels, labels)
+	}
+	if fsys.calls[missing] != 1 {
+		t.Errorf("Expected a permanent error not to be retried, got %d attempts", fsys.calls[missing])
+	}
+	if fsys.calls[flaky] != 3 {
+		t.Errorf("Expected a transient error to use the retry budget, got %d attempts", fsys.calls[flaky])
+	}
+
+	m := w.Report().Metadata()
+	expected := struct{ incidents, transient, permanent, skipped, retries int }{3, 1, 2, 3, 2}
+	got := struct{ incidents, transient, permanent, skipped, retries int }{m.Incidents, m.TransientErrors, m.PermanentErrors, m.Skipped, m.Retries}
+	if got != expected {
+		t.Errorf("Expected incident counts %+v, got %+v", expected, got)
+	}
+	if m.RetryBudget != 2 {
+		t.Errorf("Expected retry budget 2 in the metadata, got %d", m.RetryBudget)
+	}
+
+	lines := w.Report().Lines()
+	if len(lines) != 4 || !strings.Contains(lines[0], "3 skipped") {
+		t.Errorf("Expected a summary and one line per incident, got %v", lines)
+	}
+}
+
+func TestWalkerFailsWithoutSkipPolicy(t *testing.T) {
+	dataDir := t.TempDir()
+	writeTestImages(t, dataDir, 2, 2)
+
+	cases := map[string]string{
+		"unreadable image":     filepath.Join(dataDir, "n00", "img_001.png"),
+		"unreadable directory": filepath.Join(dataDir, "n01"),
+	}
+	for name, path := range cases {
+		fsys := newScriptedFS()
+		fsys.always[path] = syscall.EACCES
+		cfg := DefaultConfig()
+		w, _ := scriptedWalker(cfg, fsys)
+		if _, _, err := loadWithWalker(cfg, w, dataDir, 2); err == nil {
+			t.Errorf("%s: expected the load to fail without -skip-unreadable", name)
+		}
+	}
+
+	// The root is required even under the skip policy
+	fsys := newScriptedFS()
+	fsys.always[dataDir] = syscall.ENOENT
+	cfg := DefaultConfig()
+	cfg.SkipUnreadable = true
+	w, _ := scriptedWalker(cfg, fsys)
+	if _, _, err := loadWithWalker(cfg, w, dataDir, 2); err == nil {
+		t.Errorf("Expected an unreadable dataset root to fail the load")
+	}
+}
+
+// errSink keeps the benchmarked errors reachable so their construction is not optimized away
+var errSink error
+
+// BenchmarkFmtErrorfWrap creates errors the way loadImage reports a failed open, wrapping the
+// underlying error with %w so callers can still test it with errors.Is
+func BenchmarkFmtErrorfWrap(b *testing.B) {
+	cause := &fs.PathError{Op: "open", Path: "train/n01443537/images/n01443537_0.JPEG", Err: syscall.EIO}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		errSink = fmt.Errorf("failed to open image: %w", cause)
+	}
+	if !errors.Is(errSink, syscall.EIO) {
+		b.Fatal("Wrapped error lost its cause")
+	}
+}
+
+// BenchmarkErrorsNew creates unwrapped errors with the same message, the cost floor for a
+// freshly allocated error. A pre-allocated sentinel would cost nothing per call but drop the path
+// and cause, which the retry logic in walker.do needs to tell transient failures apart.
+func BenchmarkErrorsNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		errSink = errors.New("failed to open image")
+	}
+}
+
+// walkBenchmarkDir returns the Tiny ImageNet training split when it is available, otherwise a
+// generated tree of the same layout: 200 class directories of images/ with 50 empty PNGs each
+func walkBenchmarkDir(b *testing.B) string {
+	b.Helper()
+	if _, err := os.Stat(defaultDataDir); err == nil {
+		return defaultDataDir
+	}
+	root := b.TempDir()
+	for class := 0; class < 200; class++ {
+		dir := filepath.Join(root, fmt.Sprintf("n%08d", class), "images")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		for i := 0; i < 50; i++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("n%08d_%d.png", class, i)), nil, 0644); err != nil {
+				b.Fatalf("Failed to write image: %v", err)
+			}
+		}
+	}
+	return root
+}
+
+// pathsSink keeps the benchmarked path lists reachable so the walks are not optimized away
+var pathsSink []string
+
+// BenchmarkCollectImagePaths compares listing the training split with filepath.Walk, which
+// calls os.Lstat on every entry, filepath.WalkDir, which passes fs.DirEntry values, and the
+// retrying walker that LoadTinyImageNet uses, which reads each directory with os.ReadDir
+func BenchmarkCollectImagePaths(b *testing.B) {
+	root := walkBenchmarkDir(b)
+	isImage := func(path string) bool { return filepath.Ext(path) == ".jpg" || filepath.Ext(path) == ".png" }
+
+	b.Run("filepath.Walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var paths []string
+			err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+				if err == nil && !info.IsDir() && isImage(path) {
+					paths = append(paths, path)
+				}
+				return err
+			})
+			if err != nil {
+				b.Fatalf("Walk failed: %v", err)
+			}
+			pathsSink = paths
+		}
+	})
+	b.Run("filepath.WalkDir", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var paths []string
+			err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() && isImage(path) {
+					paths = append(paths, path)
+				}
+				return err
+			})
+			if err != nil {
+				b.Fatalf("WalkDir failed: %v", err)
+			}
+			pathsSink = paths
+		}
+	})
+	b.Run("walker", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			paths, err := collectImagePaths(DefaultConfig(), root)
+			if err != nil {
+				b.Fatalf("collectImagePaths failed: %v", err)
+			}
+			pathsSink = paths
+		}
+	})
+}
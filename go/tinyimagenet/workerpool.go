@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang/internal/backpressure"
+)
+
+// errPoolClosed is returned by Submit once Wait has been called
+var errPoolClosed = errors.New("worker pool is closed")
+
+// BatchResult aggregates the work done by a BoundedWorkerPool
+type BatchResult struct {
+	Batches            int
+	ImagesProcessed    int
+	ProcessingTime     time.Duration // Sum of the time workers spent processing batches
+	Elapsed            time.Duration // From the creation of the pool until Wait returned
+	BackpressureEvents int           // Number of Submit calls that blocked on a full queue
+	BlockedTime        time.Duration // Total time Submit spent blocked
+}
+
+// BoundedWorkerPool processes batches on a fixed number of goroutines fed from a queue of bounded
+// depth, so a fast producer is held back by the workers instead of queueing the whole dataset
+type BoundedWorkerPool struct {
+	cfg   BenchmarkConfig
+	queue *backpressure.Queue[ImageBatch]
+	start time.Time
+	wg    sync.WaitGroup
+
+	// closeMu lets Wait close the queue only once no Submit is in progress
+	closeMu sync.RWMutex
+	closed  bool
+
+	mu     sync.Mutex
+	result BatchResult
+}
+
+// NewBoundedWorkerPool starts numWorkers goroutines that process submitted batches with the
+// kernel selected by cfg. At most queueDepth batches wait for a free worker.
+func NewBoundedWorkerPool(cfg BenchmarkConfig, numWorkers, queueDepth int) *BoundedWorkerPool {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	p := &BoundedWorkerPool{
+		cfg:   cfg,
+		queue: backpressure.NewQueue[ImageBatch](queueDepth),
+		start: time.Now(),
+	}
+	p.wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// work processes batches from the queue until it is closed and drained
+func (p *BoundedWorkerPool) work() {
+	defer p.wg.Done()
+	for {
+		batch, ok := p.queue.Get()
+		if !ok {
+			return
+		}
+		start := time.Now()
+		for i, image := range batch.Images {
+			batch.Images[i] = ProcessImage(p.cfg, image)
+		}
+		elapsed := time.Since(start)
+
+		p.mu.Lock()
+		p.result.Batches++
+		p.result.ImagesProcessed += len(batch.Images)
+		p.result.ProcessingTime += elapsed
+		p.mu.Unlock()
+	}
+}
+
+// Submit queues batch for processing, blocking while the queue is full. It returns an error once
+// Wait has been called.
+func (p *BoundedWorkerPool) Submit(batch ImageBatch) error {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		return errPoolClosed
+	}
+	p.queue.Put(batch)
+	return nil
+}
+
+// Wait stops accepting batches, waits for the queued ones to be processed and returns the totals
+func (p *BoundedWorkerPool) Wait() BatchResult {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		p.queue.Close()
+	}
+	p.closeMu.Unlock()
+	p.wg.Wait()
+
+	stats := p.queue.Stats()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := p.result
+	result.Elapsed = time.Since(p.start)
+	result.BackpressureEvents = stats.BlockedPuts
+	result.BlockedTime = stats.ProducerBlocked
+	return result
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBoundedWorkerPool(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ImageHeight, cfg.ImageWidth, cfg.Channels = 64, 64, 3
+	cfg.Kernel = KernelBlur
+	cfg.BatchSize, cfg.SyntheticImages = 8, 80
+	images, labels, err := loadDataset(cfg, "")
+	if err != nil {
+		t.Fatalf("Failed to generate synthetic dataset: %v", err)
+	}
+
+	// One slow worker and room for one waiting batch: submitting ten at once must block
+	pool := NewBoundedWorkerPool(cfg, 1, 1)
+	for start := 0; start < len(images); start += cfg.BatchSize {
+		batch := ImageBatch{Images: images[start : start+cfg.BatchSize], Labels: labels[start : start+cfg.BatchSize]}
+		if err := pool.Submit(batch); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	result := pool.Wait()
+
+	if result.Batches != 10 || result.ImagesProcessed != 80 {
+		t.Errorf("Expected 10 batches and 80 images, got %d and %d", result.Batches, result.ImagesProcessed)
+	}
+	if result.BackpressureEvents == 0 {
+		t.Errorf("Expected Submit to block on the full queue")
+	}
+	if err := pool.Submit(ImageBatch{}); !errors.Is(err, errPoolClosed) {
+		t.Errorf("Expected errPoolClosed after Wait, got %v", err)
+	}
+}